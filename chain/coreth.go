@@ -60,6 +60,10 @@ func (self *ETHChain) GenerateBlock() (*types.Block, error) {
 	return self.backend.Miner().GenerateBlock()
 }
 
+func (self *ETHChain) GenerateBlockAt(timestamp uint64) (*types.Block, error) {
+	return self.backend.Miner().GenerateBlockAt(timestamp)
+}
+
 func (self *ETHChain) BlockChain() *core.BlockChain {
 	return self.backend.BlockChain()
 }
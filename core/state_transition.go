@@ -318,7 +318,7 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	sender := vm.AccountRef(msg.From())
 	homestead := st.evm.ChainConfig().IsHomestead(st.evm.Context.BlockNumber)
 	istanbul := st.evm.ChainConfig().IsIstanbul(st.evm.Context.BlockNumber)
-	apricotPhase1 := st.evm.ChainConfig().IsApricotPhase1(st.evm.Context.Time)
+	refundMode := st.evm.ChainConfig().RefundRule(st.evm.Context.Time)
 
 	contractCreation := msg.To() == nil
 
@@ -398,7 +398,7 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 		}
 
 	}
-	st.refundGas(apricotPhase1)
+	st.refundGas(refundMode)
 	if vmerr == nil && prioritisedFTSOContract {
 		nominalGasUsed := uint64(21000)
 		nominalGasPrice := uint64(225_000_000_000)
@@ -435,9 +435,9 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	}, nil
 }
 
-func (st *StateTransition) refundGas(apricotPhase1 bool) {
+func (st *StateTransition) refundGas(refundMode params.RefundMode) {
 	// Inspired by: https://gist.github.com/holiman/460f952716a74eeb9ab358bb1836d821#gistcomment-3642048
-	if !apricotPhase1 {
+	if refundMode == params.FullRefund {
 		// Apply refund counter, capped to half of the used gas.
 		refund := st.gasUsed() / 2
 		if refund > st.state.GetRefund() {
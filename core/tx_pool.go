@@ -96,6 +96,11 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrUnprotectedTx is returned if a transaction without EIP-155 replay
+	// protection is submitted after EIP-155 activates on a chain that does not
+	// allow unprotected transactions.
+	ErrUnprotectedTx = errors.New("only replay-protected (EIP-155) transactions allowed")
 )
 
 var (
@@ -256,6 +261,7 @@ type TxPool struct {
 	signer      types.Signer
 	mu          sync.RWMutex
 
+	eip155   bool // Fork indicator whether we are in the EIP-155 stage.
 	istanbul bool // Fork indicator whether we are in the istanbul stage.
 	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
 	eip1559  bool // Fork indicator whether we are using EIP-1559 type transactions.
@@ -653,6 +659,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if !pool.eip1559 && tx.Type() == types.DynamicFeeTxType {
 		return ErrTxTypeNotSupported
 	}
+	// Reject unprotected transactions after EIP-155 unless the chain allows them.
+	if pool.eip155 && !pool.chainconfig.AllowUnprotectedTxs && !tx.Protected() {
+		return ErrUnprotectedTx
+	}
 	// Reject transactions over defined size to prevent DOS attacks
 	if uint64(tx.Size()) > txMaxSize {
 		return ErrOversizedData
@@ -1385,6 +1395,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 
 	// Update all fork indicator by next pending block number.
 	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
+	pool.eip155 = pool.chainconfig.IsEIP155(next)
 	pool.istanbul = pool.chainconfig.IsIstanbul(next)
 
 	timestamp := new(big.Int).SetUint64(newHead.Time)
@@ -413,6 +413,47 @@ func TestTransactionNegativeValue(t *testing.T) {
 	}
 }
 
+func TestTransactionUnprotected(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		allowed       bool
+		expectedError error
+	}{
+		{name: "unprotected txs allowed", allowed: true, expectedError: nil},
+		{name: "unprotected txs rejected", allowed: false, expectedError: ErrUnprotectedTx},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			config := *params.TestChainConfig
+			config.AllowUnprotectedTxs = test.allowed
+
+			pool, key := setupTxPoolWithConfig(&config)
+			defer pool.Stop()
+
+			// transaction signs with the Homestead signer, so it carries no
+			// chain ID.
+			unprotected := transaction(0, 100000, key)
+			if unprotected.Protected() {
+				t.Fatal("expected transaction to be unprotected")
+			}
+			from, _ := deriveSender(unprotected)
+			testAddBalance(pool, from, big.NewInt(1000000))
+			if err := pool.addRemoteSync(unprotected); err != test.expectedError {
+				t.Fatalf("expected %v, got %v", test.expectedError, err)
+			}
+
+			// Replay-protected transactions are admitted either way.
+			protected, _ := types.SignTx(types.NewTransaction(pool.Nonce(from), common.Address{}, big.NewInt(100), 100000, big.NewInt(1), nil), types.NewEIP155Signer(config.ChainID), key)
+			if err := pool.addRemoteSync(protected); err != nil {
+				t.Fatalf("expected protected transaction to be admitted, got %v", err)
+			}
+		})
+	}
+}
+
 func TestTransactionTipAboveFeeCap(t *testing.T) {
 	t.Parallel()
 
@@ -19,9 +19,9 @@ import (
 // BLS12-381 Curve Operations added to the set of precompiled contracts
 
 var (
-	genesisContractAddr    = common.HexToAddress("0x0100000000000000000000000000000000000000")
-	nativeAssetBalanceAddr = common.HexToAddress("0x0100000000000000000000000000000000000001")
-	nativeAssetCallAddr    = common.HexToAddress("0x0100000000000000000000000000000000000002")
+	genesisContractAddr    = params.GenesisContractAddr
+	nativeAssetBalanceAddr = params.NativeAssetBalanceAddr
+	nativeAssetCallAddr    = params.NativeAssetCallAddr
 )
 
 // StatefulPrecompiledContract is the interface for executing a precompiled contract
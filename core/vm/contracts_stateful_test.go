@@ -15,6 +15,20 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestStatefulPrecompileRegistry(t *testing.T) {
+	for addr, p := range PrecompiledContractsApricotPhase2 {
+		var stateful bool
+		switch p.(type) {
+		case *wrappedPrecompiledContract, *deprecatedContract:
+		default:
+			stateful = true
+		}
+		if params.TestChainConfig.IsStatefulPrecompile(addr) != stateful {
+			t.Errorf("Expected IsStatefulPrecompile(%s) to be %t", addr.Hex(), stateful)
+		}
+	}
+}
+
 func TestPrecompiledContractSpendsGas(t *testing.T) {
 	unwrapped := &sha256hash{}
 
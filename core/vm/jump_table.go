@@ -74,6 +74,45 @@ var (
 // JumpTable contains the EVM opcodes supported at a given fork.
 type JumpTable [256]*operation
 
+// instructionSetFor returns the instruction set in effect under [rules].
+func instructionSetFor(rules params.Rules) JumpTable {
+	switch {
+	case rules.IsApricotPhase3:
+		return apricotPhase3InstructionSet
+	case rules.IsApricotPhase2:
+		return apricotPhase2InstructionSet
+	case rules.IsApricotPhase1:
+		return apricotPhase1InstructionSet
+	case rules.IsIstanbul:
+		return istanbulInstructionSet
+	case rules.IsConstantinople:
+		return constantinopleInstructionSet
+	case rules.IsByzantium:
+		return byzantiumInstructionSet
+	case rules.IsEIP158:
+		return spuriousDragonInstructionSet
+	case rules.IsEIP150:
+		return tangerineWhistleInstructionSet
+	case rules.IsHomestead:
+		return homesteadInstructionSet
+	default:
+		return frontierInstructionSet
+	}
+}
+
+// OpGasCost returns the gas charged for executing [op] under [rules], so that
+// gas can be estimated offline. It returns false if [op] is not defined under
+// [rules], or if its cost depends on the execution, such as the memory it
+// expands or, as of Apricot Phase 2, whether the storage or account it
+// accesses is warm (EIP-2929).
+func OpGasCost(rules params.Rules, op OpCode) (uint64, bool) {
+	operation := instructionSetFor(rules)[op]
+	if operation == nil || operation.dynamicGas != nil {
+		return 0, false
+	}
+	return operation.constantGas, true
+}
+
 // newApricotPhase3InstructionSet returns the frontier, homestead, byzantium,
 // contantinople, istanbul, petersburg, apricotPhase1, 2, and 3 instructions.
 func newApricotPhase3InstructionSet() JumpTable {
@@ -0,0 +1,44 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/flare-foundation/coreth/params"
+)
+
+func TestOpGasCost(t *testing.T) {
+	apricotPhase1Rules := params.TestApricotPhase1Config.AvalancheRules(new(big.Int), new(big.Int))
+	apricotPhase2Rules := params.TestApricotPhase2Config.AvalancheRules(new(big.Int), new(big.Int))
+	apricotPhase3Rules := params.TestApricotPhase3Config.AvalancheRules(new(big.Int), new(big.Int))
+
+	tests := []struct {
+		name        string
+		rules       params.Rules
+		op          OpCode
+		expectedGas uint64
+		expectedOK  bool
+	}{
+		// SLOAD and BALANCE are repriced by EIP-1884 in Istanbul, and their
+		// cost depends on whether they access warm state as of Apricot Phase 2.
+		{"SLOAD before AP2", apricotPhase1Rules, SLOAD, params.SloadGasEIP2200, true},
+		{"SLOAD after AP2", apricotPhase2Rules, SLOAD, 0, false},
+		{"BALANCE before AP2", apricotPhase1Rules, BALANCE, params.BalanceGasEIP1884, true},
+		{"BALANCE after AP2", apricotPhase2Rules, BALANCE, 0, false},
+		{"ADD after AP2", apricotPhase2Rules, ADD, GasFastestStep, true},
+		// BASEFEE is only defined as of Apricot Phase 3.
+		{"BASEFEE before AP3", apricotPhase2Rules, BASEFEE, 0, false},
+		{"BASEFEE after AP3", apricotPhase3Rules, BASEFEE, GasQuickStep, true},
+		// The cost of MSTORE depends on the memory it expands.
+		{"MSTORE", apricotPhase2Rules, MSTORE, 0, false},
+	}
+	for _, test := range tests {
+		gas, ok := OpGasCost(test.rules, test.op)
+		if gas != test.expectedGas || ok != test.expectedOK {
+			t.Errorf("%s: expected (%d, %t), found (%d, %t)", test.name, test.expectedGas, test.expectedOK, gas, ok)
+		}
+	}
+}
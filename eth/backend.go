@@ -195,13 +195,19 @@ func New(stack *node.Node, config *Config,
 
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine)
 
+	// The transaction pool rejects unprotected transactions unless the chain
+	// config allows them, so the node option can only restrict them further.
+	allowUnprotectedTxs := config.AllowUnprotectedTxs && chainConfig.AllowUnprotectedTxs
 	eth.APIBackend = &EthAPIBackend{
 		extRPCEnabled:       stack.Config().ExtRPCEnabled(),
-		allowUnprotectedTxs: config.AllowUnprotectedTxs,
+		allowUnprotectedTxs: allowUnprotectedTxs,
 		eth:                 eth,
 	}
-	if config.AllowUnprotectedTxs {
+	switch {
+	case allowUnprotectedTxs:
 		log.Info("Unprotected transactions allowed")
+	case config.AllowUnprotectedTxs:
+		log.Warn("Unprotected transactions are not allowed by the chain config, ignoring the node option")
 	}
 	gpoParams := config.GPO
 	eth.APIBackend.gpo = gasprice.NewOracle(eth.APIBackend, gpoParams)
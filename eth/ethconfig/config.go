@@ -148,6 +148,7 @@ type Config struct {
 
 	// AllowUnprotectedTxs allow unprotected transactions to be locally issued.
 	// Unprotected transactions are transactions that are signed without EIP-155
-	// replay protection.
+	// replay protection. It has no effect unless the chain config also allows
+	// them, since the transaction pool rejects them otherwise.
 	AllowUnprotectedTxs bool
 }
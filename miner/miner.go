@@ -62,7 +62,13 @@ func (miner *Miner) SetEtherbase(addr common.Address) {
 }
 
 func (miner *Miner) GenerateBlock() (*types.Block, error) {
-	return miner.worker.commitNewWork()
+	return miner.worker.commitNewWork(nil)
+}
+
+// GenerateBlockAt generates a block on top of the current block with the
+// given [timestamp], which must be after the timestamp of the current block.
+func (miner *Miner) GenerateBlockAt(timestamp uint64) (*types.Block, error) {
+	return miner.worker.commitNewWork(&timestamp)
 }
 
 // SubscribePendingLogs starts delivering logs from pending transactions
@@ -104,17 +104,24 @@ func (w *worker) setEtherbase(addr common.Address) {
 }
 
 // commitNewWork generates several new sealing tasks based on the parent block.
-func (w *worker) commitNewWork() (*types.Block, error) {
+// If [blockTimestamp] is nil, the timestamp is derived from the current time.
+func (w *worker) commitNewWork(blockTimestamp *uint64) (*types.Block, error) {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
 	tstart := time.Now()
 	timestamp := tstart.Unix()
 	parent := w.chain.CurrentBlock()
+	switch {
+	case blockTimestamp != nil:
+		if *blockTimestamp <= parent.Time() {
+			return nil, fmt.Errorf("block timestamp %d is not after parent timestamp %d", *blockTimestamp, parent.Time())
+		}
+		timestamp = int64(*blockTimestamp)
 	// Note: in order to support asynchronous block production, blocks are allowed to have
 	// the same timestamp as their parent. This allows more than one block to be produced
 	// per second.
-	if parent.Time() >= uint64(timestamp) {
+	case parent.Time() >= uint64(timestamp):
 		timestamp = int64(parent.Time())
 	}
 
@@ -24,4 +24,25 @@ var (
 	ApricotPhase3InitialBaseFee int64 = 225_000_000_000
 	ApricotPhase4MinBaseFee     int64 = 25_000_000_000
 	ApricotPhase4MaxBaseFee     int64 = 1_000_000_000_000
+
+	// DefaultMaxAtomicInputs and DefaultMaxAtomicOutputs bound the number of
+	// inputs and outputs of a single atomic tx when the chain config does not
+	// specify its own limits.
+	DefaultMaxAtomicInputs  uint64 = 1024
+	DefaultMaxAtomicOutputs uint64 = 1024
+
+	// ApricotPhase5AtomicTxMaxSize is the maximum size, in bytes, of a signed
+	// atomic tx as of Apricot Phase 5, which matches the maximum size of the
+	// EVM txs admitted by the tx pool.
+	ApricotPhase5AtomicTxMaxSize = 128 * units.KiB
+
+	// DefaultMinAtomicFeePerGas is the minimum fee, in nAVAX per unit of gas,
+	// atomic transactions must burn on the public networks. It matches
+	// ApricotPhase3MinBaseFee converted from wei.
+	DefaultMinAtomicFeePerGas int64 = 75
+
+	// AtomicTxDecimals is the number of decimal places of AVAX in atomic
+	// transactions. Within the EVM, AVAX has EVMDecimals decimal places.
+	AtomicTxDecimals uint8 = 9
+	EVMDecimals      uint8 = 18
 )
@@ -27,9 +27,13 @@
 package params
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -48,6 +52,8 @@ var (
 	LocalChainID = big.NewInt(9_223_372_036_854_775_771)
 
 	errNonGenesisForkByHeight = errors.New("coreth only supports forking by height at the genesis block")
+	errConfigTrailingData     = errors.New("chain config contains trailing data")
+	errUnknownRuleFlag        = errors.New("unknown rule flag")
 )
 
 var (
@@ -70,6 +76,7 @@ var (
 		ApricotPhase2BlockTimestamp: big.NewInt(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()),
 		ApricotPhase3BlockTimestamp: big.NewInt(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()),
 		ApricotPhase4BlockTimestamp: big.NewInt(time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()),
+		MinAtomicFeePerGas:          big.NewInt(DefaultMinAtomicFeePerGas),
 	}
 
 	// SongbirdChainConfig is the configuration for the Fuji Test Network
@@ -91,6 +98,7 @@ var (
 		ApricotPhase2BlockTimestamp: big.NewInt(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()),
 		ApricotPhase3BlockTimestamp: big.NewInt(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()),
 		ApricotPhase4BlockTimestamp: big.NewInt(time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()),
+		MinAtomicFeePerGas:          big.NewInt(DefaultMinAtomicFeePerGas),
 	}
 
 	// CostonChainConfig is the configuration for the Fuji Test Network
@@ -112,6 +120,7 @@ var (
 		ApricotPhase2BlockTimestamp: big.NewInt(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()),
 		ApricotPhase3BlockTimestamp: big.NewInt(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()),
 		ApricotPhase4BlockTimestamp: big.NewInt(time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()),
+		MinAtomicFeePerGas:          big.NewInt(DefaultMinAtomicFeePerGas),
 	}
 
 	// FlareLocalChainConfig is the configuration for the Avalanche Local Network
@@ -133,14 +142,17 @@ var (
 		ApricotPhase2BlockTimestamp: big.NewInt(0),
 		ApricotPhase3BlockTimestamp: big.NewInt(0),
 		ApricotPhase4BlockTimestamp: big.NewInt(0),
+		AtomicTxsTimestamp:          big.NewInt(0),
+		AllowUnprotectedTxs:         true,
 	}
 
-	TestChainConfig         = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0)}
-	TestLaunchConfig        = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil}
-	TestApricotPhase1Config = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil}
-	TestApricotPhase2Config = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil}
-	TestApricotPhase3Config = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil}
-	TestApricotPhase4Config = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0)}
+	TestChainConfig         = TestConfigWithPhases(4)
+	TestLaunchConfig        = TestConfigWithPhases(0)
+	TestApricotPhase1Config = TestConfigWithPhases(1)
+	TestApricotPhase2Config = TestConfigWithPhases(2)
+	TestApricotPhase3Config = TestConfigWithPhases(3)
+	TestApricotPhase4Config = TestConfigWithPhases(4)
+	TestApricotPhase5Config = TestConfigWithPhases(5)
 	TestRules               = TestChainConfig.AvalancheRules(new(big.Int), new(big.Int))
 )
 
@@ -149,58 +161,117 @@ var (
 // ChainConfig is stored in the database on a per block basis. This means
 // that any network, identified by its genesis block, can have its own
 // set of configuration options.
+//
+// Every fork activation point is tagged with `fork:"<name>,<block|timestamp>"`,
+// optionally followed by ",optional" if later forks may be enabled while it is
+// not, or by ",unordered" if it may be scheduled independently of the other
+// forks. Forks are expected in activation order.
 type ChainConfig struct {
 	ChainID *big.Int `json:"chainId"` // chainId identifies the current chain and is used for replay protection
 
-	HomesteadBlock *big.Int `json:"homesteadBlock,omitempty"` // Homestead switch block (nil = no fork, 0 = already homestead)
+	HomesteadBlock *big.Int `json:"homesteadBlock,omitempty" fork:"Homestead,block"` // Homestead switch block (nil = no fork, 0 = already homestead)
 
-	DAOForkBlock   *big.Int `json:"daoForkBlock,omitempty"`   // TheDAO hard-fork switch block (nil = no fork)
-	DAOForkSupport bool     `json:"daoForkSupport,omitempty"` // Whether the nodes supports or opposes the DAO hard-fork
+	DAOForkBlock   *big.Int `json:"daoForkBlock,omitempty" fork:"DAO,block,optional"` // TheDAO hard-fork switch block (nil = no fork)
+	DAOForkSupport bool     `json:"daoForkSupport,omitempty"`                         // Whether the nodes supports or opposes the DAO hard-fork
 
 	// EIP150 implements the Gas price changes (https://github.com/ethereum/EIPs/issues/150)
-	EIP150Block *big.Int    `json:"eip150Block,omitempty"` // EIP150 HF block (nil = no fork)
-	EIP150Hash  common.Hash `json:"eip150Hash,omitempty"`  // EIP150 HF hash (needed for header only clients as only gas pricing changed)
+	EIP150Block *big.Int    `json:"eip150Block,omitempty" fork:"EIP150,block"` // EIP150 HF block (nil = no fork)
+	EIP150Hash  common.Hash `json:"eip150Hash,omitempty"`                      // EIP150 HF hash (needed for header only clients as only gas pricing changed)
 
-	EIP155Block *big.Int `json:"eip155Block,omitempty"` // EIP155 HF block
-	EIP158Block *big.Int `json:"eip158Block,omitempty"` // EIP158 HF block
+	EIP155Block *big.Int `json:"eip155Block,omitempty" fork:"EIP155,block"` // EIP155 HF block
+	EIP158Block *big.Int `json:"eip158Block,omitempty" fork:"EIP158,block"` // EIP158 HF block
 
-	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
-	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
-	PetersburgBlock     *big.Int `json:"petersburgBlock,omitempty"`     // Petersburg switch block (nil = same as Constantinople)
-	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty"`       // Istanbul switch block (nil = no fork, 0 = already on istanbul)
-	MuirGlacierBlock    *big.Int `json:"muirGlacierBlock,omitempty"`    // Eip-2384 (bomb delay) switch block (nil = no fork, 0 = already activated)
+	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty" fork:"Byzantium,block"`               // Byzantium switch block (nil = no fork, 0 = already on byzantium)
+	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty" fork:"Constantinople,block"`     // Constantinople switch block (nil = no fork, 0 = already activated)
+	PetersburgBlock     *big.Int `json:"petersburgBlock,omitempty" fork:"Petersburg,block"`             // Petersburg switch block (nil = same as Constantinople)
+	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty" fork:"Istanbul,block"`                 // Istanbul switch block (nil = no fork, 0 = already on istanbul)
+	MuirGlacierBlock    *big.Int `json:"muirGlacierBlock,omitempty" fork:"Muir Glacier,block,optional"` // Eip-2384 (bomb delay) switch block (nil = no fork, 0 = already activated)
 
 	// Avalanche Network Upgrades
-	ApricotPhase1BlockTimestamp *big.Int `json:"apricotPhase1BlockTimestamp,omitempty"` // Apricot Phase 1 Block Timestamp (nil = no fork, 0 = already activated)
+	ApricotPhase1BlockTimestamp *big.Int `json:"apricotPhase1BlockTimestamp,omitempty" fork:"Apricot Phase 1,timestamp"` // Apricot Phase 1 Block Timestamp (nil = no fork, 0 = already activated)
 	// Apricot Phase 2 Block Timestamp (nil = no fork, 0 = already activated)
 	// Apricot Phase 2 includes a modified version of the Berlin Hard Fork from Ethereum
-	ApricotPhase2BlockTimestamp *big.Int `json:"apricotPhase2BlockTimestamp,omitempty"`
+	ApricotPhase2BlockTimestamp *big.Int `json:"apricotPhase2BlockTimestamp,omitempty" fork:"Apricot Phase 2,timestamp"`
 	// Apricot Phase 3 introduces dynamic fees and a modified version of the London Hard Fork from Ethereum (nil = no fork, 0 = already activated)
-	ApricotPhase3BlockTimestamp *big.Int `json:"apricotPhase3BlockTimestamp,omitempty"`
+	ApricotPhase3BlockTimestamp *big.Int `json:"apricotPhase3BlockTimestamp,omitempty" fork:"Apricot Phase 3,timestamp"`
 	// Apricot Phase 4 introduces the notion of a block fee to the dynamic fee algorithm (nil = no fork, 0 = already activated)
-	ApricotPhase4BlockTimestamp *big.Int `json:"apricotPhase4BlockTimestamp,omitempty"`
+	ApricotPhase4BlockTimestamp *big.Int `json:"apricotPhase4BlockTimestamp,omitempty" fork:"Apricot Phase 4,timestamp"`
+	// Apricot Phase 5 Block Timestamp (nil = no fork, 0 = already activated)
+	// It is optional so that the Flare upgrades below may be scheduled without it
+	ApricotPhase5BlockTimestamp *big.Int `json:"apricotPhase5BlockTimestamp,omitempty" fork:"Apricot Phase 5,timestamp,optional"`
+
+	// Fee Manager Activation Timestamp (nil = no fork, 0 = already activated)
+	// Once active, the dynamic fee parameters are read from the on-chain fee manager
+	FeeManagerActivationTimestamp *big.Int `json:"feeManagerActivationTimestamp,omitempty" fork:"Fee manager,timestamp"`
+
+	// Sponsored Import Timestamp (nil = no fork, 0 = already activated)
+	// Once active, the fee of an import tx may be paid by a sponsor account on this chain
+	SponsoredImportTimestamp *big.Int `json:"sponsoredImportTimestamp,omitempty" fork:"Sponsored import,timestamp,optional"`
+
+	// Atomic Txs Timestamp (nil = no fork, 0 = already activated)
+	// Once active, funds may be imported and exported through atomic txs
+	AtomicTxsTimestamp *big.Int `json:"atomicTxsTimestamp,omitempty" fork:"Atomic txs,timestamp,unordered"`
+
+	// AtomicFeeRecipient, if set, receives the fees paid by atomic transactions once
+	// Apricot Phase 3 is active instead of having them burned (nil = burn fees)
+	AtomicFeeRecipient *common.Address `json:"atomicFeeRecipient,omitempty"`
+
+	// MaxAtomicInputs and MaxAtomicOutputs limit the number of inputs and outputs
+	// of a single atomic transaction (0 = use the default limit)
+	MaxAtomicInputs  uint64 `json:"maxAtomicInputs,omitempty"`
+	MaxAtomicOutputs uint64 `json:"maxAtomicOutputs,omitempty"`
+
+	// AllowUnprotectedTxs permits the transaction pool to admit transactions
+	// without EIP-155 replay protection once EIP-155 is active. When false, it
+	// takes precedence over the allow-unprotected-txs node option.
+	AllowUnprotectedTxs bool `json:"allowUnprotectedTxs,omitempty"`
+
+	// MinAtomicFeePerGas is the minimum fee, in nAVAX per unit of gas, an atomic
+	// transaction must burn to enter the mempool once Apricot Phase 3 is active
+	// (nil = no minimum)
+	MinAtomicFeePerGas *big.Int `json:"minAtomicFeePerGas,omitempty"`
+
+	// BlockGasCostOverride, if set, replaces the block gas cost parameters of
+	// Apricot Phase 4 until the fee manager is activated (nil = use the
+	// Apricot Phase 4 parameters)
+	BlockGasCostOverride *BlockGasCostConfig `json:"blockGasCostOverride,omitempty"`
+
+	// MaxExportAmount is the maximum amount of AVAX, in nAVAX, a single export
+	// transaction may export (nil = no limit)
+	MaxExportAmount *big.Int `json:"maxExportAmount,omitempty"`
 }
 
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Petersburg: %v Istanbul: %v, Muir Glacier: %v, Apricot Phase 1: %v, Apricot Phase 2: %v, Apricot Phase 3: %v, Apricot Phase 4: %v, Engine: Dummy Consensus Engine}",
-		c.ChainID,
-		c.HomesteadBlock,
-		c.DAOForkBlock,
-		c.DAOForkSupport,
-		c.EIP150Block,
-		c.EIP155Block,
-		c.EIP158Block,
-		c.ByzantiumBlock,
-		c.ConstantinopleBlock,
-		c.PetersburgBlock,
-		c.IstanbulBlock,
-		c.MuirGlacierBlock,
-		c.ApricotPhase1BlockTimestamp,
-		c.ApricotPhase2BlockTimestamp,
-		c.ApricotPhase3BlockTimestamp,
-		c.ApricotPhase4BlockTimestamp,
-	)
+	var b strings.Builder
+	fmt.Fprintf(&b, "{ChainID: %v, DAOSupport: %v", c.ChainID, c.DAOForkSupport)
+	for _, fork := range forkFields(c) {
+		fmt.Fprintf(&b, ", %s: %v", fork.Desc, fork.Ptr)
+	}
+	b.WriteString(", Engine: Dummy Consensus Engine}")
+	return b.String()
+}
+
+// GenesisJSON returns [c] encoded as the "config" object of a genesis file.
+// Forks that are not scheduled are omitted.
+func (c *ChainConfig) GenesisJSON() ([]byte, error) {
+	return json.MarshalIndent(c, "", "  ")
+}
+
+// ParseChainConfigStrict parses a chain config from [data], the "config"
+// object of a genesis file. Unlike json.Unmarshal, it rejects unknown fields
+// and trailing data, so that a misspelled fork is not silently ignored.
+func ParseChainConfigStrict(data []byte) (*ChainConfig, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	config := new(ChainConfig)
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("failed to parse chain config: %w", err)
+	}
+	if decoder.More() {
+		return nil, errConfigTrailingData
+	}
+	return config, nil
 }
 
 // IsHomestead returns whether num is either equal to the homestead block or greater.
@@ -218,6 +289,16 @@ func (c *ChainConfig) IsEIP150(num *big.Int) bool {
 	return isForked(c.EIP150Block, num)
 }
 
+// EIP150HashFor returns the EIP150 hash and true if EIP150 is active at num
+// and the hash is set, so header-only clients can check it. Otherwise, it
+// returns false.
+func (c *ChainConfig) EIP150HashFor(num *big.Int) (common.Hash, bool) {
+	if !c.IsEIP150(num) || c.EIP150Hash == (common.Hash{}) {
+		return common.Hash{}, false
+	}
+	return c.EIP150Hash, true
+}
+
 // IsEIP155 returns whether num is either equal to the EIP155 fork block or greater.
 func (c *ChainConfig) IsEIP155(num *big.Int) bool {
 	return isForked(c.EIP155Block, num)
@@ -275,12 +356,132 @@ func (c *ChainConfig) IsApricotPhase3(blockTimestamp *big.Int) bool {
 	return isForked(c.ApricotPhase3BlockTimestamp, blockTimestamp)
 }
 
+// IsLondonTime returns whether [blockTimestamp] represents a block with the
+// base fee rules of the London upgrade, which Avalanche introduced in Apricot
+// Phase 3. It is equivalent to IsApricotPhase3.
+func (c *ChainConfig) IsLondonTime(blockTimestamp *big.Int) bool {
+	return c.IsApricotPhase3(blockTimestamp)
+}
+
+// IsLondon is a compatibility shim for tooling ported from upstream, which
+// schedules London by block number. Apricot Phase 3 is scheduled by
+// timestamp, so it cannot be looked up from [num] alone: IsLondon returns
+// true only if Apricot Phase 3 is active from genesis, and false otherwise.
+// Callers that know the block timestamp should use IsLondonTime.
+func (c *ChainConfig) IsLondon(num *big.Int) bool {
+	return num != nil && c.IsApricotPhase3(common.Big0)
+}
+
 // IsApricotPhase4 returns whether [blockTimestamp] represents a block
 // with a timestamp after the Apricot Phase 4 upgrade time.
 func (c *ChainConfig) IsApricotPhase4(blockTimestamp *big.Int) bool {
 	return isForked(c.ApricotPhase4BlockTimestamp, blockTimestamp)
 }
 
+// IsApricotPhase5 returns whether [blockTimestamp] represents a block
+// with a timestamp after the Apricot Phase 5 upgrade time.
+func (c *ChainConfig) IsApricotPhase5(blockTimestamp *big.Int) bool {
+	return isForked(c.ApricotPhase5BlockTimestamp, blockTimestamp)
+}
+
+// IsFeeManager returns whether [blockTimestamp] represents a block
+// with a timestamp after the fee manager activation time.
+func (c *ChainConfig) IsFeeManager(blockTimestamp *big.Int) bool {
+	return isForked(c.FeeManagerActivationTimestamp, blockTimestamp)
+}
+
+// IsSponsoredImport returns whether [blockTimestamp] represents a block
+// with a timestamp after the sponsored import activation time.
+func (c *ChainConfig) IsSponsoredImport(blockTimestamp *big.Int) bool {
+	return isForked(c.SponsoredImportTimestamp, blockTimestamp)
+}
+
+// IsAtomicTxs returns whether [blockTimestamp] represents a block
+// with a timestamp after the atomic txs activation time.
+func (c *ChainConfig) IsAtomicTxs(blockTimestamp *big.Int) bool {
+	return isForked(c.AtomicTxsTimestamp, blockTimestamp)
+}
+
+// ActiveForks returns the names of the forks active at the block with
+// [blockNum] and [blockTimestamp], in activation order, such as "Homestead"
+// or "ApricotPhase3".
+func (c *ChainConfig) ActiveForks(blockNum, blockTimestamp *big.Int) []string {
+	var active []string
+	for _, fork := range []struct {
+		id       ForkID
+		isActive bool
+	}{
+		{HomesteadFork, c.IsHomestead(blockNum)},
+		{DAOFork, c.IsDAOFork(blockNum)},
+		{EIP150Fork, c.IsEIP150(blockNum)},
+		{EIP155Fork, c.IsEIP155(blockNum)},
+		{EIP158Fork, c.IsEIP158(blockNum)},
+		{ByzantiumFork, c.IsByzantium(blockNum)},
+		{ConstantinopleFork, c.IsConstantinople(blockNum)},
+		{PetersburgFork, c.IsPetersburg(blockNum)},
+		{IstanbulFork, c.IsIstanbul(blockNum)},
+		{MuirGlacierFork, c.IsMuirGlacier(blockNum)},
+		{ApricotPhase1Fork, c.IsApricotPhase1(blockTimestamp)},
+		{ApricotPhase2Fork, c.IsApricotPhase2(blockTimestamp)},
+		{ApricotPhase3Fork, c.IsApricotPhase3(blockTimestamp)},
+		{ApricotPhase4Fork, c.IsApricotPhase4(blockTimestamp)},
+		{ApricotPhase5Fork, c.IsApricotPhase5(blockTimestamp)},
+		{FeeManagerFork, c.IsFeeManager(blockTimestamp)},
+		{SponsoredImportFork, c.IsSponsoredImport(blockTimestamp)},
+		{AtomicTxsFork, c.IsAtomicTxs(blockTimestamp)},
+	} {
+		if fork.isActive {
+			active = append(active, fork.id.String())
+		}
+	}
+	return active
+}
+
+// RefundMode describes how the refund counter is applied at the end of a
+// transaction.
+type RefundMode int
+
+const (
+	// FullRefund means that the refund counter is returned to the sender,
+	// capped at half of the gas used.
+	FullRefund RefundMode = iota
+	// NoRefund means that the refund counter is ignored.
+	NoRefund
+)
+
+// String implements the fmt.Stringer interface.
+func (m RefundMode) String() string {
+	switch m {
+	case FullRefund:
+		return "FullRefund"
+	case NoRefund:
+		return "NoRefund"
+	default:
+		return fmt.Sprintf("RefundMode(%d)", int(m))
+	}
+}
+
+// RefundRule returns how gas refunds are handled for a block with the given
+// timestamp. Apricot Phase 1 removed gas refunds entirely, rather than
+// reducing them as EIP-3529 does upstream.
+func (c *ChainConfig) RefundRule(blockTimestamp *big.Int) RefundMode {
+	if c.IsApricotPhase1(blockTimestamp) {
+		return NoRefund
+	}
+	return FullRefund
+}
+
+// EffectiveGasPrice returns the price per gas paid by a legacy transaction
+// with [gasPrice] in a block with the given timestamp and [baseFee]. Once
+// Apricot Phase 3 is active, the price is clamped to the base fee. Before
+// that, or if [baseFee] is nil, the gas price is paid in full.
+func (c *ChainConfig) EffectiveGasPrice(baseFee, gasPrice *big.Int, blockTimestamp *big.Int) *big.Int {
+	if c.IsApricotPhase3(blockTimestamp) && baseFee != nil && baseFee.Cmp(gasPrice) < 0 {
+		return new(big.Int).Set(baseFee)
+	}
+	return new(big.Int).Set(gasPrice)
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
@@ -299,6 +500,17 @@ func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *Confi
 	return lasterr
 }
 
+// CheckCompatibleAt is like CheckCompatible, but additionally checks that none
+// of the Apricot Phases already active at [timestamp] have been rescheduled.
+// Timestamp forks cannot be rewound by height, so a timestamp incompatibility
+// is reported with a RewindTo of 0.
+func (c *ChainConfig) CheckCompatibleAt(newcfg *ChainConfig, height uint64, timestamp uint64) *ConfigCompatError {
+	if err := c.CheckCompatible(newcfg, height); err != nil {
+		return err
+	}
+	return c.checkCompatibleTimestamps(newcfg, new(big.Int).SetUint64(timestamp))
+}
+
 // CheckConfigForkOrder checks that we don't "skip" any forks, geth isn't pluggable enough
 // to guarantee that forks can be implemented in a different order than on official networks
 func (c *ChainConfig) CheckConfigForkOrder() error {
@@ -307,19 +519,25 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 		block    *big.Int
 		optional bool // if true, the fork may be nil and next fork is still allowed
 	}
+	var (
+		blockForks     []fork
+		timestampForks []fork
+	)
+	for _, f := range forkFields(c) {
+		if f.Unordered {
+			// Unordered forks may be scheduled independently of the others
+			continue
+		}
+		cur := fork{name: f.Name, block: f.Ptr, optional: f.Optional}
+		if f.Timestamp {
+			timestampForks = append(timestampForks, cur)
+		} else {
+			blockForks = append(blockForks, cur)
+		}
+	}
+
 	var lastFork fork
-	for _, cur := range []fork{
-		{name: "homesteadBlock", block: c.HomesteadBlock},
-		{name: "daoForkBlock", block: c.DAOForkBlock, optional: true},
-		{name: "eip150Block", block: c.EIP150Block},
-		{name: "eip155Block", block: c.EIP155Block},
-		{name: "eip158Block", block: c.EIP158Block},
-		{name: "byzantiumBlock", block: c.ByzantiumBlock},
-		{name: "constantinopleBlock", block: c.ConstantinopleBlock},
-		{name: "petersburgBlock", block: c.PetersburgBlock},
-		{name: "istanbulBlock", block: c.IstanbulBlock},
-		{name: "muirGlacierBlock", block: c.MuirGlacierBlock, optional: true},
-	} {
+	for _, cur := range blockForks {
 		if cur.block != nil && common.Big0.Cmp(cur.block) != 0 {
 			return errNonGenesisForkByHeight
 		}
@@ -349,12 +567,7 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 	// the block number forks since it would not be a meaningful comparison.
 	// Instead, we check only that Apricot Phases are enabled in order.
 	lastFork = fork{}
-	for _, cur := range []fork{
-		{name: "apricotPhase1BlockTimestamp", block: c.ApricotPhase1BlockTimestamp},
-		{name: "apricotPhase2BlockTimestamp", block: c.ApricotPhase2BlockTimestamp},
-		{name: "apricotPhase3BlockTimestamp", block: c.ApricotPhase3BlockTimestamp},
-		{name: "apricotPhase4BlockTimestamp", block: c.ApricotPhase4BlockTimestamp},
-	} {
+	for _, cur := range timestampForks {
 		if lastFork.name != "" {
 			// Next one must be higher number
 			if lastFork.block == nil && cur.block != nil {
@@ -374,57 +587,55 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 		}
 	}
 	// TODO(aaronbuchwald) check that avalanche block timestamps are at least possible with the other rule set changes
-	// additional change: require that block number hard forks are either 0 or nil since they should not
-	// be enabled at a specific block number.
+	// Note: the requirement that block number hard forks are either 0 or nil is
+	// checked above and is exposed as EthForksNeutralized.
 
 	return nil
 }
 
 func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
-	if isForkIncompatible(c.HomesteadBlock, newcfg.HomesteadBlock, head) {
-		return newCompatError("Homestead fork block", c.HomesteadBlock, newcfg.HomesteadBlock)
-	}
-	if isForkIncompatible(c.DAOForkBlock, newcfg.DAOForkBlock, head) {
-		return newCompatError("DAO fork block", c.DAOForkBlock, newcfg.DAOForkBlock)
-	}
-	if c.IsDAOFork(head) && c.DAOForkSupport != newcfg.DAOForkSupport {
-		return newCompatError("DAO fork support flag", c.DAOForkBlock, newcfg.DAOForkBlock)
-	}
-	if isForkIncompatible(c.EIP150Block, newcfg.EIP150Block, head) {
-		return newCompatError("EIP150 fork block", c.EIP150Block, newcfg.EIP150Block)
-	}
-	if isForkIncompatible(c.EIP155Block, newcfg.EIP155Block, head) {
-		return newCompatError("EIP155 fork block", c.EIP155Block, newcfg.EIP155Block)
-	}
-	if isForkIncompatible(c.EIP158Block, newcfg.EIP158Block, head) {
-		return newCompatError("EIP158 fork block", c.EIP158Block, newcfg.EIP158Block)
-	}
-	if c.IsEIP158(head) && !configNumEqual(c.ChainID, newcfg.ChainID) {
-		return newCompatError("EIP158 chain ID", c.EIP158Block, newcfg.EIP158Block)
-	}
-	if isForkIncompatible(c.ByzantiumBlock, newcfg.ByzantiumBlock, head) {
-		return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
-	}
-	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
-		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
-	}
-	if isForkIncompatible(c.PetersburgBlock, newcfg.PetersburgBlock, head) {
-		// the only case where we allow Petersburg to be set in the past is if it is equal to Constantinople
-		// mainly to satisfy fork ordering requirements which state that Petersburg fork be set if Constantinople fork is set
-		if isForkIncompatible(c.ConstantinopleBlock, newcfg.PetersburgBlock, head) {
-			return newCompatError("Petersburg fork block", c.PetersburgBlock, newcfg.PetersburgBlock)
+	newForks := forkFields(newcfg)
+	for i, fork := range forkFields(c) {
+		if fork.Timestamp {
+			continue
+		}
+		newFork := newForks[i]
+		if isForkIncompatible(fork.Ptr, newFork.Ptr, head) {
+			// the only case where we allow Petersburg to be set in the past is if it is equal to Constantinople
+			// mainly to satisfy fork ordering requirements which state that Petersburg fork be set if Constantinople fork is set
+			if fork.Name != "petersburgBlock" || isForkIncompatible(c.ConstantinopleBlock, newcfg.PetersburgBlock, head) {
+				return newCompatError(fork.Desc+" fork block", fork.Ptr, newFork.Ptr)
+			}
+		}
+		switch fork.Name {
+		case "daoForkBlock":
+			if c.IsDAOFork(head) && c.DAOForkSupport != newcfg.DAOForkSupport {
+				return newCompatError("DAO fork support flag", c.DAOForkBlock, newcfg.DAOForkBlock)
+			}
+		case "eip158Block":
+			if c.IsEIP158(head) && !configNumEqual(c.ChainID, newcfg.ChainID) {
+				return newCompatError("EIP158 chain ID", c.EIP158Block, newcfg.EIP158Block)
+			}
 		}
-	}
-	if isForkIncompatible(c.IstanbulBlock, newcfg.IstanbulBlock, head) {
-		return newCompatError("Istanbul fork block", c.IstanbulBlock, newcfg.IstanbulBlock)
-	}
-	if isForkIncompatible(c.MuirGlacierBlock, newcfg.MuirGlacierBlock, head) {
-		return newCompatError("Muir Glacier fork block", c.MuirGlacierBlock, newcfg.MuirGlacierBlock)
 	}
 	// TODO(aaronbuchwald) ensure that Avalanche Blocktimestamps are not modified
 	return nil
 }
 
+func (c *ChainConfig) checkCompatibleTimestamps(newcfg *ChainConfig, timestamp *big.Int) *ConfigCompatError {
+	newForks := forkFields(newcfg)
+	for i, fork := range forkFields(c) {
+		if !fork.Timestamp {
+			continue
+		}
+		newFork := newForks[i]
+		if isForkIncompatible(fork.Ptr, newFork.Ptr, timestamp) {
+			return &ConfigCompatError{What: fork.Desc + " fork timestamp", StoredConfig: fork.Ptr, NewConfig: newFork.Ptr}
+		}
+	}
+	return nil
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2, head *big.Int) bool {
@@ -495,6 +706,35 @@ type Rules struct {
 	IsApricotPhase2 bool
 	IsApricotPhase3 bool
 	IsApricotPhase4 bool
+	IsApricotPhase5 bool
+
+	// IsFeeManager is set once the dynamic fee parameters are read from the
+	// on-chain fee manager.
+	IsFeeManager bool
+
+	// IsSponsoredImport is set once the fee of an import tx may be paid by a
+	// sponsor account.
+	IsSponsoredImport bool
+
+	// IsAtomicTxs is set once funds may be imported and exported through
+	// atomic txs.
+	IsAtomicTxs bool
+
+	// AtomicFeeRecipient is the address credited with atomic transaction fees,
+	// or nil if the fees are burned.
+	AtomicFeeRecipient *common.Address
+
+	// MaxAtomicInputs and MaxAtomicOutputs limit the number of inputs and
+	// outputs of a single atomic transaction. AvalancheRules sets them to the
+	// limits of the chain config, or to DefaultMaxAtomicInputs and
+	// DefaultMaxAtomicOutputs if it sets none, so they are only 0 (= no limit)
+	// in Rules built by hand.
+	MaxAtomicInputs  uint64
+	MaxAtomicOutputs uint64
+
+	// MaxExportAmount is the maximum amount of AVAX, in nAVAX, a single export
+	// transaction may export, or nil if there is no limit.
+	MaxExportAmount *big.Int
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -525,5 +765,35 @@ func (c *ChainConfig) AvalancheRules(blockNum, blockTimestamp *big.Int) Rules {
 	rules.IsApricotPhase2 = c.IsApricotPhase2(blockTimestamp)
 	rules.IsApricotPhase3 = c.IsApricotPhase3(blockTimestamp)
 	rules.IsApricotPhase4 = c.IsApricotPhase4(blockTimestamp)
+	rules.IsApricotPhase5 = c.IsApricotPhase5(blockTimestamp)
+	rules.IsFeeManager = c.IsFeeManager(blockTimestamp)
+	rules.IsSponsoredImport = c.IsSponsoredImport(blockTimestamp)
+	rules.IsAtomicTxs = c.IsAtomicTxs(blockTimestamp)
+	if rules.IsApricotPhase3 && c.AtomicFeeRecipient != nil {
+		recipient := *c.AtomicFeeRecipient
+		rules.AtomicFeeRecipient = &recipient
+	}
+	rules.MaxAtomicInputs = c.MaxAtomicInputs
+	if rules.MaxAtomicInputs == 0 {
+		rules.MaxAtomicInputs = DefaultMaxAtomicInputs
+	}
+	rules.MaxAtomicOutputs = c.MaxAtomicOutputs
+	if rules.MaxAtomicOutputs == 0 {
+		rules.MaxAtomicOutputs = DefaultMaxAtomicOutputs
+	}
+	if c.MaxExportAmount != nil {
+		rules.MaxExportAmount = new(big.Int).Set(c.MaxExportAmount)
+	}
 	return rules
 }
+
+// With returns a copy of [r] with the boolean rule named [flag], such as
+// "IsApricotPhase3", set to [value].
+func (r Rules) With(flag string, value bool) (Rules, error) {
+	field := reflect.ValueOf(&r).Elem().FieldByName(flag)
+	if !field.IsValid() || field.Kind() != reflect.Bool {
+		return Rules{}, fmt.Errorf("%w: %q", errUnknownRuleFlag, flag)
+	}
+	field.SetBool(value)
+	return r, nil
+}
@@ -0,0 +1,91 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// apricotPhaseForks lists the Apricot Phases in activation order.
+var apricotPhaseForks = []ForkID{
+	ApricotPhase1Fork,
+	ApricotPhase2Fork,
+	ApricotPhase3Fork,
+	ApricotPhase4Fork,
+	ApricotPhase5Fork,
+}
+
+// ChainConfigOption sets a field of a ChainConfig built by NewChainConfig.
+type ChainConfigOption func(*ChainConfig)
+
+// NewChainConfig returns a ChainConfig without any fork scheduled, modified by
+// each of [opts] in order.
+func NewChainConfig(opts ...ChainConfigOption) *ChainConfig {
+	c := &ChainConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithChainID sets the chain ID of the config.
+func WithChainID(chainID *big.Int) ChainConfigOption {
+	return func(c *ChainConfig) {
+		c.ChainID = new(big.Int).Set(chainID)
+	}
+}
+
+// WithFork schedules [id] at [at], which is a block number for the Ethereum
+// forks and a block timestamp for the Apricot Phases. It panics if [id] is
+// unknown.
+func WithFork(id ForkID, at *big.Int) ChainConfigOption {
+	return func(c *ChainConfig) {
+		point, err := c.forkPoint(id)
+		if err != nil {
+			panic(err)
+		}
+		*point = new(big.Int).Set(at)
+	}
+}
+
+// WithAllowUnprotectedTxs sets whether the config allows transactions without
+// EIP-155 replay protection.
+func WithAllowUnprotectedTxs(allow bool) ChainConfigOption {
+	return func(c *ChainConfig) {
+		c.AllowUnprotectedTxs = allow
+	}
+}
+
+// TestConfigWithPhases returns a test config with chain ID 1, every Ethereum
+// fork other than the DAO fork activated at genesis and the first [phases]
+// Apricot Phases activated at timestamp 0. It panics if [phases] is not
+// between 0 and the number of Apricot Phases.
+func TestConfigWithPhases(phases int) *ChainConfig {
+	if phases < 0 || phases > len(apricotPhaseForks) {
+		panic(fmt.Sprintf("invalid number of Apricot Phases: %d", phases))
+	}
+
+	opts := []ChainConfigOption{
+		WithChainID(big.NewInt(1)),
+		WithAllowUnprotectedTxs(true),
+	}
+	for _, id := range []ForkID{
+		HomesteadFork,
+		EIP150Fork,
+		EIP155Fork,
+		EIP158Fork,
+		ByzantiumFork,
+		ConstantinopleFork,
+		PetersburgFork,
+		IstanbulFork,
+		MuirGlacierFork,
+	} {
+		opts = append(opts, WithFork(id, big.NewInt(0)))
+	}
+	for _, id := range apricotPhaseForks[:phases] {
+		opts = append(opts, WithFork(id, big.NewInt(0)))
+	}
+	return NewChainConfig(opts...)
+}
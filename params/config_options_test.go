@@ -0,0 +1,62 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestConfigWithPhasesMatchesLiteral(t *testing.T) {
+	expected := &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, 0, 0, true, nil, nil, nil}
+	if config := TestConfigWithPhases(3); !reflect.DeepEqual(config, expected) {
+		t.Fatalf("Expected %s, found %s", expected, config)
+	}
+}
+
+func TestConfigWithPhasesSchedule(t *testing.T) {
+	for phases := 0; phases <= len(apricotPhaseForks); phases++ {
+		config := TestConfigWithPhases(phases)
+		if err := config.CheckConfigForkOrder(); err != nil {
+			t.Fatalf("Expected config with %d phases to have a valid fork order, found %s", phases, err)
+		}
+		for i, id := range apricotPhaseForks {
+			point, err := config.forkPoint(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if active := *point != nil; active != (i < phases) {
+				t.Fatalf("Expected %s to be scheduled = %t in config with %d phases", id, i < phases, phases)
+			}
+		}
+	}
+
+	// Each call returns a distinct config, so tests can modify it.
+	if TestConfigWithPhases(4) == TestConfigWithPhases(4) {
+		t.Fatal("Expected distinct configs")
+	}
+}
+
+func TestNewChainConfigOptions(t *testing.T) {
+	config := NewChainConfig(
+		WithChainID(big.NewInt(5)),
+		WithFork(ApricotPhase1Fork, big.NewInt(10)),
+		WithAllowUnprotectedTxs(true),
+	)
+	if config.ChainID.Cmp(big.NewInt(5)) != 0 {
+		t.Fatalf("Expected chain ID 5, found %s", config.ChainID)
+	}
+	if config.ApricotPhase1BlockTimestamp.Cmp(big.NewInt(10)) != 0 {
+		t.Fatalf("Expected Apricot Phase 1 at 10, found %s", config.ApricotPhase1BlockTimestamp)
+	}
+	if config.HomesteadBlock != nil {
+		t.Fatalf("Expected Homestead to be unscheduled, found %s", config.HomesteadBlock)
+	}
+	if !config.AllowUnprotectedTxs {
+		t.Fatal("Expected unprotected txs to be allowed")
+	}
+}
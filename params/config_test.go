@@ -0,0 +1,479 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestRefundRule(t *testing.T) {
+	config := *TestLaunchConfig
+	config.ApricotPhase1BlockTimestamp = big.NewInt(10)
+
+	tests := []struct {
+		timestamp int64
+		expected  RefundMode
+	}{
+		{timestamp: 0, expected: FullRefund},
+		{timestamp: 9, expected: FullRefund},
+		{timestamp: 10, expected: NoRefund},
+		{timestamp: 11, expected: NoRefund},
+	}
+	for _, test := range tests {
+		if mode := config.RefundRule(big.NewInt(test.timestamp)); mode != test.expected {
+			t.Errorf("Expected refund mode %s at timestamp %d, found %s", test.expected, test.timestamp, mode)
+		}
+	}
+}
+
+func TestAvalancheRulesMaxAtomicInputsOutputs(t *testing.T) {
+	rules := TestChainConfig.AvalancheRules(common.Big0, common.Big0)
+	if rules.MaxAtomicInputs != DefaultMaxAtomicInputs || rules.MaxAtomicOutputs != DefaultMaxAtomicOutputs {
+		t.Fatalf("Expected default atomic limits (%d, %d), found (%d, %d)", DefaultMaxAtomicInputs, DefaultMaxAtomicOutputs, rules.MaxAtomicInputs, rules.MaxAtomicOutputs)
+	}
+
+	config := *TestChainConfig
+	config.MaxAtomicInputs = 5
+	config.MaxAtomicOutputs = 7
+	rules = config.AvalancheRules(common.Big0, common.Big0)
+	if rules.MaxAtomicInputs != 5 || rules.MaxAtomicOutputs != 7 {
+		t.Fatalf("Expected configured atomic limits (5, 7), found (%d, %d)", rules.MaxAtomicInputs, rules.MaxAtomicOutputs)
+	}
+}
+
+func TestCheckCompatibleAtTimestamps(t *testing.T) {
+	stored := *TestApricotPhase2Config
+	stored.ApricotPhase3BlockTimestamp = big.NewInt(50)
+	stored.ApricotPhase4BlockTimestamp = big.NewInt(1000)
+
+	tests := map[string]struct {
+		apricotPhase3 *big.Int
+		apricotPhase4 *big.Int
+		expectedWhat  string
+	}{
+		"unchanged": {
+			apricotPhase3: big.NewInt(50),
+			apricotPhase4: big.NewInt(1000),
+		},
+		"pending fork moved earlier": {
+			apricotPhase3: big.NewInt(50),
+			apricotPhase4: big.NewInt(500),
+		},
+		"active fork moved earlier": {
+			apricotPhase3: big.NewInt(10),
+			apricotPhase4: big.NewInt(1000),
+			expectedWhat:  "Apricot Phase 3 fork timestamp",
+		},
+		"active fork moved later": {
+			apricotPhase3: big.NewInt(150),
+			apricotPhase4: big.NewInt(1000),
+			expectedWhat:  "Apricot Phase 3 fork timestamp",
+		},
+		"pending fork moved into the past": {
+			apricotPhase3: big.NewInt(50),
+			apricotPhase4: big.NewInt(90),
+			expectedWhat:  "Apricot Phase 4 fork timestamp",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			newcfg := stored
+			newcfg.ApricotPhase3BlockTimestamp = test.apricotPhase3
+			newcfg.ApricotPhase4BlockTimestamp = test.apricotPhase4
+
+			err := stored.CheckCompatibleAt(&newcfg, 0, 100)
+			switch {
+			case test.expectedWhat == "" && err != nil:
+				t.Fatalf("Expected configs to be compatible, found %s", err)
+			case test.expectedWhat != "" && err == nil:
+				t.Fatalf("Expected incompatible %s, but configs were compatible", test.expectedWhat)
+			case test.expectedWhat != "" && err.What != test.expectedWhat:
+				t.Fatalf("Expected incompatible %s, found %s", test.expectedWhat, err.What)
+			}
+		})
+	}
+}
+
+func TestEIP150HashFor(t *testing.T) {
+	hash := common.HexToHash("0x2086799aeebeae135c246c65021c82b4e15a2c451340993aacfd2751886514f0")
+	config := *TestChainConfig
+	config.EIP150Block = big.NewInt(10)
+	config.EIP150Hash = hash
+
+	if _, ok := config.EIP150HashFor(big.NewInt(9)); ok {
+		t.Fatal("Expected no EIP150 hash before EIP150 activates")
+	}
+	got, ok := config.EIP150HashFor(big.NewInt(10))
+	if !ok || got != hash {
+		t.Fatalf("Expected EIP150 hash %s once EIP150 activates, found %s (%t)", hash, got, ok)
+	}
+
+	config.EIP150Hash = common.Hash{}
+	if _, ok := config.EIP150HashFor(big.NewInt(10)); ok {
+		t.Fatal("Expected no EIP150 hash when the hash is not set")
+	}
+}
+
+func TestGenesisJSON(t *testing.T) {
+	recipient := common.HexToAddress("0x0100000000000000000000000000000000000000")
+	config := *TestApricotPhase4Config
+	config.FeeManagerActivationTimestamp = big.NewInt(100)
+	config.AtomicFeeRecipient = &recipient
+	config.MaxAtomicInputs = 10
+
+	genesisJSON, err := config.GenesisJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseChainConfigStrict(genesisJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-encoding the parsed config must reproduce the original output.
+	reencoded, err := parsed.GenesisJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(genesisJSON, reencoded) {
+		t.Fatalf("Expected re-encoded config to equal\n%s\nfound\n%s", genesisJSON, reencoded)
+	}
+	if parsed.ChainID.Cmp(config.ChainID) != 0 || parsed.ForkHash() != config.ForkHash() {
+		t.Fatalf("Expected parsed config %s to equal %s", parsed, &config)
+	}
+	if *parsed.AtomicFeeRecipient != recipient || parsed.MaxAtomicInputs != 10 {
+		t.Fatalf("Parsed config %s lost non-fork fields", parsed)
+	}
+
+	// Forks that aren't scheduled are omitted.
+	config.FeeManagerActivationTimestamp = nil
+	genesisJSON, err = config.GenesisJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(genesisJSON, []byte("feeManagerActivationTimestamp")) {
+		t.Fatalf("Expected unscheduled fork to be omitted from %s", genesisJSON)
+	}
+	if parsed, err = ParseChainConfigStrict(genesisJSON); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.FeeManagerActivationTimestamp != nil {
+		t.Fatalf("Expected unscheduled fork to remain unscheduled, found %d", parsed.FeeManagerActivationTimestamp)
+	}
+
+	if _, err := ParseChainConfigStrict([]byte(`{"chainId":1,"apricotPhase3Timestamp":0}`)); err == nil {
+		t.Fatal("Expected unknown field to be rejected")
+	}
+	if _, err := ParseChainConfigStrict([]byte(`{"chainId":1}{}`)); !errors.Is(err, errConfigTrailingData) {
+		t.Fatalf("Expected %s, found %v", errConfigTrailingData, err)
+	}
+}
+
+func TestEffectiveGasPrice(t *testing.T) {
+	config := *TestApricotPhase2Config
+	config.ApricotPhase3BlockTimestamp = big.NewInt(10)
+
+	tests := []struct {
+		name      string
+		timestamp int64
+		baseFee   *big.Int
+		gasPrice  *big.Int
+		expected  *big.Int
+	}{
+		{name: "pre-AP3", timestamp: 9, baseFee: big.NewInt(100), gasPrice: big.NewInt(150), expected: big.NewInt(150)},
+		{name: "pre-AP3 without base fee", timestamp: 9, baseFee: nil, gasPrice: big.NewInt(150), expected: big.NewInt(150)},
+		{name: "AP3 above base fee", timestamp: 10, baseFee: big.NewInt(100), gasPrice: big.NewInt(150), expected: big.NewInt(100)},
+		{name: "AP3 below base fee", timestamp: 10, baseFee: big.NewInt(100), gasPrice: big.NewInt(50), expected: big.NewInt(50)},
+		{name: "AP3 without base fee", timestamp: 10, baseFee: nil, gasPrice: big.NewInt(150), expected: big.NewInt(150)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			price := config.EffectiveGasPrice(test.baseFee, test.gasPrice, big.NewInt(test.timestamp))
+			if price.Cmp(test.expected) != 0 {
+				t.Fatalf("Expected effective gas price %d, found %d", test.expected, price)
+			}
+			if price == test.gasPrice || price == test.baseFee {
+				t.Fatal("Expected effective gas price to be a copy")
+			}
+		})
+	}
+}
+
+func TestRulesWith(t *testing.T) {
+	rules := TestApricotPhase2Config.AvalancheRules(common.Big0, common.Big0)
+	if rules.IsApricotPhase3 {
+		t.Fatal("Expected Apricot Phase 3 to be inactive")
+	}
+
+	phase3, err := rules.With("IsApricotPhase3", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !phase3.IsApricotPhase3 {
+		t.Fatal("Expected Apricot Phase 3 to be forced on")
+	}
+	if rules.IsApricotPhase3 {
+		t.Fatal("Expected the original rules to be left unmodified")
+	}
+	if !phase3.IsApricotPhase2 || phase3.ChainID.Cmp(rules.ChainID) != 0 {
+		t.Fatal("Expected the other rules to be copied")
+	}
+
+	phase2, err := phase3.With("IsApricotPhase3", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if phase2.IsApricotPhase3 {
+		t.Fatal("Expected Apricot Phase 3 to be forced off")
+	}
+
+	for _, flag := range []string{"IsApricotPhase9", "ChainID", "MaxAtomicInputs", ""} {
+		if _, err := rules.With(flag, true); !errors.Is(err, errUnknownRuleFlag) {
+			t.Fatalf("Expected %s for flag %q, found %v", errUnknownRuleFlag, flag, err)
+		}
+	}
+}
+
+func TestIsLondon(t *testing.T) {
+	// London maps to Apricot Phase 3, which is scheduled by timestamp.
+	config := TestConfigWithPhases(2)
+	config.ApricotPhase3BlockTimestamp = big.NewInt(100)
+	if config.IsLondonTime(big.NewInt(99)) {
+		t.Fatal("Expected London to be inactive before Apricot Phase 3")
+	}
+	if !config.IsLondonTime(big.NewInt(100)) {
+		t.Fatal("Expected London to be active with Apricot Phase 3")
+	}
+	// A block number does not determine whether a later Apricot Phase 3 is
+	// active, so IsLondon conservatively reports it inactive.
+	if config.IsLondon(big.NewInt(1_000_000)) {
+		t.Fatal("Expected London to be inactive by number when Apricot Phase 3 is scheduled after genesis")
+	}
+
+	// If Apricot Phase 3 is active from genesis, so is London at every block.
+	config = TestConfigWithPhases(3)
+	if !config.IsLondon(big.NewInt(0)) || !config.IsLondon(big.NewInt(1_000_000)) {
+		t.Fatal("Expected London to be active by number when Apricot Phase 3 is active from genesis")
+	}
+	if !config.IsLondonTime(big.NewInt(0)) {
+		t.Fatal("Expected London to be active at genesis")
+	}
+
+	config = TestConfigWithPhases(2)
+	if config.IsLondon(big.NewInt(0)) || config.IsLondonTime(big.NewInt(1_000_000)) {
+		t.Fatal("Expected London to be inactive without Apricot Phase 3")
+	}
+}
+
+func TestApricotPhase5(t *testing.T) {
+	config := TestConfigWithPhases(4)
+	config.ApricotPhase5BlockTimestamp = big.NewInt(100)
+	if err := config.CheckConfigForkOrder(); err != nil {
+		t.Fatalf("Expected Apricot Phase 5 after Apricot Phase 4 to be valid, found %s", err)
+	}
+	if config.IsApricotPhase5(big.NewInt(99)) || config.AvalancheRules(common.Big0, big.NewInt(99)).IsApricotPhase5 {
+		t.Fatal("Expected Apricot Phase 5 to be inactive before its timestamp")
+	}
+	if !config.IsApricotPhase5(big.NewInt(100)) || !config.AvalancheRules(common.Big0, big.NewInt(100)).IsApricotPhase5 {
+		t.Fatal("Expected Apricot Phase 5 to be active at its timestamp")
+	}
+
+	// Apricot Phase 5 is optional, so the later forks may be scheduled
+	// without it.
+	config = TestConfigWithPhases(4)
+	config.FeeManagerActivationTimestamp = big.NewInt(10)
+	if err := config.CheckConfigForkOrder(); err != nil {
+		t.Fatalf("Expected Apricot Phase 4 without Apricot Phase 5 to be valid, found %s", err)
+	}
+	if config.IsApricotPhase5(big.NewInt(1_000_000)) {
+		t.Fatal("Expected unscheduled Apricot Phase 5 to be inactive")
+	}
+
+	config = TestConfigWithPhases(3)
+	config.ApricotPhase5BlockTimestamp = big.NewInt(10)
+	if err := config.CheckConfigForkOrder(); err == nil {
+		t.Fatal("Expected Apricot Phase 5 without Apricot Phase 4 to be invalid")
+	}
+
+	config = TestConfigWithPhases(3)
+	config.ApricotPhase4BlockTimestamp = big.NewInt(20)
+	config.ApricotPhase5BlockTimestamp = big.NewInt(10)
+	if err := config.CheckConfigForkOrder(); err == nil {
+		t.Fatal("Expected Apricot Phase 5 before Apricot Phase 4 to be invalid")
+	}
+
+	if !strings.Contains(TestApricotPhase5Config.String(), "Apricot Phase 5") {
+		t.Fatalf("Expected config description to include Apricot Phase 5, found %s", TestApricotPhase5Config)
+	}
+}
+
+func TestActiveForks(t *testing.T) {
+	// The Flare config with the Apricot Phases scheduled before genesis
+	// activated at genesis.
+	config := *FlareChainConfig
+	config.ReconcileWithGenesisTime(uint64(time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()))
+
+	expected := []string{
+		"Homestead",
+		"DAOFork",
+		"EIP150",
+		"EIP155",
+		"EIP158",
+		"Byzantium",
+		"Constantinople",
+		"Petersburg",
+		"Istanbul",
+		"MuirGlacier",
+		"ApricotPhase1",
+		"ApricotPhase2",
+		"ApricotPhase3",
+	}
+	if active := config.ActiveForks(common.Big0, common.Big0); !reflect.DeepEqual(active, expected) {
+		t.Fatalf("Expected active forks %v, found %v", expected, active)
+	}
+
+	expected = append(expected, "ApricotPhase4")
+	if active := config.ActiveForks(common.Big0, config.ApricotPhase4BlockTimestamp); !reflect.DeepEqual(active, expected) {
+		t.Fatalf("Expected active forks %v, found %v", expected, active)
+	}
+
+	if active := NewChainConfig().ActiveForks(common.Big0, common.Big0); len(active) != 0 {
+		t.Fatalf("Expected no active forks, found %v", active)
+	}
+}
+
+func TestAtomicTxs(t *testing.T) {
+	config := TestConfigWithPhases(4)
+	config.AtomicTxsTimestamp = big.NewInt(100)
+	if config.IsAtomicTxs(big.NewInt(99)) || config.AvalancheRules(common.Big0, big.NewInt(99)).IsAtomicTxs {
+		t.Fatal("Expected atomic txs to be inactive before their timestamp")
+	}
+	if !config.IsAtomicTxs(big.NewInt(100)) || !config.AvalancheRules(common.Big0, big.NewInt(100)).IsAtomicTxs {
+		t.Fatal("Expected atomic txs to be active at their timestamp")
+	}
+
+	// Atomic txs are unordered, so they may be scheduled before, after or
+	// without the Apricot Phases.
+	for _, timestamp := range []int64{0, 10, 1_000} {
+		config := TestConfigWithPhases(0)
+		config.ApricotPhase1BlockTimestamp = big.NewInt(50)
+		config.AtomicTxsTimestamp = big.NewInt(timestamp)
+		if err := config.CheckConfigForkOrder(); err != nil {
+			t.Fatalf("Expected atomic txs at %d to be valid, found %s", timestamp, err)
+		}
+	}
+	config = TestConfigWithPhases(4)
+	if config.IsAtomicTxs(big.NewInt(1_000_000)) {
+		t.Fatal("Expected unscheduled atomic txs to be inactive")
+	}
+}
+
+// assertRulesMonotonic checks that the boolean flags of the rules of [c] never
+// turn off again once they are on. The rules are sampled on a grid of heights
+// and timestamps around every fork activation point of [c], and each flag must
+// be monotonic along both dimensions: for increasing timestamps at every
+// height, and for increasing heights at every timestamp.
+func assertRulesMonotonic(c *ChainConfig, t *testing.T) {
+	t.Helper()
+
+	heights := []*big.Int{common.Big0}
+	timestamps := []*big.Int{common.Big0}
+	for _, fork := range forkFields(c) {
+		if fork.Ptr == nil {
+			continue
+		}
+		points := []*big.Int{
+			new(big.Int).Sub(fork.Ptr, common.Big1),
+			new(big.Int).Set(fork.Ptr),
+			new(big.Int).Add(fork.Ptr, common.Big1),
+		}
+		for _, point := range points {
+			if point.Sign() < 0 {
+				continue
+			}
+			if fork.Timestamp {
+				timestamps = append(timestamps, point)
+			} else {
+				heights = append(heights, point)
+			}
+		}
+	}
+	sortBigInts := func(values []*big.Int) {
+		sort.Slice(values, func(i, j int) bool { return values[i].Cmp(values[j]) < 0 })
+	}
+	sortBigInts(heights)
+	sortBigInts(timestamps)
+
+	grid := make([][]Rules, len(heights))
+	for i, height := range heights {
+		grid[i] = make([]Rules, len(timestamps))
+		for j, timestamp := range timestamps {
+			grid[i][j] = c.AvalancheRules(height, timestamp)
+		}
+	}
+
+	// checkFlags fails if a flag set in [prev] is unset in [next].
+	checkFlags := func(prev, next Rules, height, timestamp *big.Int) {
+		prevVal, nextVal := reflect.ValueOf(prev), reflect.ValueOf(next)
+		for k := 0; k < prevVal.NumField(); k++ {
+			if prevVal.Field(k).Kind() != reflect.Bool {
+				continue
+			}
+			if prevVal.Field(k).Bool() && !nextVal.Field(k).Bool() {
+				t.Fatalf("Expected %s to remain set at height %d and timestamp %d", prevVal.Type().Field(k).Name, height, timestamp)
+			}
+		}
+	}
+	for i, height := range heights {
+		for j, timestamp := range timestamps {
+			if j > 0 {
+				checkFlags(grid[i][j-1], grid[i][j], height, timestamp)
+			}
+			if i > 0 {
+				checkFlags(grid[i-1][j], grid[i][j], height, timestamp)
+			}
+		}
+	}
+}
+
+func TestRulesMonotonic(t *testing.T) {
+	// Give every fork a distinct activation point, so that every boundary is
+	// crossed separately.
+	staggered := &ChainConfig{ChainID: big.NewInt(1)}
+	for id := ForkID(0); id < numForks; id++ {
+		point, err := staggered.forkPoint(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		*point = big.NewInt(int64(id+1) * 10)
+	}
+
+	for name, config := range map[string]*ChainConfig{
+		"flare":           FlareChainConfig,
+		"songbird":        SongbirdChainConfig,
+		"coston":          CostonChainConfig,
+		"flare local":     FlareLocalChainConfig,
+		"test":            TestChainConfig,
+		"test launch":     TestLaunchConfig,
+		"apricot phase 1": TestApricotPhase1Config,
+		"apricot phase 2": TestApricotPhase2Config,
+		"apricot phase 3": TestApricotPhase3Config,
+		"apricot phase 4": TestApricotPhase4Config,
+		"apricot phase 5": TestApricotPhase5Config,
+		"staggered":       staggered,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assertRulesMonotonic(config, t)
+		})
+	}
+}
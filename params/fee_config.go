@@ -0,0 +1,132 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	errNoFeeConfigSource       = errors.New("fee manager is active, but no fee config source was provided")
+	errInvalidTargetGas        = errors.New("target gas must be positive")
+	errInvalidFeeDenominator   = errors.New("base fee change denominator must be positive")
+	errInvalidBaseFeeBounds    = errors.New("invalid base fee bounds")
+	errInvalidBlockGasCostInfo = errors.New("invalid block gas cost parameters")
+)
+
+// FeeConfig holds the parameters of the dynamic fee algorithm.
+type FeeConfig struct {
+	// TargetGas is the amount of gas targeted over a 10 second window.
+	TargetGas uint64
+
+	// MinBaseFee and MaxBaseFee bound the base fee, in wei.
+	MinBaseFee *big.Int
+	MaxBaseFee *big.Int
+
+	// BaseFeeChangeDenominator bounds the amount the base fee can change
+	// between blocks.
+	BaseFeeChangeDenominator uint64
+
+	// MinBlockGasCost and MaxBlockGasCost bound the block gas cost, which
+	// changes by BlockGasCostStep for every second the block time deviates
+	// from its target.
+	MinBlockGasCost  *big.Int
+	MaxBlockGasCost  *big.Int
+	BlockGasCostStep *big.Int
+}
+
+// BlockGasCostConfig holds the block gas cost parameters of the dynamic fee
+// algorithm that a network may override.
+type BlockGasCostConfig struct {
+	MinBlockGasCost  *big.Int `json:"minBlockGasCost"`
+	MaxBlockGasCost  *big.Int `json:"maxBlockGasCost"`
+	BlockGasCostStep *big.Int `json:"blockGasCostStep"`
+}
+
+// FeeConfigSource provides the fee config stored on-chain by the fee manager.
+type FeeConfigSource interface {
+	FeeConfig() (FeeConfig, error)
+}
+
+// StaticFeeConfig returns the fee config of Apricot Phase 4, which is used
+// until the fee manager is activated.
+func StaticFeeConfig() FeeConfig {
+	return FeeConfig{
+		TargetGas:                10_000_000,
+		MinBaseFee:               big.NewInt(ApricotPhase4MinBaseFee),
+		MaxBaseFee:               big.NewInt(ApricotPhase4MaxBaseFee),
+		BaseFeeChangeDenominator: BaseFeeChangeDenominator,
+		MinBlockGasCost:          big.NewInt(0),
+		MaxBlockGasCost:          big.NewInt(1_000_000),
+		BlockGasCostStep:         big.NewInt(50_000),
+	}
+}
+
+// ValidateDynamicFeeConfig returns an error if [cfg] cannot be used by the
+// dynamic fee algorithm.
+func ValidateDynamicFeeConfig(cfg FeeConfig) error {
+	switch {
+	case cfg.TargetGas == 0:
+		return errInvalidTargetGas
+	case cfg.BaseFeeChangeDenominator == 0:
+		return errInvalidFeeDenominator
+	case cfg.MinBaseFee == nil || cfg.MaxBaseFee == nil:
+		return fmt.Errorf("%w: base fee bounds must be set", errInvalidBaseFeeBounds)
+	case cfg.MinBaseFee.Sign() < 0:
+		return fmt.Errorf("%w: negative min base fee %d", errInvalidBaseFeeBounds, cfg.MinBaseFee)
+	case cfg.MinBaseFee.Cmp(cfg.MaxBaseFee) > 0:
+		return fmt.Errorf("%w: min base fee %d is above max base fee %d", errInvalidBaseFeeBounds, cfg.MinBaseFee, cfg.MaxBaseFee)
+	case cfg.MinBlockGasCost == nil || cfg.MaxBlockGasCost == nil || cfg.BlockGasCostStep == nil:
+		return fmt.Errorf("%w: block gas cost parameters must be set", errInvalidBlockGasCostInfo)
+	case cfg.MinBlockGasCost.Sign() < 0 || cfg.BlockGasCostStep.Sign() < 0:
+		return fmt.Errorf("%w: negative min block gas cost %d or step %d", errInvalidBlockGasCostInfo, cfg.MinBlockGasCost, cfg.BlockGasCostStep)
+	case cfg.MinBlockGasCost.Cmp(cfg.MaxBlockGasCost) > 0:
+		return fmt.Errorf("%w: min block gas cost %d is above max block gas cost %d", errInvalidBlockGasCostInfo, cfg.MinBlockGasCost, cfg.MaxBlockGasCost)
+	}
+	return nil
+}
+
+// FeeConfig returns the fee config in effect at [blockTimestamp]. Once the
+// fee manager is active, the config is read from [source] and validated
+// before it is returned. Before that, the static config is returned, with
+// the block gas cost override of [c] applied once Apricot Phase 4 is active,
+// and [source] is not consulted.
+func (c *ChainConfig) FeeConfig(blockTimestamp *big.Int, source FeeConfigSource) (FeeConfig, error) {
+	if !c.IsFeeManager(blockTimestamp) {
+		return c.staticFeeConfig(blockTimestamp)
+	}
+	if source == nil {
+		return FeeConfig{}, errNoFeeConfigSource
+	}
+	cfg, err := source.FeeConfig()
+	if err != nil {
+		return FeeConfig{}, err
+	}
+	if err := ValidateDynamicFeeConfig(cfg); err != nil {
+		return FeeConfig{}, fmt.Errorf("invalid on-chain fee config: %w", err)
+	}
+	return cfg, nil
+}
+
+// staticFeeConfig returns the fee config in effect at [blockTimestamp] before
+// the fee manager is activated.
+func (c *ChainConfig) staticFeeConfig(blockTimestamp *big.Int) (FeeConfig, error) {
+	cfg := StaticFeeConfig()
+	if c.BlockGasCostOverride == nil || !c.IsApricotPhase4(blockTimestamp) {
+		return cfg, nil
+	}
+	cfg.MinBlockGasCost = c.BlockGasCostOverride.MinBlockGasCost
+	cfg.MaxBlockGasCost = c.BlockGasCostOverride.MaxBlockGasCost
+	cfg.BlockGasCostStep = c.BlockGasCostOverride.BlockGasCostStep
+	if err := ValidateDynamicFeeConfig(cfg); err != nil {
+		return FeeConfig{}, fmt.Errorf("invalid block gas cost override: %w", err)
+	}
+	// Copy the override so callers cannot modify [c] through the result.
+	cfg.MinBlockGasCost = new(big.Int).Set(cfg.MinBlockGasCost)
+	cfg.MaxBlockGasCost = new(big.Int).Set(cfg.MaxBlockGasCost)
+	cfg.BlockGasCostStep = new(big.Int).Set(cfg.BlockGasCostStep)
+	return cfg, nil
+}
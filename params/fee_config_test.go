@@ -0,0 +1,195 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type testFeeConfigSource struct {
+	config FeeConfig
+	calls  int
+}
+
+func (s *testFeeConfigSource) FeeConfig() (FeeConfig, error) {
+	s.calls++
+	return s.config, nil
+}
+
+func TestFeeManagerForkOrder(t *testing.T) {
+	config := TestConfigWithPhases(4)
+	config.FeeManagerActivationTimestamp = big.NewInt(10)
+	if err := config.CheckConfigForkOrder(); err != nil {
+		t.Fatalf("Expected fee manager after Apricot Phase 4 to be valid, found %s", err)
+	}
+
+	config = TestConfigWithPhases(3)
+	config.FeeManagerActivationTimestamp = big.NewInt(10)
+	if err := config.CheckConfigForkOrder(); err == nil {
+		t.Fatal("Expected fee manager without Apricot Phase 4 to be invalid")
+	}
+
+	config = TestConfigWithPhases(3)
+	config.ApricotPhase4BlockTimestamp = big.NewInt(20)
+	config.FeeManagerActivationTimestamp = big.NewInt(10)
+	if err := config.CheckConfigForkOrder(); err == nil {
+		t.Fatal("Expected fee manager before Apricot Phase 4 to be invalid")
+	}
+}
+
+func TestFeeConfigSource(t *testing.T) {
+	config := TestConfigWithPhases(4)
+	config.FeeManagerActivationTimestamp = big.NewInt(10)
+
+	source := &testFeeConfigSource{
+		config: FeeConfig{
+			TargetGas:                15_000_000,
+			MinBaseFee:               big.NewInt(1),
+			MaxBaseFee:               big.NewInt(2),
+			BaseFeeChangeDenominator: 8,
+			MinBlockGasCost:          big.NewInt(3),
+			MaxBlockGasCost:          big.NewInt(4),
+			BlockGasCostStep:         big.NewInt(5),
+		},
+	}
+
+	if rules := config.AvalancheRules(new(big.Int), big.NewInt(9)); rules.IsFeeManager {
+		t.Fatal("Expected fee manager to be inactive before its activation")
+	}
+	feeConfig, err := config.FeeConfig(big.NewInt(9), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(feeConfig, StaticFeeConfig()) {
+		t.Fatalf("Expected static fee config before activation, found %+v", feeConfig)
+	}
+	if source.calls != 0 {
+		t.Fatalf("Expected source not to be consulted before activation, found %d calls", source.calls)
+	}
+
+	if rules := config.AvalancheRules(new(big.Int), big.NewInt(10)); !rules.IsFeeManager {
+		t.Fatal("Expected fee manager to be active at its activation")
+	}
+	feeConfig, err = config.FeeConfig(big.NewInt(10), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(feeConfig, source.config) {
+		t.Fatalf("Expected on-chain fee config after activation, found %+v", feeConfig)
+	}
+	if source.calls != 1 {
+		t.Fatalf("Expected source to be consulted once, found %d calls", source.calls)
+	}
+
+	if _, err := config.FeeConfig(big.NewInt(10), nil); !errors.Is(err, errNoFeeConfigSource) {
+		t.Fatalf("Expected %s, found %v", errNoFeeConfigSource, err)
+	}
+}
+
+func TestValidateDynamicFeeConfig(t *testing.T) {
+	if err := ValidateDynamicFeeConfig(StaticFeeConfig()); err != nil {
+		t.Fatalf("Expected static fee config to be valid, found %s", err)
+	}
+
+	tests := map[string]struct {
+		modify      func(cfg *FeeConfig)
+		expectedErr error
+	}{
+		"zero target gas": {
+			modify:      func(cfg *FeeConfig) { cfg.TargetGas = 0 },
+			expectedErr: errInvalidTargetGas,
+		},
+		"zero denominator": {
+			modify:      func(cfg *FeeConfig) { cfg.BaseFeeChangeDenominator = 0 },
+			expectedErr: errInvalidFeeDenominator,
+		},
+		"nil min base fee": {
+			modify:      func(cfg *FeeConfig) { cfg.MinBaseFee = nil },
+			expectedErr: errInvalidBaseFeeBounds,
+		},
+		"negative min base fee": {
+			modify:      func(cfg *FeeConfig) { cfg.MinBaseFee = big.NewInt(-1) },
+			expectedErr: errInvalidBaseFeeBounds,
+		},
+		"min base fee above max base fee": {
+			modify:      func(cfg *FeeConfig) { cfg.MinBaseFee = new(big.Int).Add(cfg.MaxBaseFee, common.Big1) },
+			expectedErr: errInvalidBaseFeeBounds,
+		},
+		"nil block gas cost step": {
+			modify:      func(cfg *FeeConfig) { cfg.BlockGasCostStep = nil },
+			expectedErr: errInvalidBlockGasCostInfo,
+		},
+		"negative min block gas cost": {
+			modify:      func(cfg *FeeConfig) { cfg.MinBlockGasCost = big.NewInt(-1) },
+			expectedErr: errInvalidBlockGasCostInfo,
+		},
+		"min block gas cost above max block gas cost": {
+			modify:      func(cfg *FeeConfig) { cfg.MinBlockGasCost = new(big.Int).Add(cfg.MaxBlockGasCost, common.Big1) },
+			expectedErr: errInvalidBlockGasCostInfo,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := StaticFeeConfig()
+			test.modify(&cfg)
+			if err := ValidateDynamicFeeConfig(cfg); !errors.Is(err, test.expectedErr) {
+				t.Fatalf("Expected %s, found %v", test.expectedErr, err)
+			}
+		})
+	}
+
+	// An invalid on-chain config is not applied.
+	config := TestConfigWithPhases(4)
+	config.FeeManagerActivationTimestamp = big.NewInt(0)
+	source := &testFeeConfigSource{config: StaticFeeConfig()}
+	source.config.TargetGas = 0
+	if _, err := config.FeeConfig(big.NewInt(0), source); !errors.Is(err, errInvalidTargetGas) {
+		t.Fatalf("Expected %s, found %v", errInvalidTargetGas, err)
+	}
+}
+
+func TestBlockGasCostOverride(t *testing.T) {
+	config := TestConfigWithPhases(3)
+	config.ApricotPhase4BlockTimestamp = big.NewInt(10)
+	config.BlockGasCostOverride = &BlockGasCostConfig{
+		MinBlockGasCost:  big.NewInt(100),
+		MaxBlockGasCost:  big.NewInt(2_000_000),
+		BlockGasCostStep: big.NewInt(10_000),
+	}
+
+	// The override does not apply before Apricot Phase 4.
+	feeConfig, err := config.FeeConfig(big.NewInt(9), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(feeConfig, StaticFeeConfig()) {
+		t.Fatalf("Expected static fee config before Apricot Phase 4, found %+v", feeConfig)
+	}
+
+	feeConfig, err = config.FeeConfig(big.NewInt(10), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := StaticFeeConfig()
+	expected.MinBlockGasCost = big.NewInt(100)
+	expected.MaxBlockGasCost = big.NewInt(2_000_000)
+	expected.BlockGasCostStep = big.NewInt(10_000)
+	if !reflect.DeepEqual(feeConfig, expected) {
+		t.Fatalf("Expected %+v with the override applied, found %+v", expected, feeConfig)
+	}
+	feeConfig.BlockGasCostStep.SetUint64(1)
+	if config.BlockGasCostOverride.BlockGasCostStep.Cmp(big.NewInt(10_000)) != 0 {
+		t.Fatal("Expected the returned fee config not to alias the override")
+	}
+
+	config.BlockGasCostOverride.MinBlockGasCost = big.NewInt(3_000_000)
+	if _, err := config.FeeConfig(big.NewInt(10), nil); !errors.Is(err, errInvalidBlockGasCostInfo) {
+		t.Fatalf("Expected invalid override to fail with %s, found %v", errInvalidBlockGasCostInfo, err)
+	}
+}
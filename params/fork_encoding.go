@@ -0,0 +1,124 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// forkBytesVersion is the version of the encoding of AvalancheForkBytes.
+const forkBytesVersion = 0
+
+// forkBytesHeaderLen is the length of the version and fork count that start
+// the encoding of AvalancheForkBytes.
+const forkBytesHeaderLen = 2
+
+// encodedForks lists the forks activated by timestamp in their encoding order.
+// Forks added later are appended, even if their ForkID is not the last, so
+// that existing encodings keep their meaning.
+var encodedForks = []ForkID{
+	ApricotPhase1Fork,
+	ApricotPhase2Fork,
+	ApricotPhase3Fork,
+	ApricotPhase4Fork,
+	FeeManagerFork,
+	SponsoredImportFork,
+	ApricotPhase5Fork,
+	AtomicTxsFork,
+}
+
+var (
+	errForkBytesTooShort       = errors.New("encoded forks are too short")
+	errForkBytesUnknownVersion = errors.New("unknown fork encoding version")
+	errForkBytesTooManyForks   = errors.New("encoded forks contain unknown forks")
+	errForkBytesInvalidFlag    = errors.New("encoded forks contain an invalid presence flag")
+)
+
+// AvalancheForkBytes returns a compact encoding of the chain ID of [c] and
+// of the timestamps of its forks activated by timestamp, for peers to cheaply
+// compare which network and upgrades they are running. It is encoded as:
+//
+//   - the encoding version, 1 byte
+//   - the number of forks that follow, 1 byte
+//   - for each fork activated by timestamp, in the order of encodedForks, a
+//     byte that is 1 if it is scheduled and 0 if it is not,
+//     followed by its timestamp as a big-endian uint64 if it is scheduled
+//   - the chain ID as big-endian bytes, using the rest of the encoding
+//
+// A nil chain ID is encoded as zero, and a timestamp that doesn't fit in a
+// uint64, which Validate rejects, as the maximum uint64. Forks added later
+// are appended, so the encoding of a config without them only grows by the
+// byte marking them as unscheduled.
+func (c *ChainConfig) AvalancheForkBytes() []byte {
+	b := make([]byte, forkBytesHeaderLen, forkBytesHeaderLen+9*len(encodedForks)+32)
+	b[0] = forkBytesVersion
+	b[1] = byte(len(encodedForks))
+	for _, id := range encodedForks {
+		// ForkTimestamp only fails for forks activated by block number, which
+		// cannot occur here.
+		timestamp, _ := c.ForkTimestamp(id)
+		if timestamp == nil {
+			b = append(b, 0)
+			continue
+		}
+		value := uint64(math.MaxUint64)
+		if timestamp.IsUint64() {
+			value = timestamp.Uint64()
+		}
+		var encoded [8]byte
+		binary.BigEndian.PutUint64(encoded[:], value)
+		b = append(b, 1)
+		b = append(b, encoded[:]...)
+	}
+	if c.ChainID != nil {
+		b = append(b, c.ChainID.Bytes()...)
+	}
+	return b
+}
+
+// ParseAvalancheForkBytes returns a ChainConfig holding the chain ID and fork
+// timestamps encoded in [b] by AvalancheForkBytes. All of its other fields
+// are unset. A fork missing from an encoding that predates it is unscheduled.
+func ParseAvalancheForkBytes(b []byte) (*ChainConfig, error) {
+	if len(b) < forkBytesHeaderLen {
+		return nil, fmt.Errorf("%w: %d < %d", errForkBytesTooShort, len(b), forkBytesHeaderLen)
+	}
+	if b[0] != forkBytesVersion {
+		return nil, fmt.Errorf("%w: %d", errForkBytesUnknownVersion, b[0])
+	}
+	numEncodedForks := int(b[1])
+	if numEncodedForks > len(encodedForks) {
+		return nil, fmt.Errorf("%w: %d forks encoded", errForkBytesTooManyForks, numEncodedForks)
+	}
+
+	c := &ChainConfig{}
+	rest := b[forkBytesHeaderLen:]
+	for _, id := range encodedForks[:numEncodedForks] {
+		if len(rest) < 1 {
+			return nil, fmt.Errorf("%w: missing %s", errForkBytesTooShort, id)
+		}
+		flag := rest[0]
+		rest = rest[1:]
+		switch flag {
+		case 0:
+			continue
+		case 1:
+		default:
+			return nil, fmt.Errorf("%w: %d for %s", errForkBytesInvalidFlag, flag, id)
+		}
+		if len(rest) < 8 {
+			return nil, fmt.Errorf("%w: missing %s timestamp", errForkBytesTooShort, id)
+		}
+		// forkPoint only fails for unknown forks, which cannot occur here.
+		point, _ := c.forkPoint(id)
+		*point = new(big.Int).SetUint64(binary.BigEndian.Uint64(rest[:8]))
+		rest = rest[8:]
+	}
+	c.ChainID = new(big.Int).SetBytes(rest)
+	return c, nil
+}
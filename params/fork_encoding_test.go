@@ -0,0 +1,89 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestAvalancheForkBytesRoundTrip(t *testing.T) {
+	staggered := *TestLaunchConfig
+	staggered.ChainID = big.NewInt(1 << 40)
+	staggered.ApricotPhase1BlockTimestamp = big.NewInt(10)
+	staggered.ApricotPhase2BlockTimestamp = big.NewInt(20)
+	staggered.FeeManagerActivationTimestamp = new(big.Int).SetUint64(MaxForkTimestamp)
+
+	configs := map[string]*ChainConfig{
+		"flare":     FlareChainConfig,
+		"songbird":  SongbirdChainConfig,
+		"coston":    CostonChainConfig,
+		"local":     FlareLocalChainConfig,
+		"launch":    TestLaunchConfig,
+		"phase 4":   TestApricotPhase4Config,
+		"staggered": &staggered,
+	}
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			encoded := config.AvalancheForkBytes()
+			decoded, err := ParseAvalancheForkBytes(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded.ChainID.Cmp(config.ChainID) != 0 {
+				t.Fatalf("Expected chain ID %d, found %d", config.ChainID, decoded.ChainID)
+			}
+			for id := ApricotPhase1Fork; id < numForks; id++ {
+				expected, _ := config.ForkTimestamp(id)
+				found, _ := decoded.ForkTimestamp(id)
+				if !configNumEqual(expected, found) {
+					t.Fatalf("Expected %s at %v, found %v", id, expected, found)
+				}
+			}
+			if reencoded := decoded.AvalancheForkBytes(); !bytes.Equal(encoded, reencoded) {
+				t.Fatalf("Expected re-encoding to be %x, found %x", encoded, reencoded)
+			}
+		})
+	}
+
+	if a, b := TestApricotPhase3Config.AvalancheForkBytes(), TestApricotPhase4Config.AvalancheForkBytes(); bytes.Equal(a, b) {
+		t.Fatal("Expected configs with different fork schedules to encode differently")
+	}
+}
+
+func TestParseAvalancheForkBytes(t *testing.T) {
+	// An encoding made before Apricot Phase 2 existed, with Apricot Phase 1
+	// scheduled at 5 and a chain ID of 7.
+	decoded, err := ParseAvalancheForkBytes([]byte{0, 1, 1, 0, 0, 0, 0, 0, 0, 0, 5, 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ChainID.Cmp(big.NewInt(7)) != 0 || decoded.ApricotPhase1BlockTimestamp.Cmp(big.NewInt(5)) != 0 {
+		t.Fatalf("Unexpected decoded chain ID %d and Apricot Phase 1 timestamp %d", decoded.ChainID, decoded.ApricotPhase1BlockTimestamp)
+	}
+	if decoded.ApricotPhase2BlockTimestamp != nil {
+		t.Fatalf("Expected Apricot Phase 2 to be unscheduled, found %d", decoded.ApricotPhase2BlockTimestamp)
+	}
+
+	tests := map[string]struct {
+		encoded     []byte
+		expectedErr error
+	}{
+		"empty":             {encoded: nil, expectedErr: errForkBytesTooShort},
+		"unknown version":   {encoded: []byte{1, 0}, expectedErr: errForkBytesUnknownVersion},
+		"unknown forks":     {encoded: []byte{0, byte(len(encodedForks) + 1)}, expectedErr: errForkBytesTooManyForks},
+		"missing flag":      {encoded: []byte{0, 1}, expectedErr: errForkBytesTooShort},
+		"invalid flag":      {encoded: []byte{0, 1, 2}, expectedErr: errForkBytesInvalidFlag},
+		"missing timestamp": {encoded: []byte{0, 1, 1, 0, 0}, expectedErr: errForkBytesTooShort},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseAvalancheForkBytes(test.encoded); !errors.Is(err, test.expectedErr) {
+				t.Fatalf("Expected error %s, found %v", test.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,558 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	errUnknownFork   = errors.New("unknown fork")
+	errForkInPast    = errors.New("fork activation is not in the future")
+	errNegativeValue = errors.New("negative value")
+
+	errNotTimestampFork = errors.New("fork is not activated by timestamp")
+
+	errUnknownChainID   = errors.New("no canonical chain config for chain ID")
+	errMigrationChainID = errors.New("chain config to migrate belongs to another chain")
+
+	errForkTimestampTooLarge = errors.New("fork timestamp is beyond the maximum fork timestamp")
+	errForkTimestampOverflow = errors.New("fork timestamp does not fit in an int64")
+)
+
+// MaxForkTimestamp is the latest timestamp a fork may be scheduled at,
+// 2200-01-01T00:00:00Z. A later timestamp is almost certainly a typo, which
+// would otherwise silently mean that the fork never activates.
+const MaxForkTimestamp uint64 = 7_258_118_400
+
+// ForkID identifies one of the network upgrades scheduled by a ChainConfig.
+type ForkID int
+
+const (
+	HomesteadFork ForkID = iota
+	DAOFork
+	EIP150Fork
+	EIP155Fork
+	EIP158Fork
+	ByzantiumFork
+	ConstantinopleFork
+	PetersburgFork
+	IstanbulFork
+	MuirGlacierFork
+	ApricotPhase1Fork
+	ApricotPhase2Fork
+	ApricotPhase3Fork
+	ApricotPhase4Fork
+	ApricotPhase5Fork
+	FeeManagerFork
+	SponsoredImportFork
+	AtomicTxsFork
+
+	// numForks is the number of forks defined above and must remain last.
+	numForks
+
+	// NoFork is returned by ForkAt when no fork is active.
+	NoFork ForkID = -1
+)
+
+var forkNames = map[ForkID]string{
+	NoFork:              "NoFork",
+	HomesteadFork:       "Homestead",
+	DAOFork:             "DAOFork",
+	EIP150Fork:          "EIP150",
+	EIP155Fork:          "EIP155",
+	EIP158Fork:          "EIP158",
+	ByzantiumFork:       "Byzantium",
+	ConstantinopleFork:  "Constantinople",
+	PetersburgFork:      "Petersburg",
+	IstanbulFork:        "Istanbul",
+	MuirGlacierFork:     "MuirGlacier",
+	ApricotPhase1Fork:   "ApricotPhase1",
+	ApricotPhase2Fork:   "ApricotPhase2",
+	ApricotPhase3Fork:   "ApricotPhase3",
+	ApricotPhase4Fork:   "ApricotPhase4",
+	ApricotPhase5Fork:   "ApricotPhase5",
+	FeeManagerFork:      "FeeManager",
+	SponsoredImportFork: "SponsoredImport",
+	AtomicTxsFork:       "AtomicTxs",
+}
+
+// String implements the fmt.Stringer interface.
+func (id ForkID) String() string {
+	if name, ok := forkNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("ForkID(%d)", int(id))
+}
+
+// ParseForkID returns the fork whose String representation is [name].
+func ParseForkID(name string) (ForkID, error) {
+	for id := ForkID(0); id < numForks; id++ {
+		if id.String() == name {
+			return id, nil
+		}
+	}
+	return NoFork, fmt.Errorf("%w: %q", errUnknownFork, name)
+}
+
+// ForkByName returns the activation point of the fork named [name] in [c], or
+// nil if it is not scheduled, and whether it activates by "block" or by
+// "timestamp". [name] may be the JSON name of the fork field, its description
+// or its ForkID name, and is matched case-insensitively.
+func (c *ChainConfig) ForkByName(name string) (*big.Int, string, error) {
+	for i, field := range forkFields(c) {
+		if !strings.EqualFold(name, field.Name) && !strings.EqualFold(name, field.Desc) && !strings.EqualFold(name, ForkID(i).String()) {
+			continue
+		}
+		dimension := "block"
+		if field.Timestamp {
+			dimension = "timestamp"
+		}
+		if field.Ptr == nil {
+			return nil, dimension, nil
+		}
+		return new(big.Int).Set(field.Ptr), dimension, nil
+	}
+	return nil, "", fmt.Errorf("%w: %q", errUnknownFork, name)
+}
+
+// forkField describes the activation point of a fork held by a ChainConfig.
+type forkField struct {
+	// Name is the JSON name of the field.
+	Name string
+	// Desc is the human readable name of the fork.
+	Desc string
+	// Timestamp is true if the fork activates by block timestamp rather than
+	// by block number.
+	Timestamp bool
+	// Optional is true if later forks may be enabled while this one is not.
+	Optional bool
+	// Unordered is true if the fork may be scheduled independently of the
+	// other forks. Unordered forks are also optional.
+	Unordered bool
+	Ptr       *big.Int
+}
+
+// taggedForkField is a ChainConfig field tagged as a fork, identified by its
+// index in the struct.
+type taggedForkField struct {
+	index int
+	field forkField
+}
+
+// taggedForkFields holds the ChainConfig fields tagged as forks, in
+// declaration order.
+var taggedForkFields = parseForkFields()
+
+// parseForkFields parses the fork tags of ChainConfig. It panics on a
+// malformed tag, since that is a programming error.
+func parseForkFields() []taggedForkField {
+	var fields []taggedForkField
+	typ := reflect.TypeOf(ChainConfig{})
+	for i := 0; i < typ.NumField(); i++ {
+		structField := typ.Field(i)
+		tag, ok := structField.Tag.Lookup("fork")
+		if !ok {
+			continue
+		}
+		if structField.Type != reflect.TypeOf((*big.Int)(nil)) {
+			panic(fmt.Sprintf("fork field %s must be a *big.Int", structField.Name))
+		}
+		parts := strings.Split(tag, ",")
+		if len(parts) < 2 || len(parts) > 3 || (len(parts) == 3 && parts[2] != "optional" && parts[2] != "unordered") {
+			panic(fmt.Sprintf("malformed fork tag %q on field %s", tag, structField.Name))
+		}
+		field := forkField{
+			Name:      strings.Split(structField.Tag.Get("json"), ",")[0],
+			Desc:      parts[0],
+			Optional:  len(parts) == 3,
+			Unordered: len(parts) == 3 && parts[2] == "unordered",
+		}
+		switch parts[1] {
+		case "block":
+		case "timestamp":
+			field.Timestamp = true
+		default:
+			panic(fmt.Sprintf("unknown fork kind %q on field %s", parts[1], structField.Name))
+		}
+		fields = append(fields, taggedForkField{index: i, field: field})
+	}
+	return fields
+}
+
+// forkFields returns the activation points of the forks of [c] in activation
+// order. The forks are the fields of ChainConfig tagged with `fork`.
+func forkFields(c *ChainConfig) []forkField {
+	val := reflect.ValueOf(c).Elem()
+	fields := make([]forkField, len(taggedForkFields))
+	for i, f := range taggedForkFields {
+		fields[i] = f.field
+		fields[i].Ptr = val.Field(f.index).Interface().(*big.Int)
+	}
+	return fields
+}
+
+// Validate returns an error if a fork of [c] is scheduled by a timestamp
+// that doesn't fit in an int64, as Unix timestamps do, or that is beyond
+// MaxForkTimestamp.
+func (c *ChainConfig) Validate() error {
+	maxTimestamp := new(big.Int).SetUint64(MaxForkTimestamp)
+	for _, fork := range forkFields(c) {
+		if !fork.Timestamp || fork.Ptr == nil {
+			continue
+		}
+		if !fork.Ptr.IsInt64() {
+			return fmt.Errorf("%w: %s is scheduled at %d", errForkTimestampOverflow, fork.Name, fork.Ptr)
+		}
+		if fork.Ptr.Cmp(maxTimestamp) > 0 {
+			return fmt.Errorf("%w: %s is scheduled at %d, but the maximum is %d (%s)",
+				errForkTimestampTooLarge, fork.Name, fork.Ptr, MaxForkTimestamp, time.Unix(int64(MaxForkTimestamp), 0).UTC().Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// forkPoint returns a pointer to the field of [c] holding the activation
+// point of [id].
+func (c *ChainConfig) forkPoint(id ForkID) (**big.Int, error) {
+	switch id {
+	case HomesteadFork:
+		return &c.HomesteadBlock, nil
+	case DAOFork:
+		return &c.DAOForkBlock, nil
+	case EIP150Fork:
+		return &c.EIP150Block, nil
+	case EIP155Fork:
+		return &c.EIP155Block, nil
+	case EIP158Fork:
+		return &c.EIP158Block, nil
+	case ByzantiumFork:
+		return &c.ByzantiumBlock, nil
+	case ConstantinopleFork:
+		return &c.ConstantinopleBlock, nil
+	case PetersburgFork:
+		return &c.PetersburgBlock, nil
+	case IstanbulFork:
+		return &c.IstanbulBlock, nil
+	case MuirGlacierFork:
+		return &c.MuirGlacierBlock, nil
+	case ApricotPhase1Fork:
+		return &c.ApricotPhase1BlockTimestamp, nil
+	case ApricotPhase2Fork:
+		return &c.ApricotPhase2BlockTimestamp, nil
+	case ApricotPhase3Fork:
+		return &c.ApricotPhase3BlockTimestamp, nil
+	case ApricotPhase4Fork:
+		return &c.ApricotPhase4BlockTimestamp, nil
+	case ApricotPhase5Fork:
+		return &c.ApricotPhase5BlockTimestamp, nil
+	case FeeManagerFork:
+		return &c.FeeManagerActivationTimestamp, nil
+	case SponsoredImportFork:
+		return &c.SponsoredImportTimestamp, nil
+	case AtomicTxsFork:
+		return &c.AtomicTxsTimestamp, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownFork, id)
+	}
+}
+
+// ForkTimestamp returns the timestamp [id] is scheduled at, or nil if it is
+// not scheduled. It returns an error if [id] is activated by block number.
+func (c *ChainConfig) ForkTimestamp(id ForkID) (*big.Int, error) {
+	if id < ApricotPhase1Fork {
+		return nil, fmt.Errorf("%w: %s", errNotTimestampFork, id)
+	}
+	point, err := c.forkPoint(id)
+	if err != nil {
+		return nil, err
+	}
+	return *point, nil
+}
+
+// Normalize canonicalizes the *big.Int fields of [c] in place, so that configs
+// parsed from different sources compare equal. Nil values are left unset and
+// zero values are replaced with a fresh zero. It returns an error, without
+// modifying [c], if any of the values is negative.
+func (c *ChainConfig) Normalize() error {
+	type field struct {
+		name  string
+		value **big.Int
+	}
+	fields := []field{
+		{"chain ID", &c.ChainID},
+		{"minimum atomic fee per gas", &c.MinAtomicFeePerGas},
+		{"maximum export amount", &c.MaxExportAmount},
+	}
+	for id := ForkID(0); id < numForks; id++ {
+		// forkPoint only fails for unknown forks, which cannot occur here.
+		point, _ := c.forkPoint(id)
+		fields = append(fields, field{id.String(), point})
+	}
+
+	for _, field := range fields {
+		if *field.value != nil && (*field.value).Sign() < 0 {
+			return fmt.Errorf("%w for %s: %v", errNegativeValue, field.name, *field.value)
+		}
+	}
+	for _, field := range fields {
+		if *field.value != nil {
+			*field.value = new(big.Int).Set(*field.value)
+		}
+	}
+	return nil
+}
+
+// ReconcileWithGenesisTime marks every fork activated by timestamp that is
+// scheduled before [genesisTime] as active from genesis, by setting its
+// timestamp to 0. Such a fork was never a transition in the middle of the
+// chain, since every block, including the genesis block, is after it.
+//
+// Note that the reconciled config is not compatible with a stored config that
+// still schedules the fork at its original timestamp, so it must be applied
+// before the config is first stored.
+func (c *ChainConfig) ReconcileWithGenesisTime(genesisTime uint64) {
+	genesis := new(big.Int).SetUint64(genesisTime)
+	for id := ApricotPhase1Fork; id < numForks; id++ {
+		// forkPoint only fails for unknown forks, which cannot occur here.
+		point, _ := c.forkPoint(id)
+		if *point == nil || (*point).Sign() == 0 || (*point).Cmp(genesis) >= 0 {
+			continue
+		}
+		log.Info("Fork scheduled before genesis is active from genesis", "fork", id, "timestamp", *point, "genesisTime", genesisTime)
+		*point = new(big.Int)
+	}
+}
+
+// ForkAt returns the latest fork active at the block with [height] and
+// [timestamp], or NoFork if none is. The Ethereum forks are activated by
+// height and the later forks by timestamp.
+func (c *ChainConfig) ForkAt(height, timestamp *big.Int) ForkID {
+	for id := numForks - 1; id >= 0; id-- {
+		// forkPoint only fails for unknown forks, which cannot occur here.
+		point, _ := c.forkPoint(id)
+		at := timestamp
+		if id < ApricotPhase1Fork {
+			at = height
+		}
+		if isForked(*point, at) {
+			return id
+		}
+	}
+	return NoFork
+}
+
+// RuleSpan is a range of block timestamps over which the rules of a chain
+// are constant.
+type RuleSpan struct {
+	// FromTs and ToTs are the first and last timestamps of the span.
+	FromTs, ToTs *big.Int
+	Rules        Rules
+}
+
+// RuleSpans splits the timestamps from [fromTs] to [toTs], both included,
+// into the spans over which the rules of [c] are constant, in increasing
+// order, so that callers processing many blocks can compute the rules once
+// per span. It returns nil if the range is empty.
+//
+// The rules are those of a block after genesis. The Ethereum forks, which
+// CheckConfigForkOrder only allows at genesis, are the same at every such
+// block.
+func (c *ChainConfig) RuleSpans(fromTs, toTs *big.Int) []RuleSpan {
+	if fromTs == nil || toTs == nil || fromTs.Cmp(toTs) > 0 {
+		return nil
+	}
+
+	// The rules can only change at the activation of a fork activated by
+	// timestamp.
+	var boundaries []*big.Int
+	for id := ApricotPhase1Fork; id < numForks; id++ {
+		// forkPoint only fails for unknown forks, which cannot occur here.
+		point, _ := c.forkPoint(id)
+		if *point != nil && (*point).Cmp(fromTs) > 0 && (*point).Cmp(toTs) <= 0 {
+			boundaries = append(boundaries, *point)
+		}
+	}
+	sort.Slice(boundaries, func(i, j int) bool { return boundaries[i].Cmp(boundaries[j]) < 0 })
+
+	spans := []RuleSpan{{
+		FromTs: new(big.Int).Set(fromTs),
+		Rules:  c.AvalancheRules(common.Big1, fromTs),
+	}}
+	for _, boundary := range boundaries {
+		rules := c.AvalancheRules(common.Big1, boundary)
+		last := &spans[len(spans)-1]
+		// Forks scheduled at the same timestamp, or that do not change any
+		// rule, do not start a new span.
+		if reflect.DeepEqual(last.Rules, rules) {
+			continue
+		}
+		last.ToTs = new(big.Int).Sub(boundary, common.Big1)
+		spans = append(spans, RuleSpan{
+			FromTs: new(big.Int).Set(boundary),
+			Rules:  rules,
+		})
+	}
+	spans[len(spans)-1].ToTs = new(big.Int).Set(toTs)
+	return spans
+}
+
+// EthForksNeutralized returns true if every Ethereum fork scheduled by block
+// number is either unset or activated at genesis. Avalanche produces blocks
+// asynchronously, so upgrades after genesis must be scheduled by timestamp.
+func (c *ChainConfig) EthForksNeutralized() bool {
+	for id := HomesteadFork; id <= MuirGlacierFork; id++ {
+		// forkPoint only fails for unknown forks, which cannot occur here.
+		point, _ := c.forkPoint(id)
+		if *point != nil && (*point).Sign() != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ProposeFork checks whether scheduling [id] at [at] would be valid without
+// modifying [c]. It returns an error if [at] is not after [now] or if the
+// resulting config would fail CheckConfigForkOrder.
+func (c *ChainConfig) ProposeFork(id ForkID, at *big.Int, now *big.Int) error {
+	if at == nil {
+		return fmt.Errorf("cannot propose %s without an activation point", id)
+	}
+	if now != nil && at.Cmp(now) <= 0 {
+		return fmt.Errorf("%w: %s proposed at %v, but current point is %v", errForkInPast, id, at, now)
+	}
+
+	proposed := *c
+	point, err := proposed.forkPoint(id)
+	if err != nil {
+		return err
+	}
+	*point = new(big.Int).Set(at)
+	if err := proposed.CheckConfigForkOrder(); err != nil {
+		return fmt.Errorf("proposing %s at %v: %w", id, at, err)
+	}
+	return nil
+}
+
+// ConfigForChainID returns a copy of the canonical chain config of the network
+// with [chainID], or an error if it is not a known network.
+func ConfigForChainID(chainID *big.Int) (*ChainConfig, error) {
+	for _, config := range []*ChainConfig{FlareChainConfig, SongbirdChainConfig, CostonChainConfig, FlareLocalChainConfig} {
+		if chainID != nil && config.ChainID.Cmp(chainID) == 0 {
+			cpy := *config
+			return &cpy, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", errUnknownChainID, chainID)
+}
+
+// MigrateChainConfig returns a copy of [old], a config that may have been
+// stored before the Apricot phases were added, in which every unset Apricot
+// phase is scheduled as in the canonical config of [network]. Apricot phases
+// set in [old] are left as they are. It returns an error if [network] is not
+// a known network, if [old] has the chain ID of another network, or if the
+// migrated config fails CheckConfigForkOrder.
+func MigrateChainConfig(old *ChainConfig, network *big.Int) (*ChainConfig, error) {
+	canonical, err := ConfigForChainID(network)
+	if err != nil {
+		return nil, err
+	}
+	if old.ChainID != nil && old.ChainID.Cmp(network) != 0 {
+		return nil, fmt.Errorf("%w: config has chain ID %d, but network is %d", errMigrationChainID, old.ChainID, network)
+	}
+
+	migrated := *old
+	if migrated.ChainID == nil {
+		migrated.ChainID = new(big.Int).Set(network)
+	}
+	for _, id := range apricotPhaseForks {
+		// forkPoint only fails for unknown forks, which cannot occur here.
+		point, _ := migrated.forkPoint(id)
+		if *point != nil {
+			continue
+		}
+		canonicalPoint, _ := canonical.forkPoint(id)
+		if *canonicalPoint != nil {
+			*point = new(big.Int).Set(*canonicalPoint)
+		}
+	}
+	if err := migrated.CheckConfigForkOrder(); err != nil {
+		return nil, fmt.Errorf("migrated chain config is invalid: %w", err)
+	}
+	return &migrated, nil
+}
+
+// SetFork schedules [id] at [at], or unschedules it if [at] is nil. If the
+// resulting config fails CheckConfigForkOrder, [c] is left unchanged and the
+// error is returned.
+func (c *ChainConfig) SetFork(id ForkID, at *big.Int) error {
+	point, err := c.forkPoint(id)
+	if err != nil {
+		return err
+	}
+	prev := *point
+	if at != nil {
+		at = new(big.Int).Set(at)
+	}
+	*point = at
+	if err := c.CheckConfigForkOrder(); err != nil {
+		*point = prev
+		return fmt.Errorf("setting %s to %v: %w", id, at, err)
+	}
+	return nil
+}
+
+// ForkHash returns a hash of the fork schedule of [c]: its chain ID, the
+// activation point of every fork in ForkID order, and its DAO fork support.
+// The hash only depends on these values, so two configs with the same fork
+// schedule hash identically across processes.
+func (c *ChainConfig) ForkHash() common.Hash {
+	var buf []byte
+	appendPoint := func(point *big.Int) {
+		if point == nil {
+			buf = append(buf, 0)
+			return
+		}
+		buf = append(buf, 1)
+		buf = append(buf, common.BigToHash(point).Bytes()...)
+	}
+
+	appendPoint(c.ChainID)
+	for id := ForkID(0); id < numForks; id++ {
+		// forkPoint only fails for unknown forks, which cannot occur here.
+		point, _ := c.forkPoint(id)
+		appendPoint(*point)
+	}
+	if c.DAOForkSupport {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	return crypto.Keccak256Hash(buf)
+}
+
+// SameForkSchedule returns true if [c] and [other] schedule every fork at the
+// same activation point. Unlike ForkHash, which also covers the chain ID and
+// so identifies a chain, it ignores every field other than the fork
+// activation points, including the chain ID. It is meant to decide whether a
+// peer follows the same rules, not whether two configs are identical.
+func (c *ChainConfig) SameForkSchedule(other *ChainConfig) bool {
+	otherForks := forkFields(other)
+	for i, fork := range forkFields(c) {
+		if !configNumEqual(fork.Ptr, otherForks[i].Ptr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,560 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestProposeFork(t *testing.T) {
+	config := *TestApricotPhase2Config
+	config.ApricotPhase3BlockTimestamp = big.NewInt(100)
+
+	tests := map[string]struct {
+		id          ForkID
+		at          *big.Int
+		now         *big.Int
+		expectedErr error
+		shouldErr   bool
+	}{
+		"valid future timestamp": {
+			id:  ApricotPhase4Fork,
+			at:  big.NewInt(200),
+			now: big.NewInt(50),
+		},
+		"timestamp in the past": {
+			id:          ApricotPhase4Fork,
+			at:          big.NewInt(200),
+			now:         big.NewInt(300),
+			expectedErr: errForkInPast,
+			shouldErr:   true,
+		},
+		"timestamp equal to now": {
+			id:          ApricotPhase4Fork,
+			at:          big.NewInt(200),
+			now:         big.NewInt(200),
+			expectedErr: errForkInPast,
+			shouldErr:   true,
+		},
+		"before previous phase": {
+			id:        ApricotPhase4Fork,
+			at:        big.NewInt(99),
+			now:       big.NewInt(50),
+			shouldErr: true,
+		},
+		"after following phase": {
+			id:        ApricotPhase2Fork,
+			at:        big.NewInt(150),
+			now:       big.NewInt(50),
+			shouldErr: true,
+		},
+		"non-genesis height fork": {
+			id:          IstanbulFork,
+			at:          big.NewInt(10),
+			now:         big.NewInt(5),
+			expectedErr: errNonGenesisForkByHeight,
+			shouldErr:   true,
+		},
+		"unknown fork": {
+			id:          ForkID(-1),
+			at:          big.NewInt(200),
+			now:         big.NewInt(50),
+			expectedErr: errUnknownFork,
+			shouldErr:   true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := config.ProposeFork(test.id, test.at, test.now)
+			if !test.shouldErr {
+				if err != nil {
+					t.Fatalf("Unexpected error proposing %s at %v: %s", test.id, test.at, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected proposing %s at %v to fail", test.id, test.at)
+			}
+			if test.expectedErr != nil && !errors.Is(err, test.expectedErr) {
+				t.Fatalf("Expected error %s, found %s", test.expectedErr, err)
+			}
+		})
+	}
+
+	if config.ApricotPhase4BlockTimestamp != nil {
+		t.Fatalf("ProposeFork modified the config: %v", config.ApricotPhase4BlockTimestamp)
+	}
+}
+
+func TestMigrateChainConfig(t *testing.T) {
+	// A Songbird config stored before Apricot Phase 4 was added, with Apricot
+	// Phase 3 scheduled differently from the canonical config.
+	legacy := *SongbirdChainConfig
+	legacy.ApricotPhase3BlockTimestamp = big.NewInt(time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC).Unix())
+	legacy.ApricotPhase4BlockTimestamp = nil
+
+	migrated, err := MigrateChainConfig(&legacy, SongbirdChainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated.ApricotPhase4BlockTimestamp.Cmp(SongbirdChainConfig.ApricotPhase4BlockTimestamp) != 0 {
+		t.Fatalf("Expected %s to be migrated to %d, found %v", ApricotPhase4Fork, SongbirdChainConfig.ApricotPhase4BlockTimestamp, migrated.ApricotPhase4BlockTimestamp)
+	}
+	if migrated.ApricotPhase3BlockTimestamp.Cmp(legacy.ApricotPhase3BlockTimestamp) != 0 {
+		t.Fatalf("Expected %s to be left at %d, found %d", ApricotPhase3Fork, legacy.ApricotPhase3BlockTimestamp, migrated.ApricotPhase3BlockTimestamp)
+	}
+	if legacy.ApricotPhase4BlockTimestamp != nil {
+		t.Fatal("MigrateChainConfig modified the config being migrated")
+	}
+	if migrated.ApricotPhase4BlockTimestamp == SongbirdChainConfig.ApricotPhase4BlockTimestamp {
+		t.Fatal("Expected the migrated timestamp not to alias the canonical config")
+	}
+
+	// A config missing its chain ID is assigned the network's.
+	legacy.ChainID = nil
+	if migrated, err = MigrateChainConfig(&legacy, SongbirdChainID); err != nil {
+		t.Fatal(err)
+	}
+	if migrated.ChainID.Cmp(SongbirdChainID) != 0 {
+		t.Fatalf("Expected chain ID %d, found %v", SongbirdChainID, migrated.ChainID)
+	}
+
+	if _, err := MigrateChainConfig(SongbirdChainConfig, FlareChainID); !errors.Is(err, errMigrationChainID) {
+		t.Fatalf("Expected migrating to another network to fail with %s, found %v", errMigrationChainID, err)
+	}
+	if _, err := MigrateChainConfig(&legacy, big.NewInt(12345)); !errors.Is(err, errUnknownChainID) {
+		t.Fatalf("Expected migrating to an unknown network to fail with %s, found %v", errUnknownChainID, err)
+	}
+
+	// Migrated phases must still be in order with the phases that were set.
+	legacy.ApricotPhase3BlockTimestamp = new(big.Int).Add(SongbirdChainConfig.ApricotPhase4BlockTimestamp, common.Big1)
+	if _, err := MigrateChainConfig(&legacy, SongbirdChainID); err == nil {
+		t.Fatal("Expected migration to fail when the migrated fork is out of order")
+	}
+}
+
+func TestSetFork(t *testing.T) {
+	config := *TestApricotPhase2Config
+	config.ApricotPhase3BlockTimestamp = big.NewInt(100)
+
+	at := big.NewInt(200)
+	if err := config.SetFork(ApricotPhase4Fork, at); err != nil {
+		t.Fatalf("Unexpected error setting %s: %s", ApricotPhase4Fork, err)
+	}
+	if config.ApricotPhase4BlockTimestamp.Cmp(at) != 0 {
+		t.Fatalf("Expected %s to be set to %v, found %v", ApricotPhase4Fork, at, config.ApricotPhase4BlockTimestamp)
+	}
+	at.SetInt64(300)
+	if config.ApricotPhase4BlockTimestamp.Int64() != 200 {
+		t.Fatal("SetFork retained the caller's activation point")
+	}
+
+	// Moving Apricot Phase 3 after Apricot Phase 4 breaks the fork order, so
+	// the change must be reverted.
+	if err := config.SetFork(ApricotPhase3Fork, big.NewInt(250)); err == nil {
+		t.Fatal("Expected setting Apricot Phase 3 after Apricot Phase 4 to fail")
+	}
+	if config.ApricotPhase3BlockTimestamp.Int64() != 100 {
+		t.Fatalf("Expected %s to be reverted to 100, found %v", ApricotPhase3Fork, config.ApricotPhase3BlockTimestamp)
+	}
+	if err := config.SetFork(ApricotPhase3Fork, nil); err == nil {
+		t.Fatal("Expected unscheduling Apricot Phase 3 while Apricot Phase 4 is scheduled to fail")
+	}
+	if config.ApricotPhase3BlockTimestamp.Int64() != 100 {
+		t.Fatalf("Expected %s to be reverted to 100, found %v", ApricotPhase3Fork, config.ApricotPhase3BlockTimestamp)
+	}
+
+	if err := config.SetFork(ForkID(-1), big.NewInt(1)); !errors.Is(err, errUnknownFork) {
+		t.Fatalf("Expected error %s, found %v", errUnknownFork, err)
+	}
+}
+
+func TestForkHash(t *testing.T) {
+	config := *TestApricotPhase3Config
+	if config.ForkHash() != TestApricotPhase3Config.ForkHash() {
+		t.Fatal("expected copies of a config to have the same fork hash")
+	}
+
+	// Unrelated fields do not change the fork hash.
+	config.MaxAtomicInputs = 10
+	if config.ForkHash() != TestApricotPhase3Config.ForkHash() {
+		t.Fatal("expected fork hash to ignore fields outside of the fork schedule")
+	}
+
+	config.ApricotPhase4BlockTimestamp = big.NewInt(0)
+	if config.ForkHash() == TestApricotPhase3Config.ForkHash() {
+		t.Fatal("expected fork hash to change when a fork is scheduled")
+	}
+	if config.ForkHash() != TestApricotPhase4Config.ForkHash() {
+		t.Fatal("expected configs with the same fork schedule to have the same fork hash")
+	}
+
+	config.ApricotPhase4BlockTimestamp = big.NewInt(1)
+	if config.ForkHash() == TestApricotPhase4Config.ForkHash() {
+		t.Fatal("expected fork hash to change when a fork is rescheduled")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	config := TestConfigWithPhases(2)
+	// A zero parsed with a negative sign must compare equal to the canonical zero.
+	config.HomesteadBlock = new(big.Int).Neg(new(big.Int))
+	config.ApricotPhase3BlockTimestamp = new(big.Int).SetBytes([]byte{0, 0, 100})
+	if err := config.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	if config.HomesteadBlock.Sign() != 0 || config.ApricotPhase3BlockTimestamp.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("Expected normalized values to be preserved, found %v and %v", config.HomesteadBlock, config.ApricotPhase3BlockTimestamp)
+	}
+	if config.ApricotPhase4BlockTimestamp != nil {
+		t.Fatal("Expected unset fork to remain nil")
+	}
+
+	config.ApricotPhase4BlockTimestamp = big.NewInt(-1)
+	err := config.Normalize()
+	if !errors.Is(err, errNegativeValue) {
+		t.Fatalf("Expected %s, found %v", errNegativeValue, err)
+	}
+	if config.ApricotPhase4BlockTimestamp.Cmp(big.NewInt(-1)) != 0 {
+		t.Fatal("Expected a failed Normalize to leave the config unmodified")
+	}
+}
+
+func TestEthForksNeutralized(t *testing.T) {
+	for _, config := range []*ChainConfig{TestLaunchConfig, TestChainConfig, FlareChainConfig, SongbirdChainConfig, CostonChainConfig, FlareLocalChainConfig} {
+		if !config.EthForksNeutralized() {
+			t.Fatalf("Expected %s to have neutralized Ethereum forks", config)
+		}
+	}
+
+	// Unset forks are neutral.
+	config := *TestChainConfig
+	config.MuirGlacierBlock = nil
+	if !config.EthForksNeutralized() {
+		t.Fatal("Expected config with an unset fork to have neutralized Ethereum forks")
+	}
+
+	config.IstanbulBlock = big.NewInt(10)
+	if config.EthForksNeutralized() {
+		t.Fatal("Expected config with Istanbul at height 10 not to have neutralized Ethereum forks")
+	}
+}
+
+func TestForkAt(t *testing.T) {
+	config := *TestApricotPhase2Config
+	config.ApricotPhase3BlockTimestamp = big.NewInt(100)
+	config.ApricotPhase4BlockTimestamp = big.NewInt(200)
+
+	tests := []struct {
+		height, timestamp int64
+		expected          ForkID
+	}{
+		{height: 0, timestamp: 0, expected: ApricotPhase2Fork},
+		{height: 10, timestamp: 99, expected: ApricotPhase2Fork},
+		{height: 10, timestamp: 100, expected: ApricotPhase3Fork},
+		{height: 10, timestamp: 199, expected: ApricotPhase3Fork},
+		{height: 10, timestamp: 200, expected: ApricotPhase4Fork},
+		{height: 10, timestamp: 1000, expected: ApricotPhase4Fork},
+	}
+	for _, test := range tests {
+		if id := config.ForkAt(big.NewInt(test.height), big.NewInt(test.timestamp)); id != test.expected {
+			t.Errorf("Expected %s at height %d and timestamp %d, found %s", test.expected, test.height, test.timestamp, id)
+		}
+	}
+
+	// Without any Apricot Phase, the latest Ethereum fork active by height is
+	// returned.
+	config = *TestLaunchConfig
+	config.MuirGlacierBlock = nil
+	if id := config.ForkAt(big.NewInt(0), big.NewInt(1000)); id != IstanbulFork {
+		t.Errorf("Expected %s, found %s", IstanbulFork, id)
+	}
+	if id := NewChainConfig().ForkAt(big.NewInt(0), big.NewInt(0)); id != NoFork {
+		t.Errorf("Expected %s, found %s", NoFork, id)
+	}
+}
+
+func TestRuleSpans(t *testing.T) {
+	config := *TestApricotPhase2Config
+	config.ApricotPhase3BlockTimestamp = big.NewInt(100)
+	config.ApricotPhase4BlockTimestamp = big.NewInt(200)
+	// Scheduled with Apricot Phase 4, so it does not start a span of its own.
+	config.FeeManagerActivationTimestamp = big.NewInt(200)
+
+	spans := config.RuleSpans(big.NewInt(50), big.NewInt(250))
+	expected := []struct {
+		from, to int64
+	}{
+		{from: 50, to: 99},
+		{from: 100, to: 199},
+		{from: 200, to: 250},
+	}
+	if len(spans) != len(expected) {
+		t.Fatalf("Expected %d spans, found %d", len(expected), len(spans))
+	}
+	for i, span := range spans {
+		if span.FromTs.Int64() != expected[i].from || span.ToTs.Int64() != expected[i].to {
+			t.Fatalf("Expected span %d to be [%d, %d], found [%s, %s]", i, expected[i].from, expected[i].to, span.FromTs, span.ToTs)
+		}
+		// The rules of a span are those of every timestamp in it.
+		for _, timestamp := range []*big.Int{span.FromTs, span.ToTs} {
+			if rules := config.AvalancheRules(common.Big1, timestamp); !reflect.DeepEqual(span.Rules, rules) {
+				t.Fatalf("Expected the rules of span %d at %s to be %+v, found %+v", i, timestamp, rules, span.Rules)
+			}
+		}
+	}
+	if spans[0].Rules.IsApricotPhase3 || !spans[1].Rules.IsApricotPhase3 || spans[1].Rules.IsApricotPhase4 || !spans[2].Rules.IsApricotPhase4 {
+		t.Fatal("Expected the spans to be split at the Apricot Phase 3 and 4 activations")
+	}
+
+	// A fork at the start of the range does not split it.
+	if spans := config.RuleSpans(big.NewInt(100), big.NewInt(150)); len(spans) != 1 || !spans[0].Rules.IsApricotPhase3 {
+		t.Fatalf("Expected a single Apricot Phase 3 span, found %+v", spans)
+	}
+	if spans := config.RuleSpans(big.NewInt(150), big.NewInt(100)); spans != nil {
+		t.Fatalf("Expected no spans for an empty range, found %+v", spans)
+	}
+}
+
+func TestForkFields(t *testing.T) {
+	expectedNames := []string{
+		"homesteadBlock",
+		"daoForkBlock",
+		"eip150Block",
+		"eip155Block",
+		"eip158Block",
+		"byzantiumBlock",
+		"constantinopleBlock",
+		"petersburgBlock",
+		"istanbulBlock",
+		"muirGlacierBlock",
+		"apricotPhase1BlockTimestamp",
+		"apricotPhase2BlockTimestamp",
+		"apricotPhase3BlockTimestamp",
+		"apricotPhase4BlockTimestamp",
+		"apricotPhase5BlockTimestamp",
+		"feeManagerActivationTimestamp",
+		"sponsoredImportTimestamp",
+		"atomicTxsTimestamp",
+	}
+
+	// Give every fork a distinct activation point, so that each reflected
+	// field can be matched to the field returned by forkPoint.
+	config := &ChainConfig{}
+	for id := ForkID(0); id < numForks; id++ {
+		point, err := config.forkPoint(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		*point = big.NewInt(int64(id))
+	}
+
+	fields := forkFields(config)
+	if len(fields) != len(expectedNames) || len(fields) != int(numForks) {
+		t.Fatalf("Expected %d fork fields, found %d", len(expectedNames), len(fields))
+	}
+	for i, field := range fields {
+		id := ForkID(i)
+		if field.Name != expectedNames[i] {
+			t.Fatalf("Expected fork field %d to be %s, found %s", i, expectedNames[i], field.Name)
+		}
+		point, err := config.forkPoint(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if field.Ptr != *point {
+			t.Fatalf("Expected fork field %s to hold the activation point of %s", field.Name, id)
+		}
+		if field.Timestamp != (id >= ApricotPhase1Fork) {
+			t.Fatalf("Expected fork field %s to activate by timestamp: %t", field.Name, id >= ApricotPhase1Fork)
+		}
+		if field.Optional != (id == DAOFork || id == MuirGlacierFork || id == ApricotPhase5Fork || id == SponsoredImportFork || id == AtomicTxsFork) {
+			t.Fatalf("Unexpected optional flag on fork field %s", field.Name)
+		}
+		if field.Unordered != (id == AtomicTxsFork) {
+			t.Fatalf("Unexpected unordered flag on fork field %s", field.Name)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	config := TestConfigWithPhases(4)
+	config.FeeManagerActivationTimestamp = new(big.Int).SetUint64(MaxForkTimestamp)
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected fork at the maximum timestamp to be valid, found %s", err)
+	}
+
+	// 1e12, tens of thousands of years from now, can't be meant as a real
+	// activation time.
+	config.ApricotPhase4BlockTimestamp = big.NewInt(1_000_000_000_000)
+	if err := config.Validate(); !errors.Is(err, errForkTimestampTooLarge) {
+		t.Fatalf("Expected %s, found %v", errForkTimestampTooLarge, err)
+	}
+}
+
+func TestValidateTimestampOverflow(t *testing.T) {
+	config := TestConfigWithPhases(4)
+	config.ApricotPhase4BlockTimestamp = new(big.Int).Add(big.NewInt(math.MaxInt64), common.Big1)
+	if err := config.Validate(); !errors.Is(err, errForkTimestampOverflow) {
+		t.Fatalf("Expected %s, found %v", errForkTimestampOverflow, err)
+	}
+
+	config.ApricotPhase4BlockTimestamp, _ = new(big.Int).SetString("1000000000000000000000000000000", 10)
+	if err := config.Validate(); !errors.Is(err, errForkTimestampOverflow) {
+		t.Fatalf("Expected %s, found %v", errForkTimestampOverflow, err)
+	}
+}
+
+func TestSameForkSchedule(t *testing.T) {
+	config := TestConfigWithPhases(4)
+	other := TestConfigWithPhases(4)
+	other.ChainID = big.NewInt(12345)
+	other.MaxAtomicInputs = 1
+	if !config.SameForkSchedule(other) || !other.SameForkSchedule(config) {
+		t.Fatal("Expected configs differing only in chain ID and atomic limits to share a fork schedule")
+	}
+	if config.ForkHash() == other.ForkHash() {
+		t.Fatal("Expected configs with different chain IDs to have different fork hashes")
+	}
+
+	other.ApricotPhase4BlockTimestamp = big.NewInt(10)
+	if config.SameForkSchedule(other) {
+		t.Fatal("Expected configs with different Apricot Phase 4 timestamps to have different fork schedules")
+	}
+	other.ApricotPhase4BlockTimestamp = nil
+	if config.SameForkSchedule(other) {
+		t.Fatal("Expected scheduled and unscheduled Apricot Phase 4 to be different fork schedules")
+	}
+}
+
+func TestForkTimestamp(t *testing.T) {
+	id, err := ParseForkID("ApricotPhase3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != ApricotPhase3Fork {
+		t.Fatalf("Expected %s, found %s", ApricotPhase3Fork, id)
+	}
+	if _, err := ParseForkID("NoSuchFork"); !errors.Is(err, errUnknownFork) {
+		t.Fatalf("Expected unknown fork name to fail with %s, found %v", errUnknownFork, err)
+	}
+	if _, err := ParseForkID(NoFork.String()); !errors.Is(err, errUnknownFork) {
+		t.Fatalf("Expected %s to fail with %s, found %v", NoFork, errUnknownFork, err)
+	}
+
+	config := TestConfigWithPhases(2)
+	config.ApricotPhase3BlockTimestamp = big.NewInt(100)
+	timestamp, err := config.ForkTimestamp(ApricotPhase3Fork)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timestamp.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("Expected Apricot Phase 3 at 100, found %s", timestamp)
+	}
+	timestamp, err = config.ForkTimestamp(ApricotPhase4Fork)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timestamp != nil {
+		t.Fatalf("Expected unscheduled Apricot Phase 4, found %s", timestamp)
+	}
+	if _, err := config.ForkTimestamp(IstanbulFork); !errors.Is(err, errNotTimestampFork) {
+		t.Fatalf("Expected %s to fail with %s, found %v", IstanbulFork, errNotTimestampFork, err)
+	}
+}
+
+func TestReconcileWithGenesisTime(t *testing.T) {
+	config := *TestLaunchConfig
+	config.ApricotPhase1BlockTimestamp = big.NewInt(0)
+	scheduled := big.NewInt(50)
+	config.ApricotPhase2BlockTimestamp = scheduled
+	config.ApricotPhase3BlockTimestamp = big.NewInt(100)
+	config.ApricotPhase4BlockTimestamp = big.NewInt(200)
+	rulesBefore := config.AvalancheRules(common.Big0, big.NewInt(100))
+
+	config.ReconcileWithGenesisTime(100)
+
+	// Apricot Phase 2 was scheduled before genesis, so it is active from
+	// genesis. Apricot Phase 3 activates at the genesis time itself and is
+	// left unchanged, as are the unscheduled forks.
+	expected := map[ForkID]*big.Int{
+		ApricotPhase1Fork:   big.NewInt(0),
+		ApricotPhase2Fork:   big.NewInt(0),
+		ApricotPhase3Fork:   big.NewInt(100),
+		ApricotPhase4Fork:   big.NewInt(200),
+		ApricotPhase5Fork:   nil,
+		FeeManagerFork:      nil,
+		SponsoredImportFork: nil,
+		AtomicTxsFork:       nil,
+	}
+	for id, timestamp := range expected {
+		found, err := config.ForkTimestamp(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !configNumEqual(found, timestamp) {
+			t.Fatalf("Expected %s to be scheduled at %v, found %v", id, timestamp, found)
+		}
+	}
+	if scheduled.Cmp(big.NewInt(50)) != 0 {
+		t.Fatalf("Expected the replaced timestamp to be left unmodified, found %d", scheduled)
+	}
+
+	// Blocks after genesis are subject to the same rules.
+	rulesAfter := config.AvalancheRules(common.Big0, big.NewInt(100))
+	if rulesBefore.IsApricotPhase2 != rulesAfter.IsApricotPhase2 || rulesBefore.IsApricotPhase3 != rulesAfter.IsApricotPhase3 {
+		t.Fatalf("Expected reconciling with the genesis time not to change the rules at genesis")
+	}
+	if err := config.CheckConfigForkOrder(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForkByName(t *testing.T) {
+	config := *TestApricotPhase2Config
+	config.ApricotPhase3BlockTimestamp = big.NewInt(100)
+
+	tests := []struct {
+		name              string
+		expectedValue     *big.Int
+		expectedDimension string
+	}{
+		{"istanbulBlock", big.NewInt(0), "block"},
+		{"Istanbul", big.NewInt(0), "block"},
+		{"apricotPhase3BlockTimestamp", big.NewInt(100), "timestamp"},
+		{"Apricot Phase 3", big.NewInt(100), "timestamp"},
+		{"apricotphase3", big.NewInt(100), "timestamp"},
+		{"ApricotPhase4", nil, "timestamp"},
+	}
+	for _, test := range tests {
+		value, dimension, err := config.ForkByName(test.name)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		if !configNumEqual(value, test.expectedValue) || dimension != test.expectedDimension {
+			t.Fatalf("%s: expected %v by %s, found %v by %s", test.name, test.expectedValue, test.expectedDimension, value, dimension)
+		}
+	}
+
+	// The returned value is a copy.
+	value, _, err := config.ForkByName("apricotPhase3BlockTimestamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value.SetInt64(0)
+	if config.ApricotPhase3BlockTimestamp.Int64() != 100 {
+		t.Fatal("Modifying the returned value modified the config")
+	}
+
+	if _, _, err := config.ForkByName("ApricotPhase9"); !errors.Is(err, errUnknownFork) {
+		t.Fatalf("Expected unknown fork name to fail with %s, found %v", errUnknownFork, err)
+	}
+}
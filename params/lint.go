@@ -0,0 +1,101 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Codes identifying the warnings reported by ChainConfig.Lint
+const (
+	LintNonGenesisEthFork      = "non-genesis-eth-fork"
+	LintDAOForkSupport         = "dao-fork-support"
+	LintDistantApricotPhase    = "distant-apricot-phase"
+	LintMissingPetersburgBlock = "missing-petersburg-block"
+)
+
+// lintForkHorizon is how far in the future an Apricot phase may be scheduled
+// before it is considered suspicious.
+const lintForkHorizon = 5 * 365 * 24 * time.Hour
+
+// LintWarning describes a non-fatal misconfiguration of a ChainConfig.
+type LintWarning struct {
+	Code    string
+	Message string
+}
+
+// String implements the fmt.Stringer interface.
+func (w LintWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Code, w.Message)
+}
+
+// Lint returns warnings for settings that are valid, but unlikely to be
+// intended on an Avalanche chain. Unlike CheckConfigForkOrder, it never
+// rejects the config.
+func (c *ChainConfig) Lint() []LintWarning {
+	return c.lint(time.Now())
+}
+
+func (c *ChainConfig) lint(now time.Time) []LintWarning {
+	var warnings []LintWarning
+	for _, fork := range []struct {
+		name  string
+		block *big.Int
+	}{
+		{"homesteadBlock", c.HomesteadBlock},
+		{"daoForkBlock", c.DAOForkBlock},
+		{"eip150Block", c.EIP150Block},
+		{"eip155Block", c.EIP155Block},
+		{"eip158Block", c.EIP158Block},
+		{"byzantiumBlock", c.ByzantiumBlock},
+		{"constantinopleBlock", c.ConstantinopleBlock},
+		{"petersburgBlock", c.PetersburgBlock},
+		{"istanbulBlock", c.IstanbulBlock},
+		{"muirGlacierBlock", c.MuirGlacierBlock},
+	} {
+		if fork.block != nil && fork.block.Cmp(common.Big0) != 0 {
+			warnings = append(warnings, LintWarning{
+				Code:    LintNonGenesisEthFork,
+				Message: fmt.Sprintf("%s is set to %v, but Ethereum forks should activate at genesis", fork.name, fork.block),
+			})
+		}
+	}
+	if c.DAOForkSupport {
+		warnings = append(warnings, LintWarning{
+			Code:    LintDAOForkSupport,
+			Message: "daoForkSupport is set, but the DAO fork has no meaning on Avalanche",
+		})
+	}
+
+	horizon := big.NewInt(now.Add(lintForkHorizon).Unix())
+	for _, fork := range []struct {
+		name      string
+		timestamp *big.Int
+	}{
+		{"apricotPhase1BlockTimestamp", c.ApricotPhase1BlockTimestamp},
+		{"apricotPhase2BlockTimestamp", c.ApricotPhase2BlockTimestamp},
+		{"apricotPhase3BlockTimestamp", c.ApricotPhase3BlockTimestamp},
+		{"apricotPhase4BlockTimestamp", c.ApricotPhase4BlockTimestamp},
+		{"apricotPhase5BlockTimestamp", c.ApricotPhase5BlockTimestamp},
+	} {
+		if fork.timestamp != nil && fork.timestamp.Cmp(horizon) > 0 {
+			warnings = append(warnings, LintWarning{
+				Code:    LintDistantApricotPhase,
+				Message: fmt.Sprintf("%s is scheduled at %v, more than %v in the future", fork.name, fork.timestamp, lintForkHorizon),
+			})
+		}
+	}
+
+	if c.ConstantinopleBlock != nil && c.PetersburgBlock == nil {
+		warnings = append(warnings, LintWarning{
+			Code:    LintMissingPetersburgBlock,
+			Message: "constantinopleBlock is set without petersburgBlock, leaving EIP-1283 enabled",
+		})
+	}
+	return warnings
+}
@@ -0,0 +1,56 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestLint(t *testing.T) {
+	now := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		modify       func(c *ChainConfig)
+		expectedCode string
+	}{
+		"non-genesis ethereum fork": {
+			modify:       func(c *ChainConfig) { c.IstanbulBlock = big.NewInt(10) },
+			expectedCode: LintNonGenesisEthFork,
+		},
+		"dao fork support": {
+			modify:       func(c *ChainConfig) { c.DAOForkSupport = true },
+			expectedCode: LintDAOForkSupport,
+		},
+		"distant apricot phase": {
+			modify: func(c *ChainConfig) {
+				c.ApricotPhase4BlockTimestamp = big.NewInt(time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC).Unix())
+			},
+			expectedCode: LintDistantApricotPhase,
+		},
+		"missing petersburg block": {
+			modify:       func(c *ChainConfig) { c.PetersburgBlock = nil },
+			expectedCode: LintMissingPetersburgBlock,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := *TestChainConfig
+			test.modify(&config)
+
+			warnings := config.lint(now)
+			if len(warnings) != 1 {
+				t.Fatalf("Expected exactly one warning, found %v", warnings)
+			}
+			if warnings[0].Code != test.expectedCode {
+				t.Fatalf("Expected warning code %s, found %s", test.expectedCode, warnings[0].Code)
+			}
+		})
+	}
+
+	if warnings := TestChainConfig.lint(now); len(warnings) != 0 {
+		t.Fatalf("Expected no warnings for TestChainConfig, found %v", warnings)
+	}
+}
@@ -0,0 +1,29 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package params
+
+import "github.com/ethereum/go-ethereum/common"
+
+// Addresses of the precompiled contracts added by Avalanche
+var (
+	GenesisContractAddr    = common.HexToAddress("0x0100000000000000000000000000000000000000")
+	NativeAssetBalanceAddr = common.HexToAddress("0x0100000000000000000000000000000000000001")
+	NativeAssetCallAddr    = common.HexToAddress("0x0100000000000000000000000000000000000002")
+)
+
+// statefulPrecompiles is the set of precompiled contracts that read or modify
+// the EVM state, so must be run with access to it. The remaining precompiles,
+// including the deprecated genesis contract, only depend on their input.
+var statefulPrecompiles = map[common.Address]struct{}{
+	NativeAssetBalanceAddr: {},
+	NativeAssetCallAddr:    {},
+}
+
+// IsStatefulPrecompile returns whether [addr] is the address of a precompiled
+// contract that reads or modifies state. It does not check whether the
+// precompile is enabled at any particular block.
+func (c *ChainConfig) IsStatefulPrecompile(addr common.Address) bool {
+	_, ok := statefulPrecompiles[addr]
+	return ok
+}
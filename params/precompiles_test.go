@@ -0,0 +1,30 @@
+// (c) 2019-2020, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestIsStatefulPrecompile(t *testing.T) {
+	tests := map[string]struct {
+		addr     common.Address
+		stateful bool
+	}{
+		"native asset balance": {addr: NativeAssetBalanceAddr, stateful: true},
+		"native asset call":    {addr: NativeAssetCallAddr, stateful: true},
+		"genesis contract":     {addr: GenesisContractAddr, stateful: false},
+		"ecrecover":            {addr: common.BytesToAddress([]byte{1}), stateful: false},
+		"not a precompile":     {addr: common.HexToAddress("0x0100000000000000000000000000000000000003"), stateful: false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if stateful := TestChainConfig.IsStatefulPrecompile(test.addr); stateful != test.stateful {
+				t.Fatalf("Expected IsStatefulPrecompile(%s) to be %t, found %t", test.addr.Hex(), test.stateful, stateful)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// rulesEncodingVersion is the version of the binary encoding of Rules.
+const rulesEncodingVersion = 1
+
+// rulesHeaderLen is the length of the fixed size part of an encoded Rules:
+// the version, the rule bitset, the presence flags and the atomic tx limits.
+const rulesHeaderLen = 1 + 4 + 1 + 8 + 8
+
+// hasAtomicFeeRecipient is set in the presence flags of an encoded Rules if
+// an atomic fee recipient follows the header.
+const hasAtomicFeeRecipient = 1 << 0
+
+// hasMaxExportAmount is set in the presence flags of an encoded Rules if a
+// maximum export amount, prefixed by its length in bytes, follows the atomic
+// fee recipient.
+const hasMaxExportAmount = 1 << 1
+
+var (
+	errRulesTooShort          = errors.New("encoded rules are too short")
+	errRulesUnknownVersion    = errors.New("unknown rules encoding version")
+	errRulesNegativeChainID   = errors.New("cannot encode rules with a negative chain ID")
+	errRulesUnknownFlags      = errors.New("encoded rules contain unknown flags")
+	errRulesUnknownRuleBitset = errors.New("encoded rules contain unknown rule bits")
+	errRulesInvalidMaxExport  = errors.New("cannot encode rules with a negative or oversized maximum export amount")
+)
+
+// ruleFlags returns the boolean rules of [r] in their encoding order.
+func (r *Rules) ruleFlags() []*bool {
+	return []*bool{
+		&r.IsHomestead,
+		&r.IsEIP150,
+		&r.IsEIP155,
+		&r.IsEIP158,
+		&r.IsByzantium,
+		&r.IsConstantinople,
+		&r.IsPetersburg,
+		&r.IsIstanbul,
+		&r.IsApricotPhase1,
+		&r.IsApricotPhase2,
+		&r.IsApricotPhase3,
+		&r.IsApricotPhase4,
+		&r.IsFeeManager,
+		&r.IsSponsoredImport,
+		&r.IsApricotPhase5,
+		&r.IsAtomicTxs,
+	}
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+// The boolean rules are packed into a bitset and the ChainID is appended as
+// big-endian bytes, so that the rules can be cheaply sent to another process.
+func (r Rules) MarshalBinary() ([]byte, error) {
+	if r.ChainID != nil && r.ChainID.Sign() < 0 {
+		return nil, errRulesNegativeChainID
+	}
+	if r.MaxExportAmount != nil && (r.MaxExportAmount.Sign() < 0 || len(r.MaxExportAmount.Bytes()) > 255) {
+		return nil, errRulesInvalidMaxExport
+	}
+
+	var bitset uint32
+	for i, flag := range r.ruleFlags() {
+		if *flag {
+			bitset |= 1 << i
+		}
+	}
+	var flags byte
+	if r.AtomicFeeRecipient != nil {
+		flags |= hasAtomicFeeRecipient
+	}
+	if r.MaxExportAmount != nil {
+		flags |= hasMaxExportAmount
+	}
+
+	b := make([]byte, rulesHeaderLen, rulesHeaderLen+common.AddressLength+32)
+	b[0] = rulesEncodingVersion
+	binary.BigEndian.PutUint32(b[1:5], bitset)
+	b[5] = flags
+	binary.BigEndian.PutUint64(b[6:14], r.MaxAtomicInputs)
+	binary.BigEndian.PutUint64(b[14:22], r.MaxAtomicOutputs)
+	if r.AtomicFeeRecipient != nil {
+		b = append(b, r.AtomicFeeRecipient.Bytes()...)
+	}
+	if r.MaxExportAmount != nil {
+		maxExportBytes := r.MaxExportAmount.Bytes()
+		b = append(b, byte(len(maxExportBytes)))
+		b = append(b, maxExportBytes...)
+	}
+	if r.ChainID != nil {
+		b = append(b, r.ChainID.Bytes()...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+// A nil ChainID is decoded as zero.
+func (r *Rules) UnmarshalBinary(b []byte) error {
+	if len(b) < rulesHeaderLen {
+		return fmt.Errorf("%w: %d < %d", errRulesTooShort, len(b), rulesHeaderLen)
+	}
+	if b[0] != rulesEncodingVersion {
+		return fmt.Errorf("%w: %d", errRulesUnknownVersion, b[0])
+	}
+
+	decoded := Rules{}
+	ruleFlags := decoded.ruleFlags()
+	bitset := binary.BigEndian.Uint32(b[1:5])
+	if bitset>>len(ruleFlags) != 0 {
+		return fmt.Errorf("%w: %#x", errRulesUnknownRuleBitset, bitset)
+	}
+	for i, flag := range ruleFlags {
+		*flag = bitset&(1<<i) != 0
+	}
+	flags := b[5]
+	if flags&^(hasAtomicFeeRecipient|hasMaxExportAmount) != 0 {
+		return fmt.Errorf("%w: %#x", errRulesUnknownFlags, flags)
+	}
+	decoded.MaxAtomicInputs = binary.BigEndian.Uint64(b[6:14])
+	decoded.MaxAtomicOutputs = binary.BigEndian.Uint64(b[14:22])
+
+	rest := b[rulesHeaderLen:]
+	if flags&hasAtomicFeeRecipient != 0 {
+		if len(rest) < common.AddressLength {
+			return fmt.Errorf("%w: missing atomic fee recipient", errRulesTooShort)
+		}
+		recipient := common.BytesToAddress(rest[:common.AddressLength])
+		decoded.AtomicFeeRecipient = &recipient
+		rest = rest[common.AddressLength:]
+	}
+	if flags&hasMaxExportAmount != 0 {
+		if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+			return fmt.Errorf("%w: missing maximum export amount", errRulesTooShort)
+		}
+		decoded.MaxExportAmount = new(big.Int).SetBytes(rest[1 : 1+int(rest[0])])
+		rest = rest[1+int(rest[0]):]
+	}
+	decoded.ChainID = new(big.Int).SetBytes(rest)
+
+	*r = decoded
+	return nil
+}
@@ -0,0 +1,84 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package params
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestRulesBinaryRoundTrip(t *testing.T) {
+	recipient := common.Address{0x01, 0x02}
+	withRecipient := *TestApricotPhase4Config
+	withRecipient.AtomicFeeRecipient = &recipient
+	withMaxExport := withRecipient
+	withMaxExport.MaxExportAmount = big.NewInt(1_000_000_000)
+
+	tests := map[string]Rules{
+		"launch":                 TestLaunchConfig.AvalancheRules(common.Big0, common.Big0),
+		"apricot phase 4":        TestApricotPhase4Config.AvalancheRules(common.Big0, common.Big0),
+		"apricot phase 5":        TestApricotPhase5Config.AvalancheRules(common.Big0, common.Big0),
+		"flare":                  FlareChainConfig.AvalancheRules(common.Big0, big.NewInt(1_000_000_000)),
+		"local chain ID":         FlareLocalChainConfig.AvalancheRules(common.Big0, common.Big0),
+		"atomic fee recipient":   withRecipient.AvalancheRules(common.Big0, common.Big0),
+		"zero value chain ID":    {ChainID: new(big.Int), IsEIP155: true},
+		"custom atomic tx limit": {ChainID: big.NewInt(14), MaxAtomicInputs: 3, MaxAtomicOutputs: 4},
+		"max export amount":      withMaxExport.AvalancheRules(common.Big0, common.Big0),
+	}
+	for name, rules := range tests {
+		t.Run(name, func(t *testing.T) {
+			b, err := rules.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded Rules
+			if err := decoded.UnmarshalBinary(b); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(rules, decoded) {
+				t.Fatalf("Expected decoded rules %+v, found %+v", rules, decoded)
+			}
+		})
+	}
+}
+
+func TestRulesUnmarshalBinaryInvalid(t *testing.T) {
+	valid, err := TestChainConfig.AvalancheRules(common.Big0, common.Big0).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unknownVersion := append([]byte{}, valid...)
+	unknownVersion[0] = rulesEncodingVersion + 1
+	unknownRule := append([]byte{}, valid...)
+	unknownRule[1] = 0xff
+	missingRecipient := append([]byte{}, valid[:rulesHeaderLen]...)
+	missingRecipient[5] = hasAtomicFeeRecipient
+	missingMaxExport := append([]byte{}, valid[:rulesHeaderLen]...)
+	missingMaxExport[5] = hasMaxExportAmount
+	missingMaxExport = append(missingMaxExport, 8, 0x01)
+
+	tests := map[string]struct {
+		b           []byte
+		expectedErr error
+	}{
+		"empty":              {b: nil, expectedErr: errRulesTooShort},
+		"unknown version":    {b: unknownVersion, expectedErr: errRulesUnknownVersion},
+		"unknown rule bit":   {b: unknownRule, expectedErr: errRulesUnknownRuleBitset},
+		"missing recipient":  {b: missingRecipient, expectedErr: errRulesTooShort},
+		"missing max export": {b: missingMaxExport, expectedErr: errRulesTooShort},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var rules Rules
+			if err := rules.UnmarshalBinary(test.b); !errors.Is(err, test.expectedErr) {
+				t.Fatalf("Expected error %s, found %v", test.expectedErr, err)
+			}
+		})
+	}
+}
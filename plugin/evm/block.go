@@ -118,20 +118,23 @@ func (b *Block) Accept() error {
 		return fmt.Errorf("failed to put %s as the last accepted block: %w", b.ID(), err)
 	}
 
-	tx, err := vm.extractAtomicTx(b.ethBlock)
+	txs, err := vm.extractAtomicTxs(b.ethBlock)
 	if err != nil {
 		return err
 	}
-	if tx == nil {
+	if len(txs) == 0 {
 		return vm.db.Commit()
 	}
 
-	// Remove the accepted transaction from the mempool
-	vm.mempool.RemoveTx(tx.ID())
+	for _, tx := range txs {
+		// Remove the accepted transaction from the mempool
+		vm.mempool.RemoveTx(tx.ID())
+		vm.network.AtomicTxAccepted(tx.ID())
 
-	// Save the accepted atomic transaction
-	if err := vm.writeAtomicTx(b, tx); err != nil {
-		return err
+		// Save the accepted atomic transaction
+		if err := vm.writeAtomicTx(b, tx); err != nil {
+			return err
+		}
 	}
 
 	if bonusBlocks.Contains(b.id) {
@@ -144,16 +147,29 @@ func (b *Block) Accept() error {
 		return fmt.Errorf("failed to create commit batch due to: %w", err)
 	}
 
-	return tx.UnsignedAtomicTx.Accept(vm.ctx, batch)
+	// Apply the shared memory requests of all the atomic txs at once, so
+	// that they are accepted atomically with [batch].
+	requests, err := mergeAtomicOps(txs)
+	if err != nil {
+		return err
+	}
+	if err := vm.ctx.SharedMemory.Apply(requests, batch); err != nil {
+		return err
+	}
+	rules := vm.chainConfig.AvalancheRules(b.ethBlock.Number(), new(big.Int).SetUint64(b.ethBlock.Time()))
+	for _, tx := range txs {
+		vm.recordAcceptedAtomicTx(tx, rules)
+	}
+	return nil
 }
 
 // Reject implements the snowman.Block interface
-// If [b] contains an atomic transaction, attempt to re-issue it
+// If [b] contains atomic transactions, attempt to re-issue them
 func (b *Block) Reject() error {
 	b.status = choices.Rejected
 	log.Debug(fmt.Sprintf("Rejecting block %s (%s) at height %d", b.ID().Hex(), b.ID(), b.Height()))
-	tx, _ := b.vm.extractAtomicTx(b.ethBlock)
-	if tx != nil {
+	txs, _ := b.vm.extractAtomicTxs(b.ethBlock)
+	for _, tx := range txs {
 		b.vm.mempool.RemoveTx(tx.ID())
 		if err := b.vm.issueTx(tx, false /* set local to false when re-issuing */); err != nil {
 			log.Debug("Failed to re-issue transaction in rejected block", "txID", tx.ID(), "err", err)
@@ -218,13 +234,25 @@ func (b *Block) verify(writes bool) error {
 		return errRejectedParent
 	}
 
-	// If the tx is an atomic tx, ensure that it doesn't conflict with any of
-	// its processing ancestry.
-	atomicTx, err := vm.extractAtomicTx(b.ethBlock)
+	// If the block contains atomic txs, ensure that they don't conflict with
+	// each other or with any of their processing ancestry.
+	atomicTxs, err := vm.extractAtomicTxs(b.ethBlock)
 	if err != nil {
 		return err
 	}
-	if atomicTx != nil {
+	if len(atomicTxs) > 0 {
+		if err := verifyAtomicTxOrder(atomicTxs, rules); err != nil {
+			return err
+		}
+		inputs := ids.Set{}
+		for _, atomicTx := range atomicTxs {
+			txInputs := atomicTx.UnsignedAtomicTx.InputUTXOs()
+			if inputs.Overlaps(txInputs) {
+				return errConflictingAtomicInputs
+			}
+			inputs.Union(txInputs)
+		}
+
 		// If the ancestor is unknown, then the parent failed verification when
 		// it was called.
 		// If the ancestor is rejected, then this block shouldn't be inserted
@@ -245,9 +273,11 @@ func (b *Block) verify(writes bool) error {
 		if bonusBlocks.Contains(b.id) {
 			log.Info("skipping atomic tx verification on bonus block", "block", b.id)
 		} else {
-			utx := atomicTx.UnsignedAtomicTx
-			if err := utx.SemanticVerify(vm, atomicTx, ancestor, b.ethBlock.BaseFee(), rules); err != nil {
-				return fmt.Errorf("invalid block due to failed semanatic verify: %w at height %d", err, b.Height())
+			for _, atomicTx := range atomicTxs {
+				utx := atomicTx.UnsignedAtomicTx
+				if err := utx.SemanticVerify(vm, atomicTx, ancestor, b.ethBlock.BaseFee(), rules); err != nil {
+					return fmt.Errorf("invalid block due to failed semanatic verify: %w at height %d", err, b.Height())
+				}
 			}
 		}
 	}
@@ -12,6 +12,8 @@ import (
 	"github.com/flare-foundation/coreth/core/types"
 	"github.com/flare-foundation/coreth/params"
 	"github.com/flare-foundation/coreth/trie"
+
+	"github.com/flare-foundation/flare/utils/math"
 )
 
 var (
@@ -126,13 +128,13 @@ func (v blockValidatorPhase0) SyntacticVerify(b *Block) error {
 	}
 	// Block must not be empty
 	//
-	// Note: extractAtomicTx also asserts a maximum size
-	atomicTx, err := b.vm.extractAtomicTx(b.ethBlock)
+	// Note: extractAtomicTxs also asserts a maximum size
+	atomicTxs, err := b.vm.extractAtomicTxs(b.ethBlock)
 	if err != nil {
 		return err
 	}
 	txs := b.ethBlock.Transactions()
-	if len(txs) == 0 && atomicTx == nil {
+	if len(txs) == 0 && len(atomicTxs) == 0 {
 		return errEmptyBlock
 	}
 
@@ -231,13 +233,13 @@ func (blockValidatorPhase1) SyntacticVerify(b *Block) error {
 	}
 	// Block must not be empty
 	//
-	// Note: extractAtomicTx also asserts a maximum size
-	atomicTx, err := b.vm.extractAtomicTx(b.ethBlock)
+	// Note: extractAtomicTxs also asserts a maximum size
+	atomicTxs, err := b.vm.extractAtomicTxs(b.ethBlock)
 	if err != nil {
 		return err
 	}
 	txs := b.ethBlock.Transactions()
-	if len(txs) == 0 && atomicTx == nil {
+	if len(txs) == 0 && len(atomicTxs) == 0 {
 		return errEmptyBlock
 	}
 
@@ -341,13 +343,13 @@ func (blockValidatorPhase3) SyntacticVerify(b *Block) error {
 	}
 	// Block must not be empty
 	//
-	// Note: extractAtomicTx also asserts a maximum size
-	atomicTx, err := b.vm.extractAtomicTx(b.ethBlock)
+	// Note: extractAtomicTxs also asserts a maximum size
+	atomicTxs, err := b.vm.extractAtomicTxs(b.ethBlock)
 	if err != nil {
 		return err
 	}
 	txs := b.ethBlock.Transactions()
-	if len(txs) == 0 && atomicTx == nil {
+	if len(txs) == 0 && len(atomicTxs) == 0 {
 		return errEmptyBlock
 	}
 
@@ -444,13 +446,13 @@ func (blockValidatorPhase4) SyntacticVerify(b *Block) error {
 	}
 	// Block must not be empty
 	//
-	// Note: extractAtomicTx also asserts a maximum size
-	atomicTx, err := b.vm.extractAtomicTx(b.ethBlock)
+	// Note: extractAtomicTxs also asserts a maximum size
+	atomicTxs, err := b.vm.extractAtomicTxs(b.ethBlock)
 	if err != nil {
 		return err
 	}
 	txs := b.ethBlock.Transactions()
-	if len(txs) == 0 && atomicTx == nil {
+	if len(txs) == 0 && len(atomicTxs) == 0 {
 		return errEmptyBlock
 	}
 
@@ -467,12 +469,19 @@ func (blockValidatorPhase4) SyntacticVerify(b *Block) error {
 	if !ethHeader.ExtDataGasUsed.IsUint64() {
 		return fmt.Errorf("too large extDataGasUsed : bitlen %d", ethHeader.ExtDataGasUsed.BitLen())
 	}
-	if atomicTx != nil {
+	if len(atomicTxs) > 0 {
 		// We perform this check manually here to avoid the overhead of having to
-		// reparse the atomicTx in `CalcExtDataGasUsed`.
-		gasUsed, err := atomicTx.GasUsed()
-		if err != nil {
-			return err
+		// reparse the atomicTxs in `CalcExtDataGasUsed`.
+		rules := b.vm.chainConfig.AvalancheRules(ethHeader.Number, new(big.Int).SetUint64(ethHeader.Time))
+		var gasUsed uint64
+		for _, atomicTx := range atomicTxs {
+			txGasUsed, err := atomicTx.GasUsed(rules)
+			if err != nil {
+				return err
+			}
+			if gasUsed, err = math.Add64(gasUsed, txGasUsed); err != nil {
+				return err
+			}
 		}
 		if ethHeader.ExtDataGasUsed.Cmp(new(big.Int).SetUint64(gasUsed)) != 0 {
 			return fmt.Errorf("invalid extDataGasUsed: have %d, want %d", ethHeader.ExtDataGasUsed, gasUsed)
@@ -4,14 +4,22 @@
 package evm
 
 import (
+	"encoding/binary"
+
 	"github.com/flare-foundation/flare/codec"
 	"github.com/flare-foundation/flare/codec/linearcodec"
 	"github.com/flare-foundation/flare/utils/wrappers"
 	"github.com/flare-foundation/flare/vms/secp256k1fx"
 )
 
-// Codec does serialization and deserialization
-var Codec codec.Manager
+var (
+	// Codec does serialization and deserialization
+	Codec codec.Manager
+
+	// unsignedAtomicTxTypeIDs are the type IDs of the unsigned atomic txs
+	// registered in [Codec]
+	unsignedAtomicTxTypeIDs = make(map[uint32]bool)
+)
 
 func init() {
 	Codec = codec.NewDefaultManager()
@@ -31,10 +39,28 @@ func init() {
 		c.RegisterType(&secp256k1fx.Credential{}),
 		c.RegisterType(&secp256k1fx.Input{}),
 		c.RegisterType(&secp256k1fx.OutputOwners{}),
+		// Registered last so that the type IDs above are unchanged
+		c.RegisterType(&UnsignedSponsoredImportTx{}),
+		c.RegisterType(&UnsignedContractImportTx{}),
 		Codec.RegisterCodec(codecVersion, c),
 	)
 
 	if errs.Errored() {
 		panic(errs.Err)
 	}
+
+	// The type ID of the unsigned tx follows the codec version in the
+	// encoding of a tx
+	for _, utx := range []UnsignedAtomicTx{
+		&UnsignedImportTx{},
+		&UnsignedExportTx{},
+		&UnsignedSponsoredImportTx{},
+		&UnsignedContractImportTx{},
+	} {
+		txBytes, err := Codec.Marshal(codecVersion, &Tx{UnsignedAtomicTx: utx})
+		if err != nil {
+			panic(err)
+		}
+		unsignedAtomicTxTypeIDs[binary.BigEndian.Uint32(txBytes[wrappers.ShortLen:])] = true
+	}
 }
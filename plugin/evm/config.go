@@ -5,6 +5,8 @@ package evm
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/flare-foundation/coreth/eth"
@@ -27,8 +29,16 @@ const (
 	defaultContinuousProfilerMaxFiles  = 5
 	defaultTxRegossipFrequency         = 1 * time.Minute
 	defaultTxRegossipMaxSize           = 15
+
+	// Bounds of the keystore scrypt parameters. The maximum cost uses 1 GiB
+	// of memory.
+	minKeystoreScryptN = 1 << 12
+	maxKeystoreScryptN = 1 << 20
+	maxKeystoreScryptP = 16
 )
 
+var errInvalidKeystoreKDF = errors.New("invalid keystore scrypt parameters")
+
 type Duration struct {
 	time.Duration
 }
@@ -40,6 +50,11 @@ type Config struct {
 	CorethAdminAPIEnabled bool `json:"coreth-admin-api-enabled"`
 	NetAPIEnabled         bool `json:"net-api-enabled"`
 
+	// NetVersionNetworkID makes net_version return the Avalanche network ID
+	// instead of the EVM chain ID. Ethereum tooling generally expects the chain
+	// ID, so this is disabled by default.
+	NetVersionNetworkID bool `json:"net-version-network-id"`
+
 	// Continuous Profiler
 	ContinuousProfilerDir       string   `json:"continuous-profiler-dir"`       // If set to non-empty string creates a continuous profiler
 	ContinuousProfilerFrequency Duration `json:"continuous-profiler-frequency"` // Frequency to run continuous profiler if enabled
@@ -75,12 +90,23 @@ type Config struct {
 	KeystoreDirectory             string `json:"keystore-directory"` // both absolute and relative supported
 	KeystoreExternalSigner        string `json:"keystore-external-signer"`
 	KeystoreInsecureUnlockAllowed bool   `json:"keystore-insecure-unlock-allowed"`
+	// KeystoreScryptN and KeystoreScryptP set the scrypt cost of encrypting
+	// the keys imported into the user keystore. If KeystoreScryptN is 0, keys
+	// are only protected by the encryption of the keystore itself.
+	KeystoreScryptN int `json:"keystore-scrypt-n"`
+	KeystoreScryptP int `json:"keystore-scrypt-p"`
 
 	// Gossip Settings
 	RemoteTxGossipOnlyEnabled bool     `json:"remote-tx-gossip-only-enabled"`
 	TxRegossipFrequency       Duration `json:"tx-regossip-frequency"`
 	TxRegossipMaxSize         int      `json:"tx-regossip-max-size"`
 
+	// Atomic Tx Settings
+	// AllowedAtomicAssets restricts the assets this node accepts in atomic txs
+	// issued to its mempool. If empty, every asset is accepted. AVAX is always
+	// accepted.
+	AllowedAtomicAssets []string `json:"allowed-atomic-assets"`
+
 	// Log level
 	LogLevel string `json:"log-level"`
 }
@@ -105,6 +131,25 @@ func (c Config) EthAPIs() []string {
 	return ethAPIs
 }
 
+// validateKeystoreKDF returns an error if the keystore scrypt parameters are
+// set but outside of the bounds that keep key imports both secure and
+// feasible. The scrypt r parameter is fixed at 8, so the memory used is
+// 1 KiB * N * p.
+func (c Config) validateKeystoreKDF() error {
+	n, p := c.KeystoreScryptN, c.KeystoreScryptP
+	switch {
+	case n == 0 && p == 0:
+		return nil
+	case n < minKeystoreScryptN || n > maxKeystoreScryptN || n&(n-1) != 0:
+		return fmt.Errorf("%w: N must be a power of 2 in [%d, %d], found %d", errInvalidKeystoreKDF, minKeystoreScryptN, maxKeystoreScryptN, n)
+	case p < 1 || p > maxKeystoreScryptP:
+		return fmt.Errorf("%w: p must be in [1, %d], found %d", errInvalidKeystoreKDF, maxKeystoreScryptP, p)
+	case n*p > maxKeystoreScryptN:
+		return fmt.Errorf("%w: N * p must be at most %d, found %d", errInvalidKeystoreKDF, maxKeystoreScryptN, n*p)
+	}
+	return nil
+}
+
 func (c Config) EthBackendSettings() eth.Settings {
 	return eth.Settings{MaxBlocksPerRequest: c.MaxBlocksPerRequest}
 }
@@ -58,3 +58,32 @@ func TestUnmarshalConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateKeystoreKDF(t *testing.T) {
+	tests := map[string]struct {
+		n, p      int
+		expectErr bool
+	}{
+		"disabled":         {n: 0, p: 0},
+		"minimum":          {n: minKeystoreScryptN, p: 1},
+		"maximum":          {n: maxKeystoreScryptN, p: 1},
+		"parallel":         {n: 1 << 16, p: maxKeystoreScryptP},
+		"missing N":        {n: 0, p: 1, expectErr: true},
+		"missing p":        {n: 1 << 16, p: 0, expectErr: true},
+		"N too small":      {n: minKeystoreScryptN / 2, p: 1, expectErr: true},
+		"N too large":      {n: maxKeystoreScryptN * 2, p: 1, expectErr: true},
+		"N not power of 2": {n: minKeystoreScryptN + 1, p: 1, expectErr: true},
+		"p too large":      {n: minKeystoreScryptN, p: maxKeystoreScryptP + 1, expectErr: true},
+		"memory too large": {n: maxKeystoreScryptN, p: 2, expectErr: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := Config{KeystoreScryptN: test.n, KeystoreScryptP: test.p}.validateKeystoreKDF()
+			if test.expectErr {
+				assert.ErrorIs(t, err, errInvalidKeystoreKDF)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package evm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/flare-foundation/coreth/core/state"
+	"github.com/flare-foundation/coreth/core/vm"
+	"github.com/flare-foundation/coreth/params"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/flare-foundation/flare/ids"
+	"github.com/flare-foundation/flare/snow"
+	"github.com/flare-foundation/flare/utils/crypto"
+	"github.com/flare-foundation/flare/utils/math"
+	"github.com/flare-foundation/flare/vms/components/avax"
+	"github.com/flare-foundation/flare/vms/secp256k1fx"
+)
+
+var (
+	// ContractImportCaller is the caller of the contract called by a contract
+	// import. It holds the imported AVAX only until the call sends it to the
+	// contract, and no key controls it.
+	ContractImportCaller = common.HexToAddress("0x0200000000000000000000000000000000000000")
+
+	// ContractImportCallGas is the gas limit of the contract call made by a
+	// contract import. It is charged in full as part of the gas used by the
+	// import, however much of it the call uses, and the rest is not refunded.
+	ContractImportCallGas uint64 = 100_000
+)
+
+// contractCaller is implemented by atomic txs that call a contract once their
+// EVMStateTransfer is applied. The call is made in the same state, as of the
+// block described by [blockCtx]. When a tx is verified at the tip, [blockCtx]
+// describes the expected next block, so the call may still fail in the block
+// that is built, in which case the builder discards the tx.
+type contractCaller interface {
+	EVMCall(blockCtx vm.BlockContext, state *state.StateDB, config *params.ChainConfig) error
+}
+
+// UnsignedContractImportTx is an import tx that deposits the imported AVAX
+// into a contract by calling it with [Data], such as the ABI-encoded call of
+// its deposit function. It is a separate type rather than an optional field
+// of UnsignedImportTx so that the encoding and IDs of existing import txs are
+// unchanged.
+//
+// Its only output credits AVAX to [Contract]. The AVAX is sent as the value
+// of the call from ContractImportCaller, so a tx whose call fails is invalid
+// rather than leaving the AVAX credited to the contract.
+//
+// Atomic txs have no receipts, so the call is restricted: it is invalid if it
+// emits logs, which would not be recorded anywhere, and it observes a zero
+// COINBASE and DIFFICULTY, which otherwise depend on the node that builds the
+// block.
+type UnsignedContractImportTx struct {
+	UnsignedImportTx `serialize:"true"`
+	// Contract that is credited and called
+	Contract common.Address `serialize:"true" json:"contract"`
+	// Data is the calldata of the call
+	Data []byte `serialize:"true" json:"data"`
+}
+
+// Verify this transaction is well-formed
+func (tx *UnsignedContractImportTx) Verify(
+	xChainID ids.ID,
+	ctx *snow.Context,
+	rules params.Rules,
+) error {
+	switch {
+	case tx == nil:
+		return errNilTx
+	case !rules.IsApricotPhase5:
+		return errContractImportNotActive
+	}
+	if err := tx.UnsignedImportTx.Verify(xChainID, ctx, rules); err != nil {
+		return err
+	}
+	if len(tx.Outs) != 1 || tx.Outs[0].Address != tx.Contract || tx.Outs[0].AssetID != ctx.AVAXAssetID {
+		return errInvalidContractImportOutput
+	}
+	return nil
+}
+
+// SigningBytes returns the bytes the credentials of [tx] sign over, so that
+// it can be signed by an offline signer.
+func (tx *UnsignedContractImportTx) SigningBytes() ([]byte, error) {
+	return signingBytes(tx)
+}
+
+// GasUsed returns the gas used by the import if [size] bytes of it are
+// charged, plus the gas limit of the contract call.
+func (tx *UnsignedContractImportTx) GasUsed(size int) (uint64, error) {
+	cost, err := tx.UnsignedImportTx.GasUsed(size)
+	if err != nil {
+		return 0, err
+	}
+	return math.Add64(cost, ContractImportCallGas)
+}
+
+// depositAmount returns the amount sent to the contract, in wei.
+func (tx *UnsignedContractImportTx) depositAmount() *big.Int {
+	return XtoC(tx.Outs[0].Amount)
+}
+
+// EVMStateTransfer credits the imported AVAX to ContractImportCaller, which
+// sends it to the contract in EVMCall
+func (tx *UnsignedContractImportTx) EVMStateTransfer(ctx *snow.Context, state *state.StateDB, rules params.Rules) error {
+	amount := tx.depositAmount()
+	log.Debug("crosschain X->C contract", "addr", tx.Contract, "amount", tx.Outs[0].Amount, "assetID", "AVAX")
+	if overflowsBalance(state.GetBalance(ContractImportCaller), amount) {
+		return fmt.Errorf("%w: crediting %s to %s", errBalanceOverflow, amount, ContractImportCaller)
+	}
+	state.AddBalance(ContractImportCaller, amount)
+	return creditAtomicFee(ctx, tx, state, rules)
+}
+
+// EVMCall calls the contract with the imported AVAX as value. The access list
+// is reset first, so that the gas used by the call does not depend on the
+// txs before it.
+func (tx *UnsignedContractImportTx) EVMCall(blockCtx vm.BlockContext, state *state.StateDB, config *params.ChainConfig) error {
+	if state.GetCodeSize(tx.Contract) == 0 {
+		return fmt.Errorf("%w: %s", errNotAContract, tx.Contract)
+	}
+	blockCtx.Coinbase = common.Address{}
+	blockCtx.Difficulty = new(big.Int)
+
+	txHash := common.Hash(tx.ID())
+	state.Prepare(txHash, 0)
+	rules := config.AvalancheRules(blockCtx.BlockNumber, blockCtx.Time)
+	state.PrepareAccessList(ContractImportCaller, &tx.Contract, vm.ActivePrecompiles(rules), nil)
+
+	evm := vm.NewEVM(blockCtx, vm.TxContext{Origin: ContractImportCaller}, state, config, vm.Config{})
+	_, _, err := evm.Call(vm.AccountRef(ContractImportCaller), tx.Contract, tx.Data, ContractImportCallGas, tx.depositAmount())
+	if err != nil {
+		return fmt.Errorf("%w: calling %s: %v", errContractCallFailed, tx.Contract, err)
+	}
+	if logs := state.GetLogs(txHash, common.Hash{}); len(logs) != 0 {
+		return fmt.Errorf("%w: calling %s emitted %d logs", errContractCallLogs, tx.Contract, len(logs))
+	}
+	return nil
+}
+
+// newContractImportTx returns a new contract import that imports the AVAX of
+// [keys] from [chainID] and calls [contract] with [data] and the imported AVAX
+// less the fee.
+func (vm *VM) newContractImportTx(
+	chainID ids.ID, // chain to import from
+	contract common.Address, // contract to deposit into
+	data []byte, // calldata of the deposit
+	baseFee *big.Int, // fee to use
+	keys []*crypto.PrivateKeySECP256K1R, // Keys to import the funds
+) (*Tx, error) {
+	rules := vm.currentRules()
+	switch {
+	case !rules.IsApricotPhase5:
+		return nil, errContractImportNotActive
+	case vm.ctx.XChainID != chainID:
+		return nil, errWrongChainID
+	case baseFee == nil:
+		return nil, errNilBaseFeeApricotPhase3
+	}
+
+	kc := secp256k1fx.NewKeychain()
+	for _, key := range keys {
+		kc.Add(key)
+	}
+
+	atomicUTXOs, _, _, err := vm.GetAtomicUTXOs(chainID, kc.Addresses(), ids.ShortEmpty, ids.Empty, -1)
+	if err != nil {
+		return nil, fmt.Errorf("problem retrieving atomic UTXOs: %w", err)
+	}
+
+	importedInputs := []*avax.TransferableInput{}
+	signers := [][]*crypto.PrivateKeySECP256K1R{}
+
+	var importedAmount uint64
+	now := vm.clock.Unix()
+	for _, utxo := range atomicUTXOs {
+		// Only AVAX can be sent as the value of the call
+		if utxo.AssetID() != vm.ctx.AVAXAssetID {
+			continue
+		}
+		inputIntf, utxoSigners, err := kc.Spend(utxo.Out, now)
+		if err != nil {
+			continue
+		}
+		input, ok := inputIntf.(avax.TransferableIn)
+		if !ok {
+			continue
+		}
+		importedAmount, err = math.Add64(importedAmount, input.Amount())
+		if err != nil {
+			return nil, err
+		}
+		importedInputs = append(importedInputs, &avax.TransferableInput{
+			UTXOID: utxo.UTXOID,
+			Asset:  utxo.Asset,
+			In:     input,
+		})
+		signers = append(signers, utxoSigners)
+	}
+	avax.SortTransferableInputsWithSigners(importedInputs, signers)
+
+	utx := &UnsignedContractImportTx{
+		UnsignedImportTx: UnsignedImportTx{
+			NetworkID:    vm.ctx.NetworkID,
+			BlockchainID: vm.ctx.ChainID,
+			Outs: []EVMOutput{{
+				Address: contract,
+				Amount:  importedAmount,
+				AssetID: vm.ctx.AVAXAssetID,
+			}},
+			ImportedInputs: importedInputs,
+			SourceChain:    chainID,
+		},
+		Contract: contract,
+		Data:     data,
+	}
+	// The credentials are charged, so the estimate is signed by the same keys
+	// as the final tx.
+	tx := &Tx{UnsignedAtomicTx: utx}
+	if err := tx.Sign(vm.codec, signers); err != nil {
+		return nil, err
+	}
+	gasUsed, err := tx.GasUsed(rules)
+	if err != nil {
+		return nil, err
+	}
+	txFee, err := calculateDynamicFee(gasUsed, baseFee)
+	if err != nil {
+		return nil, err
+	}
+	// The deposit must be non-zero, as empty outputs are invalid
+	if importedAmount <= txFee {
+		return nil, errInsufficientFundsForFee
+	}
+	utx.Outs[0].Amount = importedAmount - txFee
+
+	tx = &Tx{UnsignedAtomicTx: utx}
+	if err := tx.Sign(vm.codec, signers); err != nil {
+		return nil, err
+	}
+	return tx, utx.Verify(vm.ctx.XChainID, vm.ctx, rules)
+}
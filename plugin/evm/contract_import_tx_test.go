@@ -0,0 +1,291 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package evm
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/flare-foundation/coreth/core"
+	"github.com/flare-foundation/flare/ids"
+	"github.com/flare-foundation/flare/snow/choices"
+	"github.com/flare-foundation/flare/utils/crypto"
+)
+
+var (
+	testDepositContract = common.HexToAddress("0x0300000000000000000000000000000000000000")
+
+	// testDepositContractCode adds the value it is called with to the storage
+	// slot of the beneficiary address in the first word of its arguments, and
+	// reverts if the beneficiary is the zero address:
+	//
+	//	PUSH1 4 CALLDATALOAD DUP1 ISZERO PUSH1 0x10 JUMPI
+	//	DUP1 SLOAD CALLVALUE ADD SWAP1 SSTORE STOP
+	//	STOP JUMPDEST PUSH1 0 DUP1 REVERT
+	testDepositContractCode = common.FromHex("0x600435801560105780543401905500005b600080fd")
+
+	// testLogContract emits a log: PUSH1 0 PUSH1 0 LOG0 STOP
+	testLogContract     = common.HexToAddress("0x0300000000000000000000000000000000000001")
+	testLogContractCode = common.FromHex("0x60006000a000")
+
+	// testContextContract stores the COINBASE and DIFFICULTY it observes in
+	// slots 0 and 1: COINBASE PUSH1 0 SSTORE DIFFICULTY PUSH1 1 SSTORE STOP
+	testContextContract     = common.HexToAddress("0x0300000000000000000000000000000000000002")
+	testContextContractCode = common.FromHex("0x41600055446001550000")
+)
+
+// contractImportGenesisJSON returns a genesis as of Apricot Phase 5 that
+// deploys the test contracts.
+func contractImportGenesisJSON(t *testing.T) string {
+	genesis := &core.Genesis{}
+	if err := json.Unmarshal([]byte(genesisJSONApricotPhase5), genesis); err != nil {
+		t.Fatal(err)
+	}
+	for addr, code := range map[common.Address][]byte{
+		testDepositContract: testDepositContractCode,
+		testLogContract:     testLogContractCode,
+		testContextContract: testContextContractCode,
+	} {
+		genesis.Alloc[addr] = core.GenesisAccount{
+			Code:    code,
+			Balance: common.Big0,
+		}
+	}
+	genesisBytes, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(genesisBytes)
+}
+
+// depositCalldata returns the calldata of a deposit for [beneficiary].
+func depositCalldata(beneficiary common.Address) []byte {
+	// deposit(address)
+	return append(common.FromHex("0xf340fa01"), common.LeftPadBytes(beneficiary.Bytes(), common.HashLength)...)
+}
+
+func TestContractImportTx(t *testing.T) {
+	importAmount := uint64(50000000)
+	issuer, vm, _, _, _ := GenesisVMWithUTXOs(t, true, contractImportGenesisJSON(t), "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: importAmount,
+	})
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	tx, err := vm.newContractImportTx(vm.ctx.XChainID, testDepositContract, depositCalldata(testEthAddrs[1]), initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deposit := tx.UnsignedAtomicTx.(*UnsignedContractImportTx).Outs[0].Amount
+	burned, err := tx.Burned(vm.ctx.AVAXAssetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deposit+burned != importAmount {
+		t.Fatalf("Expected the deposit %d and fee %d to add up to the imported amount %d", deposit, burned, importAmount)
+	}
+
+	if err := vm.issueTx(tx, true /*=local*/); err != nil {
+		t.Fatal(err)
+	}
+
+	<-issuer
+
+	blk, err := vm.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.SetPreference(blk.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Accept(); err != nil {
+		t.Fatal(err)
+	}
+	if status := blk.Status(); status != choices.Accepted {
+		t.Fatalf("Expected status of accepted block to be %s, but found %s", choices.Accepted, status)
+	}
+
+	state, err := vm.chain.CurrentState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedBalance := XtoC(deposit)
+	if balance := state.GetBalance(testDepositContract); balance.Cmp(expectedBalance) != 0 {
+		t.Fatalf("Expected contract balance %d, found %d", expectedBalance, balance)
+	}
+	// The contract recorded the deposit of the beneficiary
+	recorded := state.GetState(testDepositContract, common.BytesToHash(testEthAddrs[1].Bytes())).Big()
+	if recorded.Cmp(expectedBalance) != 0 {
+		t.Fatalf("Expected contract to record deposit %d, found %d", expectedBalance, recorded)
+	}
+	if balance := state.GetBalance(ContractImportCaller); balance.Sign() != 0 {
+		t.Fatalf("Expected the caller to hold no balance after the call, found %d", balance)
+	}
+}
+
+func TestContractImportTxInvalidCall(t *testing.T) {
+	tests := map[string]struct {
+		contract    common.Address
+		data        []byte
+		expectedErr error
+	}{
+		"reverted call": {
+			contract:    testDepositContract,
+			data:        depositCalldata(common.Address{}),
+			expectedErr: errContractCallFailed,
+		},
+		"call emitting logs": {
+			contract:    testLogContract,
+			expectedErr: errContractCallLogs,
+		},
+		"not a contract": {
+			contract:    testEthAddrs[1],
+			data:        depositCalldata(testEthAddrs[1]),
+			expectedErr: errNotAContract,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, vm, _, _, _ := GenesisVMWithUTXOs(t, true, contractImportGenesisJSON(t), "", "", map[ids.ShortID]uint64{
+				testShortIDAddrs[0]: 50000000,
+			})
+			defer func() {
+				if err := vm.Shutdown(); err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			tx, err := vm.newContractImportTx(vm.ctx.XChainID, test.contract, test.data, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := vm.issueTx(tx, true /*=local*/); !errors.Is(err, test.expectedErr) {
+				t.Fatalf("Expected issuing the tx to fail with %s, found %v", test.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestContractImportTxNotActive(t *testing.T) {
+	_, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase4, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: 50000000,
+	})
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if _, err := vm.newContractImportTx(vm.ctx.XChainID, testDepositContract, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]}); !errors.Is(err, errContractImportNotActive) {
+		t.Fatalf("Expected building the tx to fail with %s, found %v", errContractImportNotActive, err)
+	}
+
+	// A contract import is invalid before Apricot Phase 5, even if built by
+	// another node.
+	utx := &UnsignedContractImportTx{Contract: testDepositContract}
+	if err := utx.Verify(vm.ctx.XChainID, vm.ctx, vm.currentRules()); !errors.Is(err, errContractImportNotActive) {
+		t.Fatalf("Expected verification to fail with %s, found %v", errContractImportNotActive, err)
+	}
+}
+
+func TestContractImportTxVerify(t *testing.T) {
+	_, vm, _, _, _ := GenesisVMWithUTXOs(t, true, contractImportGenesisJSON(t), "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: 50000000,
+	})
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	tx, err := vm.newContractImportTx(vm.ctx.XChainID, testDepositContract, depositCalldata(testEthAddrs[1]), initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	utx := tx.UnsignedAtomicTx.(*UnsignedContractImportTx)
+	rules := vm.currentRules()
+
+	// The output must credit the contract
+	utx.Outs[0].Address = testEthAddrs[1]
+	if err := utx.Verify(vm.ctx.XChainID, vm.ctx, rules); !errors.Is(err, errInvalidContractImportOutput) {
+		t.Fatalf("Expected verification to fail with %s, found %v", errInvalidContractImportOutput, err)
+	}
+	utx.Outs[0].Address = testDepositContract
+
+	if err := utx.Verify(vm.ctx.XChainID, vm.ctx, rules); err != nil {
+		t.Fatal(err)
+	}
+
+	// The gas used includes the gas limit of the call
+	gasUsed, err := tx.GasUsed(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err := tx.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	importGasUsed, err := utx.UnsignedImportTx.GasUsed(size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gasUsed != importGasUsed+ContractImportCallGas {
+		t.Fatalf("Expected gas used %d, found %d", importGasUsed+ContractImportCallGas, gasUsed)
+	}
+}
+
+func TestContractImportTxBlockContext(t *testing.T) {
+	issuer, vm, _, _, _ := GenesisVMWithUTXOs(t, true, contractImportGenesisJSON(t), "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: 50000000,
+	})
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	tx, err := vm.newContractImportTx(vm.ctx.XChainID, testContextContract, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.issueTx(tx, true /*=local*/); err != nil {
+		t.Fatal(err)
+	}
+
+	<-issuer
+
+	blk, err := vm.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.SetPreference(blk.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Accept(); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := vm.chain.CurrentState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The call observes neither the coinbase nor the difficulty of the block
+	for slot := int64(0); slot < 2; slot++ {
+		if value := state.GetState(testContextContract, common.BigToHash(big.NewInt(slot))); value != (common.Hash{}) {
+			t.Fatalf("Expected the call to store zero in slot %d, found %s", slot, value)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package evm
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// XtoC converts [nAVAX], an amount of AVAX in the denomination of the X-Chain,
+// to wei, the denomination of the EVM. The conversion is exact.
+func XtoC(nAVAX uint64) *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(nAVAX), x2cRate)
+}
+
+// CtoX converts [wei] to nAVAX, the denomination of AVAX on the X-Chain. Any
+// amount below 1 nAVAX is truncated, so CtoX(XtoC(n)) == n for every n and
+// XtoC(CtoX(wei)) is [wei] rounded down to a whole nAVAX. It returns an error
+// if [wei] is negative or if the result does not fit in a uint64.
+func CtoX(wei *big.Int) (uint64, error) {
+	if wei.Sign() < 0 {
+		return 0, fmt.Errorf("%w: %s", errNegativeAmount, wei)
+	}
+	nAVAX := new(big.Int).Quo(wei, x2cRate)
+	if !nAVAX.IsUint64() {
+		return 0, fmt.Errorf("%w: %s wei", errAmountOverflow, wei)
+	}
+	return nAVAX.Uint64(), nil
+}
@@ -0,0 +1,60 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package evm
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+// assertScalingRoundTrip verifies that [nAvax] survives a round trip through
+// wei, and that any amount of wei between it and the next nAVAX truncates to
+// it.
+func assertScalingRoundTrip(nAvax uint64, t *testing.T) {
+	t.Helper()
+
+	wei := XtoC(nAvax)
+	if expected := new(big.Int).Mul(new(big.Int).SetUint64(nAvax), big.NewInt(x2cRateInt64)); wei.Cmp(expected) != 0 {
+		t.Fatalf("Expected %d nAVAX to be %d wei, found %d", nAvax, expected, wei)
+	}
+	roundTrip, err := CtoX(wei)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roundTrip != nAvax {
+		t.Fatalf("Expected %d nAVAX to round trip, found %d", nAvax, roundTrip)
+	}
+
+	for _, remainder := range []int64{1, x2cRateInt64 / 2, x2cRateMinus1Int64} {
+		truncated, err := CtoX(new(big.Int).Add(wei, big.NewInt(remainder)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if truncated != nAvax {
+			t.Fatalf("Expected %d nAVAX plus %d wei to truncate to %d nAVAX, found %d", nAvax, remainder, nAvax, truncated)
+		}
+	}
+}
+
+func TestScalingRoundTrip(t *testing.T) {
+	for _, nAvax := range []uint64{0, 1, 2, uint64(x2cRateInt64), math.MaxUint64 / 2, math.MaxUint64 - 1, math.MaxUint64} {
+		assertScalingRoundTrip(nAvax, t)
+	}
+}
+
+func TestCtoXInvalid(t *testing.T) {
+	if _, err := CtoX(big.NewInt(-1)); !errors.Is(err, errNegativeAmount) {
+		t.Fatalf("Expected negative amount to fail with %s, found %v", errNegativeAmount, err)
+	}
+	tooLarge := new(big.Int).Add(XtoC(math.MaxUint64), big.NewInt(x2cRateInt64))
+	if _, err := CtoX(tooLarge); !errors.Is(err, errAmountOverflow) {
+		t.Fatalf("Expected amount above the maximum nAVAX to fail with %s, found %v", errAmountOverflow, err)
+	}
+	// Less than 1 nAVAX truncates to zero rather than rounding up.
+	if nAvax, err := CtoX(big.NewInt(x2cRateMinus1Int64)); err != nil || nAvax != 0 {
+		t.Fatalf("Expected less than 1 nAVAX to truncate to 0, found %d, %v", nAvax, err)
+	}
+}
@@ -10,11 +10,16 @@ import (
 	"github.com/flare-foundation/coreth/core/state"
 	"github.com/flare-foundation/coreth/params"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/flare-foundation/flare/chains/atomic"
 	"github.com/flare-foundation/flare/database"
 	"github.com/flare-foundation/flare/ids"
 	"github.com/flare-foundation/flare/snow"
 	"github.com/flare-foundation/flare/utils/crypto"
+	"github.com/flare-foundation/flare/utils/math"
 	"github.com/flare-foundation/flare/vms/components/avax"
+	"github.com/flare-foundation/flare/vms/secp256k1fx"
 )
 
 // UnsignedExportTx is an unsigned ExportTx
@@ -34,7 +39,11 @@ type UnsignedExportTx struct {
 
 // InputUTXOs returns a set of all the hash(address:nonce) exporting funds.
 func (tx *UnsignedExportTx) InputUTXOs() ids.Set {
-	return ids.Set{}
+	set := ids.NewSet(len(tx.Ins))
+	for _, in := range tx.Ins {
+		set.Add(in.InputID())
+	}
+	return set
 }
 
 // Verify this transaction is well-formed
@@ -43,16 +52,148 @@ func (tx *UnsignedExportTx) Verify(
 	ctx *snow.Context,
 	rules params.Rules,
 ) error {
-	return errWrongChainID
+	switch {
+	case tx == nil:
+		return errNilTx
+	case !rules.IsAtomicTxs:
+		return errAtomicTxDisabled
+	case tx.DestinationChain != xChainID:
+		return errWrongChainID
+	case len(tx.ExportedOutputs) == 0:
+		return errNoExportOutputs
+	case tx.NetworkID != ctx.NetworkID:
+		return errWrongNetworkID
+	case ctx.ChainID != tx.BlockchainID:
+		return errWrongBlockchainID
+	}
+
+	if err := verifyAtomicTxSize(len(tx.Ins), len(tx.ExportedOutputs), rules); err != nil {
+		return err
+	}
+
+	for _, in := range tx.Ins {
+		if err := in.Verify(); err != nil {
+			return err
+		}
+	}
+	if err := verifyEVMInputNonces(tx.Ins); err != nil {
+		return err
+	}
+
+	for _, out := range tx.ExportedOutputs {
+		if err := out.Verify(); err != nil {
+			return err
+		}
+	}
+	if !avax.IsSortedTransferableOutputs(tx.ExportedOutputs, Codec) {
+		return errOutputsNotSorted
+	}
+	if rules.IsApricotPhase1 && !IsSortedAndUniqueEVMInputs(tx.Ins) {
+		return errInputsNotSortedUnique
+	}
+	if rules.MaxExportAmount != nil {
+		var exported uint64
+		for _, out := range tx.ExportedOutputs {
+			if out.AssetID() != ctx.AVAXAssetID {
+				continue
+			}
+			var err error
+			if exported, err = math.Add64(exported, out.Output().Amount()); err != nil {
+				return err
+			}
+		}
+		if new(big.Int).SetUint64(exported).Cmp(rules.MaxExportAmount) > 0 {
+			return fmt.Errorf("%w: %d > %d", errExportAmountTooLarge, exported, rules.MaxExportAmount)
+		}
+	}
+
+	return nil
+}
+
+// verifyEVMInputNonces verifies that all of the inputs in [ins] spent from the
+// same address carry the same nonce. The state transfer of an export checks
+// every input against the current nonce of its address, and then increments
+// the nonce of each address once, so inputs from an address with any other
+// nonces could never be applied.
+func verifyEVMInputNonces(ins []EVMInput) error {
+	nonces := make(map[common.Address]uint64, len(ins))
+	for _, in := range ins {
+		nonce, ok := nonces[in.Address]
+		if !ok {
+			nonces[in.Address] = in.Nonce
+			continue
+		}
+		if nonce != in.Nonce {
+			return fmt.Errorf("%w: %s spends with nonces %d and %d", errNonSequentialNonces, in.Address.Hex(), nonce, in.Nonce)
+		}
+	}
+	return nil
+}
+
+// SigningBytes returns the bytes the credentials of [tx] sign over, so that
+// it can be signed by an offline signer. Each signature is over the SHA256
+// hash of these bytes. Tx.ID() hashes the signed tx instead, so it depends on
+// the credentials as well.
+func (tx *UnsignedExportTx) SigningBytes() ([]byte, error) {
+	return signingBytes(tx)
 }
 
-func (tx *UnsignedExportTx) GasUsed() (uint64, error) {
-	return 0, fmt.Errorf("exportTx transactions disabled")
+// GasUsed returns the gas used by the export if [size] bytes of it are
+// charged, plus the cost of verifying the signatures of its inputs.
+func (tx *UnsignedExportTx) GasUsed(size int) (uint64, error) {
+	byteCost := calcBytesCost(size)
+	numSigs := uint64(len(tx.Ins))
+	sigCost, err := math.Mul64(numSigs, secp256k1fx.CostPerSignature)
+	if err != nil {
+		return 0, err
+	}
+	return math.Add64(byteCost, sigCost)
 }
 
 // Amount of [assetID] burned by this transaction
 func (tx *UnsignedExportTx) Burned(assetID ids.ID) (uint64, error) {
-	return 0, fmt.Errorf("exportTx transactions disabled")
+	var (
+		spent uint64
+		input uint64
+		err   error
+	)
+	for _, out := range tx.ExportedOutputs {
+		if out.AssetID() == assetID {
+			spent, err = math.Add64(spent, out.Output().Amount())
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	for _, in := range tx.Ins {
+		if in.AssetID == assetID {
+			input, err = math.Add64(input, in.Amount)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return math.Sub64(input, spent)
+}
+
+// AllBurned returns the amount of each asset burned by this transaction
+func (tx *UnsignedExportTx) AllBurned() (map[ids.ID]uint64, error) {
+	burned := make(map[ids.ID]uint64)
+	for _, in := range tx.Ins {
+		burned[in.AssetID] = 0
+	}
+	for _, out := range tx.ExportedOutputs {
+		burned[out.AssetID()] = 0
+	}
+	for assetID := range burned {
+		amount, err := tx.Burned(assetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate burned amount of %s: %w", assetID, err)
+		}
+		burned[assetID] = amount
+	}
+	return burned, nil
 }
 
 // SemanticVerify this transaction is valid.
@@ -63,12 +204,147 @@ func (tx *UnsignedExportTx) SemanticVerify(
 	baseFee *big.Int,
 	rules params.Rules,
 ) error {
-	return fmt.Errorf("exportTx transactions disabled")
+	if err := stx.Verify(vm.ctx.XChainID, vm.ctx, rules); err != nil {
+		return err
+	}
+	gasUsed, err := stx.GasUsed(rules)
+	if err != nil {
+		return err
+	}
+	if err := tx.verifyBalance(vm.ctx.AVAXAssetID, gasUsed, baseFee, rules); err != nil {
+		return err
+	}
+	for i, input := range tx.Ins {
+		cred, ok := stx.Creds[i].(*secp256k1fx.Credential)
+		if !ok {
+			return fmt.Errorf("expected *secp256k1fx.Credential but got %T", cred)
+		}
+		if err := cred.Verify(); err != nil {
+			return err
+		}
+
+		if len(cred.Sigs) != 1 {
+			return fmt.Errorf("expected one signature for EVM Input Credential, but found: %d", len(cred.Sigs))
+		}
+		pubKeyIntf, err := vm.secpFactory.RecoverPublicKey(tx.UnsignedBytes(), cred.Sigs[0][:])
+		if err != nil {
+			return err
+		}
+		pubKey, ok := pubKeyIntf.(*crypto.PublicKeySECP256K1R)
+		if !ok {
+			// This should never happen
+			return fmt.Errorf("expected *crypto.PublicKeySECP256K1R but got %T", pubKeyIntf)
+		}
+		if input.Address != PublicKeyToEthAddress(pubKey) {
+			return errPublicKeySignatureMismatch
+		}
+	}
+
+	return nil
+}
+
+// verifyBalance verifies that the inputs of [tx] cover its exported outputs
+// plus the fee. The fee is paid in [avaxAssetID], is calculated from [gasUsed]
+// and [baseFee] after Apricot Phase 3 and is fixed before that. Overpaying the fee is
+// allowed, so that a tx built at one base fee stays valid if the base fee
+// drops. From Apricot Phase 5, every other asset must balance exactly; before
+// that, its inputs only need to cover its outputs.
+func (tx *UnsignedExportTx) verifyBalance(avaxAssetID ids.ID, gasUsed uint64, baseFee *big.Int, rules params.Rules) error {
+	var fee uint64
+	switch {
+	case rules.IsApricotPhase3:
+		if baseFee == nil {
+			return errNilBaseFee
+		}
+		var err error
+		fee, err = calculateDynamicFee(gasUsed, baseFee)
+		if err != nil {
+			return err
+		}
+	default:
+		fee = params.AvalancheAtomicTxFee
+	}
+
+	assets := ids.Set{avaxAssetID: struct{}{}}
+	for _, in := range tx.Ins {
+		assets.Add(in.AssetID)
+	}
+	for _, out := range tx.ExportedOutputs {
+		assets.Add(out.AssetID())
+	}
+	for assetID := range assets {
+		burned, err := tx.Burned(assetID)
+		if err != nil {
+			return fmt.Errorf("%w for asset %s: %s", errExportImbalance, assetID, err)
+		}
+		switch {
+		case assetID == avaxAssetID && burned < fee:
+			return fmt.Errorf("%w for asset %s: burned %d, but expected at least %d", errExportImbalance, assetID, burned, fee)
+		case assetID != avaxAssetID && rules.IsApricotPhase5 && burned != 0:
+			return fmt.Errorf("%w for asset %s: burned %d, but expected 0", errExportImbalance, assetID, burned)
+		}
+	}
+	return nil
 }
 
 // Accept this transaction.
 func (tx *UnsignedExportTx) Accept(ctx *snow.Context, batch database.Batch) error {
-	return fmt.Errorf("exportTx transactions disabled")
+	return tx.Apply(ctx, batch)
+}
+
+// Requests returns the shared memory requests of this tx, which put the
+// exported UTXOs into the destination chain.
+func (tx *UnsignedExportTx) Requests() (map[ids.ID]*atomic.Requests, error) {
+	txID := tx.ID()
+	utxoIDs := ExportUTXOIDs(txID, len(tx.ExportedOutputs))
+
+	elems := make([]*atomic.Element, len(tx.ExportedOutputs))
+	for i, out := range tx.ExportedOutputs {
+		utxo := &avax.UTXO{
+			UTXOID: avax.UTXOID{
+				TxID:        txID,
+				OutputIndex: uint32(i),
+			},
+			Asset: avax.Asset{ID: out.AssetID()},
+			Out:   out.Out,
+		}
+
+		utxoBytes, err := Codec.Marshal(codecVersion, utxo)
+		if err != nil {
+			return nil, err
+		}
+		elem := &atomic.Element{
+			Key:   utxoIDs[i][:],
+			Value: utxoBytes,
+		}
+		if out, ok := utxo.Out.(avax.Addressable); ok {
+			elem.Traits = out.Addresses()
+		}
+
+		elems[i] = elem
+	}
+
+	return map[ids.ID]*atomic.Requests{tx.DestinationChain: {PutRequests: elems}}, nil
+}
+
+// ExportUTXOIDs returns the IDs of the UTXOs produced by the export tx
+// [txID] with [numOutputs] exported outputs, in output order. The IDs only
+// depend on the tx ID, so they are known before the tx is accepted.
+func ExportUTXOIDs(txID ids.ID, numOutputs int) []ids.ID {
+	utxoIDs := make([]ids.ID, numOutputs)
+	for i := range utxoIDs {
+		utxoID := avax.UTXOID{
+			TxID:        txID,
+			OutputIndex: uint32(i),
+		}
+		utxoIDs[i] = utxoID.InputID()
+	}
+	return utxoIDs
+}
+
+// Apply puts the exported UTXOs into shared memory atomically with [batch].
+func (tx *UnsignedExportTx) Apply(ctx *snow.Context, batch database.Batch) error {
+	return applyAtomicOps(ctx, tx, batch)
 }
 
 // newExportTx returns a new ExportTx
@@ -80,10 +356,147 @@ func (vm *VM) newExportTx(
 	baseFee *big.Int, // fee to use post-AP3
 	keys []*crypto.PrivateKeySECP256K1R, // Pay the fee and provide the tokens
 ) (*Tx, error) {
-	return nil, errWrongChainID
+	if !vm.currentRules().IsAtomicTxs {
+		return nil, errAtomicTxDisabled
+	}
+	if vm.ctx.XChainID != chainID {
+		return nil, errWrongChainID
+	}
+	// [amount] is denominated as in atomic txs, so an amount of AVAX
+	// converted from wei is zero if it was less than the x2c rate.
+	if amount == 0 {
+		return nil, fmt.Errorf("%w: amounts of AVAX below %d wei round down to zero", errZeroExportAmount, x2cRate)
+	}
+
+	outs := []*avax.TransferableOutput{{ // Exported to X-Chain
+		Asset: avax.Asset{ID: assetID},
+		Out: &secp256k1fx.TransferOutput{
+			Amt: amount,
+			OutputOwners: secp256k1fx.OutputOwners{
+				Locktime:  0,
+				Threshold: 1,
+				Addrs:     []ids.ShortID{to},
+			},
+		},
+	}}
+
+	var (
+		avaxNeeded           uint64 = 0
+		ins, avaxIns         []EVMInput
+		signers, avaxSigners [][]*crypto.PrivateKeySECP256K1R
+		err                  error
+	)
+
+	// consume non-AVAX
+	if assetID != vm.ctx.AVAXAssetID {
+		ins, signers, err = vm.GetSpendableFunds(keys, assetID, amount)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't generate tx inputs/signers: %w", err)
+		}
+	} else {
+		avaxNeeded = amount
+	}
+
+	rules := vm.currentRules()
+	switch {
+	case rules.IsApricotPhase3:
+		utx := &UnsignedExportTx{
+			NetworkID:        vm.ctx.NetworkID,
+			BlockchainID:     vm.ctx.ChainID,
+			DestinationChain: chainID,
+			Ins:              ins,
+			ExportedOutputs:  outs,
+		}
+		// The credentials of the non-AVAX inputs are charged as of Apricot
+		// Phase 5, so they are included in the estimate.
+		tx := &Tx{UnsignedAtomicTx: utx}
+		if err := tx.Sign(vm.codec, signers); err != nil {
+			return nil, err
+		}
+
+		var cost uint64
+		cost, err = tx.GasUsed(rules)
+		if err != nil {
+			return nil, err
+		}
+
+		avaxIns, avaxSigners, err = vm.GetSpendableAVAXWithFee(keys, avaxNeeded, cost, baseFee, rules)
+	default:
+		var newAvaxNeeded uint64
+		newAvaxNeeded, err = math.Add64(avaxNeeded, params.AvalancheAtomicTxFee)
+		if err != nil {
+			return nil, errOverflowExport
+		}
+		avaxIns, avaxSigners, err = vm.GetSpendableFunds(keys, vm.ctx.AVAXAssetID, newAvaxNeeded)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate tx inputs/signers: %w", err)
+	}
+	ins = append(ins, avaxIns...)
+	signers = append(signers, avaxSigners...)
+
+	if err := vm.verifyPendingNonces(ins); err != nil {
+		return nil, err
+	}
+
+	avax.SortTransferableOutputs(outs, vm.codec)
+	SortEVMInputsAndSigners(ins, signers)
+
+	// Create the transaction
+	utx := &UnsignedExportTx{
+		NetworkID:        vm.ctx.NetworkID,
+		BlockchainID:     vm.ctx.ChainID,
+		DestinationChain: chainID,
+		Ins:              ins,
+		ExportedOutputs:  outs,
+	}
+	tx := &Tx{UnsignedAtomicTx: utx}
+	if err := tx.Sign(vm.codec, signers); err != nil {
+		return nil, err
+	}
+	return tx, utx.Verify(vm.ctx.XChainID, vm.ctx, vm.currentRules())
+}
+
+// verifyPendingNonces verifies that the nonce of each of [ins] matches the
+// pending nonce of its address. An input built from a stale nonce can never be
+// accepted, so this reports the problem when the export tx is built.
+func (vm *VM) verifyPendingNonces(ins []EVMInput) error {
+	txPool := vm.chain.GetTxPool()
+	for _, in := range ins {
+		if pendingNonce := txPool.Nonce(in.Address); in.Nonce != pendingNonce {
+			return fmt.Errorf("%w: input from %s has nonce %d, but the pending nonce is %d", errNonceMismatch, in.Address, in.Nonce, pendingNonce)
+		}
+	}
+	return nil
 }
 
 // EVMStateTransfer executes the state update from the atomic export transaction
-func (tx *UnsignedExportTx) EVMStateTransfer(ctx *snow.Context, state *state.StateDB) error {
-	return errInsufficientFunds
+func (tx *UnsignedExportTx) EVMStateTransfer(ctx *snow.Context, state *state.StateDB, rules params.Rules) error {
+	addrs := map[[20]byte]uint64{}
+	for _, from := range tx.Ins {
+		if from.AssetID == ctx.AVAXAssetID {
+			log.Debug("crosschain C->X", "addr", from.Address, "amount", from.Amount, "assetID", "AVAX")
+			// Convert the input amount in nAVAX back to wei before export.
+			amount := XtoC(from.Amount)
+			if state.GetBalance(from.Address).Cmp(amount) < 0 {
+				return errInsufficientFunds
+			}
+			state.SubBalance(from.Address, amount)
+		} else {
+			log.Debug("crosschain C->X", "addr", from.Address, "amount", from.Amount, "assetID", from.AssetID)
+			amount := new(big.Int).SetUint64(from.Amount)
+			if state.GetBalanceMultiCoin(from.Address, common.Hash(from.AssetID)).Cmp(amount) < 0 {
+				return errInsufficientFunds
+			}
+			state.SubBalanceMultiCoin(from.Address, common.Hash(from.AssetID), amount)
+		}
+		if state.GetNonce(from.Address) != from.Nonce {
+			return errInvalidNonce
+		}
+		addrs[from.Address] = from.Nonce
+	}
+	for addr, nonce := range addrs {
+		state.SetNonce(addr, nonce+1)
+	}
+	return creditAtomicFee(ctx, tx, state, rules)
 }
@@ -5,6 +5,7 @@ package evm
 
 import (
 	"bytes"
+	"errors"
 	"math/big"
 	"testing"
 
@@ -52,7 +53,7 @@ func createExportTxOptions(t *testing.T, vm *VM, issuer chan engCommon.Message,
 	}
 
 	// Import the funds
-	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -364,7 +365,7 @@ func TestExportTxEVMStateTransfer(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+			tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -401,7 +402,7 @@ func TestExportTxEVMStateTransfer(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			err = newTx.EVMStateTransfer(vm.ctx, stateDB)
+			err = newTx.EVMStateTransfer(vm.ctx, stateDB, vm.currentRules())
 			if test.shouldErr {
 				if err == nil {
 					t.Fatal("expected EVMStateTransfer to fail")
@@ -1105,10 +1106,101 @@ func TestExportTxVerify(t *testing.T) {
 	if err := exportTx.Verify(testXChainID, ctx, apricotRulesPhase1); err == nil {
 		t.Fatal("ExportTx should have failed verification due to non-unique inputs")
 	}
+	multiCoinInput := evmInputs[0]
+	multiCoinInput.AssetID = testAvaxAssetID
+	multiCoinInput.AssetID[0]++
+	exportTx.Ins = []EVMInput{evmInputs[0], multiCoinInput}
+	SortEVMInputsAndSigners(exportTx.Ins, emptySigners)
+	// Test inputs of different assets from one address with the same nonce
+	// pass verification
+	if err := exportTx.Verify(testXChainID, ctx, apricotRulesPhase1); err != nil {
+		t.Fatalf("ExportTx with two inputs sharing a nonce should have passed verification, but failed due to %s", err)
+	}
+	multiCoinInput.Nonce = evmInputs[0].Nonce + 2
+	exportTx.Ins = []EVMInput{evmInputs[0], multiCoinInput}
+	SortEVMInputsAndSigners(exportTx.Ins, emptySigners)
+	// Test inputs from one address with different nonces fail verification
+	for _, rules := range []params.Rules{apricotRulesPhase0, apricotRulesPhase1} {
+		if err := exportTx.Verify(testXChainID, ctx, rules); !errors.Is(err, errNonSequentialNonces) {
+			t.Fatalf("Expected ExportTx with non-contiguous nonces from one address to fail with %s, found %v", errNonSequentialNonces, err)
+		}
+	}
+
+	exportTx.Ins = evmInputs
+	limitedRules := apricotRulesPhase1
+	limitedRules.MaxAtomicInputs = 2
+	limitedRules.MaxAtomicOutputs = 2
+	// Test ExportTx at the input and output limits passes verification
+	if err := exportTx.Verify(testXChainID, ctx, limitedRules); err != nil {
+		t.Fatalf("ExportTx at the input and output limits should have passed verification, but failed due to %s", err)
+	}
+	limitedRules.MaxAtomicInputs = 1
+	// Test ExportTx above the input limit fails verification
+	if err := exportTx.Verify(testXChainID, ctx, limitedRules); !errors.Is(err, errTooManyAtomicInputs) {
+		t.Fatalf("ExportTx should have failed verification due to %s, but found %v", errTooManyAtomicInputs, err)
+	}
+	limitedRules.MaxAtomicInputs = 2
+	limitedRules.MaxAtomicOutputs = 1
+	// Test ExportTx above the output limit fails verification
+	if err := exportTx.Verify(testXChainID, ctx, limitedRules); !errors.Is(err, errTooManyAtomicOutputs) {
+		t.Fatalf("ExportTx should have failed verification due to %s, but found %v", errTooManyAtomicOutputs, err)
+	}
 }
 
 // Note: this is a brittle test to ensure that the gas cost of a transaction does
 // not change
+func TestExportTxVerifyMaxExportAmount(t *testing.T) {
+	var exportAmount uint64 = 10000000
+	exportTx := &UnsignedExportTx{
+		NetworkID:        testNetworkID,
+		BlockchainID:     testCChainID,
+		DestinationChain: testXChainID,
+		Ins: []EVMInput{
+			{
+				Address: testEthAddrs[0],
+				Amount:  2 * exportAmount,
+				AssetID: testAvaxAssetID,
+				Nonce:   0,
+			},
+		},
+	}
+	for _, addr := range testShortIDAddrs[:2] {
+		exportTx.ExportedOutputs = append(exportTx.ExportedOutputs, &avax.TransferableOutput{
+			Asset: avax.Asset{ID: testAvaxAssetID},
+			Out: &secp256k1fx.TransferOutput{
+				Amt: exportAmount,
+				OutputOwners: secp256k1fx.OutputOwners{
+					Threshold: 1,
+					Addrs:     []ids.ShortID{addr},
+				},
+			},
+		})
+	}
+	avax.SortTransferableOutputs(exportTx.ExportedOutputs, Codec)
+	ctx := NewContext()
+
+	tests := map[string]struct {
+		maxExportAmount *big.Int
+		expectedErr     error
+	}{
+		"no limit":    {maxExportAmount: nil},
+		"below limit": {maxExportAmount: new(big.Int).SetUint64(2*exportAmount + 1)},
+		"at limit":    {maxExportAmount: new(big.Int).SetUint64(2 * exportAmount)},
+		"above limit": {maxExportAmount: new(big.Int).SetUint64(2*exportAmount - 1), expectedErr: errExportAmountTooLarge},
+		"zero limit":  {maxExportAmount: new(big.Int), expectedErr: errExportAmountTooLarge},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rules := apricotRulesPhase3
+			rules.MaxExportAmount = test.maxExportAmount
+			err := exportTx.Verify(testXChainID, ctx, rules)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("Expected error %v, found %v", test.expectedErr, err)
+			}
+		})
+	}
+}
+
 func TestExportTxGasCost(t *testing.T) {
 	avaxAssetID := ids.GenerateTestID()
 	chainID := ids.GenerateTestID()
@@ -1319,7 +1411,7 @@ func TestExportTxGasCost(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			gasUsed, err := tx.GasUsed()
+			gasUsed, err := tx.GasUsed(apricotRulesPhase4)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -1381,6 +1473,14 @@ func TestNewExportTx(t *testing.T) {
 			bal:                44446500,
 			expectedBurnedAVAX: 276750,
 		},
+		{
+			// The credential of each input is charged as well.
+			name:               "apricot phase 5",
+			genesis:            genesisJSONApricotPhase5,
+			rules:              apricotRulesPhase5,
+			bal:                44411850,
+			expectedBurnedAVAX: 294075,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -1424,7 +1524,7 @@ func TestNewExportTx(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+			tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -1486,7 +1586,7 @@ func TestNewExportTx(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
-			err = exportTx.EVMStateTransfer(vm.ctx, sdb)
+			err = exportTx.EVMStateTransfer(vm.ctx, sdb, test.rules)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -1608,7 +1708,7 @@ func TestNewExportTxMulticoin(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+			tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -1668,7 +1768,7 @@ func TestNewExportTxMulticoin(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
-			err = exportTx.EVMStateTransfer(vm.ctx, stdb)
+			err = exportTx.EVMStateTransfer(vm.ctx, stdb, test.rules)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -1683,3 +1783,373 @@ func TestNewExportTxMulticoin(t *testing.T) {
 		})
 	}
 }
+
+func TestNewExportTxStaleNonce(t *testing.T) {
+	genesisJSON, err := fundAddressByGenesis([]common.Address{testEthAddrs[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, vm, _, _, _ := GenesisVM(t, true, genesisJSON, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	keys := []*crypto.PrivateKeySECP256K1R{testKeys[0]}
+	exportAmount := uint64(1000)
+	if _, err := vm.newExportTx(vm.ctx.AVAXAssetID, exportAmount, vm.ctx.XChainID, testShortIDAddrs[0], initialBaseFee, keys); err != nil {
+		t.Fatalf("Failed to create export tx without pending txs: %s", err)
+	}
+
+	// Issuing an eth tx from the same account advances its pending nonce past
+	// the nonce in the current state.
+	ethTxs := getValidEthTxs(testKeys[0].ToECDSA(), 1, initialBaseFee)
+	for i, err := range vm.chain.AddRemoteTxsSync(ethTxs) {
+		if err != nil {
+			t.Fatalf("Failed to add eth tx at index %d: %s", i, err)
+		}
+	}
+
+	_, err = vm.newExportTx(vm.ctx.AVAXAssetID, exportAmount, vm.ctx.XChainID, testShortIDAddrs[0], initialBaseFee, keys)
+	if !errors.Is(err, errNonceMismatch) {
+		t.Fatalf("Expected export tx with a stale nonce to fail with %s, found %v", errNonceMismatch, err)
+	}
+}
+
+// Export inputs debit the sender's account by exactly the amount exported
+// plus the fee, so the rest of the balance remains in the account rather
+// than being burned. Unlike a UTXO, an account doesn't need a change output.
+func TestNewExportTxLeavesChangeInAccount(t *testing.T) {
+	genesisJSON, err := fundAddressByGenesis([]common.Address{testEthAddrs[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, vm, _, _, _ := GenesisVM(t, true, genesisJSON, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	state, err := vm.chain.CurrentState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance := new(big.Int).Div(state.GetBalance(testEthAddrs[0]), x2cRate).Uint64()
+	nonce := state.GetNonce(testEthAddrs[0])
+
+	exportAmount := uint64(1000)
+	tx, err := vm.newExportTx(vm.ctx.AVAXAssetID, exportAmount, vm.ctx.XChainID, testShortIDAddrs[0], initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exportTx := tx.UnsignedAtomicTx.(*UnsignedExportTx)
+	if len(exportTx.Ins) != 1 {
+		t.Fatalf("Expected a single input, found %d", len(exportTx.Ins))
+	}
+	in := exportTx.Ins[0]
+
+	gasUsed, err := tx.GasUsed(vm.currentRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee, err := calculateDynamicFee(gasUsed, initialBaseFee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	burned, err := tx.Burned(vm.ctx.AVAXAssetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if burned != fee {
+		t.Fatalf("Expected export tx to burn only the fee %d, but burned %d", fee, burned)
+	}
+	if in.Amount != exportAmount+fee {
+		t.Fatalf("Expected input to debit %d, found %d", exportAmount+fee, in.Amount)
+	}
+	if in.Amount >= balance {
+		t.Fatalf("Expected input %d to be less than the balance %d", in.Amount, balance)
+	}
+	if in.Address != testEthAddrs[0] || in.Nonce != nonce {
+		t.Fatalf("Expected input from %s with nonce %d, found %s with nonce %d", testEthAddrs[0].Hex(), nonce, in.Address.Hex(), in.Nonce)
+	}
+}
+
+func TestNewExportTxZeroAmount(t *testing.T) {
+	genesisJSON, err := fundAddressByGenesis([]common.Address{testEthAddrs[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, vm, _, _, _ := GenesisVM(t, true, genesisJSON, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Less than 1 nAVAX rounds down to zero when converted from wei.
+	exportAmount := new(big.Int).Div(big.NewInt(x2cRateInt64-1), x2cRate).Uint64()
+	keys := []*crypto.PrivateKeySECP256K1R{testKeys[0]}
+	_, err = vm.newExportTx(vm.ctx.AVAXAssetID, exportAmount, vm.ctx.XChainID, testShortIDAddrs[0], initialBaseFee, keys)
+	if !errors.Is(err, errZeroExportAmount) {
+		t.Fatalf("Expected export of a sub-nAVAX amount to fail with %s, found %v", errZeroExportAmount, err)
+	}
+
+	if _, err := vm.newExportTx(vm.ctx.AVAXAssetID, 1, vm.ctx.XChainID, testShortIDAddrs[0], initialBaseFee, keys); err != nil {
+		t.Fatalf("Failed to create export tx of 1 nAVAX: %s", err)
+	}
+}
+
+func TestExportTxRequests(t *testing.T) {
+	var exportAmount uint64 = 10000000
+	utx := &UnsignedExportTx{
+		NetworkID:        testNetworkID,
+		BlockchainID:     testCChainID,
+		DestinationChain: testXChainID,
+		Ins: []EVMInput{
+			{
+				Address: testEthAddrs[0],
+				Amount:  2 * exportAmount,
+				AssetID: testAvaxAssetID,
+				Nonce:   0,
+			},
+		},
+		ExportedOutputs: []*avax.TransferableOutput{
+			{
+				Asset: avax.Asset{ID: testAvaxAssetID},
+				Out: &secp256k1fx.TransferOutput{
+					Amt: exportAmount,
+					OutputOwners: secp256k1fx.OutputOwners{
+						Threshold: 1,
+						Addrs:     []ids.ShortID{testShortIDAddrs[0]},
+					},
+				},
+			},
+			{
+				Asset: avax.Asset{ID: testAvaxAssetID},
+				Out: &secp256k1fx.TransferOutput{
+					Amt: exportAmount,
+					OutputOwners: secp256k1fx.OutputOwners{
+						Threshold: 1,
+						Addrs:     []ids.ShortID{testShortIDAddrs[1]},
+					},
+				},
+			},
+		},
+	}
+	tx := &Tx{UnsignedAtomicTx: utx}
+	if err := tx.Sign(Codec, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := utx.Requests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("Expected requests for 1 chain, found %d", len(requests))
+	}
+	chainRequests, ok := requests[testXChainID]
+	if !ok {
+		t.Fatal("Expected requests for the destination chain")
+	}
+	if len(chainRequests.RemoveRequests) != 0 {
+		t.Fatalf("Expected no remove requests, found %d", len(chainRequests.RemoveRequests))
+	}
+	if len(chainRequests.PutRequests) != len(utx.ExportedOutputs) {
+		t.Fatalf("Expected %d put requests, found %d", len(utx.ExportedOutputs), len(chainRequests.PutRequests))
+	}
+	for i, elem := range chainRequests.PutRequests {
+		utxo := &avax.UTXO{}
+		if _, err := Codec.Unmarshal(elem.Value, utxo); err != nil {
+			t.Fatal(err)
+		}
+		if utxo.TxID != tx.ID() || utxo.OutputIndex != uint32(i) {
+			t.Fatalf("Expected put request %d to produce UTXO %s:%d, found %s:%d", i, tx.ID(), i, utxo.TxID, utxo.OutputIndex)
+		}
+		utxoID := utxo.InputID()
+		if !bytes.Equal(elem.Key, utxoID[:]) {
+			t.Fatalf("Expected put request %d to be keyed by %s", i, utxoID)
+		}
+		if len(elem.Traits) != 1 || !bytes.Equal(elem.Traits[0], testShortIDAddrs[i][:]) {
+			t.Fatalf("Expected put request %d to be indexed by %s", i, testShortIDAddrs[i])
+		}
+	}
+}
+
+func TestExportTxVerifyBalance(t *testing.T) {
+	var (
+		exportAmount  uint64 = 10000000
+		customAmount  uint64 = 100
+		customAssetID        = ids.ID{1, 2, 3, 4, 5, 7}
+	)
+	newExportTx := func(avaxIn uint64) *UnsignedExportTx {
+		tx := &UnsignedExportTx{
+			NetworkID:        testNetworkID,
+			BlockchainID:     testCChainID,
+			DestinationChain: testXChainID,
+			Ins: []EVMInput{
+				{
+					Address: testEthAddrs[0],
+					Amount:  avaxIn,
+					AssetID: testAvaxAssetID,
+				},
+				{
+					Address: testEthAddrs[0],
+					Amount:  customAmount,
+					AssetID: customAssetID,
+				},
+			},
+			ExportedOutputs: []*avax.TransferableOutput{
+				{
+					Asset: avax.Asset{ID: testAvaxAssetID},
+					Out: &secp256k1fx.TransferOutput{
+						Amt: exportAmount,
+						OutputOwners: secp256k1fx.OutputOwners{
+							Threshold: 1,
+							Addrs:     []ids.ShortID{testShortIDAddrs[0]},
+						},
+					},
+				},
+				{
+					Asset: avax.Asset{ID: customAssetID},
+					Out: &secp256k1fx.TransferOutput{
+						Amt: customAmount,
+						OutputOwners: secp256k1fx.OutputOwners{
+							Threshold: 1,
+							Addrs:     []ids.ShortID{testShortIDAddrs[0]},
+						},
+					},
+				},
+			},
+		}
+		avax.SortTransferableOutputs(tx.ExportedOutputs, Codec)
+		return tx
+	}
+
+	// The input amounts don't change the size of the tx, so neither do they
+	// change the gas used.
+	signedTx := &Tx{UnsignedAtomicTx: newExportTx(0)}
+	if err := signedTx.Sign(Codec, nil); err != nil {
+		t.Fatal(err)
+	}
+	gasUsed, err := signedTx.GasUsed(apricotRulesPhase3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Before Apricot Phase 3 the fee is fixed.
+	if err := newExportTx(exportAmount+params.AvalancheAtomicTxFee).verifyBalance(testAvaxAssetID, gasUsed, nil, apricotRulesPhase2); err != nil {
+		t.Fatalf("Failed to verify balanced export tx: %s", err)
+	}
+	if err := newExportTx(exportAmount+params.AvalancheAtomicTxFee-1).verifyBalance(testAvaxAssetID, gasUsed, nil, apricotRulesPhase2); !errors.Is(err, errExportImbalance) {
+		t.Fatalf("Expected %s for inputs that don't cover the fee, found %v", errExportImbalance, err)
+	}
+
+	// After Apricot Phase 3 the fee is calculated from the gas used.
+	fee, err := calculateDynamicFee(gasUsed, initialBaseFee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := newExportTx(exportAmount+fee).verifyBalance(testAvaxAssetID, gasUsed, initialBaseFee, apricotRulesPhase3); err != nil {
+		t.Fatalf("Failed to verify balanced export tx: %s", err)
+	}
+	if err := newExportTx(exportAmount+fee-1).verifyBalance(testAvaxAssetID, gasUsed, initialBaseFee, apricotRulesPhase3); !errors.Is(err, errExportImbalance) {
+		t.Fatalf("Expected %s for inputs that don't cover the fee, found %v", errExportImbalance, err)
+	}
+	if err := newExportTx(exportAmount-1).verifyBalance(testAvaxAssetID, gasUsed, initialBaseFee, apricotRulesPhase3); !errors.Is(err, errExportImbalance) {
+		t.Fatalf("Expected %s for inputs that don't cover the outputs, found %v", errExportImbalance, err)
+	}
+	// Overpaying the fee is allowed, as the base fee may drop between building
+	// and verifying the tx.
+	if err := newExportTx(exportAmount+fee+1).verifyBalance(testAvaxAssetID, gasUsed, initialBaseFee, apricotRulesPhase3); err != nil {
+		t.Fatalf("Failed to verify export tx overpaying the fee: %s", err)
+	}
+
+	// Non-native assets may be burned before Apricot Phase 5, but must balance
+	// exactly after it.
+	tx := newExportTx(exportAmount + fee)
+	for i := range tx.Ins {
+		if tx.Ins[i].AssetID == customAssetID {
+			tx.Ins[i].Amount++
+		}
+	}
+	if err := tx.verifyBalance(testAvaxAssetID, gasUsed, initialBaseFee, apricotRulesPhase3); err != nil {
+		t.Fatalf("Failed to verify export tx burning a non-native asset before Apricot Phase 5: %s", err)
+	}
+	if err := tx.verifyBalance(testAvaxAssetID, gasUsed, initialBaseFee, apricotRulesPhase5); !errors.Is(err, errExportImbalance) {
+		t.Fatalf("Expected %s for unbalanced non-native asset, found %v", errExportImbalance, err)
+	}
+	for i := range tx.Ins {
+		if tx.Ins[i].AssetID == customAssetID {
+			tx.Ins[i].Amount -= 2
+		}
+	}
+	if err := tx.verifyBalance(testAvaxAssetID, gasUsed, initialBaseFee, apricotRulesPhase3); !errors.Is(err, errExportImbalance) {
+		t.Fatalf("Expected %s for non-native inputs not covering outputs, found %v", errExportImbalance, err)
+	}
+}
+
+func TestExportUTXOIDs(t *testing.T) {
+	utx := &UnsignedExportTx{
+		NetworkID:        testNetworkID,
+		BlockchainID:     testCChainID,
+		DestinationChain: testXChainID,
+		Ins: []EVMInput{
+			{
+				Address: testEthAddrs[0],
+				Amount:  30,
+				AssetID: testAvaxAssetID,
+			},
+		},
+	}
+	for i := 0; i < 3; i++ {
+		utx.ExportedOutputs = append(utx.ExportedOutputs, &avax.TransferableOutput{
+			Asset: avax.Asset{ID: testAvaxAssetID},
+			Out: &secp256k1fx.TransferOutput{
+				Amt: uint64(i + 1),
+				OutputOwners: secp256k1fx.OutputOwners{
+					Threshold: 1,
+					Addrs:     []ids.ShortID{testShortIDAddrs[0]},
+				},
+			},
+		})
+	}
+	tx := &Tx{UnsignedAtomicTx: utx}
+	if err := tx.Sign(Codec, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	utxoIDs := ExportUTXOIDs(tx.ID(), len(utx.ExportedOutputs))
+	if len(utxoIDs) != len(utx.ExportedOutputs) {
+		t.Fatalf("Expected %d UTXO IDs, found %d", len(utx.ExportedOutputs), len(utxoIDs))
+	}
+
+	// The UTXO IDs must match the keys of the UTXOs written to shared memory
+	// on acceptance.
+	requests, err := utx.Requests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	putRequests := requests[testXChainID].PutRequests
+	if len(putRequests) != len(utxoIDs) {
+		t.Fatalf("Expected %d put requests, found %d", len(utxoIDs), len(putRequests))
+	}
+	for i, elem := range putRequests {
+		if !bytes.Equal(elem.Key, utxoIDs[i][:]) {
+			t.Fatalf("Expected UTXO ID %d to be %x, found %s", i, elem.Key, utxoIDs[i])
+		}
+		utxo := &avax.UTXO{}
+		if _, err := Codec.Unmarshal(elem.Value, utxo); err != nil {
+			t.Fatal(err)
+		}
+		if utxo.InputID() != utxoIDs[i] {
+			t.Fatalf("Expected UTXO ID %d to be %s, found %s", i, utxo.InputID(), utxoIDs[i])
+		}
+	}
+
+	if utxoIDs := ExportUTXOIDs(tx.ID(), 0); len(utxoIDs) != 0 {
+		t.Fatalf("Expected no UTXO IDs, found %d", len(utxoIDs))
+	}
+}
@@ -11,11 +11,15 @@ import (
 	"github.com/flare-foundation/coreth/params"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/flare-foundation/flare/chains/atomic"
 	"github.com/flare-foundation/flare/database"
 	"github.com/flare-foundation/flare/ids"
 	"github.com/flare-foundation/flare/snow"
 	"github.com/flare-foundation/flare/utils/crypto"
+	"github.com/flare-foundation/flare/utils/math"
 	"github.com/flare-foundation/flare/vms/components/avax"
+	"github.com/flare-foundation/flare/vms/secp256k1fx"
 )
 
 // UnsignedImportTx is an unsigned ImportTx
@@ -35,7 +39,11 @@ type UnsignedImportTx struct {
 
 // InputUTXOs returns the UTXOIDs of the imported funds
 func (tx *UnsignedImportTx) InputUTXOs() ids.Set {
-	return ids.Set{}
+	set := ids.NewSet(len(tx.ImportedInputs))
+	for _, in := range tx.ImportedInputs {
+		set.Add(in.InputID())
+	}
+	return set
 }
 
 // Verify this transaction is well-formed
@@ -44,16 +52,265 @@ func (tx *UnsignedImportTx) Verify(
 	ctx *snow.Context,
 	rules params.Rules,
 ) error {
-	return errWrongChainID
+	switch {
+	case tx == nil:
+		return errNilTx
+	case !rules.IsAtomicTxs:
+		return errAtomicTxDisabled
+	case tx.SourceChain != xChainID:
+		return errWrongChainID
+	case len(tx.ImportedInputs) == 0:
+		return errNoImportInputs
+	case tx.NetworkID != ctx.NetworkID:
+		return errWrongNetworkID
+	case ctx.ChainID != tx.BlockchainID:
+		return errWrongBlockchainID
+	case rules.IsApricotPhase3 && len(tx.Outs) == 0:
+		return errNoEVMOutputs
+	}
+
+	if err := verifyAtomicTxSize(len(tx.ImportedInputs), len(tx.Outs), rules); err != nil {
+		return err
+	}
+
+	for _, out := range tx.Outs {
+		if err := out.Verify(); err != nil {
+			return fmt.Errorf("EVM Output failed verification: %w", err)
+		}
+	}
+
+	if err := verifyImportInputAmounts(tx.ImportedInputs); err != nil {
+		return err
+	}
+	for _, in := range tx.ImportedInputs {
+		if err := in.Verify(); err != nil {
+			return fmt.Errorf("atomic input failed verification: %w", err)
+		}
+	}
+	if !avax.IsSortedAndUniqueTransferableInputs(tx.ImportedInputs) {
+		return errInputsNotSortedUnique
+	}
+
+	if rules.IsApricotPhase2 {
+		if err := verifyUniqueEVMOutputs(tx.Outs); err != nil {
+			return err
+		}
+		if !IsSortedAndUniqueEVMOutputs(tx.Outs) {
+			return errOutputsNotSortedUnique
+		}
+	} else if rules.IsApricotPhase1 {
+		if !IsSortedEVMOutputs(tx.Outs) {
+			return errOutputsNotSorted
+		}
+	}
+
+	// Ensure that no asset is credited by more than was imported, so that
+	// such a tx is rejected here rather than during acceptance.
+	imported := make(map[ids.ID]uint64)
+	for _, in := range tx.ImportedInputs {
+		// verifyImportInputAmounts ensures that this cannot overflow.
+		imported[in.AssetID()] += in.In.Amount()
+	}
+	creditedAssets, err := tx.CreditedAssets()
+	if err != nil {
+		return err
+	}
+	for assetID, credited := range creditedAssets {
+		if credited > imported[assetID] {
+			return fmt.Errorf("%w: outputs credit %d of asset %s, but only %d is imported",
+				errOutputsExceedInputs, credited, assetID, imported[assetID])
+		}
+	}
+
+	return nil
+}
+
+// verifyImportInputAmounts verifies that every input in [ins] imports a
+// non-zero amount, and that the amount imported of each asset fits in a
+// uint64.
+func verifyImportInputAmounts(ins []*avax.TransferableInput) error {
+	imported := make(map[ids.ID]uint64)
+	for i, in := range ins {
+		if in == nil || in.In == nil {
+			// Rejected by the verification of the input itself
+			continue
+		}
+		amount := in.In.Amount()
+		if amount == 0 {
+			return fmt.Errorf("%w: input %d", errZeroImportInput, i)
+		}
+		assetID := in.AssetID()
+		total, err := math.Add64(imported[assetID], amount)
+		if err != nil {
+			return fmt.Errorf("%w of asset %s at input %d", errOverflowImport, assetID, i)
+		}
+		imported[assetID] = total
+	}
+	return nil
+}
+
+// SigningBytes returns the bytes the credentials of [tx] sign over, so that
+// it can be signed by an offline signer. Each signature is over the SHA256
+// hash of these bytes. Tx.ID() hashes the signed tx instead, so it depends on
+// the credentials as well.
+func (tx *UnsignedImportTx) SigningBytes() ([]byte, error) {
+	return signingBytes(tx)
+}
+
+// GasUsed returns the gas used by the import if [size] bytes of it are
+// charged, plus the cost of verifying the signatures of its inputs.
+func (tx *UnsignedImportTx) GasUsed(size int) (uint64, error) {
+	var (
+		cost = calcBytesCost(size)
+		err  error
+	)
+	for _, in := range tx.ImportedInputs {
+		inCost, err := in.In.Cost()
+		if err != nil {
+			return 0, err
+		}
+		cost, err = math.Add64(cost, inCost)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return cost, err
+}
+
+const (
+	// importTxFixedBytes is the size of a serialized import tx without inputs
+	// or outputs: codec version (2), type ID (4), network ID (4), blockchain ID
+	// (32), source chain (32) and the number of inputs and outputs (4 each).
+	importTxFixedBytes = 2 + 4 + 4 + 32 + 32 + 4 + 4
+	// importTxInputBytes is the size of a serialized single signature input:
+	// UTXO ID (32 + 4), asset ID (32), input type ID (4), amount (8) and the
+	// signature indices (4 + 4).
+	importTxInputBytes = 32 + 4 + 32 + 4 + 8 + 4 + 4
+	// importTxOutputBytes is the size of a serialized EVM output: address (20),
+	// amount (8) and asset ID (32).
+	importTxOutputBytes = 20 + 8 + 32
+	// importTxCredentialBytes is the size of a serialized single signature
+	// credential: type ID (4), the number of signatures (4) and the signature
+	// (65). The credentials are only charged as of Apricot Phase 5.
+	importTxCredentialBytes = 4 + 4 + 65
+)
+
+// EstimateImportGas returns the gas used by an import tx consuming
+// [numInputs] single signature UTXOs into [numOutputs] EVM outputs. It applies
+// the same gas model as GasUsed, so the fee of an import can be quoted before
+// the UTXOs it consumes are known.
+func EstimateImportGas(numInputs, numOutputs int, rules params.Rules) (uint64, error) {
+	switch {
+	case numInputs <= 0:
+		return 0, errNoImportInputs
+	case numOutputs < 0:
+		return 0, fmt.Errorf("invalid number of outputs: %d", numOutputs)
+	case rules.IsApricotPhase3 && numOutputs == 0:
+		return 0, errNoEVMOutputs
+	}
+	if err := verifyAtomicTxSize(numInputs, numOutputs, rules); err != nil {
+		return 0, err
+	}
+
+	size := importTxFixedBytes + numInputs*importTxInputBytes + numOutputs*importTxOutputBytes
+	if rules.IsApricotPhase5 {
+		// The number of credentials (4) and one credential per input
+		size += 4 + numInputs*importTxCredentialBytes
+	}
+	sigCost, err := math.Mul64(uint64(numInputs), secp256k1fx.CostPerSignature)
+	if err != nil {
+		return 0, err
+	}
+	return math.Add64(calcBytesCost(size), sigCost)
 }
 
-func (tx *UnsignedImportTx) GasUsed() (uint64, error) {
-	return 0, fmt.Errorf("exportTx transactions disabled")
+// MinImportAmount returns the smallest amount of AVAX that an import tx
+// consuming [numInputs] UTXOs into [numOutputs] EVM outputs must import to
+// credit a non-zero AVAX output once the fee is paid. [numOutputs] includes the
+// AVAX output itself. Importing less than this results in an import that nets
+// to zero.
+func MinImportAmount(numInputs, numOutputs int, baseFee *big.Int, rules params.Rules) (*big.Int, error) {
+	var fee uint64
+	switch {
+	case rules.IsApricotPhase3:
+		if baseFee == nil {
+			return nil, errNilBaseFeeApricotPhase3
+		}
+		gasUsed, err := EstimateImportGas(numInputs, numOutputs, rules)
+		if err != nil {
+			return nil, err
+		}
+		fee, err = calculateDynamicFee(gasUsed, baseFee)
+		if err != nil {
+			return nil, err
+		}
+	case rules.IsApricotPhase2:
+		fee = params.AvalancheAtomicTxFee
+	}
+	return new(big.Int).Add(new(big.Int).SetUint64(fee), common.Big1), nil
 }
 
 // Amount of [assetID] burned by this transaction
 func (tx *UnsignedImportTx) Burned(assetID ids.ID) (uint64, error) {
-	return 0, fmt.Errorf("exportTx transactions disabled")
+	var (
+		spent uint64
+		input uint64
+		err   error
+	)
+	for _, out := range tx.Outs {
+		if out.AssetID == assetID {
+			spent, err = math.Add64(spent, out.Amount)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	for _, in := range tx.ImportedInputs {
+		if in.AssetID() == assetID {
+			input, err = math.Add64(input, in.Input().Amount())
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return math.Sub64(input, spent)
+}
+
+// AllBurned returns the amount of each asset burned by this transaction
+func (tx *UnsignedImportTx) AllBurned() (map[ids.ID]uint64, error) {
+	burned := make(map[ids.ID]uint64)
+	for _, in := range tx.ImportedInputs {
+		burned[in.AssetID()] = 0
+	}
+	for _, out := range tx.Outs {
+		burned[out.AssetID] = 0
+	}
+	for assetID := range burned {
+		amount, err := tx.Burned(assetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate burned amount of %s: %w", assetID, err)
+		}
+		burned[assetID] = amount
+	}
+	return burned, nil
+}
+
+// CreditedAssets returns the total amount of each asset credited by the
+// outputs of this transaction. The native asset is keyed by the AVAX asset ID
+// and its total is denominated in nAVAX, while every other key is a multicoin
+// asset credited to the multicoin balance of the recipient. An error is
+// returned if the total of an asset overflows.
+func (tx *UnsignedImportTx) CreditedAssets() (map[ids.ID]uint64, error) {
+	credited := make(map[ids.ID]uint64)
+	for _, out := range tx.Outs {
+		total, err := math.Add64(credited[out.AssetID], out.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("%w: asset %s", errOverflowCredit, out.AssetID)
+		}
+		credited[out.AssetID] = total
+	}
+	return credited, nil
 }
 
 // SemanticVerify this transaction is valid.
@@ -64,7 +321,110 @@ func (tx *UnsignedImportTx) SemanticVerify(
 	baseFee *big.Int,
 	rules params.Rules,
 ) error {
-	return fmt.Errorf("exportTx transactions disabled")
+	return tx.semanticVerify(vm, stx, nil, parent, baseFee, rules)
+}
+
+// semanticVerify verifies [tx], signed by [stx], against the shared memory
+// and [parent]. If [sponsor] is not nil, the AVAX it spends is consumed
+// alongside the imported inputs to pay the fee.
+func (tx *UnsignedImportTx) semanticVerify(
+	vm *VM,
+	stx *Tx,
+	sponsor *EVMInput,
+	parent *Block,
+	baseFee *big.Int,
+	rules params.Rules,
+) error {
+	if err := stx.Verify(vm.ctx.XChainID, vm.ctx, rules); err != nil {
+		return err
+	}
+	if !vm.ctx.IsBootstrapped() {
+		// Allow for force committing during bootstrapping, as the imported
+		// UTXOs may already have been spent.
+		return nil
+	}
+	utxos, err := tx.getImportedUTXOs(vm)
+	if err != nil {
+		return err
+	}
+
+	// Check the transaction consumes and produces the right amounts
+	fc := avax.NewFlowChecker()
+	switch {
+	// Apply dynamic fees to import transactions as of Apricot Phase 3
+	case rules.IsApricotPhase3:
+		gasUsed, err := stx.GasUsed(rules)
+		if err != nil {
+			return err
+		}
+		txFee, err := calculateDynamicFee(gasUsed, baseFee)
+		if err != nil {
+			return err
+		}
+		fc.Produce(vm.ctx.AVAXAssetID, txFee)
+
+	// Apply fees to import transactions as of Apricot Phase 2
+	case rules.IsApricotPhase2:
+		fc.Produce(vm.ctx.AVAXAssetID, params.AvalancheAtomicTxFee)
+	}
+	for _, out := range tx.Outs {
+		fc.Produce(out.AssetID, out.Amount)
+	}
+	for _, in := range tx.ImportedInputs {
+		fc.Consume(in.AssetID(), in.Input().Amount())
+	}
+	if sponsor != nil {
+		fc.Consume(sponsor.AssetID, sponsor.Amount)
+	}
+	if err := fc.Verify(); err != nil {
+		return fmt.Errorf("import tx flow check failed due to: %w", err)
+	}
+
+	for i, in := range tx.ImportedInputs {
+		if err := vm.fx.VerifyTransfer(tx, in.In, stx.Creds[i], utxos[i].Out); err != nil {
+			return fmt.Errorf("import tx transfer failed verification: %w", err)
+		}
+	}
+
+	// The inputs of [stx] include the nonce of the sponsor, if any.
+	return vm.conflicts(stx.UnsignedAtomicTx.InputUTXOs(), parent)
+}
+
+// getImportedUTXOs returns the UTXOs consumed by the inputs of [tx], fetched
+// from the shared memory of its source chain. It returns errWrongUTXOSource if
+// an input does not refer to a UTXO exported by the source chain.
+func (tx *UnsignedImportTx) getImportedUTXOs(vm *VM) ([]*avax.UTXO, error) {
+	utxoIDs := make([][]byte, len(tx.ImportedInputs))
+	for i, in := range tx.ImportedInputs {
+		inputID := in.UTXOID.InputID()
+		utxoIDs[i] = inputID[:]
+	}
+	// allUTXOBytes is guaranteed to be the same length as utxoIDs
+	allUTXOBytes, err := vm.ctx.SharedMemory.Get(tx.SourceChain, utxoIDs)
+	switch {
+	case err == database.ErrNotFound:
+		return nil, fmt.Errorf("failed to fetch import UTXOs from %s with %w", tx.SourceChain, errWrongUTXOSource)
+	case err != nil:
+		return nil, fmt.Errorf("failed to fetch import UTXOs from %s with %w", tx.SourceChain, err)
+	}
+
+	utxos := make([]*avax.UTXO, len(tx.ImportedInputs))
+	for i, in := range tx.ImportedInputs {
+		utxo := &avax.UTXO{}
+		if _, err := vm.codec.Unmarshal(allUTXOBytes[i], utxo); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal UTXO: %w", err)
+		}
+		// The source chain indexes its UTXOs by their ID, so a UTXO stored
+		// under a different ID was not produced by the source chain.
+		if utxo.InputID() != in.InputID() {
+			return nil, fmt.Errorf("%w: expected UTXO %s, found %s", errWrongUTXOSource, in.InputID(), utxo.InputID())
+		}
+		if utxo.AssetID() != in.AssetID() {
+			return nil, errAssetIDMismatch
+		}
+		utxos[i] = utxo
+	}
+	return utxos, nil
 }
 
 // Accept this transaction and spend imported inputs
@@ -73,21 +433,289 @@ func (tx *UnsignedImportTx) SemanticVerify(
 // only to have the transaction not be Accepted. This would be inconsistent.
 // Recall that imported UTXOs are not kept in a versionDB.
 func (tx *UnsignedImportTx) Accept(ctx *snow.Context, batch database.Batch) error {
-	return fmt.Errorf("exportTx transactions disabled")
+	return tx.Apply(ctx, batch)
+}
+
+// Requests returns the shared memory requests of this tx, which remove the
+// imported UTXOs from the source chain.
+func (tx *UnsignedImportTx) Requests() (map[ids.ID]*atomic.Requests, error) {
+	utxoIDs := make([][]byte, len(tx.ImportedInputs))
+	for i, in := range tx.ImportedInputs {
+		inputID := in.InputID()
+		utxoIDs[i] = inputID[:]
+	}
+	return map[ids.ID]*atomic.Requests{tx.SourceChain: {RemoveRequests: utxoIDs}}, nil
+}
+
+// Apply removes the imported UTXOs from shared memory atomically with [batch].
+func (tx *UnsignedImportTx) Apply(ctx *snow.Context, batch database.Batch) error {
+	return applyAtomicOps(ctx, tx, batch)
 }
 
-// newImportTx returns a new ImportTx
+// newImportTx returns a new ImportTx. The imported AVAX is sent to the
+// outputs of [splits], and the rest of it to [to]. The fee is paid by the AVAX
+// outputs at the indices [feeOutputs], where the output to [to] comes after
+// [splits], or by the last of them if [feeOutputs] is nil.
 func (vm *VM) newImportTx(
 	chainID ids.ID, // chain to import from
 	to common.Address, // Address of recipient
+	splits []EVMOutput, // AVAX outputs to pay before [to]
+	feeOutputs []int, // indices of the AVAX outputs that pay the fee
 	baseFee *big.Int, // fee to use post-AP3
 	keys []*crypto.PrivateKeySECP256K1R, // Keys to import the funds
 ) (*Tx, error) {
-	return nil, errWrongChainID
+	if !vm.currentRules().IsAtomicTxs {
+		return nil, errAtomicTxDisabled
+	}
+	if vm.ctx.XChainID != chainID {
+		return nil, errWrongChainID
+	}
+
+	kc := secp256k1fx.NewKeychain()
+	for _, key := range keys {
+		kc.Add(key)
+	}
+
+	atomicUTXOs, _, _, err := vm.GetAtomicUTXOs(chainID, kc.Addresses(), ids.ShortEmpty, ids.Empty, -1)
+	if err != nil {
+		return nil, fmt.Errorf("problem retrieving atomic UTXOs: %w", err)
+	}
+
+	importedInputs := []*avax.TransferableInput{}
+	signers := [][]*crypto.PrivateKeySECP256K1R{}
+
+	importedAmount := make(map[ids.ID]uint64)
+	now := vm.clock.Unix()
+	for _, utxo := range atomicUTXOs {
+		inputIntf, utxoSigners, err := kc.Spend(utxo.Out, now)
+		if err != nil {
+			continue
+		}
+		input, ok := inputIntf.(avax.TransferableIn)
+		if !ok {
+			continue
+		}
+		aid := utxo.AssetID()
+		importedAmount[aid], err = math.Add64(importedAmount[aid], input.Amount())
+		if err != nil {
+			return nil, err
+		}
+		importedInputs = append(importedInputs, &avax.TransferableInput{
+			UTXOID: utxo.UTXOID,
+			Asset:  utxo.Asset,
+			In:     input,
+		})
+		signers = append(signers, utxoSigners)
+	}
+	avax.SortTransferableInputsWithSigners(importedInputs, signers)
+	importedAVAXAmount := importedAmount[vm.ctx.AVAXAssetID]
+
+	outs := make([]EVMOutput, 0, len(importedAmount))
+	for assetID, amount := range importedAmount {
+		// Skip the AVAX amount since it is included separately to account for
+		// the fee
+		if assetID == vm.ctx.AVAXAssetID || amount == 0 {
+			continue
+		}
+		outs = append(outs, EVMOutput{
+			Address: to,
+			Amount:  amount,
+			AssetID: assetID,
+		})
+	}
+
+	rules := vm.currentRules()
+
+	var (
+		txFeeWithoutChange uint64
+		txFeeWithChange    uint64
+	)
+	switch {
+	case rules.IsApricotPhase3:
+		if baseFee == nil {
+			return nil, errNilBaseFeeApricotPhase3
+		}
+		utx := &UnsignedImportTx{
+			NetworkID:      vm.ctx.NetworkID,
+			BlockchainID:   vm.ctx.ChainID,
+			Outs:           append(append([]EVMOutput{}, outs...), splits...),
+			ImportedInputs: importedInputs,
+			SourceChain:    chainID,
+		}
+		// The credentials are charged as of Apricot Phase 5, so the estimate
+		// is signed by the same keys as the final tx.
+		tx := &Tx{UnsignedAtomicTx: utx}
+		if err := tx.Sign(vm.codec, signers); err != nil {
+			return nil, err
+		}
+
+		gasUsedWithoutChange, err := tx.GasUsed(rules)
+		if err != nil {
+			return nil, err
+		}
+		gasUsedWithChange := gasUsedWithoutChange + EVMOutputGas
+
+		txFeeWithoutChange, err = calculateDynamicFee(gasUsedWithoutChange, baseFee)
+		if err != nil {
+			return nil, err
+		}
+		txFeeWithChange, err = calculateDynamicFee(gasUsedWithChange, baseFee)
+		if err != nil {
+			return nil, err
+		}
+	case rules.IsApricotPhase2:
+		txFeeWithoutChange = params.AvalancheAtomicTxFee
+		txFeeWithChange = params.AvalancheAtomicTxFee
+	}
+
+	// AVAX outputs
+	avaxOuts := make([]EVMOutput, 0, len(splits)+1)
+	var splitAmount uint64
+	for _, split := range splits {
+		if split.AssetID != vm.ctx.AVAXAssetID {
+			return nil, fmt.Errorf("%w: split to %s pays %s", errInvalidImportSplit, split.Address.Hex(), split.AssetID)
+		}
+		splitAmount, err = math.Add64(splitAmount, split.Amount)
+		if err != nil {
+			return nil, err
+		}
+		avaxOuts = append(avaxOuts, split)
+	}
+	if importedAVAXAmount < splitAmount {
+		return nil, fmt.Errorf("%w: imported %d, but the splits pay %d", errInsufficientFunds, importedAVAXAmount, splitAmount)
+	}
+	change := importedAVAXAmount - splitAmount
+
+	txFee := txFeeWithoutChange
+	switch {
+	case len(splits) == 0 && change < txFeeWithoutChange: // imported amount goes toward paying tx fee
+		return nil, errInsufficientFundsForFee
+	case len(splits) == 0 && change > txFeeWithChange, len(splits) != 0 && change > 0:
+		avaxOuts = append(avaxOuts, EVMOutput{
+			Address: to,
+			Amount:  change,
+			AssetID: vm.ctx.AVAXAssetID,
+		})
+		txFee = txFeeWithChange
+	}
+
+	if feeOutputs == nil && len(avaxOuts) != 0 {
+		feeOutputs = []int{len(avaxOuts) - 1}
+	}
+	if len(feeOutputs) != 0 {
+		avaxOuts, err = deductImportFee(avaxOuts, feeOutputs, txFee, vm.ctx.AVAXAssetID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	outs = append(outs, avaxOuts...)
+
+	// If no outputs are produced, return an error.
+	// Note: this can happen if there is exactly enough AVAX to pay the
+	// transaction fee, but no other funds to be imported.
+	if len(outs) == 0 {
+		return nil, errNoEVMOutputs
+	}
+
+	// Splits may credit [to] or the same address more than once, which import
+	// verification rejects, so they are coalesced. The fee was estimated with
+	// each of them as a separate output, so it still covers the merged tx.
+	outs, err = mergeEVMOutputs(outs)
+	if err != nil {
+		return nil, err
+	}
+	SortEVMOutputs(outs)
+
+	// Create the transaction
+	utx := &UnsignedImportTx{
+		NetworkID:      vm.ctx.NetworkID,
+		BlockchainID:   vm.ctx.ChainID,
+		Outs:           outs,
+		ImportedInputs: importedInputs,
+		SourceChain:    chainID,
+	}
+	tx := &Tx{UnsignedAtomicTx: utx}
+	if err := tx.Sign(vm.codec, signers); err != nil {
+		return nil, err
+	}
+	return tx, utx.Verify(vm.ctx.XChainID, vm.ctx, vm.currentRules())
+}
+
+// deductImportFee deducts [fee] from the AVAX outputs of [outs] at the
+// indices [feeOutputs]. The fee is split evenly between them, with any
+// remainder taken from the first. Each of them must cover its share of the
+// fee, and outputs left empty by the fee are removed from the returned outputs.
+func deductImportFee(outs []EVMOutput, feeOutputs []int, fee uint64, avaxAssetID ids.ID) ([]EVMOutput, error) {
+	if len(feeOutputs) == 0 {
+		return nil, fmt.Errorf("%w: no output designated to pay the fee", errInvalidFeeOutput)
+	}
+
+	deducted := make([]EVMOutput, len(outs))
+	copy(deducted, outs)
+	designated := make(map[int]struct{}, len(feeOutputs))
+	share := fee / uint64(len(feeOutputs))
+	remainder := fee % uint64(len(feeOutputs))
+	for i, index := range feeOutputs {
+		if index < 0 || index >= len(outs) {
+			return nil, fmt.Errorf("%w: index %d is out of range [0, %d)", errInvalidFeeOutput, index, len(outs))
+		}
+		if _, ok := designated[index]; ok {
+			return nil, fmt.Errorf("%w: index %d is designated more than once", errInvalidFeeOutput, index)
+		}
+		designated[index] = struct{}{}
+
+		out := &deducted[index]
+		if out.AssetID != avaxAssetID {
+			return nil, fmt.Errorf("%w: output %d pays %s, but the fee is paid in %s", errInvalidFeeOutput, index, out.AssetID, avaxAssetID)
+		}
+		outFee := share
+		if i == 0 {
+			outFee += remainder
+		}
+		if out.Amount < outFee {
+			return nil, fmt.Errorf("%w: output %d has %d, but must pay %d", errInsufficientFundsForFee, index, out.Amount, outFee)
+		}
+		out.Amount -= outFee
+	}
+
+	nonEmpty := deducted[:0]
+	for _, out := range deducted {
+		if out.Amount > 0 {
+			nonEmpty = append(nonEmpty, out)
+		}
+	}
+	return nonEmpty, nil
 }
 
 // EVMStateTransfer performs the state transfer to increase the balances of
 // accounts accordingly with the imported EVMOutputs
-func (tx *UnsignedImportTx) EVMStateTransfer(ctx *snow.Context, state *state.StateDB) error {
-	return errInsufficientFunds
+func (tx *UnsignedImportTx) EVMStateTransfer(ctx *snow.Context, state *state.StateDB, rules params.Rules) error {
+	if err := tx.creditOutputs(ctx, state); err != nil {
+		return err
+	}
+	return creditAtomicFee(ctx, tx, state, rules)
+}
+
+// creditOutputs increases the balances of the recipients of the EVMOutputs of
+// [tx] by the amounts they import.
+func (tx *UnsignedImportTx) creditOutputs(ctx *snow.Context, state *state.StateDB) error {
+	for _, to := range tx.Outs {
+		if to.AssetID == ctx.AVAXAssetID {
+			log.Debug("crosschain X->C", "addr", to.Address, "amount", to.Amount, "assetID", "AVAX")
+			// If the asset is AVAX, convert the input amount in nAVAX to wei.
+			amount := XtoC(to.Amount)
+			if overflowsBalance(state.GetBalance(to.Address), amount) {
+				return fmt.Errorf("%w: crediting %s to %s", errBalanceOverflow, amount, to.Address)
+			}
+			state.AddBalance(to.Address, amount)
+		} else {
+			log.Debug("crosschain X->C", "addr", to.Address, "amount", to.Amount, "assetID", to.AssetID)
+			amount := new(big.Int).SetUint64(to.Amount)
+			if overflowsBalance(state.GetBalanceMultiCoin(to.Address, common.Hash(to.AssetID)), amount) {
+				return fmt.Errorf("%w: crediting %s of %s to %s", errBalanceOverflow, amount, to.AssetID, to.Address)
+			}
+			state.AddBalanceMultiCoin(to.Address, common.Hash(to.AssetID), amount)
+		}
+	}
+	return nil
 }
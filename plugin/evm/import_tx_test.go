@@ -4,10 +4,16 @@
 package evm
 
 import (
+	"bytes"
+	"errors"
+	gomath "math"
 	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	ethmath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/flare-foundation/coreth/core/rawdb"
+	"github.com/flare-foundation/coreth/core/state"
 	"github.com/flare-foundation/coreth/params"
 
 	"github.com/flare-foundation/flare/chains/atomic"
@@ -50,7 +56,7 @@ func createImportTxOptions(t *testing.T, vm *VM, sharedMemory *atomic.Memory) []
 
 	importTxs := make([]*Tx, 0, 3)
 	for _, ethAddr := range testEthAddrs {
-		importTx, err := vm.newImportTx(vm.ctx.XChainID, ethAddr, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+		importTx, err := vm.newImportTx(vm.ctx.XChainID, ethAddr, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -133,6 +139,43 @@ func TestImportTxVerify(t *testing.T) {
 			rules:       apricotRulesPhase0,
 			expectedErr: "", // Expect this transaction to be valid
 		},
+		"inputs and outputs at limit": {
+			generate: func(t *testing.T) UnsignedAtomicTx {
+				return importTx
+			},
+			ctx: ctx,
+			rules: func() params.Rules {
+				rules := apricotRulesPhase0
+				rules.MaxAtomicInputs = 2
+				rules.MaxAtomicOutputs = 2
+				return rules
+			}(),
+			expectedErr: "",
+		},
+		"too many inputs": {
+			generate: func(t *testing.T) UnsignedAtomicTx {
+				return importTx
+			},
+			ctx: ctx,
+			rules: func() params.Rules {
+				rules := apricotRulesPhase0
+				rules.MaxAtomicInputs = 1
+				return rules
+			}(),
+			expectedErr: errTooManyAtomicInputs.Error(),
+		},
+		"too many outputs": {
+			generate: func(t *testing.T) UnsignedAtomicTx {
+				return importTx
+			},
+			ctx: ctx,
+			rules: func() params.Rules {
+				rules := apricotRulesPhase0
+				rules.MaxAtomicOutputs = 1
+				return rules
+			}(),
+			expectedErr: errTooManyAtomicOutputs.Error(),
+		},
 		"invalid network ID": {
 			generate: func(t *testing.T) UnsignedAtomicTx {
 				tx := *importTx
@@ -199,6 +242,42 @@ func TestImportTxVerify(t *testing.T) {
 			rules:       apricotRulesPhase0,
 			expectedErr: "atomic input failed verification",
 		},
+		"zero amount input": {
+			generate: func(t *testing.T) UnsignedAtomicTx {
+				tx := *importTx
+				input := *tx.ImportedInputs[1]
+				input.In = &secp256k1fx.TransferInput{
+					Amt:   0,
+					Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+				}
+				tx.ImportedInputs = []*avax.TransferableInput{
+					tx.ImportedInputs[0],
+					&input,
+				}
+				return &tx
+			},
+			ctx:         ctx,
+			rules:       apricotRulesPhase0,
+			expectedErr: errZeroImportInput.Error(),
+		},
+		"imported amount overflows": {
+			generate: func(t *testing.T) UnsignedAtomicTx {
+				tx := *importTx
+				input := *tx.ImportedInputs[1]
+				input.In = &secp256k1fx.TransferInput{
+					Amt:   gomath.MaxUint64,
+					Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+				}
+				tx.ImportedInputs = []*avax.TransferableInput{
+					tx.ImportedInputs[0],
+					&input,
+				}
+				return &tx
+			},
+			ctx:         ctx,
+			rules:       apricotRulesPhase0,
+			expectedErr: errOverflowImport.Error(),
+		},
 		"unsorted outputs phase 0 passes verification": {
 			generate: func(t *testing.T) UnsignedAtomicTx {
 				tx := *importTx
@@ -262,7 +341,7 @@ func TestImportTxVerify(t *testing.T) {
 			},
 			ctx:         ctx,
 			rules:       apricotRulesPhase2,
-			expectedErr: errOutputsNotSortedUnique.Error(),
+			expectedErr: errDuplicateOutput.Error(),
 		},
 		"outputs not sorted phase 2 fails verification": {
 			generate: func(t *testing.T) UnsignedAtomicTx {
@@ -303,6 +382,28 @@ func TestImportTxVerify(t *testing.T) {
 			rules:       apricotRulesPhase3,
 			expectedErr: errNoEVMOutputs.Error(),
 		},
+		"outputs exceed imported inputs": {
+			generate: func(t *testing.T) UnsignedAtomicTx {
+				tx := *importTx
+				tx.Outs = []EVMOutput{
+					{
+						Address: testEthAddrs[0],
+						Amount:  importAmount,
+						AssetID: ctx.AVAXAssetID,
+					},
+					{
+						Address: testEthAddrs[1],
+						Amount:  importAmount + 1,
+						AssetID: ctx.AVAXAssetID,
+					},
+				}
+				SortEVMOutputs(tx.Outs)
+				return &tx
+			},
+			ctx:         ctx,
+			rules:       apricotRulesPhase3,
+			expectedErr: errOutputsExceedInputs.Error(),
+		},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -322,7 +423,7 @@ func TestNewImportTx(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+		tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -335,7 +436,7 @@ func TestNewImportTx(t *testing.T) {
 		rules := vm.currentRules()
 		switch {
 		case rules.IsApricotPhase3:
-			actualCost, err := importTx.GasUsed()
+			actualCost, err := tx.GasUsed(rules)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -356,13 +457,14 @@ func TestNewImportTx(t *testing.T) {
 		return tx
 	}
 	checkState := func(t *testing.T, vm *VM) {
-		tx, err := vm.extractAtomicTx(vm.LastAcceptedBlockInternal().(*Block).ethBlock)
+		txs, err := vm.extractAtomicTxs(vm.LastAcceptedBlockInternal().(*Block).ethBlock)
 		if err != nil {
 			t.Fatal(err)
 		}
-		if tx == nil {
-			t.Fatal("Expected import tx to be in the last accepted block, but found nil")
+		if len(txs) != 1 {
+			t.Fatalf("Expected one import tx in the last accepted block, but found %d", len(txs))
 		}
+		tx := txs[0]
 		actualAVAXBurned, err := tx.UnsignedAtomicTx.Burned(vm.ctx.AVAXAssetID)
 		if err != nil {
 			t.Fatal(err)
@@ -384,7 +486,7 @@ func TestNewImportTx(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		expectedRemainingBalance := new(big.Int).Mul(new(big.Int).SetUint64(importAmount-actualAVAXBurned), x2cRate)
+		expectedRemainingBalance := XtoC(importAmount-actualAVAXBurned)
 		addr := GetEthAddress(testKeys[0])
 		if actualBalance := sdb.GetBalance(addr); actualBalance.Cmp(expectedRemainingBalance) != 0 {
 			t.Fatalf("address remaining balance %s equal %s not %s", addr.String(), actualBalance, expectedRemainingBalance)
@@ -411,6 +513,11 @@ func TestNewImportTx(t *testing.T) {
 			checkState:  checkState,
 			genesisJSON: genesisJSONApricotPhase3,
 		},
+		"apricot phase 5": {
+			setup:       createNewImportAVAXTx,
+			checkState:  checkState,
+			genesisJSON: genesisJSONApricotPhase5,
+		},
 	}
 
 	for name, test := range tests2 {
@@ -709,7 +816,7 @@ func TestImportTxGasCost(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			gasUsed, err := tx.GasUsed()
+			gasUsed, err := tx.GasUsed(apricotRulesPhase4)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -893,7 +1000,7 @@ func TestImportTxSemanticVerify(t *testing.T) {
 				}
 				return tx
 			},
-			semanticVerifyErr: "import tx flow check failed due to",
+			semanticVerifyErr: errOutputsExceedInputs.Error(),
 		},
 		"insufficient non-AVAX funds": {
 			setup: func(t *testing.T, vm *VM, sharedMemory *atomic.Memory) *Tx {
@@ -927,7 +1034,7 @@ func TestImportTxSemanticVerify(t *testing.T) {
 				}
 				return tx
 			},
-			semanticVerifyErr: "import tx flow check failed due to",
+			semanticVerifyErr: errOutputsExceedInputs.Error(),
 		},
 		"no signatures": {
 			setup: func(t *testing.T, vm *VM, sharedMemory *atomic.Memory) *Tx {
@@ -1035,7 +1142,7 @@ func TestImportTxSemanticVerify(t *testing.T) {
 				return tx
 			},
 			genesisJSON:       genesisJSONApricotPhase3,
-			semanticVerifyErr: errOutputsNotSortedUnique.Error(),
+			semanticVerifyErr: errDuplicateOutput.Error(),
 		},
 	}
 
@@ -1151,3 +1258,622 @@ func TestImportTxEVMStateTransfer(t *testing.T) {
 		})
 	}
 }
+
+func TestImportTxEVMStateTransferFeeRecipient(t *testing.T) {
+	ctx := NewContext()
+	feeRecipient := common.Address{0xfe}
+	importAmount := uint64(10_000_000)
+	fee := uint64(1_000_000)
+
+	tx := &UnsignedImportTx{
+		NetworkID:    ctx.NetworkID,
+		BlockchainID: ctx.ChainID,
+		SourceChain:  ctx.XChainID,
+		ImportedInputs: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+			Asset:  avax.Asset{ID: ctx.AVAXAssetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   importAmount,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}},
+		Outs: []EVMOutput{{
+			Address: testEthAddrs[0],
+			Amount:  importAmount - fee,
+			AssetID: ctx.AVAXAssetID,
+		}},
+	}
+
+	tests := map[string]struct {
+		rules                params.Rules
+		expectedRecipientBal *big.Int
+	}{
+		"no recipient burns fee": {
+			rules:                apricotRulesPhase3,
+			expectedRecipientBal: new(big.Int),
+		},
+		"recipient credited with fee": {
+			rules: func() params.Rules {
+				rules := apricotRulesPhase3
+				rules.AtomicFeeRecipient = &feeRecipient
+				return rules
+			}(),
+			expectedRecipientBal: XtoC(fee),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sdb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tx.EVMStateTransfer(ctx, sdb, test.rules); err != nil {
+				t.Fatal(err)
+			}
+
+			expectedBal := XtoC(importAmount-fee)
+			if bal := sdb.GetBalance(testEthAddrs[0]); bal.Cmp(expectedBal) != 0 {
+				t.Fatalf("Expected output balance %d, found %d", expectedBal, bal)
+			}
+			if bal := sdb.GetBalance(feeRecipient); bal.Cmp(test.expectedRecipientBal) != 0 {
+				t.Fatalf("Expected fee recipient balance %d, found %d", test.expectedRecipientBal, bal)
+			}
+		})
+	}
+}
+
+func TestAvalancheRulesAtomicFeeRecipient(t *testing.T) {
+	feeRecipient := common.Address{0xfe}
+	config := *params.TestApricotPhase2Config
+	config.ApricotPhase3BlockTimestamp = big.NewInt(10)
+	config.AtomicFeeRecipient = &feeRecipient
+
+	if rules := config.AvalancheRules(common.Big0, big.NewInt(9)); rules.AtomicFeeRecipient != nil {
+		t.Fatalf("Expected no atomic fee recipient before Apricot Phase 3, found %s", rules.AtomicFeeRecipient)
+	}
+	if rules := config.AvalancheRules(common.Big0, big.NewInt(10)); rules.AtomicFeeRecipient == nil || *rules.AtomicFeeRecipient != feeRecipient {
+		t.Fatalf("Expected atomic fee recipient %s after Apricot Phase 3, found %v", feeRecipient, rules.AtomicFeeRecipient)
+	}
+}
+
+func TestImportTxEVMStateTransferBalanceOverflow(t *testing.T) {
+	ctx := NewContext()
+	assetID := ids.GenerateTestID()
+
+	tests := map[string]struct {
+		assetID ids.ID
+		preload func(sdb *state.StateDB)
+		balance func(sdb *state.StateDB) *big.Int
+	}{
+		"AVAX": {
+			assetID: ctx.AVAXAssetID,
+			preload: func(sdb *state.StateDB) {
+				sdb.SetBalance(testEthAddrs[0], new(big.Int).Set(ethmath.MaxBig256))
+			},
+			balance: func(sdb *state.StateDB) *big.Int {
+				return sdb.GetBalance(testEthAddrs[0])
+			},
+		},
+		"non-AVAX": {
+			assetID: assetID,
+			preload: func(sdb *state.StateDB) {
+				sdb.AddBalanceMultiCoin(testEthAddrs[0], common.Hash(assetID), new(big.Int).Set(ethmath.MaxBig256))
+			},
+			balance: func(sdb *state.StateDB) *big.Int {
+				return sdb.GetBalanceMultiCoin(testEthAddrs[0], common.Hash(assetID))
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sdb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.preload(sdb)
+
+			tx := &UnsignedImportTx{
+				NetworkID:    ctx.NetworkID,
+				BlockchainID: ctx.ChainID,
+				SourceChain:  ctx.XChainID,
+				Outs: []EVMOutput{{
+					Address: testEthAddrs[0],
+					Amount:  1,
+					AssetID: test.assetID,
+				}},
+			}
+			if err := tx.EVMStateTransfer(ctx, sdb, apricotRulesPhase3); !errors.Is(err, errBalanceOverflow) {
+				t.Fatalf("Expected EVMStateTransfer to fail with %s, found %v", errBalanceOverflow, err)
+			}
+			if balance := test.balance(sdb); balance.Cmp(ethmath.MaxBig256) != 0 {
+				t.Fatalf("Expected balance to remain %d, found %d", ethmath.MaxBig256, balance)
+			}
+		})
+	}
+}
+
+func TestImportTxCreditedAssets(t *testing.T) {
+	avaxAssetID := ids.GenerateTestID()
+	multicoinAssetID := ids.GenerateTestID()
+	tx := &UnsignedImportTx{
+		Outs: []EVMOutput{
+			{Address: testEthAddrs[0], Amount: 100, AssetID: avaxAssetID},
+			{Address: testEthAddrs[1], Amount: 50, AssetID: avaxAssetID},
+			{Address: testEthAddrs[0], Amount: 7, AssetID: multicoinAssetID},
+			{Address: testEthAddrs[1], Amount: 3, AssetID: multicoinAssetID},
+		},
+	}
+
+	credited, err := tx.CreditedAssets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credited) != 2 {
+		t.Fatalf("Expected 2 credited assets, found %d", len(credited))
+	}
+	if amount := credited[avaxAssetID]; amount != 150 {
+		t.Fatalf("Expected 150 of the native asset to be credited, found %d", amount)
+	}
+	if amount := credited[multicoinAssetID]; amount != 10 {
+		t.Fatalf("Expected 10 of the multicoin asset to be credited, found %d", amount)
+	}
+
+	tx.Outs = append(tx.Outs, EVMOutput{Address: testEthAddrs[0], Amount: gomath.MaxUint64, AssetID: multicoinAssetID})
+	if _, err := tx.CreditedAssets(); !errors.Is(err, errOverflowCredit) {
+		t.Fatalf("Expected overflowing total to fail with %s, found %v", errOverflowCredit, err)
+	}
+}
+
+func TestEstimateImportGas(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase3, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	rules := vm.currentRules()
+	// As of Apricot Phase 5, the credentials are charged as well.
+	apricotPhase5Rules := rules
+	apricotPhase5Rules.IsApricotPhase4 = true
+	apricotPhase5Rules.IsApricotPhase5 = true
+
+	for _, test := range []struct{ numInputs, numOutputs int }{
+		{1, 1},
+		{1, 3},
+		{4, 1},
+		{5, 2},
+	} {
+		utx := &UnsignedImportTx{
+			NetworkID:    vm.ctx.NetworkID,
+			BlockchainID: vm.ctx.ChainID,
+			SourceChain:  vm.ctx.XChainID,
+		}
+		signers := make([][]*crypto.PrivateKeySECP256K1R, test.numInputs)
+		for i := 0; i < test.numInputs; i++ {
+			utx.ImportedInputs = append(utx.ImportedInputs, &avax.TransferableInput{
+				UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+				Asset:  avax.Asset{ID: vm.ctx.AVAXAssetID},
+				In: &secp256k1fx.TransferInput{
+					Amt:   uint64(i + 1),
+					Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+				},
+			})
+			signers[i] = []*crypto.PrivateKeySECP256K1R{testKeys[0]}
+		}
+		for i := 0; i < test.numOutputs; i++ {
+			utx.Outs = append(utx.Outs, EVMOutput{
+				Address: testEthAddrs[i%len(testEthAddrs)],
+				Amount:  uint64(i + 1),
+				AssetID: vm.ctx.AVAXAssetID,
+			})
+		}
+		tx := &Tx{UnsignedAtomicTx: utx}
+		if err := tx.Sign(vm.codec, signers); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, rules := range []params.Rules{rules, apricotPhase5Rules} {
+			expectedGas, err := tx.GasUsed(rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			estimatedGas, err := EstimateImportGas(test.numInputs, test.numOutputs, rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if estimatedGas != expectedGas {
+				t.Fatalf("Expected estimated gas for %d inputs and %d outputs under Apricot Phase 5 (%t) to be %d, found %d", test.numInputs, test.numOutputs, rules.IsApricotPhase5, expectedGas, estimatedGas)
+			}
+		}
+	}
+
+	if _, err := EstimateImportGas(0, 1, rules); !errors.Is(err, errNoImportInputs) {
+		t.Fatalf("Expected estimate without inputs to fail with %s, found %v", errNoImportInputs, err)
+	}
+	if _, err := EstimateImportGas(1, 0, rules); !errors.Is(err, errNoEVMOutputs) {
+		t.Fatalf("Expected estimate without outputs to fail with %s, found %v", errNoEVMOutputs, err)
+	}
+	if _, err := EstimateImportGas(int(rules.MaxAtomicInputs)+1, 1, rules); !errors.Is(err, errTooManyAtomicInputs) {
+		t.Fatalf("Expected estimate with too many inputs to fail with %s, found %v", errTooManyAtomicInputs, err)
+	}
+}
+
+func TestMinImportAmount(t *testing.T) {
+	minAmount, err := MinImportAmount(1, 1, nil, apricotRulesPhase2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if minAmount.Uint64() != params.AvalancheAtomicTxFee+1 {
+		t.Fatalf("Expected minimum import amount %d before AP3, found %d", params.AvalancheAtomicTxFee+1, minAmount)
+	}
+	if _, err := MinImportAmount(1, 1, nil, apricotRulesPhase3); !errors.Is(err, errNilBaseFeeApricotPhase3) {
+		t.Fatalf("Expected nil base fee to fail with %s, found %v", errNilBaseFeeApricotPhase3, err)
+	}
+
+	minAmount, err = MinImportAmount(1, 1, initialBaseFee, apricotRulesPhase3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Importing the minimum amount credits a single unit of AVAX, while
+	// importing one less leaves nothing after the fee.
+	for _, test := range []struct {
+		amount      uint64
+		shouldBuild bool
+	}{
+		{minAmount.Uint64(), true},
+		{minAmount.Uint64() - 1, false},
+	} {
+		_, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase3, "", "", map[ids.ShortID]uint64{
+			testShortIDAddrs[0]: test.amount,
+		})
+		tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+		if shutdownErr := vm.Shutdown(); shutdownErr != nil {
+			t.Fatal(shutdownErr)
+		}
+		if !test.shouldBuild {
+			if err == nil {
+				t.Fatalf("Expected import of %d to fail", test.amount)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to build import of %d: %s", test.amount, err)
+		}
+		outs := tx.UnsignedAtomicTx.(*UnsignedImportTx).Outs
+		if len(outs) != 1 || outs[0].Amount != 1 {
+			t.Fatalf("Expected import of %d to credit a single output of 1, found %v", test.amount, outs)
+		}
+	}
+}
+
+func TestImportTxVerifyDuplicateOutputs(t *testing.T) {
+	ctx := NewContext()
+	importTx := &UnsignedImportTx{
+		NetworkID:    ctx.NetworkID,
+		BlockchainID: ctx.ChainID,
+		SourceChain:  ctx.XChainID,
+		ImportedInputs: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+			Asset:  avax.Asset{ID: ctx.AVAXAssetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   2,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}},
+		Outs: []EVMOutput{
+			{Address: testEthAddrs[0], Amount: 1, AssetID: ctx.AVAXAssetID},
+			{Address: testEthAddrs[0], Amount: 1, AssetID: ctx.AVAXAssetID},
+		},
+	}
+	if err := importTx.Verify(ctx.XChainID, ctx, apricotRulesPhase3); !errors.Is(err, errDuplicateOutput) {
+		t.Fatalf("Expected duplicate outputs to fail with %s, found %v", errDuplicateOutput, err)
+	}
+
+	merged, err := mergeEVMOutputs(importTx.Outs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importTx.Outs = merged
+	if err := importTx.Verify(ctx.XChainID, ctx, apricotRulesPhase3); err != nil {
+		t.Fatalf("Expected merged outputs to pass verification, found %s", err)
+	}
+	if len(importTx.Outs) != 1 || importTx.Outs[0].Amount != 2 {
+		t.Fatalf("Expected a single merged output of 2, found %+v", importTx.Outs)
+	}
+}
+
+func TestImportTxRequests(t *testing.T) {
+	inputIDs := []avax.UTXOID{
+		{TxID: ids.GenerateTestID(), OutputIndex: 0},
+		{TxID: ids.GenerateTestID(), OutputIndex: 3},
+	}
+	tx := &UnsignedImportTx{
+		NetworkID:    testNetworkID,
+		BlockchainID: testCChainID,
+		SourceChain:  testXChainID,
+	}
+	for _, utxoID := range inputIDs {
+		tx.ImportedInputs = append(tx.ImportedInputs, &avax.TransferableInput{
+			UTXOID: utxoID,
+			Asset:  avax.Asset{ID: testAvaxAssetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   1,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		})
+	}
+
+	requests, err := tx.Requests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("Expected requests for 1 chain, found %d", len(requests))
+	}
+	chainRequests, ok := requests[testXChainID]
+	if !ok {
+		t.Fatal("Expected requests for the source chain")
+	}
+	if len(chainRequests.PutRequests) != 0 {
+		t.Fatalf("Expected no put requests, found %d", len(chainRequests.PutRequests))
+	}
+	if len(chainRequests.RemoveRequests) != len(inputIDs) {
+		t.Fatalf("Expected %d remove requests, found %d", len(inputIDs), len(chainRequests.RemoveRequests))
+	}
+	for i, utxoID := range inputIDs {
+		inputID := utxoID.InputID()
+		if !bytes.Equal(chainRequests.RemoveRequests[i], inputID[:]) {
+			t.Fatalf("Expected remove request %d to be %s", i, inputID)
+		}
+	}
+}
+
+func TestImportTxGetImportedUTXOs(t *testing.T) {
+	_, vm, _, sharedMemory, _ := GenesisVM(t, false, genesisJSONApricotPhase0, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// putUTXO exports a UTXO stored under [key] from [chainID] to the C-Chain.
+	putUTXO := func(chainID ids.ID, key ids.ID, utxo *avax.UTXO) {
+		utxoBytes, err := Codec.Marshal(codecVersion, utxo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sharedMemory.NewSharedMemory(chainID).Apply(map[ids.ID]*atomic.Requests{vm.ctx.ChainID: {PutRequests: []*atomic.Element{{
+			Key:   key[:],
+			Value: utxoBytes,
+		}}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newUTXO := func() *avax.UTXO {
+		return &avax.UTXO{
+			UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+			Asset:  avax.Asset{ID: vm.ctx.AVAXAssetID},
+			Out: &secp256k1fx.TransferOutput{
+				Amt: 1,
+				OutputOwners: secp256k1fx.OutputOwners{
+					Threshold: 1,
+					Addrs:     []ids.ShortID{testShortIDAddrs[0]},
+				},
+			},
+		}
+	}
+	importTx := func(utxo *avax.UTXO) *UnsignedImportTx {
+		return &UnsignedImportTx{
+			NetworkID:    vm.ctx.NetworkID,
+			BlockchainID: vm.ctx.ChainID,
+			SourceChain:  vm.ctx.XChainID,
+			ImportedInputs: []*avax.TransferableInput{{
+				UTXOID: utxo.UTXOID,
+				Asset:  utxo.Asset,
+				In: &secp256k1fx.TransferInput{
+					Amt:   1,
+					Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+				},
+			}},
+		}
+	}
+
+	utxo := newUTXO()
+	putUTXO(vm.ctx.XChainID, utxo.InputID(), utxo)
+	utxos, err := importTx(utxo).getImportedUTXOs(vm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != 1 || utxos[0].InputID() != utxo.InputID() {
+		t.Fatalf("Expected UTXO %s to be imported", utxo.InputID())
+	}
+
+	// The UTXO was exported by a different chain than the claimed source.
+	utxo = newUTXO()
+	putUTXO(ids.GenerateTestID(), utxo.InputID(), utxo)
+	if _, err := importTx(utxo).getImportedUTXOs(vm); !errors.Is(err, errWrongUTXOSource) {
+		t.Fatalf("Expected %s, found %v", errWrongUTXOSource, err)
+	}
+
+	// The source chain stored a different UTXO under the ID being spent.
+	utxo = newUTXO()
+	spent := newUTXO()
+	putUTXO(vm.ctx.XChainID, spent.InputID(), utxo)
+	if _, err := importTx(spent).getImportedUTXOs(vm); !errors.Is(err, errWrongUTXOSource) {
+		t.Fatalf("Expected %s, found %v", errWrongUTXOSource, err)
+	}
+}
+
+func TestDeductImportFee(t *testing.T) {
+	customAssetID := ids.ID{1, 2, 3, 4, 5, 7}
+	outs := []EVMOutput{
+		{
+			Address: testEthAddrs[0],
+			Amount:  1000,
+			AssetID: testAvaxAssetID,
+		},
+		{
+			Address: testEthAddrs[1],
+			Amount:  500,
+			AssetID: testAvaxAssetID,
+		},
+		{
+			Address: testEthAddrs[1],
+			Amount:  10,
+			AssetID: customAssetID,
+		},
+	}
+
+	// The fee is taken from the designated output only.
+	deducted, err := deductImportFee(outs, []int{1}, 100, testAvaxAssetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deducted[0].Amount != 1000 || deducted[1].Amount != 400 || deducted[2].Amount != 10 {
+		t.Fatalf("Unexpected outputs after deducting the fee: %+v", deducted)
+	}
+	if outs[1].Amount != 500 {
+		t.Fatal("Expected the original outputs to be left unmodified")
+	}
+
+	// The fee is split between the designated outputs.
+	deducted, err = deductImportFee(outs, []int{0, 1}, 101, testAvaxAssetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deducted[0].Amount != 949 || deducted[1].Amount != 450 {
+		t.Fatalf("Unexpected outputs after splitting the fee: %+v", deducted)
+	}
+
+	// An output emptied by the fee is removed.
+	deducted, err = deductImportFee(outs, []int{1}, 500, testAvaxAssetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deducted) != 2 || deducted[0].Amount != 1000 || deducted[1].AssetID != customAssetID {
+		t.Fatalf("Unexpected outputs after emptying the fee-paying output: %+v", deducted)
+	}
+
+	if _, err := deductImportFee(outs, []int{1}, 501, testAvaxAssetID); !errors.Is(err, errInsufficientFundsForFee) {
+		t.Fatalf("Expected %s, found %v", errInsufficientFundsForFee, err)
+	}
+	for _, feeOutputs := range [][]int{nil, {2}, {3}, {-1}, {0, 0}} {
+		if _, err := deductImportFee(outs, feeOutputs, 100, testAvaxAssetID); !errors.Is(err, errInvalidFeeOutput) {
+			t.Fatalf("Expected %s for fee outputs %v, found %v", errInvalidFeeOutput, feeOutputs, err)
+		}
+	}
+}
+
+func TestNewImportTxFeeOutputs(t *testing.T) {
+	importAmount := uint64(50000000)
+	splitAmount := uint64(20000000)
+	_, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase4, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: importAmount,
+	})
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	keys := []*crypto.PrivateKeySECP256K1R{testKeys[0]}
+	splits := []EVMOutput{{
+		Address: testEthAddrs[1],
+		Amount:  splitAmount,
+		AssetID: vm.ctx.AVAXAssetID,
+	}}
+	amounts := func(tx *Tx) map[common.Address]uint64 {
+		amounts := make(map[common.Address]uint64)
+		for _, out := range tx.UnsignedAtomicTx.(*UnsignedImportTx).Outs {
+			amounts[out.Address] += out.Amount
+		}
+		return amounts
+	}
+
+	// The fee is split between the split and the output to [to], with the
+	// remainder paid by the first of them.
+	tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], splits, []int{0, 1}, initialBaseFee, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee, err := tx.Burned(vm.ctx.AVAXAssetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee == 0 {
+		t.Fatal("Expected the import to burn a fee")
+	}
+	if err := tx.UnsignedAtomicTx.SemanticVerify(vm, tx, vm.LastAcceptedBlockInternal().(*Block), initialBaseFee, vm.currentRules()); err != nil {
+		t.Fatal(err)
+	}
+	splitAmounts := amounts(tx)
+	if expected := splitAmount - fee/2 - fee%2; splitAmounts[testEthAddrs[1]] != expected {
+		t.Fatalf("Expected the split to receive %d, found %d", expected, splitAmounts[testEthAddrs[1]])
+	}
+	if expected := importAmount - splitAmount - fee/2; splitAmounts[testEthAddrs[0]] != expected {
+		t.Fatalf("Expected the output to [to] to receive %d, found %d", expected, splitAmounts[testEthAddrs[0]])
+	}
+
+	// The fee is paid by the designated output only.
+	tx, err = vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], splits, []int{0}, initialBaseFee, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee, err = tx.Burned(vm.ctx.AVAXAssetID); err != nil {
+		t.Fatal(err)
+	}
+	splitAmounts = amounts(tx)
+	if splitAmounts[testEthAddrs[1]] != splitAmount-fee || splitAmounts[testEthAddrs[0]] != importAmount-splitAmount {
+		t.Fatalf("Expected only the split to pay the fee %d, found outputs %v", fee, splitAmounts)
+	}
+
+	if _, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], splits, []int{2}, initialBaseFee, keys); !errors.Is(err, errInvalidFeeOutput) {
+		t.Fatalf("Expected %s, found %v", errInvalidFeeOutput, err)
+	}
+	splits[0].Amount = importAmount + 1
+	if _, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], splits, nil, initialBaseFee, keys); !errors.Is(err, errInsufficientFunds) {
+		t.Fatalf("Expected %s, found %v", errInsufficientFunds, err)
+	}
+}
+
+func TestNewImportTxMergesSplits(t *testing.T) {
+	importAmount := uint64(50000000)
+	_, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase4, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: importAmount,
+	})
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Both splits credit [to], so they are coalesced with its output.
+	splits := []EVMOutput{
+		{
+			Address: testEthAddrs[0],
+			Amount:  10000000,
+			AssetID: vm.ctx.AVAXAssetID,
+		},
+		{
+			Address: testEthAddrs[0],
+			Amount:  20000000,
+			AssetID: vm.ctx.AVAXAssetID,
+		},
+	}
+	tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], splits, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee, err := tx.Burned(vm.ctx.AVAXAssetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outs := tx.UnsignedAtomicTx.(*UnsignedImportTx).Outs
+	if len(outs) != 1 || outs[0].Address != testEthAddrs[0] || outs[0].Amount != importAmount-fee {
+		t.Fatalf("Expected a single output of %d to %s, found %+v", importAmount-fee, testEthAddrs[0].Hex(), outs)
+	}
+	if err := tx.UnsignedAtomicTx.SemanticVerify(vm, tx, vm.LastAcceptedBlockInternal().(*Block), initialBaseFee, vm.currentRules()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/flare-foundation/coreth/params"
 	"github.com/flare-foundation/flare/cache"
 	"github.com/flare-foundation/flare/ids"
 )
@@ -25,6 +26,9 @@ type Mempool struct {
 
 	// AVAXAssetID is the fee paying currency of any atomic transaction
 	AVAXAssetID ids.ID
+	// currentRules returns the rules the gas used by a transaction is
+	// calculated with when it is added
+	currentRules func() params.Rules
 	// maxSize is the maximum number of transactions allowed to be kept in mempool
 	maxSize int
 	// currentTx is the transaction about to be added to a block.
@@ -46,10 +50,12 @@ type Mempool struct {
 	txHeap *txHeap
 }
 
-// NewMempool returns a Mempool with [maxSize]
-func NewMempool(AVAXAssetID ids.ID, maxSize int) *Mempool {
+// NewMempool returns a Mempool with [maxSize] that orders transactions by
+// their gas price under [currentRules]
+func NewMempool(AVAXAssetID ids.ID, maxSize int, currentRules func() params.Rules) *Mempool {
 	return &Mempool{
 		AVAXAssetID:  AVAXAssetID,
+		currentRules: currentRules,
 		issuedTxs:    make(map[ids.ID]*Tx),
 		discardedTxs: &cache.LRU{Size: discardedTxsCacheSize},
 		Pending:      make(chan struct{}, 1),
@@ -79,21 +85,30 @@ func (m *Mempool) has(txID ids.ID) bool {
 	return found && !dropped
 }
 
-// atomicTxGasPrice is the [gasPrice] paid by a transaction to burn a given
-// amount of [AVAXAssetID] given the value of [gasUsed].
-func (m *Mempool) atomicTxGasPrice(tx *Tx) (uint64, error) {
-	gasUsed, err := tx.GasUsed()
-	if err != nil {
-		return 0, err
+// Conflicts returns true if [tx] spends an input of another tx in the
+// mempool, in which case AddTx would reject it.
+func (m *Mempool) Conflicts(tx *Tx) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	txID := tx.ID()
+	if _, exists := m.issuedTxs[txID]; exists {
+		return false
 	}
-	if gasUsed == 0 {
-		return 0, errNoGasUsed
+	if m.currentTx != nil && m.currentTx.ID() == txID {
+		return false
 	}
-	burned, err := tx.Burned(m.AVAXAssetID)
-	if err != nil {
-		return 0, err
+	if _, exists := m.txHeap.Get(txID); exists {
+		return false
 	}
-	return burned / gasUsed, nil
+	return m.utxoSet.Overlaps(tx.InputUTXOs())
+}
+
+// atomicTxGasPrice is the [gasPrice] paid by a transaction to burn a given
+// amount of [AVAXAssetID] given the value of [gasUsed].
+func (m *Mempool) atomicTxGasPrice(tx *Tx) (uint64, error) {
+	_, gasPrice, err := tx.EffectiveFee(m.AVAXAssetID, m.currentRules())
+	return gasPrice, err
 }
 
 // Add attempts to add [tx] to the mempool and returns an error if
@@ -113,6 +128,48 @@ func (m *Mempool) ForceAddTx(tx *Tx) error {
 	return m.addTx(tx, true)
 }
 
+// AddTxs adds all of [txs], which must not conflict with each other, to the
+// mempool, or none of them if any of them cannot be added. Unlike AddTx, it
+// does not evict txs from a full mempool to make room for [txs], so adding
+// them cannot drop other txs.
+func (m *Mempool) AddTxs(txs []*Tx) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	newTxs := 0
+	for _, tx := range txs {
+		txID := tx.ID()
+		if _, exists := m.issuedTxs[txID]; exists {
+			continue
+		}
+		if m.currentTx != nil && m.currentTx.ID() == txID {
+			continue
+		}
+		if _, exists := m.txHeap.Get(txID); exists {
+			continue
+		}
+		if m.utxoSet.Overlaps(tx.InputUTXOs()) {
+			return fmt.Errorf("%w: %s", errConflictingAtomicTx, txID)
+		}
+		if _, err := m.atomicTxGasPrice(tx); err != nil {
+			return err
+		}
+		newTxs++
+	}
+	if m.length()+newTxs > m.maxSize {
+		return fmt.Errorf("%w: %d txs do not fit in the mempool", errTooManyAtomicTx, newTxs)
+	}
+
+	// None of [txs] conflicts with the mempool and they all fit, so adding them
+	// cannot fail.
+	for _, tx := range txs {
+		if err := m.addTx(tx, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // addTx adds [tx] to the mempool. Assumes [m.lock] is held.
 // If [force], skips conflict checks within the mempool.
 func (m *Mempool) addTx(tx *Tx, force bool) error {
@@ -218,6 +275,25 @@ func (m *Mempool) GetPendingTx(txID ids.ID) (*Tx, bool) {
 	return m.txHeap.Get(txID)
 }
 
+// PendingTxs returns the transactions that are in the mempool and have not
+// been accepted, whether or not they have been issued into a block.
+func (m *Mempool) PendingTxs() []*Tx {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	txs := make([]*Tx, 0, m.length())
+	for _, entry := range m.txHeap.maxHeap.items {
+		txs = append(txs, entry.tx)
+	}
+	if m.currentTx != nil {
+		txs = append(txs, m.currentTx)
+	}
+	for _, tx := range m.issuedTxs {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
 // GetTx returns the transaction [txID] if it was issued
 // by this node and returns whether it was dropped and whether
 // it exists.
@@ -331,6 +407,30 @@ func (m *Mempool) RemoveTx(txID ids.ID) {
 	m.discardedTxs.Evict(txID)
 }
 
+// RemovePendingTx removes [txID] from the mempool if it has not been issued
+// into a block yet, and returns whether it was removed. The tx is marked as
+// discarded and is no longer gossiped, but it is only removed from this node:
+// peers that already received it may still include it in a block.
+func (m *Mempool) RemovePendingTx(txID ids.ID) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	tx, ok := m.txHeap.Get(txID)
+	if !ok {
+		return false
+	}
+	m.txHeap.Remove(txID)
+	m.utxoSet.Remove(tx.InputUTXOs().List()...)
+	m.discardedTxs.Put(txID, tx)
+	for i, newTx := range m.newTxs {
+		if newTx.ID() == txID {
+			m.newTxs = append(m.newTxs[:i], m.newTxs[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
 // addPending makes sure that an item is in the Pending channel.
 func (m *Mempool) addPending() {
 	select {
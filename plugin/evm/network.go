@@ -5,6 +5,7 @@ package evm
 
 import (
 	"container/heap"
+	"container/list"
 	"math/big"
 	"sync"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/flare-foundation/flare/cache"
 	"github.com/flare-foundation/flare/ids"
 	"github.com/flare-foundation/flare/snow"
+	"github.com/flare-foundation/flare/utils/timer/mockable"
 	"github.com/flare-foundation/flare/utils/wrappers"
 
 	commonEng "github.com/flare-foundation/flare/snow/engine/common"
@@ -36,6 +38,10 @@ const (
 	// [ethTxsGossipInterval] is how often we attempt to gossip newly seen
 	// transactions to other nodes.
 	ethTxsGossipInterval = 1 * time.Second
+
+	// [seenAtomicTxTTL] is how long an atomic tx ID is remembered after it has
+	// been gossiped, during which the same tx will not be gossiped again.
+	seenAtomicTxTTL = 1 * time.Minute
 )
 
 type Network interface {
@@ -48,6 +54,10 @@ type Network interface {
 	// Gossip entrypoints
 	GossipAtomicTxs(txs []*Tx) error
 	GossipEthTxs(txs []*types.Transaction) error
+
+	// AtomicTxAccepted notifies the network that [txID] has been accepted, so
+	// that it no longer needs to be tracked for gossip deduplication.
+	AtomicTxAccepted(txID ids.ID)
 }
 
 func (vm *VM) AppRequest(nodeID ids.ShortID, requestID uint32, deadline time.Time, request []byte) error {
@@ -100,10 +110,10 @@ type pushNetwork struct {
 	shutdownChan       chan struct{}
 	shutdownWg         *sync.WaitGroup
 
-	// [recentAtomicTxs] and [recentEthTxs] prevent us from over-gossiping the
+	// [seenAtomicTxs] and [recentEthTxs] prevent us from over-gossiping the
 	// same transaction in a short period of time.
-	recentAtomicTxs *cache.LRU
-	recentEthTxs    *cache.LRU
+	seenAtomicTxs *seenTxSet
+	recentEthTxs  *cache.LRU
 }
 
 func (vm *VM) newPushNetwork(
@@ -124,7 +134,7 @@ func (vm *VM) newPushNetwork(
 		ethTxsToGossip:       make(map[common.Hash]*types.Transaction),
 		shutdownChan:         vm.shutdownChan,
 		shutdownWg:           &vm.shutdownWg,
-		recentAtomicTxs:      &cache.LRU{Size: recentCacheSize},
+		seenAtomicTxs:        newSeenTxSet(&vm.clock, seenAtomicTxTTL, recentCacheSize),
 		recentEthTxs:         &cache.LRU{Size: recentCacheSize},
 	}
 	net.gossipHandler = &GossipHandler{
@@ -334,7 +344,7 @@ func (n *pushNetwork) GossipAtomicTxs(txs []*Tx) error {
 func (n *pushNetwork) gossipAtomicTx(tx *Tx) error {
 	txID := tx.ID()
 	// Don't gossip transaction if it has been recently gossiped.
-	if _, has := n.recentAtomicTxs.Get(txID); has {
+	if n.seenAtomicTxs.Has(txID) {
 		return nil
 	}
 	// If the transaction is not pending according to the mempool
@@ -342,7 +352,7 @@ func (n *pushNetwork) gossipAtomicTx(tx *Tx) error {
 	if _, pending := n.mempool.GetPendingTx(txID); !pending {
 		return nil
 	}
-	n.recentAtomicTxs.Put(txID, nil)
+	n.seenAtomicTxs.Add(txID)
 
 	msg := message.AtomicTx{
 		Tx: tx.Bytes(),
@@ -359,6 +369,94 @@ func (n *pushNetwork) gossipAtomicTx(tx *Tx) error {
 	return n.appSender.SendAppGossip(msgBytes)
 }
 
+func (n *pushNetwork) AtomicTxAccepted(txID ids.ID) {
+	n.seenAtomicTxs.Remove(txID)
+}
+
+// seenTxSet tracks recently gossiped tx IDs. Entries expire [ttl] after they
+// were added, after which the tx may be gossiped again. At most [size] entries
+// are kept, and the oldest entry is evicted to make room for a new one.
+type seenTxSet struct {
+	lock  sync.Mutex
+	clock *mockable.Clock
+	ttl   time.Duration
+	size  int
+	// [order] holds the entries from the oldest to the most recently added,
+	// so expired entries are always at its front.
+	order *list.List
+	seen  map[ids.ID]*list.Element
+}
+
+type seenTxEntry struct {
+	txID   ids.ID
+	expiry time.Time
+}
+
+func newSeenTxSet(clock *mockable.Clock, ttl time.Duration, size int) *seenTxSet {
+	return &seenTxSet{
+		clock: clock,
+		ttl:   ttl,
+		size:  size,
+		order: list.New(),
+		seen:  make(map[ids.ID]*list.Element),
+	}
+}
+
+// Has returns true if [txID] was added less than [ttl] ago.
+func (s *seenTxSet) Has(txID ids.ID) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	elem, ok := s.seen[txID]
+	if !ok {
+		return false
+	}
+	if !s.clock.Time().Before(elem.Value.(*seenTxEntry).expiry) {
+		s.remove(elem)
+		return false
+	}
+	return true
+}
+
+// Add marks [txID] as seen until [ttl] from now. Expired entries are pruned
+// from the oldest until an unexpired one is found, and the oldest entry is
+// evicted if the set is still full.
+func (s *seenTxSet) Add(txID ids.ID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock.Time()
+	if elem, ok := s.seen[txID]; ok {
+		s.remove(elem)
+	}
+	for elem := s.order.Front(); elem != nil; elem = s.order.Front() {
+		if now.Before(elem.Value.(*seenTxEntry).expiry) && s.order.Len() < s.size {
+			break
+		}
+		s.remove(elem)
+	}
+	s.seen[txID] = s.order.PushBack(&seenTxEntry{
+		txID:   txID,
+		expiry: now.Add(s.ttl),
+	})
+}
+
+// Remove forgets [txID].
+func (s *seenTxSet) Remove(txID ids.ID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if elem, ok := s.seen[txID]; ok {
+		s.remove(elem)
+	}
+}
+
+// remove deletes [elem] from the set. Assumes the lock is held.
+func (s *seenTxSet) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.seen, elem.Value.(*seenTxEntry).txID)
+}
+
 func (n *pushNetwork) sendEthTxs(txs []*types.Transaction) error {
 	if len(txs) == 0 {
 		return nil
@@ -540,6 +638,10 @@ func (h *GossipHandler) HandleAtomicTx(nodeID ids.ShortID, _ uint32, msg *messag
 	tx.Initialize(unsignedBytes, msg.Tx)
 
 	txID := tx.ID()
+	// Drop txs we have gossiped recently to avoid gossip loops.
+	if h.net.seenAtomicTxs.Has(txID) {
+		return nil
+	}
 	if _, dropped, found := h.net.mempool.GetTx(txID); found || dropped {
 		return nil
 	}
@@ -614,3 +716,5 @@ func (n *noopNetwork) GossipAtomicTxs(tx []*Tx) error {
 func (n *noopNetwork) GossipEthTxs(txs []*types.Transaction) error {
 	return nil
 }
+
+func (n *noopNetwork) AtomicTxAccepted(txID ids.ID) {}
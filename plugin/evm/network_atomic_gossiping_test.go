@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/flare-foundation/flare/ids"
+	"github.com/flare-foundation/flare/utils/timer/mockable"
 
 	"github.com/stretchr/testify/assert"
 
@@ -221,3 +222,83 @@ func TestMempoolAtmTxsAppGossipHandlingDiscardedTx(t *testing.T) {
 	assert.False(mempool.has(txID))
 	assert.True(mempool.has(conflictingTx.ID()))
 }
+
+// a gossiped tx should not be re-gossiped until [seenAtomicTxTTL] has elapsed,
+// and accepted txs should be removed from the seen set
+func TestMempoolAtmTxsGossipDeduplication(t *testing.T) {
+	assert := assert.New(t)
+
+	_, vm, _, sharedMemory, sender := GenesisVM(t, true, genesisJSONApricotPhase4, "", "")
+	defer func() {
+		assert.NoError(vm.Shutdown())
+	}()
+	now := time.Now()
+	vm.clock.Set(now)
+
+	var gossiped int
+	var gossipedLock sync.Mutex // needed to prevent race
+	sender.CantSendAppGossip = false
+	sender.SendAppGossipF = func([]byte) error {
+		gossipedLock.Lock()
+		defer gossipedLock.Unlock()
+
+		gossiped++
+		return nil
+	}
+
+	tx := createImportTxOptions(t, vm, sharedMemory)[0]
+	assert.NoError(vm.issueTx(tx, true /*=local*/))
+	time.Sleep(waitBlockTime * 3)
+	gossipedLock.Lock()
+	assert.Equal(1, gossiped)
+	gossipedLock.Unlock()
+
+	// Re-broadcasting within the TTL window is a no-op
+	vm.clock.Set(now.Add(seenAtomicTxTTL - time.Second))
+	assert.NoError(vm.network.GossipAtomicTxs([]*Tx{tx}))
+	gossipedLock.Lock()
+	assert.Equal(1, gossiped)
+	gossipedLock.Unlock()
+
+	// Once the TTL has elapsed the tx is gossiped again
+	vm.clock.Set(now.Add(seenAtomicTxTTL))
+	assert.NoError(vm.network.GossipAtomicTxs([]*Tx{tx}))
+	gossipedLock.Lock()
+	assert.Equal(2, gossiped)
+	gossipedLock.Unlock()
+
+	net, ok := vm.network.(*pushNetwork)
+	assert.True(ok)
+	assert.True(net.seenAtomicTxs.Has(tx.ID()))
+	vm.network.AtomicTxAccepted(tx.ID())
+	assert.False(net.seenAtomicTxs.Has(tx.ID()))
+}
+
+func TestSeenTxSetBounded(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := &mockable.Clock{}
+	now := time.Unix(1000, 0)
+	clock.Set(now)
+	set := newSeenTxSet(clock, time.Minute, 2)
+
+	txIDs := []ids.ID{ids.GenerateTestID(), ids.GenerateTestID(), ids.GenerateTestID()}
+	set.Add(txIDs[0])
+	clock.Set(now.Add(time.Second))
+	set.Add(txIDs[1])
+
+	// The oldest entry is evicted once the set is full
+	set.Add(txIDs[2])
+	assert.False(set.Has(txIDs[0]))
+	assert.True(set.Has(txIDs[1]))
+	assert.True(set.Has(txIDs[2]))
+	assert.Len(set.seen, 2)
+
+	// Expired entries are pruned when a tx is added
+	clock.Set(now.Add(time.Minute + time.Second))
+	set.Add(txIDs[0])
+	assert.Len(set.seen, 1)
+	assert.True(set.Has(txIDs[0]))
+	assert.False(set.Has(txIDs[1]))
+	assert.False(set.Has(txIDs[2]))
+}
@@ -4,24 +4,33 @@
 package evm
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"math/big"
 	"net/http"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/flare-foundation/coreth/consensus/dummy"
+	"github.com/flare-foundation/coreth/core"
+	"github.com/flare-foundation/coreth/core/vm"
 	"github.com/flare-foundation/coreth/params"
 	"github.com/flare-foundation/flare/api"
+	"github.com/flare-foundation/flare/database"
 	"github.com/flare-foundation/flare/ids"
 	"github.com/flare-foundation/flare/utils/constants"
 	"github.com/flare-foundation/flare/utils/crypto"
 	"github.com/flare-foundation/flare/utils/formatting"
 	"github.com/flare-foundation/flare/utils/json"
+	"github.com/flare-foundation/flare/vms/secp256k1fx"
 )
 
 // test constants
@@ -31,13 +40,34 @@ const (
 
 	// Max number of addresses that can be passed in as argument to GetUTXOs
 	maxGetUTXOsAddrs = 1024
+
+	// Max number of blocks that can be scanned by GetFeesBurned
+	maxFeesBurnedRange = 2048
+
+	// Number of recent block intervals averaged by EstimatedNextBlockTime
+	blockIntervalWindow = 16
 )
 
+// Max number of blocks that can be scanned by GetTotalSupply past the closest
+// cached supply checkpoint
+var maxSupplyScanRange uint64 = 16 * supplyCheckpointInterval
+
 var (
 	errNoAddresses   = errors.New("no addresses provided")
 	errNoSourceChain = errors.New("no source chain provided")
 	errNilTxID       = errors.New("nil transaction ID")
 
+	errInvalidHeightRange  = errors.New("invalid height range")
+	errHeightRangeTooLarge = errors.New("height range too large")
+	errForkNotActivated    = errors.New("fork has not activated")
+	errNoKeysToExport      = errors.New("user has no keys to export")
+	errUnknownUTXO         = errors.New("no accepted export tx produced the UTXO")
+	errTxAlreadyAccepted   = errors.New("tx has already been accepted")
+	errSupplyMinted        = errors.New("supply includes the native tokens minted by the keeper")
+	errTxNotPending        = errors.New("tx is not pending in the mempool")
+	errTxNotSignedByUser   = errors.New("tx was not signed by any of the user's keys")
+	errMissingChainID      = errors.New("chain config has no chain ID")
+
 	initialBaseFee = big.NewInt(params.ApricotPhase3InitialBaseFee)
 )
 
@@ -51,7 +81,15 @@ func (s *NetAPI) Listening() bool { return true } // always listening
 func (s *NetAPI) PeerCount() hexutil.Uint { return hexutil.Uint(0) }
 
 // Version returns the current ethereum protocol version.
-func (s *NetAPI) Version() string { return fmt.Sprintf("%d", s.vm.networkID) }
+// By default this is the EVM chain ID, which is what Ethereum tooling expects
+// net_version to return. If NetVersionNetworkID is set in the VM config, the
+// Avalanche network ID is returned instead.
+func (s *NetAPI) Version() string {
+	if s.vm.config.NetVersionNetworkID {
+		return fmt.Sprintf("%d", s.vm.networkID)
+	}
+	return s.vm.chainID.String()
+}
 
 // Web3API offers helper API methods
 type Web3API struct{}
@@ -81,6 +119,110 @@ func (api *SnowmanAPI) GetAcceptedFront(ctx context.Context) (*GetAcceptedFrontR
 	}, nil
 }
 
+// GetForkActivationBlock returns the hash and height of the first accepted
+// block whose timestamp activated the fork named [forkName], such as
+// "ApricotPhase3". It returns an error if the fork is not activated by
+// timestamp or has not activated by the last accepted block.
+func (api *SnowmanAPI) GetForkActivationBlock(ctx context.Context, forkName string) (*GetAcceptedFrontReply, error) {
+	id, err := params.ParseForkID(forkName)
+	if err != nil {
+		return nil, err
+	}
+	forkTimestamp, err := api.vm.chainConfig.ForkTimestamp(id)
+	if err != nil {
+		return nil, err
+	}
+	lastAccepted := api.vm.chain.LastAcceptedBlock()
+	if forkTimestamp == nil || forkTimestamp.Cmp(new(big.Int).SetUint64(lastAccepted.Time())) > 0 {
+		return nil, fmt.Errorf("%w: %s", errForkNotActivated, id)
+	}
+
+	height, err := firstBlockAtOrAfter(lastAccepted.NumberU64(), forkTimestamp.Uint64(), func(height uint64) (uint64, error) {
+		block := api.vm.chain.GetBlockByNumber(height)
+		if block == nil {
+			return 0, fmt.Errorf("could not find block at height: %d", height)
+		}
+		return block.Time(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	block := api.vm.chain.GetBlockByNumber(height)
+	return &GetAcceptedFrontReply{
+		Hash:   block.Hash(),
+		Number: block.Number(),
+	}, nil
+}
+
+// firstBlockAtOrAfter returns the lowest height in [0, lastHeight] of a
+// block with a timestamp at or after [timestamp], given the timestamps of
+// the blocks by [blockTime]. Block timestamps are non-decreasing, so the
+// heights are binary searched. The block at [lastHeight] must satisfy the
+// condition.
+func firstBlockAtOrAfter(lastHeight uint64, timestamp uint64, blockTime func(height uint64) (uint64, error)) (uint64, error) {
+	low, high := uint64(0), lastHeight
+	for low < high {
+		mid := low + (high-low)/2
+		midTime, err := blockTime(mid)
+		if err != nil {
+			return 0, err
+		}
+		if midTime >= timestamp {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	return low, nil
+}
+
+// EstimatedNextBlockTime returns the estimated unix timestamp of the next
+// block, from the average interval between recently accepted blocks. If there
+// is not enough history, or the estimate has passed, the current time is
+// returned.
+func (api *SnowmanAPI) EstimatedNextBlockTime(ctx context.Context) (uint64, error) {
+	lastAccepted := api.vm.chain.LastAcceptedBlock()
+	return estimateNextBlockTime(lastAccepted.NumberU64(), api.vm.clock.Unix(), func(height uint64) (uint64, error) {
+		block := api.vm.chain.GetBlockByNumber(height)
+		if block == nil {
+			return 0, fmt.Errorf("could not find block at height: %d", height)
+		}
+		return block.Time(), nil
+	})
+}
+
+// estimateNextBlockTime returns the timestamp of the block at [lastHeight]
+// plus the average interval between the last [blockIntervalWindow] blocks,
+// given the timestamps of the blocks by [blockTime]. The genesis timestamp
+// is not related to when blocks are produced, so it is never averaged over.
+// If fewer than two blocks follow genesis, or the estimate is before [now],
+// [now] is returned.
+func estimateNextBlockTime(lastHeight uint64, now uint64, blockTime func(height uint64) (uint64, error)) (uint64, error) {
+	if lastHeight < 2 {
+		return now, nil
+	}
+	firstHeight := uint64(1)
+	if lastHeight > blockIntervalWindow {
+		firstHeight = lastHeight - blockIntervalWindow
+	}
+	firstTime, err := blockTime(firstHeight)
+	if err != nil {
+		return 0, err
+	}
+	lastTime, err := blockTime(lastHeight)
+	if err != nil {
+		return 0, err
+	}
+	if lastTime < firstTime {
+		return 0, fmt.Errorf("block timestamp %d at height %d is before timestamp %d at height %d", lastTime, lastHeight, firstTime, firstHeight)
+	}
+	estimate := lastTime + (lastTime-firstTime)/(lastHeight-firstHeight)
+	if estimate < now {
+		return now, nil
+	}
+	return estimate, nil
+}
+
 // IssueBlock to the chain
 func (api *SnowmanAPI) IssueBlock(ctx context.Context) error {
 	log.Info("Issuing a new block")
@@ -89,6 +231,605 @@ func (api *SnowmanAPI) IssueBlock(ctx context.Context) error {
 	return nil
 }
 
+// DebugAPI introduces VM specific debugging functionality to the evm
+type DebugAPI struct{ vm *VM }
+
+// GetGenesisTimestamp returns the timestamp of the genesis block
+func (api *DebugAPI) GetGenesisTimestamp(ctx context.Context) (uint64, error) {
+	return api.vm.genesisTimestamp, nil
+}
+
+// GetChainID returns the EVM chain ID of the loaded chain config
+func (api *DebugAPI) GetChainID(ctx context.Context) (*hexutil.Big, error) {
+	if api.vm.chainConfig == nil || api.vm.chainConfig.ChainID == nil {
+		return nil, errMissingChainID
+	}
+	return (*hexutil.Big)(new(big.Int).Set(api.vm.chainConfig.ChainID)), nil
+}
+
+// GetChainConfigHash returns a hash of the fork schedule of the chain config,
+// which is equal across nodes configured with the same fork schedule
+func (api *DebugAPI) GetChainConfigHash(ctx context.Context) (common.Hash, error) {
+	return api.vm.chainConfig.ForkHash(), nil
+}
+
+// GetMultiCoinBalance returns the balance of [assetID] held by [address] in
+// the state of the accepted block at [height]. The native asset is held in the
+// regular balance and is therefore rejected.
+func (api *DebugAPI) GetMultiCoinBalance(ctx context.Context, address common.Address, assetID string, height uint64) (*hexutil.Big, error) {
+	coinID, err := ids.FromString(assetID)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse asset ID %q: %w", assetID, err)
+	}
+	if coinID == api.vm.ctx.AVAXAssetID {
+		return nil, fmt.Errorf("asset %s is the native asset and is not held as a multicoin balance", coinID)
+	}
+	if lastAccepted := api.vm.chain.LastAcceptedBlock().NumberU64(); height > lastAccepted {
+		return nil, fmt.Errorf("height %d is above the last accepted height %d", height, lastAccepted)
+	}
+	block := api.vm.chain.GetBlockByNumber(height)
+	if block == nil {
+		return nil, fmt.Errorf("couldn't find block at height %d", height)
+	}
+	state, err := api.vm.chain.BlockState(block)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load state at height %d: %w", height, err)
+	}
+	return (*hexutil.Big)(state.GetBalanceMultiCoin(address, common.Hash(coinID))), nil
+}
+
+// GetPendingBalance returns the balance, in wei, of [address] in the state of
+// the last accepted block plus the AVAX credited to it by the import txs in
+// the mempool. The result is speculative: the pending imports may never be
+// accepted, and pending spends from the address are not deducted.
+func (api *DebugAPI) GetPendingBalance(ctx context.Context, address common.Address) (*hexutil.Big, error) {
+	state, err := api.vm.chain.BlockState(api.vm.chain.LastAcceptedBlock())
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load last accepted state: %w", err)
+	}
+	balance := new(big.Int).Set(state.GetBalance(address))
+
+	for _, tx := range api.vm.mempool.PendingTxs() {
+		var importTx *UnsignedImportTx
+		switch utx := tx.UnsignedAtomicTx.(type) {
+		case *UnsignedImportTx:
+			importTx = utx
+		case *UnsignedSponsoredImportTx:
+			importTx = &utx.UnsignedImportTx
+		case *UnsignedContractImportTx:
+			importTx = &utx.UnsignedImportTx
+		default:
+			continue
+		}
+		for _, out := range importTx.Outs {
+			if out.Address == address && out.AssetID == api.vm.ctx.AVAXAssetID {
+				balance.Add(balance, XtoC(out.Amount))
+			}
+		}
+	}
+	return (*hexutil.Big)(balance), nil
+}
+
+// GetFeesBurned returns the fees, in wei, burned by the accepted blocks with
+// heights in [start, end]. This includes the base fees burned by the
+// transactions of each block and the AVAX burned by its atomic tx.
+func (api *DebugAPI) GetFeesBurned(ctx context.Context, start, end uint64) (*hexutil.Big, error) {
+	if start > end {
+		return nil, fmt.Errorf("%w: start %d is above end %d", errInvalidHeightRange, start, end)
+	}
+	if end-start >= maxFeesBurnedRange {
+		return nil, fmt.Errorf("%w: %d blocks requested, but at most %d can be scanned", errHeightRangeTooLarge, end-start+1, maxFeesBurnedRange)
+	}
+	if lastAccepted := api.vm.chain.LastAcceptedBlock().NumberU64(); end > lastAccepted {
+		return nil, fmt.Errorf("%w: end %d is above the last accepted height %d", errInvalidHeightRange, end, lastAccepted)
+	}
+
+	total := new(big.Int)
+	for height := start; height <= end; height++ {
+		block := api.vm.chain.GetBlockByNumber(height)
+		if block == nil {
+			return nil, fmt.Errorf("couldn't find block at height %d", height)
+		}
+		burned, err := api.vm.feesBurned(block)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't calculate fees burned at height %d: %w", height, err)
+		}
+		total.Add(total, burned)
+	}
+	return (*hexutil.Big)(total), nil
+}
+
+// GetTotalSupply returns the native supply, in wei, after the accepted block
+// at [height]: the supply allocated at genesis, plus the AVAX imported and
+// minus the AVAX exported and burned by the atomic txs up to [height]. The
+// fees of EVM txs are credited to the coinbase, so they stay in the supply.
+//
+// The native tokens minted by the keeper are not recorded anywhere, so the
+// supply is refused once the system trigger contract of the keeper has code,
+// as it does on Flare, Songbird and Coston. Supply checkpoints are only
+// cached in memory, so at most [maxSupplyScanRange] blocks are scanned past
+// the closest one; the supply at a greater height is reached by requesting
+// lower heights first.
+func (api *DebugAPI) GetTotalSupply(ctx context.Context, height uint64) (*hexutil.Big, error) {
+	if lastAccepted := api.vm.chain.LastAcceptedBlock().NumberU64(); height > lastAccepted {
+		return nil, fmt.Errorf("%w: height %d is above the last accepted height %d", errInvalidHeightRange, height, lastAccepted)
+	}
+	block := api.vm.chain.GetBlockByNumber(height)
+	if block == nil {
+		return nil, fmt.Errorf("couldn't find block at height %d", height)
+	}
+	state, err := api.vm.chain.BlockState(block)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load state at height %d: %w", height, err)
+	}
+	if keeper := common.HexToAddress(core.GetSystemTriggerContractAddr(block.Number())); state.GetCodeSize(keeper) != 0 {
+		return nil, fmt.Errorf("%w: keeper %s is deployed at height %d", errSupplyMinted, keeper, height)
+	}
+	if start, _ := api.vm.supplyCheckpoint(height); height-start > maxSupplyScanRange {
+		return nil, fmt.Errorf("%w: %d blocks past the closest supply checkpoint at height %d, but at most %d can be scanned", errHeightRangeTooLarge, height-start, start, maxSupplyScanRange)
+	}
+	supply, err := api.vm.totalSupply(height)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(supply), nil
+}
+
+// GetEnabledPrecompiles returns the addresses of the precompiled contracts
+// enabled by the rules of the accepted block at [height], sorted in ascending
+// order
+func (api *DebugAPI) GetEnabledPrecompiles(ctx context.Context, height uint64) ([]common.Address, error) {
+	if lastAccepted := api.vm.chain.LastAcceptedBlock().NumberU64(); height > lastAccepted {
+		return nil, fmt.Errorf("height %d is above the last accepted height %d", height, lastAccepted)
+	}
+	block := api.vm.chain.GetBlockByNumber(height)
+	if block == nil {
+		return nil, fmt.Errorf("couldn't find block at height %d", height)
+	}
+
+	rules := api.vm.chainConfig.AvalancheRules(block.Number(), new(big.Int).SetUint64(block.Time()))
+	active := vm.ActivePrecompiles(rules)
+	precompiles := make([]common.Address, len(active))
+	copy(precompiles, active)
+	sort.Slice(precompiles, func(i, j int) bool {
+		return bytes.Compare(precompiles[i][:], precompiles[j][:]) < 0
+	})
+	return precompiles, nil
+}
+
+// ConsensusState defines the reply returned from the GetConsensusState API
+// call
+type ConsensusState struct {
+	Height    uint64           `json:"height"`
+	Timestamp uint64           `json:"timestamp"`
+	Rules     params.Rules     `json:"rules"`
+	FeeConfig params.FeeConfig `json:"feeConfig"`
+}
+
+// GetConsensusState returns the height and timestamp of the last accepted
+// block together with the rules and fee config in effect at that block
+func (api *DebugAPI) GetConsensusState(ctx context.Context) (ConsensusState, error) {
+	block := api.vm.chain.LastAcceptedBlock()
+	timestamp := new(big.Int).SetUint64(block.Time())
+	feeConfig, err := api.vm.chainConfig.FeeConfig(timestamp, nil)
+	if err != nil {
+		return ConsensusState{}, fmt.Errorf("couldn't get fee config at height %d: %w", block.NumberU64(), err)
+	}
+	return ConsensusState{
+		Height:    block.NumberU64(),
+		Timestamp: block.Time(),
+		Rules:     api.vm.chainConfig.AvalancheRules(block.Number(), timestamp),
+		FeeConfig: feeConfig,
+	}, nil
+}
+
+// redactedValue replaces sensitive config values in API replies
+const redactedValue = "<redacted>"
+
+// VMConfigReply defines the reply returned from the GetVMConfig API call
+type VMConfigReply struct {
+	// Config is the config the VM was initialized with, with sensitive values
+	// redacted
+	Config Config `json:"config"`
+
+	MaxAtomicInputs    uint64       `json:"maxAtomicInputs"`
+	MaxAtomicOutputs   uint64       `json:"maxAtomicOutputs"`
+	MinAtomicFeePerGas *hexutil.Big `json:"minAtomicFeePerGas,omitempty"`
+	MempoolSize        int          `json:"mempoolSize"`
+}
+
+// GetVMConfig returns the effective runtime config of the VM. The keystore
+// settings that reveal the node's filesystem or signer are redacted.
+func (api *DebugAPI) GetVMConfig(ctx context.Context) (VMConfigReply, error) {
+	config := api.vm.config
+	if config.KeystoreDirectory != "" {
+		config.KeystoreDirectory = redactedValue
+	}
+	if config.KeystoreExternalSigner != "" {
+		config.KeystoreExternalSigner = redactedValue
+	}
+	config.AllowedAtomicAssets = append([]string(nil), config.AllowedAtomicAssets...)
+
+	rules := api.vm.currentRules()
+	reply := VMConfigReply{
+		Config:           config,
+		MaxAtomicInputs:  rules.MaxAtomicInputs,
+		MaxAtomicOutputs: rules.MaxAtomicOutputs,
+		MempoolSize:      api.vm.mempool.maxSize,
+	}
+	if minFeePerGas := api.vm.chainConfig.MinAtomicFeePerGas; minFeePerGas != nil {
+		reply.MinAtomicFeePerGas = (*hexutil.Big)(new(big.Int).Set(minFeePerGas))
+	}
+	return reply, nil
+}
+
+// AtomicGasStats defines the reply returned from the GetAtomicGasStats API call
+type AtomicGasStats struct {
+	TotalGasUsed uint64 `json:"totalGasUsed"`
+	TxCount      uint64 `json:"txCount"`
+}
+
+// GetAtomicGasStats returns the total gas used by, and the number of, atomic
+// transactions accepted since the VM started
+func (api *DebugAPI) GetAtomicGasStats(ctx context.Context) (AtomicGasStats, error) {
+	return AtomicGasStats{
+		TotalGasUsed: atomic.LoadUint64(&api.vm.atomicTxGasUsed),
+		TxCount:      atomic.LoadUint64(&api.vm.atomicTxsAccepted),
+	}, nil
+}
+
+// PendingAtomicTx describes an atomic tx in the mempool
+type PendingAtomicTx struct {
+	TxID    ids.ID `json:"txID"`
+	Type    string `json:"type"`
+	Size    int    `json:"size"`
+	GasUsed uint64 `json:"gasUsed"`
+	// Fee is the amount of AVAX burned by the tx
+	Fee       uint64 `json:"fee"`
+	FeePerGas uint64 `json:"feePerGas"`
+}
+
+// GetPendingAtomicTxs returns the atomic txs in the mempool that have not
+// been accepted, ordered by fee per gas from highest to lowest, which is the
+// order they are built into blocks in.
+func (api *DebugAPI) GetPendingAtomicTxs(ctx context.Context) ([]PendingAtomicTx, error) {
+	txs := api.vm.mempool.PendingTxs()
+	rules := api.vm.currentRules()
+	pending := make([]PendingAtomicTx, 0, len(txs))
+	for _, tx := range txs {
+		var txType string
+		switch tx.UnsignedAtomicTx.(type) {
+		case *UnsignedImportTx:
+			txType = "import"
+		case *UnsignedSponsoredImportTx:
+			txType = "sponsoredImport"
+		case *UnsignedContractImportTx:
+			txType = "contractImport"
+		case *UnsignedExportTx:
+			txType = "export"
+		default:
+			return nil, fmt.Errorf("unknown atomic tx type %T", tx.UnsignedAtomicTx)
+		}
+		size, err := tx.Size()
+		if err != nil {
+			return nil, err
+		}
+		gasUsed, err := tx.GasUsed(rules)
+		if err != nil {
+			return nil, err
+		}
+		fee, feePerGas, err := tx.EffectiveFee(api.vm.ctx.AVAXAssetID, rules)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't compute fee of tx %s: %w", tx.ID(), err)
+		}
+		pending = append(pending, PendingAtomicTx{
+			TxID:      tx.ID(),
+			Type:      txType,
+			Size:      size,
+			GasUsed:   gasUsed,
+			Fee:       fee,
+			FeePerGas: feePerGas,
+		})
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		if pending[i].FeePerGas != pending[j].FeePerGas {
+			return pending[i].FeePerGas > pending[j].FeePerGas
+		}
+		return bytes.Compare(pending[i].TxID[:], pending[j].TxID[:]) < 0
+	})
+	return pending, nil
+}
+
+// AtomicTxReply defines the reply returned from the GetAtomicTxForUTXO API
+// call
+type AtomicTxReply struct {
+	TxID        ids.ID `json:"txID"`
+	OutputIndex uint32 `json:"outputIndex"`
+	BlockHeight uint64 `json:"blockHeight"`
+	// Tx is the hex encoded tx
+	Tx string `json:"tx"`
+}
+
+// GetAtomicTxForUTXO returns the accepted export tx that produced the UTXO
+// with ID [utxoID]
+func (api *DebugAPI) GetAtomicTxForUTXO(ctx context.Context, utxoID string) (AtomicTxReply, error) {
+	id, err := ids.FromString(utxoID)
+	if err != nil {
+		return AtomicTxReply{}, fmt.Errorf("couldn't parse UTXO ID %q: %w", utxoID, err)
+	}
+	txID, outputIndex, err := api.vm.getExportTxForUTXO(id)
+	if err == database.ErrNotFound {
+		return AtomicTxReply{}, fmt.Errorf("%w: %s", errUnknownUTXO, id)
+	}
+	if err != nil {
+		return AtomicTxReply{}, err
+	}
+	tx, height, err := api.vm.getAcceptedAtomicTx(txID)
+	if err != nil {
+		return AtomicTxReply{}, fmt.Errorf("problem retrieving tx %s: %w", txID, err)
+	}
+	txHex, err := formatting.EncodeWithChecksum(formatting.Hex, tx.Bytes())
+	if err != nil {
+		return AtomicTxReply{}, err
+	}
+	return AtomicTxReply{
+		TxID:        txID,
+		OutputIndex: outputIndex,
+		BlockHeight: height,
+		Tx:          txHex,
+	}, nil
+}
+
+// AtomicTxIOExplanation describes a single input or output of an atomic tx
+type AtomicTxIOExplanation struct {
+	UTXOID    string   `json:"utxoID,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	AssetID   ids.ID   `json:"assetID"`
+	Amount    uint64   `json:"amount"`
+	Nonce     *uint64  `json:"nonce,omitempty"`
+}
+
+// AtomicTxExplanation defines the reply returned from the DecodeAtomicTx API call
+type AtomicTxExplanation struct {
+	TxID             ids.ID                  `json:"txID"`
+	Type             string                  `json:"type"`
+	NetworkID        uint32                  `json:"networkID"`
+	BlockchainID     ids.ID                  `json:"blockchainID"`
+	SourceChain      *ids.ID                 `json:"sourceChain,omitempty"`
+	DestinationChain *ids.ID                 `json:"destinationChain,omitempty"`
+	Inputs           []AtomicTxIOExplanation `json:"inputs"`
+	Outputs          []AtomicTxIOExplanation `json:"outputs"`
+	GasUsed          uint64                  `json:"gasUsed"`
+	Burned           map[ids.ID]uint64       `json:"burned"`
+}
+
+// DecodeAtomicTx parses the hex encoded atomic tx [txHex] and returns a
+// human readable breakdown of its contents
+func (api *DebugAPI) DecodeAtomicTx(ctx context.Context, txHex string) (AtomicTxExplanation, error) {
+	txBytes, err := formatting.Decode(formatting.Hex, txHex)
+	if err != nil {
+		return AtomicTxExplanation{}, fmt.Errorf("problem decoding transaction: %w", err)
+	}
+	tx, err := ParseTx(txBytes)
+	if err != nil {
+		return AtomicTxExplanation{}, fmt.Errorf("problem parsing transaction: %w", err)
+	}
+
+	explanation := AtomicTxExplanation{TxID: tx.ID()}
+	switch utx := tx.UnsignedAtomicTx.(type) {
+	case *UnsignedImportTx:
+		explanation.Type = "import"
+		explainImportTx(&explanation, utx)
+	case *UnsignedSponsoredImportTx:
+		explanation.Type = "sponsoredImport"
+		explainImportTx(&explanation, &utx.UnsignedImportTx)
+		nonce := utx.Sponsor.Nonce
+		explanation.Inputs = append(explanation.Inputs, AtomicTxIOExplanation{
+			Addresses: []string{utx.Sponsor.Address.Hex()},
+			AssetID:   utx.Sponsor.AssetID,
+			Amount:    utx.Sponsor.Amount,
+			Nonce:     &nonce,
+		})
+	case *UnsignedContractImportTx:
+		explanation.Type = "contractImport"
+		explainImportTx(&explanation, &utx.UnsignedImportTx)
+	case *UnsignedExportTx:
+		explanation.Type = "export"
+		explanation.NetworkID = utx.NetworkID
+		explanation.BlockchainID = utx.BlockchainID
+		explanation.DestinationChain = &utx.DestinationChain
+		for _, in := range utx.Ins {
+			nonce := in.Nonce
+			explanation.Inputs = append(explanation.Inputs, AtomicTxIOExplanation{
+				Addresses: []string{in.Address.Hex()},
+				AssetID:   in.AssetID,
+				Amount:    in.Amount,
+				Nonce:     &nonce,
+			})
+		}
+		for _, out := range utx.ExportedOutputs {
+			outExplanation := AtomicTxIOExplanation{
+				AssetID: out.AssetID(),
+				Amount:  out.Output().Amount(),
+			}
+			if transferOut, ok := out.Output().(*secp256k1fx.TransferOutput); ok {
+				for _, addr := range transferOut.Addrs {
+					addrStr, err := api.vm.FormatAddress(utx.DestinationChain, addr)
+					if err != nil {
+						addrStr = addr.String()
+					}
+					outExplanation.Addresses = append(outExplanation.Addresses, addrStr)
+				}
+			}
+			explanation.Outputs = append(explanation.Outputs, outExplanation)
+		}
+	default:
+		return AtomicTxExplanation{}, fmt.Errorf("unknown atomic tx type %T", utx)
+	}
+
+	if explanation.GasUsed, err = tx.GasUsed(api.vm.currentRules()); err != nil {
+		return AtomicTxExplanation{}, fmt.Errorf("failed to calculate gas used: %w", err)
+	}
+	if explanation.Burned, err = tx.AllBurned(); err != nil {
+		return AtomicTxExplanation{}, err
+	}
+	return explanation, nil
+}
+
+// explainImportTx fills [explanation] with the inputs and outputs of [utx]
+func explainImportTx(explanation *AtomicTxExplanation, utx *UnsignedImportTx) {
+	explanation.NetworkID = utx.NetworkID
+	explanation.BlockchainID = utx.BlockchainID
+	explanation.SourceChain = &utx.SourceChain
+	for _, in := range utx.ImportedInputs {
+		explanation.Inputs = append(explanation.Inputs, AtomicTxIOExplanation{
+			UTXOID:  in.UTXOID.String(),
+			AssetID: in.AssetID(),
+			Amount:  in.In.Amount(),
+		})
+	}
+	for _, out := range utx.Outs {
+		explanation.Outputs = append(explanation.Outputs, AtomicTxIOExplanation{
+			Addresses: []string{out.Address.Hex()},
+			AssetID:   out.AssetID,
+			Amount:    out.Amount,
+		})
+	}
+}
+
+// VerifyTraceStep describes the outcome of a single verification step of an
+// atomic tx
+type VerifyTraceStep struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// VerifyTrace defines the reply returned from the TraceAtomicTxVerify API call
+type VerifyTrace struct {
+	TxID       ids.ID            `json:"txID"`
+	Steps      []VerifyTraceStep `json:"steps"`
+	FailedStep string            `json:"failedStep,omitempty"`
+}
+
+// TraceAtomicTxVerify parses the hex encoded atomic tx [txHex] and runs each
+// of its verification steps in turn against the last accepted block,
+// stopping at the first step that fails. Parsing errors are returned as an
+// error rather than as part of the trace.
+func (api *DebugAPI) TraceAtomicTxVerify(ctx context.Context, txHex string) (VerifyTrace, error) {
+	txBytes, err := formatting.Decode(formatting.Hex, txHex)
+	if err != nil {
+		return VerifyTrace{}, fmt.Errorf("problem decoding transaction: %w", err)
+	}
+	tx, err := ParseTx(txBytes)
+	if err != nil {
+		return VerifyTrace{}, fmt.Errorf("problem parsing transaction: %w", err)
+	}
+
+	lastAccepted := api.vm.chain.LastAcceptedBlock()
+	parentIntf, err := api.vm.GetBlockInternal(ids.ID(lastAccepted.Hash()))
+	if err != nil {
+		return VerifyTrace{}, fmt.Errorf("failed to get last accepted block: %w", err)
+	}
+	parent, ok := parentIntf.(*Block)
+	if !ok {
+		return VerifyTrace{}, fmt.Errorf("last accepted block %s had unexpected type %T", parentIntf.ID(), parentIntf)
+	}
+	timestamp := time.Now().Unix()
+	bigTimestamp := big.NewInt(timestamp)
+	rules := api.vm.chainConfig.AvalancheRules(new(big.Int).Add(lastAccepted.Number(), common.Big1), bigTimestamp)
+	var baseFee *big.Int
+	if rules.IsApricotPhase3 {
+		_, baseFee, err = dummy.CalcBaseFee(api.vm.chainConfig, lastAccepted.Header(), uint64(timestamp))
+		if err != nil {
+			return VerifyTrace{}, fmt.Errorf("failed to calculate base fee: %w", err)
+		}
+	}
+
+	trace := VerifyTrace{TxID: tx.ID()}
+	for _, step := range []struct {
+		name   string
+		verify func() error
+	}{
+		{
+			name:   "verify",
+			verify: func() error { return tx.Verify(api.vm.ctx.XChainID, api.vm.ctx, rules) },
+		},
+		{
+			name:   "semanticVerify",
+			verify: func() error { return tx.UnsignedAtomicTx.SemanticVerify(api.vm, tx, parent, baseFee, rules) },
+		},
+	} {
+		if err := step.verify(); err != nil {
+			trace.Steps = append(trace.Steps, VerifyTraceStep{Name: step.name, Error: err.Error()})
+			trace.FailedStep = step.name
+			return trace, nil
+		}
+		trace.Steps = append(trace.Steps, VerifyTraceStep{Name: step.name, Passed: true})
+	}
+	return trace, nil
+}
+
+// AtomicTxVerifyResult is the result of re-verifying a single atomic tx
+type AtomicTxVerifyResult struct {
+	TxID   ids.ID `json:"txID"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BlockAtomicVerifyResult defines the reply returned from the
+// VerifyBlockAtomicTxs API call
+type BlockAtomicVerifyResult struct {
+	Height uint64                 `json:"height"`
+	Hash   common.Hash            `json:"hash"`
+	Txs    []AtomicTxVerifyResult `json:"txs"`
+}
+
+// VerifyBlockAtomicTxs re-runs the semantic verification of the atomic txs of
+// the accepted block at [height] against the current shared memory and
+// reports the result for each of them. The imports of an accepted block have
+// consumed their UTXOs, so they are expected to fail.
+func (api *DebugAPI) VerifyBlockAtomicTxs(ctx context.Context, height uint64) (BlockAtomicVerifyResult, error) {
+	if lastAccepted := api.vm.chain.LastAcceptedBlock().NumberU64(); height > lastAccepted {
+		return BlockAtomicVerifyResult{}, fmt.Errorf("%w: height %d is above the last accepted height %d", errInvalidHeightRange, height, lastAccepted)
+	}
+	block := api.vm.chain.GetBlockByNumber(height)
+	if block == nil {
+		return BlockAtomicVerifyResult{}, fmt.Errorf("couldn't find block at height %d", height)
+	}
+	result := BlockAtomicVerifyResult{
+		Height: height,
+		Hash:   block.Hash(),
+		Txs:    []AtomicTxVerifyResult{},
+	}
+	atomicTxs, err := api.vm.extractAtomicTxs(block)
+	if err != nil {
+		return BlockAtomicVerifyResult{}, err
+	}
+	if len(atomicTxs) == 0 {
+		return result, nil
+	}
+
+	parentIntf, err := api.vm.GetBlockInternal(ids.ID(block.ParentHash()))
+	if err != nil {
+		return BlockAtomicVerifyResult{}, fmt.Errorf("failed to get parent of block %s: %w", block.Hash().Hex(), err)
+	}
+	parent, ok := parentIntf.(*Block)
+	if !ok {
+		return BlockAtomicVerifyResult{}, fmt.Errorf("parent block %s had unexpected type %T", parentIntf.ID(), parentIntf)
+	}
+	rules := api.vm.chainConfig.AvalancheRules(block.Number(), new(big.Int).SetUint64(block.Time()))
+	for _, atomicTx := range atomicTxs {
+		txResult := AtomicTxVerifyResult{TxID: atomicTx.ID(), Passed: true}
+		if err := atomicTx.UnsignedAtomicTx.SemanticVerify(api.vm, atomicTx, parent, block.BaseFee(), rules); err != nil {
+			txResult.Passed = false
+			txResult.Error = err.Error()
+		}
+		result.Txs = append(result.Txs, txResult)
+	}
+	return result, nil
+}
+
 // AvaxAPI offers Avalanche network related API methods
 type AvaxAPI struct{ vm *VM }
 
@@ -113,6 +854,43 @@ func (service *AvaxAPI) Version(r *http.Request, args *struct{}, reply *VersionR
 	return nil
 }
 
+// GetNetworkIDReply is the response for GetNetworkID
+type GetNetworkIDReply struct {
+	NetworkID json.Uint64 `json:"networkID"`
+}
+
+// GetNetworkID returns the network ID of the VM, as opposed to the EVM chain ID
+func (service *AvaxAPI) GetNetworkID(r *http.Request, args *struct{}, reply *GetNetworkIDReply) error {
+	reply.NetworkID = json.Uint64(service.vm.networkID)
+	return nil
+}
+
+// GetImportableAssetsReply is the response for GetImportableAssets
+type GetImportableAssetsReply struct {
+	// AllAssets is true if every asset is accepted for import
+	AllAssets bool `json:"allAssets"`
+	// AssetIDs are the assets accepted for import, the native asset first. If
+	// AllAssets is true, only the native asset is listed.
+	AssetIDs []ids.ID `json:"assetIDs"`
+}
+
+// GetImportableAssets returns the assets this node accepts in atomic txs
+// issued to its mempool
+func (service *AvaxAPI) GetImportableAssets(r *http.Request, args *struct{}, reply *GetImportableAssetsReply) error {
+	allowed := service.vm.allowedAtomicAssets.List()
+	ids.SortIDs(allowed)
+
+	reply.AllAssets = len(allowed) == 0
+	reply.AssetIDs = make([]ids.ID, 0, len(allowed)+1)
+	reply.AssetIDs = append(reply.AssetIDs, service.vm.ctx.AVAXAssetID)
+	for _, assetID := range allowed {
+		if assetID != service.vm.ctx.AVAXAssetID {
+			reply.AssetIDs = append(reply.AssetIDs, assetID)
+		}
+	}
+	return nil
+}
+
 // ExportKeyArgs are arguments for ExportKey
 type ExportKeyArgs struct {
 	api.UserPass
@@ -141,10 +919,7 @@ func (service *AvaxAPI) ExportKey(r *http.Request, args *ExportKeyArgs, reply *E
 	}
 	defer db.Close()
 
-	user := user{
-		secpFactory: &service.vm.secpFactory,
-		db:          db,
-	}
+	user := service.vm.newUser(db, args.Password)
 	sk, err := user.getKey(address)
 	if err != nil {
 		return fmt.Errorf("problem retrieving private key: %w", err)
@@ -158,6 +933,60 @@ func (service *AvaxAPI) ExportKey(r *http.Request, args *ExportKeyArgs, reply *E
 	return nil
 }
 
+// ExportedKey is a private key exported by ExportAllKeys, along with the
+// address it controls
+type ExportedKey struct {
+	Address       string `json:"address"`
+	PrivateKey    string `json:"privateKey"`
+	PrivateKeyHex string `json:"privateKeyHex"`
+}
+
+// ExportAllKeysReply is the response for ExportAllKeys
+type ExportAllKeysReply struct {
+	// The decrypted private keys of the user, sorted by address
+	Keys []ExportedKey `json:"keys"`
+}
+
+// ExportAllKeys returns every private key controlled by the provided user
+func (service *AvaxAPI) ExportAllKeys(r *http.Request, args *api.UserPass, reply *ExportAllKeysReply) error {
+	log.Info("EVM: ExportAllKeys called", "username", args.Username)
+
+	db, err := service.vm.ctx.Keystore.GetDatabase(args.Username, args.Password)
+	if err != nil {
+		return fmt.Errorf("problem retrieving user '%s': %w", args.Username, err)
+	}
+	defer db.Close()
+
+	user := service.vm.newUser(db, args.Password)
+	keys, err := user.getKeys()
+	if err != nil {
+		return fmt.Errorf("problem retrieving private keys: %w", err)
+	}
+	if len(keys) == 0 {
+		return errNoKeysToExport
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		addrI, addrJ := GetEthAddress(keys[i]), GetEthAddress(keys[j])
+		return bytes.Compare(addrI.Bytes(), addrJ.Bytes()) < 0
+	})
+
+	reply.Keys = make([]ExportedKey, len(keys))
+	for i, sk := range keys {
+		encodedKey, err := formatting.EncodeWithChecksum(formatting.CB58, sk.Bytes())
+		if err != nil {
+			return fmt.Errorf("problem encoding bytes as cb58: %w", err)
+		}
+		reply.Keys[i] = ExportedKey{
+			Address:       FormatEthAddress(GetEthAddress(sk)),
+			PrivateKey:    constants.SecretKeyPrefix + encodedKey,
+			PrivateKeyHex: hexutil.Encode(sk.Bytes()),
+		}
+	}
+
+	log.Warn("EVM: exported all private keys", "username", args.Username, "numKeys", len(reply.Keys))
+	return nil
+}
+
 // ImportKeyArgs are arguments for ImportKey
 type ImportKeyArgs struct {
 	api.UserPass
@@ -196,16 +1025,35 @@ func (service *AvaxAPI) ImportKey(r *http.Request, args *ImportKeyArgs, reply *a
 	}
 	defer db.Close()
 
-	user := user{
-		secpFactory: &service.vm.secpFactory,
-		db:          db,
-	}
+	user := service.vm.newUser(db, args.Password)
 	if err := user.putAddress(sk); err != nil {
 		return fmt.Errorf("problem saving key %w", err)
 	}
 	return nil
 }
 
+// IssueTxReply is the response of the calls that issue an atomic tx
+type IssueTxReply struct {
+	api.JSONTxID
+
+	// Status is whether the tx was added to the mempool or was already
+	// processing or accepted
+	Status IssueStatus `json:"status"`
+}
+
+// issueTx issues the locally submitted [tx] and sets [response] to its ID and
+// issue status. Issuing a tx that is already processing or accepted is not an
+// error.
+func (service *AvaxAPI) issueTx(tx *Tx, response *IssueTxReply) error {
+	result, err := service.vm.IssueTxWithResult(tx)
+	response.TxID = result.TxID
+	response.Status = result.Status
+	if err != nil {
+		return fmt.Errorf("tx %s was rejected: %w", result.TxID, err)
+	}
+	return nil
+}
+
 // ImportArgs are arguments for passing into Import requests
 type ImportArgs struct {
 	api.UserPass
@@ -218,16 +1066,31 @@ type ImportArgs struct {
 
 	// The address that will receive the imported funds
 	To string `json:"to"`
+
+	// Splits are AVAX amounts, in nAVAX, to send to other addresses before
+	// the rest of the imported AVAX is sent to [To]
+	Splits []ImportSplit `json:"splits"`
+
+	// FeeOutputs are the indices of the AVAX outputs that pay the fee, where
+	// the output to [To] comes after [Splits]. The fee is split evenly
+	// between them. If empty, the last AVAX output pays the fee.
+	FeeOutputs []int `json:"feeOutputs"`
+}
+
+// ImportSplit is an amount of imported AVAX, in nAVAX, sent to an address
+type ImportSplit struct {
+	To     string      `json:"to"`
+	Amount json.Uint64 `json:"amount"`
 }
 
 // ImportAVAX is a deprecated name for Import.
-func (service *AvaxAPI) ImportAVAX(_ *http.Request, args *ImportArgs, response *api.JSONTxID) error {
+func (service *AvaxAPI) ImportAVAX(_ *http.Request, args *ImportArgs, response *IssueTxReply) error {
 	return service.Import(nil, args, response)
 }
 
 // Import issues a transaction to import AVAX from the X-chain. The AVAX
 // must have already been exported from the X-Chain.
-func (service *AvaxAPI) Import(_ *http.Request, args *ImportArgs, response *api.JSONTxID) error {
+func (service *AvaxAPI) Import(_ *http.Request, args *ImportArgs, response *IssueTxReply) error {
 	log.Info("EVM: ImportAVAX called")
 
 	chainID, err := service.vm.ctx.BCLookup.Lookup(args.SourceChain)
@@ -247,10 +1110,90 @@ func (service *AvaxAPI) Import(_ *http.Request, args *ImportArgs, response *api.
 	}
 	defer db.Close()
 
-	user := user{
-		secpFactory: &service.vm.secpFactory,
-		db:          db,
+	user := service.vm.newUser(db, args.Password)
+	privKeys, err := user.getKeys()
+	if err != nil { // Get keys
+		return fmt.Errorf("couldn't get keys controlled by the user: %w", err)
+	}
+
+	var baseFee *big.Int
+	if args.BaseFee == nil {
+		// Get the base fee to use
+		baseFee, err = service.vm.estimateBaseFee(context.Background())
+		if err != nil {
+			return err
+		}
+	} else {
+		baseFee = args.BaseFee.ToInt()
 	}
+
+	splits := make([]EVMOutput, len(args.Splits))
+	for i, split := range args.Splits {
+		splitTo, err := ParseEthAddress(split.To)
+		if err != nil {
+			return fmt.Errorf("couldn't parse address of split %d: %w", i, err)
+		}
+		splits[i] = EVMOutput{
+			Address: splitTo,
+			Amount:  uint64(split.Amount),
+			AssetID: service.vm.ctx.AVAXAssetID,
+		}
+	}
+	var feeOutputs []int
+	if len(args.FeeOutputs) != 0 {
+		feeOutputs = args.FeeOutputs
+	}
+
+	tx, err := service.vm.newImportTx(chainID, to, splits, feeOutputs, baseFee, privKeys)
+	if err != nil {
+		return err
+	}
+
+	return service.issueTx(tx, response)
+}
+
+// ImportToContractArgs are arguments for passing into ImportToContract requests
+type ImportToContractArgs struct {
+	api.UserPass
+
+	// Fee that should be used when creating the tx
+	BaseFee *hexutil.Big `json:"baseFee"`
+
+	// Chain the funds are coming from
+	SourceChain string `json:"sourceChain"`
+
+	// The contract that will receive the imported funds
+	Contract string `json:"contract"`
+
+	// ABI-encoded calldata of the call to the contract, such as the call of
+	// its deposit function
+	Data hexutil.Bytes `json:"data"`
+}
+
+// ImportToContract issues a transaction to import AVAX from the X-chain and
+// deposit it into a contract, by calling the contract with the imported AVAX
+// as value. The AVAX must have already been exported from the X-Chain.
+func (service *AvaxAPI) ImportToContract(_ *http.Request, args *ImportToContractArgs, response *IssueTxReply) error {
+	log.Info("EVM: ImportToContract called", "contract", args.Contract)
+
+	chainID, err := service.vm.ctx.BCLookup.Lookup(args.SourceChain)
+	if err != nil {
+		return fmt.Errorf("problem parsing chainID %q: %w", args.SourceChain, err)
+	}
+
+	contract, err := ParseEthAddress(args.Contract)
+	if err != nil {
+		return fmt.Errorf("couldn't parse argument 'contract' to an address: %w", err)
+	}
+
+	// Get the user's info
+	db, err := service.vm.ctx.Keystore.GetDatabase(args.Username, args.Password)
+	if err != nil {
+		return fmt.Errorf("couldn't get user '%s': %w", args.Username, err)
+	}
+	defer db.Close()
+
+	user := service.vm.newUser(db, args.Password)
 	privKeys, err := user.getKeys()
 	if err != nil { // Get keys
 		return fmt.Errorf("couldn't get keys controlled by the user: %w", err)
@@ -267,13 +1210,89 @@ func (service *AvaxAPI) Import(_ *http.Request, args *ImportArgs, response *api.
 		baseFee = args.BaseFee.ToInt()
 	}
 
-	tx, err := service.vm.newImportTx(chainID, to, baseFee, privKeys)
+	tx, err := service.vm.newContractImportTx(chainID, contract, args.Data, baseFee, privKeys)
 	if err != nil {
 		return err
 	}
 
-	response.TxID = tx.ID()
-	return service.vm.issueTx(tx, true /*=local*/)
+	return service.issueTx(tx, response)
+}
+
+// GetAtomicTxFeeArgs are the arguments to GetAtomicTxFee
+type GetAtomicTxFeeArgs struct {
+	// Number of UTXOs the import consumes
+	NumInputs json.Uint32 `json:"numInputs"`
+
+	// Number of EVM outputs the import credits
+	NumOutputs json.Uint32 `json:"numOutputs"`
+
+	// Base fee to quote the fee at. Defaults to the estimated base fee.
+	BaseFee *hexutil.Big `json:"baseFee"`
+}
+
+// GetAtomicTxFeeReply is the response from GetAtomicTxFee
+type GetAtomicTxFeeReply struct {
+	GasUsed json.Uint64 `json:"gasUsed"`
+	Fee     json.Uint64 `json:"fee"`
+}
+
+// GetAtomicTxFee returns the gas used by, and the fee in nAVAX of, an import
+// with the given number of inputs and outputs, without requiring the UTXOs to
+// be known
+func (service *AvaxAPI) GetAtomicTxFee(_ *http.Request, args *GetAtomicTxFeeArgs, reply *GetAtomicTxFeeReply) error {
+	log.Info("EVM: GetAtomicTxFee called", "numInputs", args.NumInputs, "numOutputs", args.NumOutputs)
+
+	rules := service.vm.currentRules()
+	gasUsed, err := EstimateImportGas(int(args.NumInputs), int(args.NumOutputs), rules)
+	if err != nil {
+		return err
+	}
+	reply.GasUsed = json.Uint64(gasUsed)
+
+	switch {
+	case !rules.IsApricotPhase2:
+		// Atomic txs are free before Apricot Phase 2.
+		reply.Fee = 0
+		return nil
+	case !rules.IsApricotPhase3:
+		reply.Fee = json.Uint64(params.AvalancheAtomicTxFee)
+		return nil
+	}
+
+	var baseFee *big.Int
+	if args.BaseFee == nil {
+		baseFee, err = service.vm.estimateBaseFee(context.Background())
+		if err != nil {
+			return err
+		}
+	} else {
+		baseFee = args.BaseFee.ToInt()
+	}
+	fee, err := calculateDynamicFee(gasUsed, baseFee)
+	if err != nil {
+		return err
+	}
+	reply.Fee = json.Uint64(fee)
+	return nil
+}
+
+// GetX2CRateReply is the response from GetX2CRate
+type GetX2CRateReply struct {
+	// Number of wei in the smallest denomination of AVAX in atomic txs
+	X2CRate *hexutil.Big `json:"x2cRate"`
+
+	// Number of decimal places of AVAX in atomic txs
+	AtomicTxDecimals json.Uint8 `json:"atomicTxDecimals"`
+}
+
+// GetX2CRate returns the conversion rate between wei and the denomination of
+// AVAX in atomic txs, which is the rate used to convert every atomic amount
+func (service *AvaxAPI) GetX2CRate(_ *http.Request, _ *struct{}, reply *GetX2CRateReply) error {
+	log.Info("EVM: GetX2CRate called")
+
+	reply.X2CRate = (*hexutil.Big)(new(big.Int).Set(x2cRate))
+	reply.AtomicTxDecimals = json.Uint8(params.AtomicTxDecimals)
+	return nil
 }
 
 // ExportAVAXArgs are the arguments to ExportAVAX
@@ -293,7 +1312,7 @@ type ExportAVAXArgs struct {
 
 // ExportAVAX exports AVAX from the C-Chain to the X-Chain
 // It must be imported on the X-Chain to complete the transfer
-func (service *AvaxAPI) ExportAVAX(_ *http.Request, args *ExportAVAXArgs, response *api.JSONTxID) error {
+func (service *AvaxAPI) ExportAVAX(_ *http.Request, args *ExportAVAXArgs, response *IssueTxReply) error {
 	return service.Export(nil, &ExportArgs{
 		ExportAVAXArgs: *args,
 		AssetID:        service.vm.ctx.AVAXAssetID.String(),
@@ -309,7 +1328,7 @@ type ExportArgs struct {
 
 // Export exports an asset from the C-Chain to the X-Chain
 // It must be imported on the X-Chain to complete the transfer
-func (service *AvaxAPI) Export(_ *http.Request, args *ExportArgs, response *api.JSONTxID) error {
+func (service *AvaxAPI) Export(_ *http.Request, args *ExportArgs, response *IssueTxReply) error {
 	log.Info("EVM: Export called")
 
 	assetID, err := service.parseAssetID(args.AssetID)
@@ -333,10 +1352,7 @@ func (service *AvaxAPI) Export(_ *http.Request, args *ExportArgs, response *api.
 	}
 	defer db.Close()
 
-	user := user{
-		secpFactory: &service.vm.secpFactory,
-		db:          db,
-	}
+	user := service.vm.newUser(db, args.Password)
 	privKeys, err := user.getKeys()
 	if err != nil {
 		return fmt.Errorf("couldn't get addresses controlled by the user: %w", err)
@@ -366,8 +1382,7 @@ func (service *AvaxAPI) Export(_ *http.Request, args *ExportArgs, response *api.
 		return fmt.Errorf("couldn't create tx: %w", err)
 	}
 
-	response.TxID = tx.ID()
-	return service.vm.issueTx(tx, true /*=local*/)
+	return service.issueTx(tx, response)
 }
 
 // GetUTXOs gets all utxos for passed in addresses
@@ -450,7 +1465,7 @@ func (service *AvaxAPI) GetUTXOs(r *http.Request, args *api.GetUTXOsArgs, reply
 }
 
 // IssueTx ...
-func (service *AvaxAPI) IssueTx(r *http.Request, args *api.FormattedTx, response *api.JSONTxID) error {
+func (service *AvaxAPI) IssueTx(r *http.Request, args *api.FormattedTx, response *IssueTxReply) error {
 	log.Info("EVM: IssueTx called")
 
 	txBytes, err := formatting.Decode(args.Encoding, args.Tx)
@@ -458,16 +1473,86 @@ func (service *AvaxAPI) IssueTx(r *http.Request, args *api.FormattedTx, response
 		return fmt.Errorf("problem decoding transaction: %w", err)
 	}
 
-	tx := &Tx{}
-	if _, err := service.vm.codec.Unmarshal(txBytes, tx); err != nil {
+	tx, err := ParseTx(txBytes)
+	if err != nil {
 		return fmt.Errorf("problem parsing transaction: %w", err)
 	}
-	if err := tx.Sign(service.vm.codec, nil); err != nil {
-		return fmt.Errorf("problem initializing transaction: %w", err)
+
+	return service.issueTx(tx, response)
+}
+
+// CheckAtomicTxReply is the response from CheckAtomicTx
+type CheckAtomicTxReply struct {
+	Acceptable bool   `json:"acceptable"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// CheckAtomicTx reports whether the atomic tx [args.Tx] would currently be
+// accepted into the mempool and, if not, why. The tx is verified on top of
+// the preferred block as IssueTx verifies it, but it is not issued.
+func (service *AvaxAPI) CheckAtomicTx(r *http.Request, args *api.FormattedTx, reply *CheckAtomicTxReply) error {
+	log.Info("EVM: CheckAtomicTx called")
+
+	*reply = CheckAtomicTxReply{}
+	txBytes, err := formatting.Decode(args.Encoding, args.Tx)
+	if err != nil {
+		reply.Reason = fmt.Sprintf("problem decoding transaction: %s", err)
+		return nil
+	}
+	tx, err := ParseTx(txBytes)
+	if err != nil {
+		reply.Reason = fmt.Sprintf("problem parsing transaction: %s", err)
+		return nil
+	}
+	if err := service.vm.verifyTxAtTip(tx); err != nil {
+		reply.Reason = err.Error()
+		return nil
+	}
+	if service.vm.mempool.Conflicts(tx) {
+		reply.Reason = errConflictingAtomicTx.Error()
+		return nil
+	}
+	reply.Acceptable = true
+	return nil
+}
+
+// IssueAtomicTxsArgs are the arguments for IssueAtomicTxs
+type IssueAtomicTxsArgs struct {
+	Txs      []string            `json:"txs"`
+	Encoding formatting.Encoding `json:"encoding"`
+}
+
+// IssueAtomicTxsReply defines the IssueAtomicTxs replies returned from the API
+type IssueAtomicTxsReply struct {
+	TxIDs []ids.ID `json:"txIDs"`
+}
+
+// IssueAtomicTxs issues an ordered batch of atomic transactions. The batch is
+// rejected as a whole if its transactions conflict with each other or if any
+// of them fails verification.
+func (service *AvaxAPI) IssueAtomicTxs(r *http.Request, args *IssueAtomicTxsArgs, response *IssueAtomicTxsReply) error {
+	log.Info("EVM: IssueAtomicTxs called", "numTxs", len(args.Txs))
+
+	txs := make([]*Tx, len(args.Txs))
+	txIDs := make([]ids.ID, len(args.Txs))
+	for i, txStr := range args.Txs {
+		txBytes, err := formatting.Decode(args.Encoding, txStr)
+		if err != nil {
+			return fmt.Errorf("problem decoding transaction %d: %w", i, err)
+		}
+		tx, err := ParseTx(txBytes)
+		if err != nil {
+			return fmt.Errorf("problem parsing transaction %d: %w", i, err)
+		}
+		txs[i] = tx
+		txIDs[i] = tx.ID()
 	}
 
-	response.TxID = tx.ID()
-	return service.vm.issueTx(tx, true /*=local*/)
+	if err := service.vm.issueTxs(txs); err != nil {
+		return err
+	}
+	response.TxIDs = txIDs
+	return nil
 }
 
 // GetAtomicTxStatusReply defines the GetAtomicTxStatus replies returned from the API
@@ -494,6 +1579,57 @@ func (service *AvaxAPI) GetAtomicTxStatus(r *http.Request, args *api.JSONTxID, r
 	return nil
 }
 
+// CancelAtomicTxArgs are the arguments to CancelAtomicTx
+type CancelAtomicTxArgs struct {
+	api.UserPass
+	TxID ids.ID `json:"txID"`
+}
+
+// CancelAtomicTx removes the atomic tx [args.TxID] from the mempool of this
+// node and marks it as dropped. The tx must not have been issued into a block
+// yet, and must have been signed by one of the user's keys. The cancellation
+// is not gossiped, so a peer that already received the tx may still include
+// it in a block; only spending its inputs in another tx makes it invalid.
+func (service *AvaxAPI) CancelAtomicTx(r *http.Request, args *CancelAtomicTxArgs, reply *api.SuccessResponse) error {
+	log.Info("EVM: CancelAtomicTx called", "txID", args.TxID)
+
+	if args.TxID == ids.Empty {
+		return errNilTxID
+	}
+
+	db, err := service.vm.ctx.Keystore.GetDatabase(args.Username, args.Password)
+	if err != nil {
+		return fmt.Errorf("problem retrieving user '%s': %w", args.Username, err)
+	}
+	defer db.Close()
+
+	tx, status, _, err := service.vm.getAtomicTx(args.TxID)
+	if err != nil {
+		return fmt.Errorf("problem retrieving tx %s: %w", args.TxID, err)
+	}
+	if status == Accepted {
+		return fmt.Errorf("%w: %s", errTxAlreadyAccepted, args.TxID)
+	}
+	if _, pending := service.vm.mempool.GetPendingTx(args.TxID); status != Processing || !pending {
+		return fmt.Errorf("%w: %s", errTxNotPending, args.TxID)
+	}
+
+	user := service.vm.newUser(db, args.Password)
+	signed, err := user.signedTx(tx)
+	if err != nil {
+		return err
+	}
+	if !signed {
+		return fmt.Errorf("%w: %s", errTxNotSignedByUser, args.TxID)
+	}
+
+	if !service.vm.mempool.RemovePendingTx(args.TxID) {
+		return fmt.Errorf("%w: %s", errTxNotPending, args.TxID)
+	}
+	reply.Success = true
+	return nil
+}
+
 type FormattedTx struct {
 	api.FormattedTx
 	BlockHeight *json.Uint64 `json:"blockHeight,omitempty"`
@@ -528,3 +1664,64 @@ func (service *AvaxAPI) GetAtomicTx(r *http.Request, args *api.GetTxArgs, reply
 	}
 	return nil
 }
+
+// AtomicTxIndex identifies the position of an accepted atomic tx in the
+// address index
+type AtomicTxIndex struct {
+	BlockHeight json.Uint64 `json:"blockHeight"`
+	TxID        ids.ID      `json:"txID"`
+}
+
+// GetAtomicTxsByAddressArgs are the arguments for GetAtomicTxsByAddress
+type GetAtomicTxsByAddressArgs struct {
+	// Address is the hex EVM address to look up
+	Address string `json:"address"`
+	// Limit is the maximum number of txs to return. If 0 or greater than
+	// maxUTXOsToFetch, maxUTXOsToFetch txs are returned at most.
+	Limit json.Uint32 `json:"limit"`
+	// StartIndex is the EndIndex of the previous page. If unset, the txs are
+	// returned from the start of the index.
+	StartIndex AtomicTxIndex `json:"startIndex"`
+}
+
+// GetAtomicTxsByAddressReply defines the GetAtomicTxsByAddress replies
+// returned from the API
+type GetAtomicTxsByAddressReply struct {
+	Txs []AtomicTxIndex `json:"txs"`
+	// EndIndex is the index of the last tx returned
+	EndIndex AtomicTxIndex `json:"endIndex"`
+}
+
+// GetAtomicTxsByAddress returns the accepted atomic txs that credited or
+// debited the specified address, ordered by block height
+func (service *AvaxAPI) GetAtomicTxsByAddress(r *http.Request, args *GetAtomicTxsByAddressArgs, reply *GetAtomicTxsByAddressReply) error {
+	log.Info("EVM: GetAtomicTxsByAddress called", "address", args.Address)
+
+	addr, err := ParseEthAddress(args.Address)
+	if err != nil {
+		return fmt.Errorf("couldn't parse address %q: %w", args.Address, err)
+	}
+
+	limit := int(args.Limit)
+	if limit <= 0 || limit > maxUTXOsToFetch {
+		limit = maxUTXOsToFetch
+	}
+
+	txIDs, heights, err := service.vm.getAtomicTxsByAddress(addr, uint64(args.StartIndex.BlockHeight), args.StartIndex.TxID, limit)
+	if err != nil {
+		return fmt.Errorf("problem retrieving atomic txs: %w", err)
+	}
+
+	reply.Txs = make([]AtomicTxIndex, len(txIDs))
+	for i, txID := range txIDs {
+		reply.Txs[i] = AtomicTxIndex{
+			BlockHeight: json.Uint64(heights[i]),
+			TxID:        txID,
+		}
+	}
+	reply.EndIndex = args.StartIndex
+	if len(reply.Txs) > 0 {
+		reply.EndIndex = reply.Txs[len(reply.Txs)-1]
+	}
+	return nil
+}
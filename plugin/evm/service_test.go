@@ -0,0 +1,1415 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package evm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/flare-foundation/coreth/core"
+	"github.com/flare-foundation/coreth/core/types"
+	"github.com/flare-foundation/coreth/params"
+	"github.com/flare-foundation/flare/api"
+	"github.com/flare-foundation/flare/ids"
+	"github.com/flare-foundation/flare/utils/constants"
+	"github.com/flare-foundation/flare/utils/crypto"
+	"github.com/flare-foundation/flare/utils/formatting"
+	"github.com/flare-foundation/flare/utils/units"
+	"github.com/flare-foundation/flare/vms/components/avax"
+	"github.com/flare-foundation/flare/vms/components/chain"
+	"github.com/flare-foundation/flare/vms/secp256k1fx"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestImportTx returns a signed import tx spending [amount] of AVAX from
+// [utxoID] to the first test address
+func newTestImportTx(t *testing.T, vm *VM, utxoID avax.UTXOID, amount uint64) *Tx {
+	tx := &Tx{UnsignedAtomicTx: &UnsignedImportTx{
+		NetworkID:    vm.ctx.NetworkID,
+		BlockchainID: vm.ctx.ChainID,
+		SourceChain:  vm.ctx.XChainID,
+		ImportedInputs: []*avax.TransferableInput{{
+			UTXOID: utxoID,
+			Asset:  avax.Asset{ID: vm.ctx.AVAXAssetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   amount,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}},
+		Outs: []EVMOutput{{
+			Address: testEthAddrs[0],
+			Amount:  amount,
+			AssetID: vm.ctx.AVAXAssetID,
+		}},
+	}}
+	if err := tx.Sign(vm.codec, [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}}); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestDebugAPIGetGenesisTimestamp(t *testing.T) {
+	genesisJSON := strings.Replace(genesisJSONApricotPhase0, "\"timestamp\":\"0x0\"", "\"timestamp\":\"0x5f5e100\"", 1)
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSON, "", "")
+
+	api := &DebugAPI{vm}
+	timestamp, err := api.GetGenesisTimestamp(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0x5f5e100), timestamp)
+	assert.Equal(t, vm.chain.GetGenesisBlock().Time(), timestamp)
+	assert.NoError(t, vm.Shutdown())
+}
+
+func TestDebugAPIGetAtomicGasStats(t *testing.T) {
+	issuer, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase0, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: 50000000,
+	})
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+
+	stats, err := api.GetAtomicGasStats(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, AtomicGasStats{}, stats)
+
+	tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.issueTx(tx, true /*=local*/); err != nil {
+		t.Fatal(err)
+	}
+
+	<-issuer
+
+	blk, err := vm.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Building and verifying the block does not record its atomic tx.
+	stats, err = api.GetAtomicGasStats(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, AtomicGasStats{}, stats)
+
+	if err := vm.SetPreference(blk.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Accept(); err != nil {
+		t.Fatal(err)
+	}
+
+	ethBlock := blk.(*chain.BlockWrapper).Block.(*Block).ethBlock
+	gasUsed, err := tx.GasUsed(vm.chainConfig.AvalancheRules(ethBlock.Number(), new(big.Int).SetUint64(ethBlock.Time())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err = api.GetAtomicGasStats(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, AtomicGasStats{TotalGasUsed: gasUsed, TxCount: 1}, stats)
+}
+
+func TestAvaxAPIIssueAtomicTxs(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, true, genesisJSONApricotPhase0, "", "")
+	api := &AvaxAPI{vm}
+
+	encodeTxs := func(txs ...*Tx) []string {
+		encoded := make([]string, len(txs))
+		for i, tx := range txs {
+			txStr, err := formatting.EncodeWithChecksum(formatting.Hex, tx.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded[i] = txStr
+		}
+		return encoded
+	}
+
+	utxoID := avax.UTXOID{TxID: ids.GenerateTestID()}
+	conflictingTxs := []*Tx{
+		newTestImportTx(t, vm, utxoID, 1),
+		newTestImportTx(t, vm, utxoID, 2),
+	}
+	reply := &IssueAtomicTxsReply{}
+	err := api.IssueAtomicTxs(nil, &IssueAtomicTxsArgs{
+		Txs:      encodeTxs(conflictingTxs...),
+		Encoding: formatting.Hex,
+	}, reply)
+	if !errors.Is(err, errConflictingAtomicTxBatch) {
+		t.Fatalf("Expected self-conflicting batch to fail with %s, found %v", errConflictingAtomicTxBatch, err)
+	}
+	assert.Empty(t, reply.TxIDs)
+	assert.Zero(t, vm.mempool.Len())
+
+	// A batch that does not conflict with itself is still rejected as a whole
+	// if any of its txs fails verification. The first tx spends a UTXO that
+	// does not exist in shared memory.
+	batch := []*Tx{
+		newTestImportTx(t, vm, avax.UTXOID{TxID: ids.GenerateTestID()}, 1),
+		newTestImportTx(t, vm, avax.UTXOID{TxID: ids.GenerateTestID()}, 2),
+	}
+	err = api.IssueAtomicTxs(nil, &IssueAtomicTxsArgs{
+		Txs:      encodeTxs(batch...),
+		Encoding: formatting.Hex,
+	}, reply)
+	assert.Error(t, err)
+	assert.Empty(t, reply.TxIDs)
+	assert.Zero(t, vm.mempool.Len())
+
+	assert.NoError(t, vm.Shutdown())
+}
+
+func TestAvaxAPIIssueAtomicTxsConflictsWithMempool(t *testing.T) {
+	_, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase0, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: 50000000,
+		testShortIDAddrs[1]: 50000000,
+	})
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &AvaxAPI{vm}
+
+	batch := make([]*Tx, 2)
+	encoded := make([]string, 2)
+	for i := range batch {
+		tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[i], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[i]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch[i] = tx
+		if encoded[i], err = formatting.EncodeWithChecksum(formatting.Hex, tx.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The second tx of the batch conflicts with a tx already in the mempool.
+	conflictingTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[2], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, vm.mempool.AddTx(conflictingTx))
+	vm.mempool.GetNewTxs()
+
+	// The batch is verified as a whole, so none of it is added or gossiped.
+	reply := &IssueAtomicTxsReply{}
+	err = api.IssueAtomicTxs(nil, &IssueAtomicTxsArgs{
+		Txs:      encoded,
+		Encoding: formatting.Hex,
+	}, reply)
+	if !errors.Is(err, errConflictingAtomicTx) {
+		t.Fatalf("Expected batch conflicting with the mempool to fail with %s, found %v", errConflictingAtomicTx, err)
+	}
+	assert.Empty(t, reply.TxIDs)
+	assert.Equal(t, 1, vm.mempool.Len())
+	assert.False(t, vm.mempool.has(batch[0].ID()))
+	assert.Empty(t, vm.mempool.GetNewTxs())
+
+	vm.mempool.RemoveTx(conflictingTx.ID())
+	assert.NoError(t, api.IssueAtomicTxs(nil, &IssueAtomicTxsArgs{
+		Txs:      encoded,
+		Encoding: formatting.Hex,
+	}, reply))
+	assert.Equal(t, []ids.ID{batch[0].ID(), batch[1].ID()}, reply.TxIDs)
+	assert.Len(t, vm.mempool.GetNewTxs(), 2)
+}
+
+func TestDebugAPIDecodeAtomicTx(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase0, "", "")
+	api := &DebugAPI{vm}
+
+	utxoID := avax.UTXOID{TxID: ids.GenerateTestID()}
+	tx := &Tx{UnsignedAtomicTx: &UnsignedImportTx{
+		NetworkID:    vm.ctx.NetworkID,
+		BlockchainID: vm.ctx.ChainID,
+		SourceChain:  vm.ctx.XChainID,
+		ImportedInputs: []*avax.TransferableInput{{
+			UTXOID: utxoID,
+			Asset:  avax.Asset{ID: vm.ctx.AVAXAssetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   10,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}},
+		Outs: []EVMOutput{{
+			Address: testEthAddrs[0],
+			Amount:  7,
+			AssetID: vm.ctx.AVAXAssetID,
+		}},
+	}}
+	if err := tx.Sign(vm.codec, [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}}); err != nil {
+		t.Fatal(err)
+	}
+	txHex, err := formatting.EncodeWithChecksum(formatting.Hex, tx.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gasUsed, err := tx.GasUsed(vm.currentRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explanation, err := api.DecodeAtomicTx(context.Background(), txHex)
+	assert.NoError(t, err)
+	assert.Equal(t, tx.ID(), explanation.TxID)
+	assert.Equal(t, "import", explanation.Type)
+	assert.Equal(t, vm.ctx.NetworkID, explanation.NetworkID)
+	assert.Equal(t, vm.ctx.ChainID, explanation.BlockchainID)
+	assert.Equal(t, &vm.ctx.XChainID, explanation.SourceChain)
+	assert.Nil(t, explanation.DestinationChain)
+	assert.Equal(t, []AtomicTxIOExplanation{{
+		UTXOID:  utxoID.String(),
+		AssetID: vm.ctx.AVAXAssetID,
+		Amount:  10,
+	}}, explanation.Inputs)
+	assert.Equal(t, []AtomicTxIOExplanation{{
+		Addresses: []string{testEthAddrs[0].Hex()},
+		AssetID:   vm.ctx.AVAXAssetID,
+		Amount:    7,
+	}}, explanation.Outputs)
+	assert.Equal(t, gasUsed, explanation.GasUsed)
+	assert.Equal(t, map[ids.ID]uint64{vm.ctx.AVAXAssetID: 3}, explanation.Burned)
+
+	_, err = api.DecodeAtomicTx(context.Background(), "0x00")
+	assert.Error(t, err)
+	assert.NoError(t, vm.Shutdown())
+}
+
+func TestNetAPIVersion(t *testing.T) {
+	tests := map[string]struct {
+		configJSON string
+		expected   func(vm *VM) string
+	}{
+		"default returns chain ID": {
+			configJSON: "",
+			expected:   func(vm *VM) string { return vm.chainID.String() },
+		},
+		"network ID mode": {
+			configJSON: "{\"net-version-network-id\": true}",
+			expected:   func(vm *VM) string { return fmt.Sprintf("%d", vm.networkID) },
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase0, test.configJSON, "")
+			api := &NetAPI{vm}
+			assert.Equal(t, test.expected(vm), api.Version())
+			assert.NoError(t, vm.Shutdown())
+		})
+	}
+}
+
+func TestAvaxAPIGetNetworkID(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase0, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &AvaxAPI{vm}
+
+	reply := &GetNetworkIDReply{}
+	assert.NoError(t, api.GetNetworkID(nil, nil, reply))
+	assert.Equal(t, vm.networkID, uint64(reply.NetworkID))
+	assert.NotEqual(t, vm.chainID.Uint64(), uint64(reply.NetworkID))
+}
+
+func TestDebugAPIGetChainID(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase0, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+
+	chainID, err := api.GetChainID(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(43111), chainID.ToInt())
+	assert.Equal(t, vm.chainID, chainID.ToInt())
+
+	vm.chainConfig = &params.ChainConfig{}
+	_, err = api.GetChainID(context.Background())
+	assert.ErrorIs(t, err, errMissingChainID)
+}
+
+func TestDebugAPIGetChainConfigHash(t *testing.T) {
+	getHash := func(genesisJSON string) common.Hash {
+		_, vm, _, _, _ := GenesisVM(t, false, genesisJSON, "", "")
+		defer func() {
+			assert.NoError(t, vm.Shutdown())
+		}()
+
+		hash, err := (&DebugAPI{vm}).GetChainConfigHash(context.Background())
+		assert.NoError(t, err)
+		return hash
+	}
+
+	apricotPhase0Hash := getHash(genesisJSONApricotPhase0)
+	assert.Equal(t, apricotPhase0Hash, getHash(genesisJSONApricotPhase0))
+	assert.NotEqual(t, apricotPhase0Hash, getHash(genesisJSONApricotPhase4))
+}
+
+func TestAvaxAPIGetAtomicTxFee(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase3, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &AvaxAPI{vm}
+
+	expectedGas, err := EstimateImportGas(2, 1, vm.currentRules())
+	assert.NoError(t, err)
+	expectedFee, err := calculateDynamicFee(expectedGas, initialBaseFee)
+	assert.NoError(t, err)
+
+	reply := &GetAtomicTxFeeReply{}
+	assert.NoError(t, api.GetAtomicTxFee(nil, &GetAtomicTxFeeArgs{
+		NumInputs:  2,
+		NumOutputs: 1,
+		BaseFee:    (*hexutil.Big)(initialBaseFee),
+	}, reply))
+	assert.Equal(t, expectedGas, uint64(reply.GasUsed))
+	assert.Equal(t, expectedFee, uint64(reply.Fee))
+
+	assert.ErrorIs(t, api.GetAtomicTxFee(nil, &GetAtomicTxFeeArgs{NumOutputs: 1}, reply), errNoImportInputs)
+}
+
+func TestAvaxAPIGetAtomicTxFeeBeforeApricotPhase3(t *testing.T) {
+	tests := map[string]struct {
+		genesisJSON string
+		expectedFee uint64
+	}{
+		"apricot phase 1": {
+			genesisJSON: genesisJSONApricotPhase1,
+			expectedFee: 0,
+		},
+		"apricot phase 2": {
+			genesisJSON: genesisJSONApricotPhase2,
+			expectedFee: params.AvalancheAtomicTxFee,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, vm, _, _, _ := GenesisVM(t, false, test.genesisJSON, "", "")
+			defer func() {
+				assert.NoError(t, vm.Shutdown())
+			}()
+			api := &AvaxAPI{vm}
+
+			reply := &GetAtomicTxFeeReply{}
+			assert.NoError(t, api.GetAtomicTxFee(nil, &GetAtomicTxFeeArgs{NumInputs: 1, NumOutputs: 1}, reply))
+			assert.Equal(t, test.expectedFee, uint64(reply.Fee))
+		})
+	}
+}
+
+func TestDebugAPIGetMultiCoinBalance(t *testing.T) {
+	assetID := ids.GenerateTestID()
+	genesis := &core.Genesis{}
+	if err := json.Unmarshal([]byte(genesisJSONApricotPhase0), genesis); err != nil {
+		t.Fatal(err)
+	}
+	genesis.Alloc = core.GenesisAlloc{
+		testEthAddrs[0]: core.GenesisAccount{
+			Balance:   big.NewInt(0),
+			MCBalance: core.GenesisMultiCoinBalance{common.Hash(assetID): big.NewInt(1000)},
+		},
+	}
+	genesisJSON, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, vm, _, _, _ := GenesisVM(t, false, string(genesisJSON), "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+
+	balance, err := api.GetMultiCoinBalance(context.Background(), testEthAddrs[0], assetID.String(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(1000), balance.ToInt())
+
+	balance, err = api.GetMultiCoinBalance(context.Background(), testEthAddrs[1], assetID.String(), 0)
+	assert.NoError(t, err)
+	assert.Zero(t, balance.ToInt().Sign())
+
+	_, err = api.GetMultiCoinBalance(context.Background(), testEthAddrs[0], "not an asset ID", 0)
+	assert.Error(t, err)
+	_, err = api.GetMultiCoinBalance(context.Background(), testEthAddrs[0], vm.ctx.AVAXAssetID.String(), 0)
+	assert.Error(t, err)
+	_, err = api.GetMultiCoinBalance(context.Background(), testEthAddrs[0], assetID.String(), 1)
+	assert.Error(t, err)
+}
+
+func TestAvaxAPIGetX2CRate(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase0, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &AvaxAPI{vm}
+
+	reply := &GetX2CRateReply{}
+	assert.NoError(t, api.GetX2CRate(nil, nil, reply))
+	assert.Equal(t, big.NewInt(x2cRateInt64), reply.X2CRate.ToInt())
+	assert.Equal(t, uint8(9), uint8(reply.AtomicTxDecimals))
+
+	// The reported rate must be the one used to convert atomic amounts.
+	assert.Equal(t, XtoC(1), reply.X2CRate.ToInt())
+}
+
+func TestDebugAPIGetFeesBurned(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase3, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+
+	burned, err := api.GetFeesBurned(context.Background(), 0, 0)
+	assert.NoError(t, err)
+	assert.Zero(t, burned.ToInt().Sign())
+
+	_, err = api.GetFeesBurned(context.Background(), 1, 0)
+	assert.ErrorIs(t, err, errInvalidHeightRange)
+	_, err = api.GetFeesBurned(context.Background(), 0, 1)
+	assert.ErrorIs(t, err, errInvalidHeightRange)
+	_, err = api.GetFeesBurned(context.Background(), 0, maxFeesBurnedRange)
+	assert.ErrorIs(t, err, errHeightRangeTooLarge)
+
+	// The import consumes 10 nAVAX and credits 7, burning 3.
+	tx := newTestImportTx(t, vm, avax.UTXOID{TxID: ids.GenerateTestID()}, 10)
+	tx.UnsignedAtomicTx.(*UnsignedImportTx).Outs[0].Amount = 7
+	assert.NoError(t, tx.Sign(vm.codec, [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}}))
+
+	header := &types.Header{
+		Number:  big.NewInt(1),
+		GasUsed: 21_000,
+		BaseFee: big.NewInt(100),
+	}
+	blockBurned, err := vm.feesBurned(types.NewBlock(header, nil, nil, nil, nil, tx.Bytes(), false))
+	assert.NoError(t, err)
+	expected := new(big.Int).Add(big.NewInt(21_000*100), new(big.Int).Mul(big.NewInt(3), x2cRate))
+	assert.Equal(t, expected, blockBurned)
+
+	// The AVAX burned by the import is not burned if it is credited to the
+	// atomic fee recipient.
+	recipient := testEthAddrs[1]
+	vm.chainConfig.AtomicFeeRecipient = &recipient
+	blockBurned, err = vm.feesBurned(types.NewBlock(header, nil, nil, nil, nil, tx.Bytes(), false))
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(21_000*100), blockBurned)
+	vm.chainConfig.AtomicFeeRecipient = nil
+
+	// Blocks before Apricot Phase 3 have no base fee.
+	header.BaseFee = nil
+	blockBurned, err = vm.feesBurned(types.NewBlock(header, nil, nil, nil, nil, nil, false))
+	assert.NoError(t, err)
+	assert.Zero(t, blockBurned.Sign())
+}
+
+func TestDebugAPIGetEnabledPrecompiles(t *testing.T) {
+	getPrecompiles := func(genesisJSON string) []common.Address {
+		_, vm, _, _, _ := GenesisVM(t, false, genesisJSON, "", "")
+		defer func() {
+			assert.NoError(t, vm.Shutdown())
+		}()
+		api := &DebugAPI{vm}
+
+		_, err := api.GetEnabledPrecompiles(context.Background(), 1)
+		assert.Error(t, err)
+
+		precompiles, err := api.GetEnabledPrecompiles(context.Background(), 0)
+		assert.NoError(t, err)
+		return precompiles
+	}
+
+	nativeAssetCall := common.HexToAddress("0x0100000000000000000000000000000000000002")
+	beforePhase2 := getPrecompiles(genesisJSONApricotPhase1)
+	assert.Len(t, beforePhase2, 9)
+	assert.NotContains(t, beforePhase2, nativeAssetCall)
+	assert.Equal(t, common.BytesToAddress([]byte{1}), beforePhase2[0])
+
+	afterPhase2 := getPrecompiles(genesisJSONApricotPhase2)
+	assert.Len(t, afterPhase2, 12)
+	assert.Contains(t, afterPhase2, nativeAssetCall)
+}
+
+func TestDebugAPIGetConsensusState(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase4, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+
+	front, err := (&SnowmanAPI{vm}).GetAcceptedFront(context.Background())
+	assert.NoError(t, err)
+
+	state, err := (&DebugAPI{vm}).GetConsensusState(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, front.Number.Uint64(), state.Height)
+	assert.Equal(t, vm.chain.LastAcceptedBlock().Time(), state.Timestamp)
+	assert.True(t, state.Rules.IsApricotPhase4)
+	assert.Equal(t, params.StaticFeeConfig(), state.FeeConfig)
+}
+
+func TestDebugAPITraceAtomicTxVerify(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, true, genesisJSONApricotPhase0, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+
+	_, err := api.TraceAtomicTxVerify(context.Background(), "0x00")
+	assert.Error(t, err)
+
+	// The imported UTXO was never exported to shared memory, so the tx is
+	// well-formed but fails semantic verification.
+	tx := newTestImportTx(t, vm, avax.UTXOID{TxID: ids.GenerateTestID()}, 10)
+	txHex, err := formatting.EncodeWithChecksum(formatting.Hex, tx.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trace, err := api.TraceAtomicTxVerify(context.Background(), txHex)
+	assert.NoError(t, err)
+	assert.Equal(t, tx.ID(), trace.TxID)
+	assert.Equal(t, "semanticVerify", trace.FailedStep)
+	if assert.Len(t, trace.Steps, 2) {
+		assert.Equal(t, VerifyTraceStep{Name: "verify", Passed: true}, trace.Steps[0])
+		assert.Equal(t, "semanticVerify", trace.Steps[1].Name)
+		assert.False(t, trace.Steps[1].Passed)
+		assert.Contains(t, trace.Steps[1].Error, errWrongUTXOSource.Error())
+	}
+
+	// A malformed tx fails the first step and is not semantically verified.
+	tx = newTestImportTx(t, vm, avax.UTXOID{TxID: ids.GenerateTestID()}, 10)
+	tx.UnsignedAtomicTx.(*UnsignedImportTx).NetworkID++
+	if err := tx.Sign(vm.codec, [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}}); err != nil {
+		t.Fatal(err)
+	}
+	txHex, err = formatting.EncodeWithChecksum(formatting.Hex, tx.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace, err = api.TraceAtomicTxVerify(context.Background(), txHex)
+	assert.NoError(t, err)
+	assert.Equal(t, "verify", trace.FailedStep)
+	assert.Equal(t, []VerifyTraceStep{{Name: "verify", Error: errWrongNetworkID.Error()}}, trace.Steps)
+}
+
+func TestDebugAPIGetPendingBalance(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase0, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+
+	state, err := vm.chain.BlockState(vm.chain.LastAcceptedBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	acceptedBalance := state.GetBalance(testEthAddrs[0])
+
+	balance, err := api.GetPendingBalance(context.Background(), testEthAddrs[0])
+	assert.NoError(t, err)
+	assert.Equal(t, acceptedBalance, balance.ToInt())
+
+	importAmount := uint64(10_000_000)
+	tx := newTestImportTx(t, vm, avax.UTXOID{TxID: ids.GenerateTestID()}, importAmount)
+	if err := vm.mempool.AddTx(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err = api.GetPendingBalance(context.Background(), testEthAddrs[0])
+	assert.NoError(t, err)
+	expected := new(big.Int).Add(acceptedBalance, XtoC(importAmount))
+	assert.Equal(t, expected, balance.ToInt())
+
+	// The pending import doesn't credit other addresses.
+	balance, err = api.GetPendingBalance(context.Background(), testEthAddrs[1])
+	assert.NoError(t, err)
+	assert.Equal(t, state.GetBalance(testEthAddrs[1]), balance.ToInt())
+}
+
+func TestDebugAPIGetPendingAtomicTxs(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, true, genesisJSONApricotPhase3, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+
+	pending, err := api.GetPendingAtomicTxs(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	lowFeeTx := createImportTx(t, vm, ids.ID{1}, params.AvalancheAtomicTxFee)
+	highFeeTx := createImportTx(t, vm, ids.ID{2}, 2*params.AvalancheAtomicTxFee)
+	assert.NoError(t, vm.mempool.AddTx(lowFeeTx))
+	assert.NoError(t, vm.mempool.AddTx(highFeeTx))
+
+	pending, err = api.GetPendingAtomicTxs(context.Background())
+	assert.NoError(t, err)
+	if !assert.Len(t, pending, 2) {
+		return
+	}
+	for i, tx := range []*Tx{highFeeTx, lowFeeTx} {
+		gasUsed, err := tx.GasUsed(vm.currentRules())
+		assert.NoError(t, err)
+		fee, feePerGas, err := tx.EffectiveFee(vm.ctx.AVAXAssetID, vm.currentRules())
+		assert.NoError(t, err)
+		assert.Equal(t, PendingAtomicTx{
+			TxID:      tx.ID(),
+			Type:      "import",
+			Size:      len(tx.Bytes()),
+			GasUsed:   gasUsed,
+			Fee:       fee,
+			FeePerGas: feePerGas,
+		}, pending[i])
+	}
+	assert.Greater(t, pending[0].FeePerGas, pending[1].FeePerGas)
+}
+
+func TestDebugAPIGetVMConfig(t *testing.T) {
+	configJSON := `{"rpc-gas-cap": 1234, "keystore-directory": "/secret/keystore"}`
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase2, configJSON, "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+
+	reply, err := (&DebugAPI{vm}).GetVMConfig(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1234), reply.Config.RPCGasCap)
+	assert.Equal(t, params.DefaultMaxAtomicInputs, reply.MaxAtomicInputs)
+	assert.Equal(t, defaultMempoolSize, reply.MempoolSize)
+
+	assert.Equal(t, redactedValue, reply.Config.KeystoreDirectory)
+	assert.Equal(t, "/secret/keystore", vm.config.KeystoreDirectory)
+	assert.Empty(t, reply.Config.KeystoreExternalSigner)
+}
+
+func TestAvaxAPIGetAtomicTxsByAddress(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, true, genesisJSONApricotPhase0, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	api := &AvaxAPI{vm}
+
+	getTxs := func(addr common.Address, start AtomicTxIndex) *GetAtomicTxsByAddressReply {
+		reply := &GetAtomicTxsByAddressReply{}
+		if err := api.GetAtomicTxsByAddress(nil, &GetAtomicTxsByAddressArgs{
+			Address:    addr.Hex(),
+			StartIndex: start,
+		}, reply); err != nil {
+			t.Fatal(err)
+		}
+		return reply
+	}
+
+	assert.Empty(t, getTxs(testEthAddrs[0], AtomicTxIndex{}).Txs)
+
+	// Index two imports crediting the first test address, as Accept does.
+	blk := vm.LastAcceptedBlockInternal().(*Block)
+	txs := []*Tx{
+		newTestImportTx(t, vm, avax.UTXOID{TxID: ids.GenerateTestID()}, 1),
+		newTestImportTx(t, vm, avax.UTXOID{TxID: ids.GenerateTestID()}, 2),
+	}
+	for _, tx := range txs {
+		if err := vm.writeAtomicTx(blk, tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reply := getTxs(testEthAddrs[0], AtomicTxIndex{})
+	if len(reply.Txs) != 2 {
+		t.Fatalf("Expected 2 txs for the credited address, found %d", len(reply.Txs))
+	}
+	assert.ElementsMatch(t, []ids.ID{txs[0].ID(), txs[1].ID()}, []ids.ID{reply.Txs[0].TxID, reply.Txs[1].TxID})
+	assert.Equal(t, blk.Height(), uint64(reply.Txs[0].BlockHeight))
+	assert.Equal(t, reply.Txs[1], reply.EndIndex)
+	assert.Empty(t, getTxs(testEthAddrs[1], AtomicTxIndex{}).Txs)
+
+	// Paginating from the first tx returns only the second.
+	page := getTxs(testEthAddrs[0], reply.Txs[0]).Txs
+	assert.Equal(t, reply.Txs[1:], page)
+
+	err := api.GetAtomicTxsByAddress(nil, &GetAtomicTxsByAddressArgs{Address: "not an address"}, &GetAtomicTxsByAddressReply{})
+	assert.Error(t, err)
+}
+
+func TestSnowmanAPIEstimatedNextBlockTime(t *testing.T) {
+	// Genesis at 0, followed by blocks every 2 seconds from timestamp 1000
+	blockTime := func(height uint64) (uint64, error) {
+		if height == 0 {
+			return 0, nil
+		}
+		return 1000 + 2*(height-1), nil
+	}
+
+	// Not enough history
+	estimate, err := estimateNextBlockTime(1, 1000, blockTime)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1000), estimate)
+
+	// Two blocks after genesis
+	estimate, err = estimateNextBlockTime(2, 1000, blockTime)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1004), estimate)
+
+	// More blocks than the window
+	estimate, err = estimateNextBlockTime(100, 1000, blockTime)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1200), estimate)
+
+	// Only the last window of intervals is averaged
+	slowStart := func(height uint64) (uint64, error) {
+		if height <= 10 {
+			return 100 * height, nil
+		}
+		return 1000 + 3*(height-10), nil
+	}
+	estimate, err = estimateNextBlockTime(10+blockIntervalWindow, 0, slowStart)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1000+3*blockIntervalWindow+3), estimate)
+
+	// An estimate that has passed returns the current time
+	estimate, err = estimateNextBlockTime(100, 5000, blockTime)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5000), estimate)
+
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase3, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	now := vm.clock.Unix()
+	estimate, err = (&SnowmanAPI{vm}).EstimatedNextBlockTime(context.Background())
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, estimate, now)
+}
+
+func TestSnowmanAPIGetForkActivationBlock(t *testing.T) {
+	// A chain with 10 blocks, produced every 10 seconds, that crosses Apricot
+	// Phase 3 at timestamp 45.
+	blockTime := func(height uint64) (uint64, error) { return 10 * height, nil }
+	height, err := firstBlockAtOrAfter(9, 45, blockTime)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), height)
+	height, err = firstBlockAtOrAfter(9, 50, blockTime)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), height)
+	height, err = firstBlockAtOrAfter(9, 0, blockTime)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), height)
+
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase3, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &SnowmanAPI{vm}
+
+	// Apricot Phase 3 is activated at genesis.
+	reply, err := api.GetForkActivationBlock(context.Background(), "ApricotPhase3")
+	assert.NoError(t, err)
+	assert.Equal(t, vm.chain.GetBlockByNumber(0).Hash(), reply.Hash)
+	assert.Zero(t, reply.Number.Uint64())
+
+	_, err = api.GetForkActivationBlock(context.Background(), "ApricotPhase4")
+	assert.ErrorIs(t, err, errForkNotActivated)
+	_, err = api.GetForkActivationBlock(context.Background(), "Istanbul")
+	assert.Error(t, err)
+	_, err = api.GetForkActivationBlock(context.Background(), "NoSuchFork")
+	assert.Error(t, err)
+}
+
+func TestAvaxAPICancelAtomicTx(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, true, genesisJSONApricotPhase3, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	service := &AvaxAPI{vm}
+	userPass := api.UserPass{Username: username, Password: password}
+
+	importKey := func(key *crypto.PrivateKeySECP256K1R) {
+		encodedKey, err := formatting.EncodeWithChecksum(formatting.CB58, key.Bytes())
+		assert.NoError(t, err)
+		assert.NoError(t, service.ImportKey(nil, &ImportKeyArgs{
+			UserPass:   userPass,
+			PrivateKey: constants.SecretKeyPrefix + encodedKey,
+		}, &api.JSONAddress{}))
+	}
+
+	// [tx] is signed by testKeys[0]
+	tx := createImportTx(t, vm, ids.ID{1}, params.AvalancheAtomicTxFee)
+	assert.NoError(t, vm.mempool.AddTx(tx))
+	args := &CancelAtomicTxArgs{UserPass: userPass, TxID: tx.ID()}
+
+	importKey(testKeys[1])
+	err := service.CancelAtomicTx(nil, args, &api.SuccessResponse{})
+	assert.ErrorIs(t, err, errTxNotSignedByUser)
+	_, pending := vm.mempool.GetPendingTx(tx.ID())
+	assert.True(t, pending)
+
+	importKey(testKeys[0])
+	reply := &api.SuccessResponse{}
+	assert.NoError(t, service.CancelAtomicTx(nil, args, reply))
+	assert.True(t, reply.Success)
+	_, pending = vm.mempool.GetPendingTx(tx.ID())
+	assert.False(t, pending)
+	assert.Zero(t, vm.mempool.Len())
+	// The cancelled tx is reported as dropped and is not gossiped
+	_, status, _, err := vm.getAtomicTx(tx.ID())
+	assert.NoError(t, err)
+	assert.Equal(t, Dropped, status)
+	assert.Empty(t, vm.mempool.GetNewTxs())
+
+	// The cancelled tx's inputs are released, so it can be issued again.
+	assert.NoError(t, vm.mempool.AddTx(tx))
+
+	err = service.CancelAtomicTx(nil, &CancelAtomicTxArgs{UserPass: userPass, TxID: ids.GenerateTestID()}, &api.SuccessResponse{})
+	assert.ErrorIs(t, err, errTxNotPending)
+}
+
+func TestAvaxAPIExportAllKeys(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase0, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	service := &AvaxAPI{vm}
+	userPass := api.UserPass{Username: username, Password: password}
+
+	err := service.ExportAllKeys(nil, &userPass, &ExportAllKeysReply{})
+	assert.ErrorIs(t, err, errNoKeysToExport)
+
+	// Import the keys in reverse address order
+	keys := append([]*crypto.PrivateKeySECP256K1R(nil), testKeys[:3]...)
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(GetEthAddress(keys[i]).Bytes(), GetEthAddress(keys[j]).Bytes()) > 0
+	})
+	for _, key := range keys {
+		encodedKey, err := formatting.EncodeWithChecksum(formatting.CB58, key.Bytes())
+		assert.NoError(t, err)
+		assert.NoError(t, service.ImportKey(nil, &ImportKeyArgs{
+			UserPass:   userPass,
+			PrivateKey: constants.SecretKeyPrefix + encodedKey,
+		}, &api.JSONAddress{}))
+	}
+
+	reply := &ExportAllKeysReply{}
+	assert.NoError(t, service.ExportAllKeys(nil, &userPass, reply))
+	if assert.Len(t, reply.Keys, 3) {
+		for i, exported := range reply.Keys {
+			key := keys[len(keys)-1-i]
+			exportReply := &ExportKeyReply{}
+			assert.NoError(t, service.ExportKey(nil, &ExportKeyArgs{
+				UserPass: userPass,
+				Address:  exported.Address,
+			}, exportReply))
+			assert.Equal(t, FormatEthAddress(GetEthAddress(key)), exported.Address)
+			assert.Equal(t, exportReply.PrivateKey, exported.PrivateKey)
+			assert.Equal(t, hexutil.Encode(key.Bytes()), exported.PrivateKeyHex)
+		}
+	}
+
+	err = service.ExportAllKeys(nil, &api.UserPass{Username: username, Password: "wrong"}, &ExportAllKeysReply{})
+	assert.Error(t, err)
+}
+
+func TestDebugAPIGetAtomicTxForUTXO(t *testing.T) {
+	genesisJSON, err := fundAddressByGenesis([]common.Address{testEthAddrs[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, vm, _, _, _ := GenesisVM(t, true, genesisJSON, "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+
+	exportTx, err := vm.newExportTx(vm.ctx.AVAXAssetID, 1000, vm.ctx.XChainID, testShortIDAddrs[0], initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxoID := ExportUTXOIDs(exportTx.ID(), 1)[0]
+
+	// The UTXO is unknown until the export is accepted.
+	_, err = api.GetAtomicTxForUTXO(context.Background(), utxoID.String())
+	assert.ErrorIs(t, err, errUnknownUTXO)
+
+	if err := vm.issueTx(exportTx, true /*=local*/); err != nil {
+		t.Fatal(err)
+	}
+	<-issuer
+	blk, err := vm.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.SetPreference(blk.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Accept(); err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := api.GetAtomicTxForUTXO(context.Background(), utxoID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, exportTx.ID(), reply.TxID)
+	assert.Equal(t, uint32(0), reply.OutputIndex)
+	assert.Equal(t, blk.Height(), reply.BlockHeight)
+	txBytes, err := formatting.Decode(formatting.Hex, reply.Tx)
+	assert.NoError(t, err)
+	assert.Equal(t, exportTx.Bytes(), txBytes)
+
+	_, err = api.GetAtomicTxForUTXO(context.Background(), ids.GenerateTestID().String())
+	assert.ErrorIs(t, err, errUnknownUTXO)
+	_, err = api.GetAtomicTxForUTXO(context.Background(), "not an ID")
+	assert.Error(t, err)
+}
+
+func TestDebugAPIVerifyBlockAtomicTxs(t *testing.T) {
+	issuer, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase2, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: 50000000,
+	})
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+
+	acceptTx := func(tx *Tx) {
+		if err := vm.issueTx(tx, true /*=local*/); err != nil {
+			t.Fatal(err)
+		}
+		<-issuer
+		blk, err := vm.BuildBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := blk.Verify(); err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.SetPreference(blk.ID()); err != nil {
+			t.Fatal(err)
+		}
+		if err := blk.Accept(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := []*crypto.PrivateKeySECP256K1R{testKeys[0]}
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The imported UTXO is available before the import is accepted.
+	if err := importTx.UnsignedAtomicTx.SemanticVerify(vm, importTx, vm.LastAcceptedBlockInternal().(*Block), initialBaseFee, apricotRulesPhase2); err != nil {
+		t.Fatal(err)
+	}
+	acceptTx(importTx)
+	exportTx, err := vm.newExportTx(vm.ctx.AVAXAssetID, 20000000, vm.ctx.XChainID, testShortIDAddrs[0], initialBaseFee, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acceptTx(exportTx)
+
+	result, err := api.VerifyBlockAtomicTxs(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Txs)
+
+	// Accepting the import spent its UTXO from shared memory.
+	result, err = api.VerifyBlockAtomicTxs(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), result.Height)
+	assert.Equal(t, vm.chain.GetBlockByNumber(1).Hash(), result.Hash)
+	if assert.Len(t, result.Txs, 1) {
+		assert.Equal(t, importTx.ID(), result.Txs[0].TxID)
+		assert.False(t, result.Txs[0].Passed)
+		assert.Contains(t, result.Txs[0].Error, "failed to fetch import UTXOs")
+	}
+
+	// Exports don't depend on shared memory, so they still pass.
+	result, err = api.VerifyBlockAtomicTxs(context.Background(), 2)
+	assert.NoError(t, err)
+	if assert.Len(t, result.Txs, 1) {
+		assert.Equal(t, AtomicTxVerifyResult{TxID: exportTx.ID(), Passed: true}, result.Txs[0])
+	}
+
+	_, err = api.VerifyBlockAtomicTxs(context.Background(), 3)
+	assert.ErrorIs(t, err, errInvalidHeightRange)
+}
+
+func TestDebugAPIGetTotalSupply(t *testing.T) {
+	importAmount := uint64(50000000)
+	exportAmount := uint64(20000000)
+	issuer, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase2, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: importAmount,
+	})
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+
+	getSupply := func(height uint64) *big.Int {
+		supply, err := api.GetTotalSupply(context.Background(), height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return (*big.Int)(supply)
+	}
+	acceptTx := func(tx *Tx) {
+		if err := vm.issueTx(tx, true /*=local*/); err != nil {
+			t.Fatal(err)
+		}
+		<-issuer
+		blk, err := vm.BuildBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := blk.Verify(); err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.SetPreference(blk.ID()); err != nil {
+			t.Fatal(err)
+		}
+		if err := blk.Accept(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	genesis := new(core.Genesis)
+	if err := json.Unmarshal([]byte(genesisJSONApricotPhase2), genesis); err != nil {
+		t.Fatal(err)
+	}
+	genesisSupply := new(big.Int)
+	for _, account := range genesis.Alloc {
+		genesisSupply.Add(genesisSupply, account.Balance)
+	}
+	assert.Equal(t, genesisSupply, getSupply(0))
+
+	keys := []*crypto.PrivateKeySECP256K1R{testKeys[0]}
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acceptTx(importTx)
+	exportTx, err := vm.newExportTx(vm.ctx.AVAXAssetID, exportAmount, vm.ctx.XChainID, testShortIDAddrs[0], initialBaseFee, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acceptTx(exportTx)
+
+	// Both txs burn the fixed Apricot Phase 2 fee.
+	afterImport := new(big.Int).SetUint64(importAmount - params.AvalancheAtomicTxFee)
+	afterImport.Mul(afterImport, x2cRate).Add(afterImport, genesisSupply)
+	afterExport := new(big.Int).SetUint64(exportAmount + params.AvalancheAtomicTxFee)
+	afterExport.Mul(afterExport, x2cRate).Sub(afterImport, afterExport)
+	assert.Equal(t, afterImport, getSupply(1))
+	assert.Equal(t, afterExport, getSupply(2))
+	assert.Equal(t, genesisSupply, getSupply(0))
+
+	// The supply matches the balance credited to the only funded address.
+	state, err := vm.chain.CurrentState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, new(big.Int).Add(genesisSupply, state.GetBalance(testEthAddrs[0])), afterExport)
+
+	_, err = api.GetTotalSupply(context.Background(), 3)
+	assert.ErrorIs(t, err, errInvalidHeightRange)
+}
+
+func TestDebugAPIGetTotalSupplyEVMFees(t *testing.T) {
+	importAmount := uint64(50000000)
+	issuer, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase3, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: importAmount,
+	})
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+	newTxPoolHeadChan := make(chan core.NewTxPoolReorgEvent, 1)
+	vm.chain.GetTxPool().SubscribeNewReorgEvent(newTxPoolHeadChan)
+
+	acceptBlock := func() *types.Block {
+		<-issuer
+		blk, err := vm.BuildBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := blk.Verify(); err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.SetPreference(blk.ID()); err != nil {
+			t.Fatal(err)
+		}
+		if err := blk.Accept(); err != nil {
+			t.Fatal(err)
+		}
+		<-newTxPoolHeadChan
+		return vm.chain.LastAcceptedBlock()
+	}
+
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.issueTx(importTx, true /*=local*/); err != nil {
+		t.Fatal(err)
+	}
+	acceptBlock()
+	afterImport, err := api.GetTotalSupply(context.Background(), 1)
+	assert.NoError(t, err)
+
+	tx := types.NewTransaction(0, testEthAddrs[1], big.NewInt(10), 21000, big.NewInt(params.LaunchMinGasPrice), nil)
+	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(vm.chainID), testKeys[0].ToECDSA())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range vm.chain.AddRemoteTxsSync([]*types.Transaction{signedTx}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	block := acceptBlock()
+
+	// The fees of EVM txs are credited to the coinbase, so the supply is
+	// unchanged and still matches the balances in the state.
+	afterTransfer, err := api.GetTotalSupply(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, afterImport, afterTransfer)
+
+	genesis := new(core.Genesis)
+	if err := json.Unmarshal([]byte(genesisJSONApricotPhase3), genesis); err != nil {
+		t.Fatal(err)
+	}
+	state, err := vm.chain.CurrentState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	balances := new(big.Int)
+	for _, addr := range []common.Address{testEthAddrs[0], testEthAddrs[1], block.Coinbase()} {
+		balances.Add(balances, state.GetBalance(addr))
+	}
+	for addr, account := range genesis.Alloc {
+		if addr != block.Coinbase() {
+			balances.Add(balances, account.Balance)
+		}
+	}
+	assert.Equal(t, balances, afterTransfer.ToInt())
+}
+
+func TestDebugAPIGetTotalSupplyKeeper(t *testing.T) {
+	genesis := new(core.Genesis)
+	if err := json.Unmarshal([]byte(genesisJSONApricotPhase3), genesis); err != nil {
+		t.Fatal(err)
+	}
+	genesis.Alloc[common.HexToAddress(core.GetSystemTriggerContractAddr(common.Big0))] = core.GenesisAccount{
+		Code:    []byte{0x00},
+		Balance: common.Big0,
+	}
+	genesisJSON, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, vm, _, _, _ := GenesisVM(t, true, string(genesisJSON), "", "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+
+	// The keeper may mint native tokens, which the supply does not account for
+	_, err = api.GetTotalSupply(context.Background(), 0)
+	assert.ErrorIs(t, err, errSupplyMinted)
+}
+
+func TestDebugAPIGetTotalSupplyScanRange(t *testing.T) {
+	issuer, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase3, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: 50000000,
+	})
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	api := &DebugAPI{vm}
+
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.issueTx(importTx, true /*=local*/); err != nil {
+		t.Fatal(err)
+	}
+	<-issuer
+	blk, err := vm.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.SetPreference(blk.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Accept(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(scanRange uint64) {
+		maxSupplyScanRange = scanRange
+	}(maxSupplyScanRange)
+	maxSupplyScanRange = 0
+
+	// The genesis checkpoint needs no scan, but the next block does
+	_, err = api.GetTotalSupply(context.Background(), 0)
+	assert.NoError(t, err)
+	_, err = api.GetTotalSupply(context.Background(), 1)
+	assert.ErrorIs(t, err, errHeightRangeTooLarge)
+}
+
+func TestAvaxAPIImportKeyWithScrypt(t *testing.T) {
+	configJSON := fmt.Sprintf(`{"keystore-scrypt-n": %d, "keystore-scrypt-p": 2}`, minKeystoreScryptN)
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase0, configJSON, "")
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	service := &AvaxAPI{vm}
+	userPass := api.UserPass{Username: username, Password: password}
+
+	key := testKeys[0]
+	encodedKey, err := formatting.EncodeWithChecksum(formatting.CB58, key.Bytes())
+	assert.NoError(t, err)
+	privateKey := constants.SecretKeyPrefix + encodedKey
+	assert.NoError(t, service.ImportKey(nil, &ImportKeyArgs{
+		UserPass:   userPass,
+		PrivateKey: privateKey,
+	}, &api.JSONAddress{}))
+
+	// The key is stored encrypted with scrypt rather than as the raw key.
+	db, err := vm.ctx.Keystore.GetDatabase(username, password)
+	assert.NoError(t, err)
+	storedKey, err := db.Get(GetEthAddress(key).Bytes())
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+	assert.NotEqual(t, key.Bytes(), storedKey)
+	assert.NotContains(t, string(storedKey), hexutil.Encode(key.Bytes())[2:])
+
+	reply := &ExportKeyReply{}
+	assert.NoError(t, service.ExportKey(nil, &ExportKeyArgs{
+		UserPass: userPass,
+		Address:  FormatEthAddress(GetEthAddress(key)),
+	}, reply))
+	assert.Equal(t, privateKey, reply.PrivateKey)
+	assert.Equal(t, hexutil.Encode(key.Bytes()), reply.PrivateKeyHex)
+}
+
+func TestAvaxAPIGetImportableAssets(t *testing.T) {
+	getAssets := func(configJSON string) *GetImportableAssetsReply {
+		_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase0, configJSON, "")
+		defer func() {
+			assert.NoError(t, vm.Shutdown())
+		}()
+
+		reply := &GetImportableAssetsReply{}
+		assert.NoError(t, (&AvaxAPI{vm}).GetImportableAssets(nil, nil, reply))
+		assert.Equal(t, vm.ctx.AVAXAssetID, reply.AssetIDs[0])
+		return reply
+	}
+
+	// Without an allowlist every asset is importable.
+	reply := getAssets("")
+	assert.True(t, reply.AllAssets)
+	assert.Len(t, reply.AssetIDs, 1)
+
+	allowed := []ids.ID{{2}, {1}}
+	reply = getAssets(fmt.Sprintf(`{"allowed-atomic-assets": [%q, %q]}`, allowed[0], allowed[1]))
+	assert.False(t, reply.AllAssets)
+	assert.Equal(t, []ids.ID{allowed[1], allowed[0]}, reply.AssetIDs[1:])
+}
+
+func TestAvaxAPICheckAtomicTx(t *testing.T) {
+	_, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase3, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: units.Avax,
+	})
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	service := &AvaxAPI{vm}
+	keys := []*crypto.PrivateKeySECP256K1R{testKeys[0]}
+
+	check := func(tx *Tx) *CheckAtomicTxReply {
+		txStr, err := formatting.EncodeWithChecksum(formatting.Hex, tx.Bytes())
+		assert.NoError(t, err)
+		reply := &CheckAtomicTxReply{}
+		assert.NoError(t, service.CheckAtomicTx(nil, &api.FormattedTx{Tx: txStr, Encoding: formatting.Hex}, reply))
+		return reply
+	}
+
+	tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, keys)
+	assert.NoError(t, err)
+	reply := check(tx)
+	assert.True(t, reply.Acceptable)
+	assert.Empty(t, reply.Reason)
+	// Checking the tx does not issue it.
+	assert.Zero(t, vm.mempool.Len())
+
+	// A tx spending the same UTXO as a tx in the mempool is not acceptable.
+	assert.NoError(t, vm.issueTx(tx, true /*=local*/))
+	conflictingTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[1], nil, nil, initialBaseFee, keys)
+	assert.NoError(t, err)
+	reply = check(conflictingTx)
+	assert.False(t, reply.Acceptable)
+	assert.Equal(t, errConflictingAtomicTx.Error(), reply.Reason)
+	// The tx already in the mempool is still acceptable.
+	assert.True(t, check(tx).Acceptable)
+
+	reply = &CheckAtomicTxReply{}
+	assert.NoError(t, service.CheckAtomicTx(nil, &api.FormattedTx{Tx: "0x1234", Encoding: formatting.Hex}, reply))
+	assert.False(t, reply.Acceptable)
+	assert.Contains(t, reply.Reason, "problem")
+}
+
+func TestAvaxAPIIssueTxStatus(t *testing.T) {
+	_, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase0, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: 50000000,
+	})
+	defer func() {
+		assert.NoError(t, vm.Shutdown())
+	}()
+	service := &AvaxAPI{vm}
+
+	tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txHex, err := formatting.EncodeWithChecksum(formatting.Hex, tx.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := &api.FormattedTx{Tx: txHex, Encoding: formatting.Hex}
+
+	// The first issue adds the tx to the mempool, and issuing it again reports
+	// it as known.
+	reply := &IssueTxReply{}
+	assert.NoError(t, service.IssueTx(nil, args, reply))
+	assert.Equal(t, tx.ID(), reply.TxID)
+	assert.Equal(t, IssueAdded, reply.Status)
+
+	reply = &IssueTxReply{}
+	assert.NoError(t, service.IssueTx(nil, args, reply))
+	assert.Equal(t, tx.ID(), reply.TxID)
+	assert.Equal(t, IssueKnown, reply.Status)
+
+	replyJSON, err := json.Marshal(reply)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`{"txID":"%s","status":"Known"}`, tx.ID()), string(replyJSON))
+}
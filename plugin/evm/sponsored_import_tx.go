@@ -0,0 +1,180 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package evm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/flare-foundation/coreth/core/state"
+	"github.com/flare-foundation/coreth/params"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/flare-foundation/flare/ids"
+	"github.com/flare-foundation/flare/snow"
+	"github.com/flare-foundation/flare/utils/crypto"
+	"github.com/flare-foundation/flare/utils/math"
+	"github.com/flare-foundation/flare/vms/secp256k1fx"
+)
+
+// UnsignedSponsoredImportTx is an import tx whose fee is paid by a sponsor
+// account on this chain rather than deducted from the imported funds, so that
+// its outputs credit the full imported amount. It is a separate type rather
+// than an optional field of UnsignedImportTx so that the encoding and IDs of
+// existing import txs are unchanged.
+//
+// The credentials of a signed sponsored import are those of its imported
+// inputs, followed by the credential of the sponsor.
+type UnsignedSponsoredImportTx struct {
+	UnsignedImportTx `serialize:"true"`
+	// Sponsor pays the fee of the import from its AVAX balance
+	Sponsor EVMInput `serialize:"true" json:"sponsor"`
+}
+
+// InputUTXOs returns the UTXOs imported by this transaction, along with the
+// nonce of the sponsor, so that conflicting spends of the sponsor's nonce are
+// detected like those of an export.
+func (tx *UnsignedSponsoredImportTx) InputUTXOs() ids.Set {
+	set := tx.UnsignedImportTx.InputUTXOs()
+	set.Add(tx.Sponsor.InputID())
+	return set
+}
+
+// Verify this transaction is well-formed
+func (tx *UnsignedSponsoredImportTx) Verify(
+	xChainID ids.ID,
+	ctx *snow.Context,
+	rules params.Rules,
+) error {
+	switch {
+	case tx == nil:
+		return errNilTx
+	case !rules.IsSponsoredImport:
+		return errSponsoredImportNotActive
+	}
+	if err := tx.UnsignedImportTx.Verify(xChainID, ctx, rules); err != nil {
+		return err
+	}
+	if err := tx.Sponsor.Verify(); err != nil {
+		return fmt.Errorf("sponsor failed verification: %w", err)
+	}
+	if tx.Sponsor.AssetID != ctx.AVAXAssetID {
+		return fmt.Errorf("%w: found %s", errSponsorAssetNotAVAX, tx.Sponsor.AssetID)
+	}
+	return nil
+}
+
+// SigningBytes returns the bytes the credentials of [tx] sign over, so that
+// it can be signed by an offline signer.
+func (tx *UnsignedSponsoredImportTx) SigningBytes() ([]byte, error) {
+	return signingBytes(tx)
+}
+
+// GasUsed returns the gas used by the import if [size] bytes of it are
+// charged, plus the cost of verifying the signature of the sponsor.
+func (tx *UnsignedSponsoredImportTx) GasUsed(size int) (uint64, error) {
+	cost, err := tx.UnsignedImportTx.GasUsed(size)
+	if err != nil {
+		return 0, err
+	}
+	return math.Add64(cost, secp256k1fx.CostPerSignature)
+}
+
+// Amount of [assetID] burned by this transaction, including the amount spent
+// by the sponsor
+func (tx *UnsignedSponsoredImportTx) Burned(assetID ids.ID) (uint64, error) {
+	burned, err := tx.UnsignedImportTx.Burned(assetID)
+	if err != nil || assetID != tx.Sponsor.AssetID {
+		return burned, err
+	}
+	return math.Add64(burned, tx.Sponsor.Amount)
+}
+
+// AllBurned returns the amount of each asset burned by this transaction
+func (tx *UnsignedSponsoredImportTx) AllBurned() (map[ids.ID]uint64, error) {
+	burned, err := tx.UnsignedImportTx.AllBurned()
+	if err != nil {
+		return nil, err
+	}
+	total, err := math.Add64(burned[tx.Sponsor.AssetID], tx.Sponsor.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate burned amount of %s: %w", tx.Sponsor.AssetID, err)
+	}
+	burned[tx.Sponsor.AssetID] = total
+	return burned, nil
+}
+
+// SemanticVerify this transaction is valid. The sponsor must have signed the
+// tx and hold enough AVAX in the state of [parent] to pay the fee.
+func (tx *UnsignedSponsoredImportTx) SemanticVerify(
+	vm *VM,
+	stx *Tx,
+	parent *Block,
+	baseFee *big.Int,
+	rules params.Rules,
+) error {
+	if err := tx.UnsignedImportTx.semanticVerify(vm, stx, &tx.Sponsor, parent, baseFee, rules); err != nil {
+		return err
+	}
+
+	cred, ok := stx.Creds[len(stx.Creds)-1].(*secp256k1fx.Credential)
+	if !ok {
+		return fmt.Errorf("expected *secp256k1fx.Credential but got %T", stx.Creds[len(stx.Creds)-1])
+	}
+	if err := cred.Verify(); err != nil {
+		return err
+	}
+	if len(cred.Sigs) != 1 {
+		return fmt.Errorf("expected one signature for sponsor credential, but found: %d", len(cred.Sigs))
+	}
+	pubKeyIntf, err := vm.secpFactory.RecoverPublicKey(tx.UnsignedBytes(), cred.Sigs[0][:])
+	if err != nil {
+		return err
+	}
+	pubKey, ok := pubKeyIntf.(*crypto.PublicKeySECP256K1R)
+	if !ok {
+		// This should never happen
+		return fmt.Errorf("expected *crypto.PublicKeySECP256K1R but got %T", pubKeyIntf)
+	}
+	if tx.Sponsor.Address != PublicKeyToEthAddress(pubKey) {
+		return errPublicKeySignatureMismatch
+	}
+
+	// The balance is checked again when the tx is applied, as the sponsor may
+	// spend its funds earlier in the same block.
+	parentState, err := vm.chain.BlockState(parent.ethBlock)
+	if err != nil {
+		return fmt.Errorf("couldn't load state of parent block %s: %w", parent.ID(), err)
+	}
+	if parentState.GetBalance(tx.Sponsor.Address).Cmp(tx.sponsorAmount()) < 0 {
+		return fmt.Errorf("%w: sponsor %s cannot pay %d nAVAX", errInsufficientFunds, tx.Sponsor.Address, tx.Sponsor.Amount)
+	}
+	return nil
+}
+
+// sponsorAmount returns the amount spent by the sponsor, in wei.
+func (tx *UnsignedSponsoredImportTx) sponsorAmount() *big.Int {
+	return XtoC(tx.Sponsor.Amount)
+}
+
+// EVMStateTransfer debits the fee from the sponsor and credits the imported
+// EVMOutputs in full
+func (tx *UnsignedSponsoredImportTx) EVMStateTransfer(ctx *snow.Context, state *state.StateDB, rules params.Rules) error {
+	sponsor := tx.Sponsor
+	log.Debug("crosschain X->C sponsor", "addr", sponsor.Address, "amount", sponsor.Amount, "assetID", "AVAX")
+	amount := tx.sponsorAmount()
+	if state.GetBalance(sponsor.Address).Cmp(amount) < 0 {
+		return errInsufficientFunds
+	}
+	if state.GetNonce(sponsor.Address) != sponsor.Nonce {
+		return errInvalidNonce
+	}
+	state.SubBalance(sponsor.Address, amount)
+	state.SetNonce(sponsor.Address, sponsor.Nonce+1)
+
+	if err := tx.creditOutputs(ctx, state); err != nil {
+		return err
+	}
+	return creditAtomicFee(ctx, tx, state, rules)
+}
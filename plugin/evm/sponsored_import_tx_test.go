@@ -0,0 +1,290 @@
+// (c) 2021, Flare Networks Limited. All rights reserved.
+// Please see the file LICENSE for licensing terms.
+
+package evm
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/flare-foundation/coreth/core"
+	"github.com/flare-foundation/flare/ids"
+	"github.com/flare-foundation/flare/snow/choices"
+	"github.com/flare-foundation/flare/utils/crypto"
+	"github.com/flare-foundation/flare/vms/components/avax"
+	"github.com/flare-foundation/flare/vms/secp256k1fx"
+)
+
+// sponsoredImportGenesisJSON returns a genesis that funds [sponsor] and
+// activates sponsored imports.
+func sponsoredImportGenesisJSON(t *testing.T, sponsor common.Address) string {
+	genesisJSON, err := fundAddressByGenesis([]common.Address{sponsor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := &core.Genesis{}
+	if err := json.Unmarshal([]byte(genesisJSON), genesis); err != nil {
+		t.Fatal(err)
+	}
+	// The local chain ID would be replaced by the canonical local config.
+	genesis.Config.ChainID = big.NewInt(43111)
+	genesis.Config.FeeManagerActivationTimestamp = big.NewInt(0)
+	genesis.Config.SponsoredImportTimestamp = big.NewInt(0)
+	genesis.Config.AtomicTxsTimestamp = big.NewInt(0)
+	genesisBytes, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(genesisBytes)
+}
+
+// newTestSponsoredImportTx returns a sponsored import of [utxo], owned by
+// testKeys[0], whose fee at [baseFee] is paid by testKeys[1].
+func newTestSponsoredImportTx(t *testing.T, vm *VM, utxo *avax.UTXO, baseFee *big.Int) *Tx {
+	amount := utxo.Out.(*secp256k1fx.TransferOutput).Amount()
+	utx := &UnsignedSponsoredImportTx{
+		UnsignedImportTx: UnsignedImportTx{
+			NetworkID:    vm.ctx.NetworkID,
+			BlockchainID: vm.ctx.ChainID,
+			SourceChain:  vm.ctx.XChainID,
+			ImportedInputs: []*avax.TransferableInput{{
+				UTXOID: utxo.UTXOID,
+				Asset:  utxo.Asset,
+				In: &secp256k1fx.TransferInput{
+					Amt:   amount,
+					Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+				},
+			}},
+			Outs: []EVMOutput{{
+				Address: testEthAddrs[0],
+				Amount:  amount,
+				AssetID: vm.ctx.AVAXAssetID,
+			}},
+		},
+		Sponsor: EVMInput{
+			Address: testEthAddrs[1],
+			AssetID: vm.ctx.AVAXAssetID,
+			Nonce:   0,
+		},
+	}
+	signers := [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}, {testKeys[1]}}
+
+	// The size of the tx does not depend on the amount paid by the sponsor,
+	// so the fee can be calculated before it is set.
+	utx.Sponsor.Amount = 1
+	tx := &Tx{UnsignedAtomicTx: utx}
+	if err := tx.Sign(vm.codec, signers); err != nil {
+		t.Fatal(err)
+	}
+	gasUsed, err := tx.GasUsed(vm.currentRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if utx.Sponsor.Amount, err = calculateDynamicFee(gasUsed, baseFee); err != nil {
+		t.Fatal(err)
+	}
+	tx = &Tx{UnsignedAtomicTx: utx}
+	if err := tx.Sign(vm.codec, signers); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestSponsoredImportTx(t *testing.T) {
+	genesisJSON := sponsoredImportGenesisJSON(t, testEthAddrs[1])
+	issuer, vm, _, sharedMemory, _ := GenesisVM(t, true, genesisJSON, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	importAmount := uint64(50000000)
+	utxo, err := addUTXO(sharedMemory, vm.ctx, ids.GenerateTestID(), vm.ctx.AVAXAssetID, importAmount, testShortIDAddrs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := newTestSponsoredImportTx(t, vm, utxo, initialBaseFee)
+	sponsor := tx.UnsignedAtomicTx.(*UnsignedSponsoredImportTx).Sponsor
+
+	burned, err := tx.Burned(vm.ctx.AVAXAssetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if burned != sponsor.Amount {
+		t.Fatalf("Expected the sponsored import to burn the sponsored fee %d, but burned %d", sponsor.Amount, burned)
+	}
+
+	state, err := vm.chain.CurrentState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sponsorBalance := state.GetBalance(testEthAddrs[1])
+
+	if err := vm.issueTx(tx, true /*=local*/); err != nil {
+		t.Fatal(err)
+	}
+
+	<-issuer
+
+	blk, err := vm.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.SetPreference(blk.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Accept(); err != nil {
+		t.Fatal(err)
+	}
+	if status := blk.Status(); status != choices.Accepted {
+		t.Fatalf("Expected status of accepted block to be %s, but found %s", choices.Accepted, status)
+	}
+
+	state, err = vm.chain.CurrentState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The recipient is credited the full imported amount.
+	expectedBalance := new(big.Int).Mul(new(big.Int).SetUint64(importAmount), x2cRate)
+	if balance := state.GetBalance(testEthAddrs[0]); balance.Cmp(expectedBalance) != 0 {
+		t.Fatalf("Expected recipient balance %d, found %d", expectedBalance, balance)
+	}
+	// The sponsor pays the fee.
+	expectedBalance = new(big.Int).Sub(sponsorBalance, new(big.Int).Mul(new(big.Int).SetUint64(sponsor.Amount), x2cRate))
+	if balance := state.GetBalance(testEthAddrs[1]); balance.Cmp(expectedBalance) != 0 {
+		t.Fatalf("Expected sponsor balance %d, found %d", expectedBalance, balance)
+	}
+	if nonce := state.GetNonce(testEthAddrs[1]); nonce != 1 {
+		t.Fatalf("Expected sponsor nonce 1, found %d", nonce)
+	}
+}
+
+func TestSponsoredImportTxVerify(t *testing.T) {
+	genesisJSON := sponsoredImportGenesisJSON(t, testEthAddrs[1])
+	_, vm, _, sharedMemory, _ := GenesisVM(t, true, genesisJSON, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	utxo, err := addUTXO(sharedMemory, vm.ctx, ids.GenerateTestID(), vm.ctx.AVAXAssetID, 50000000, testShortIDAddrs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := newTestSponsoredImportTx(t, vm, utxo, initialBaseFee)
+	utx := tx.UnsignedAtomicTx.(*UnsignedSponsoredImportTx)
+
+	rules := vm.currentRules()
+	if err := utx.Verify(vm.ctx.XChainID, vm.ctx, rules); err != nil {
+		t.Fatalf("Failed to verify sponsored import: %s", err)
+	}
+
+	inactiveRules := rules
+	inactiveRules.IsSponsoredImport = false
+	if err := utx.Verify(vm.ctx.XChainID, vm.ctx, inactiveRules); !errors.Is(err, errSponsoredImportNotActive) {
+		t.Fatalf("Expected verification before activation to fail with %s, found %v", errSponsoredImportNotActive, err)
+	}
+
+	utx.Sponsor.AssetID = ids.GenerateTestID()
+	if err := utx.Verify(vm.ctx.XChainID, vm.ctx, rules); !errors.Is(err, errSponsorAssetNotAVAX) {
+		t.Fatalf("Expected sponsor paying in another asset to fail with %s, found %v", errSponsorAssetNotAVAX, err)
+	}
+	utx.Sponsor.AssetID = vm.ctx.AVAXAssetID
+
+	// A sponsor that did not sign the tx is rejected.
+	parent := vm.LastAcceptedBlockInternal().(*Block)
+	utx.Sponsor.Address = testEthAddrs[2]
+	tx.Creds = nil
+	if err := tx.Sign(vm.codec, [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}, {testKeys[1]}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := utx.SemanticVerify(vm, tx, parent, initialBaseFee, rules); !errors.Is(err, errPublicKeySignatureMismatch) {
+		t.Fatalf("Expected sponsor signature mismatch to fail with %s, found %v", errPublicKeySignatureMismatch, err)
+	}
+
+	// A sponsor without funds is rejected.
+	tx.Creds = nil
+	if err := tx.Sign(vm.codec, [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}, {testKeys[2]}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := utx.SemanticVerify(vm, tx, parent, initialBaseFee, rules); !errors.Is(err, errInsufficientFunds) {
+		t.Fatalf("Expected unfunded sponsor to fail with %s, found %v", errInsufficientFunds, err)
+	}
+
+	// The sponsor must cover the fee.
+	utx.Sponsor.Address = testEthAddrs[1]
+	utx.Sponsor.Amount--
+	tx.Creds = nil
+	if err := tx.Sign(vm.codec, [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}, {testKeys[1]}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := utx.SemanticVerify(vm, tx, parent, initialBaseFee, rules); err == nil {
+		t.Fatal("Expected sponsored import paying less than the fee to fail")
+	}
+}
+
+func TestSponsoredImportTxNotActive(t *testing.T) {
+	genesisJSON, err := fundAddressByGenesis([]common.Address{testEthAddrs[1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, vm, _, sharedMemory, _ := GenesisVM(t, true, genesisJSON, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if vm.chainConfig.SponsoredImportTimestamp != nil {
+		t.Fatal("Expected sponsored imports to be unscheduled")
+	}
+
+	utxo, err := addUTXO(sharedMemory, vm.ctx, ids.GenerateTestID(), vm.ctx.AVAXAssetID, 50000000, testShortIDAddrs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := newTestSponsoredImportTx(t, vm, utxo, initialBaseFee)
+	if err := vm.issueTx(tx, true /*=local*/); !errors.Is(err, errSponsoredImportNotActive) {
+		t.Fatalf("Expected issuing a sponsored import before activation to fail with %s, found %v", errSponsoredImportNotActive, err)
+	}
+}
+
+func TestSponsoredImportTxInputUTXOs(t *testing.T) {
+	genesisJSON := sponsoredImportGenesisJSON(t, testEthAddrs[1])
+	_, vm, _, sharedMemory, _ := GenesisVM(t, true, genesisJSON, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	txs := make([]*Tx, 2)
+	for i := range txs {
+		utxo, err := addUTXO(sharedMemory, vm.ctx, ids.GenerateTestID(), vm.ctx.AVAXAssetID, 50000000, testShortIDAddrs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs[i] = newTestSponsoredImportTx(t, vm, utxo, initialBaseFee)
+	}
+
+	// The inputs include the sponsor's nonce, packed like the inputs of an
+	// export.
+	utx := txs[0].UnsignedAtomicTx.(*UnsignedSponsoredImportTx)
+	inputs := utx.InputUTXOs()
+	exportInputs := (&UnsignedExportTx{Ins: []EVMInput{utx.Sponsor}}).InputUTXOs()
+	if inputs.Len() != 2 || !inputs.Contains(utx.ImportedInputs[0].InputID()) || !inputs.Overlaps(exportInputs) {
+		t.Fatalf("Expected inputs to be the imported UTXO and the sponsor nonce, found %v", inputs.List())
+	}
+
+	// Both imports spend the same nonce of the sponsor, so they conflict.
+	if err := vm.issueTxs(txs); !errors.Is(err, errConflictingAtomicTxBatch) {
+		t.Fatalf("Expected imports sponsored with the same nonce to fail with %s, found %v", errConflictingAtomicTxBatch, err)
+	}
+}
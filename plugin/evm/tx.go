@@ -5,6 +5,7 @@ package evm
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math/big"
@@ -15,6 +16,7 @@ import (
 	"github.com/flare-foundation/coreth/core/state"
 	"github.com/flare-foundation/coreth/params"
 
+	"github.com/flare-foundation/flare/chains/atomic"
 	"github.com/flare-foundation/flare/codec"
 	"github.com/flare-foundation/flare/database"
 	"github.com/flare-foundation/flare/ids"
@@ -22,6 +24,7 @@ import (
 	"github.com/flare-foundation/flare/utils"
 	"github.com/flare-foundation/flare/utils/crypto"
 	"github.com/flare-foundation/flare/utils/hashing"
+	"github.com/flare-foundation/flare/utils/math"
 	"github.com/flare-foundation/flare/utils/wrappers"
 	"github.com/flare-foundation/flare/vms/components/verify"
 	"github.com/flare-foundation/flare/vms/secp256k1fx"
@@ -37,7 +40,12 @@ var (
 	errNilInput          = errors.New("nil input")
 	errEmptyAssetID      = errors.New("empty asset ID is not valid")
 	errNilBaseFee        = errors.New("cannot calculate dynamic fee with nil baseFee")
+	errDuplicateOutput   = errors.New("duplicate output address and asset ID")
 	errFeeOverflow       = errors.New("overflow occurred while calculating the fee")
+	errTxTruncated       = errors.New("tx bytes are truncated")
+	errTxUnknownTypeID   = errors.New("tx bytes contain an unknown type ID")
+	errTxTrailingBytes   = errors.New("tx bytes contain trailing data")
+	errBalanceOverflow   = errors.New("balance overflow")
 )
 
 // Constants for calculating the gas consumed by atomic transactions
@@ -45,6 +53,10 @@ var (
 	TxBytesGas   uint64 = 1
 	EVMOutputGas uint64 = (common.AddressLength + wrappers.LongLen + hashing.HashLen) * TxBytesGas
 	EVMInputGas  uint64 = (common.AddressLength+wrappers.LongLen+hashing.HashLen+wrappers.LongLen)*TxBytesGas + secp256k1fx.CostPerSignature
+	// CredentialGas is the cost of the single signature credential of an
+	// input, which is only charged as of Apricot Phase 5: its type ID, the
+	// number of signatures and the signature.
+	CredentialGas uint64 = (wrappers.IntLen + wrappers.IntLen + crypto.SECP256K1RSigLen) * TxBytesGas
 )
 
 // EVMOutput defines an output that is added to the EVM state created by import transactions
@@ -88,12 +100,27 @@ func (in *EVMInput) Verify() error {
 	return nil
 }
 
+// InputID returns the ID of the EVM state [in] consumes: the nonce of its
+// address, which can only be spent once.
+func (in *EVMInput) InputID() ids.ID {
+	// Total populated bytes is 20 (Address) + 8 (Nonce), however, we allocate 32 bytes
+	// to make ids.ID casting easier.
+	var rawID [32]byte
+	packer := wrappers.Packer{Bytes: rawID[:]}
+	packer.PackLong(in.Nonce)
+	packer.PackBytes(in.Address.Bytes())
+	return ids.ID(rawID)
+}
+
 // UnsignedTx is an unsigned transaction
 type UnsignedTx interface {
 	Initialize(unsignedBytes, signedBytes []byte)
 	ID() ids.ID
-	GasUsed() (uint64, error)
+	// GasUsed returns the gas used by the tx if [size] bytes of it are charged
+	GasUsed(size int) (uint64, error)
 	Burned(assetID ids.ID) (uint64, error)
+	// AllBurned returns the amount burned of every asset transferred by the tx
+	AllBurned() (map[ids.ID]uint64, error)
 	UnsignedBytes() []byte
 	Bytes() []byte
 }
@@ -113,7 +140,106 @@ type UnsignedAtomicTx interface {
 	// Accept this transaction with the additionally provided state transitions.
 	Accept(ctx *snow.Context, batch database.Batch) error
 
-	EVMStateTransfer(ctx *snow.Context, state *state.StateDB) error
+	AtomicOps
+
+	EVMStateTransfer(ctx *snow.Context, state *state.StateDB, rules params.Rules) error
+}
+
+// AtomicOps describes the operations an atomic tx performs on shared memory
+// when it is accepted.
+type AtomicOps interface {
+	// Requests returns the shared memory requests of this tx, keyed by the ID
+	// of the peer chain they apply to.
+	Requests() (map[ids.ID]*atomic.Requests, error)
+	// Apply applies the requests of this tx to shared memory atomically with
+	// [batch].
+	Apply(ctx *snow.Context, batch database.Batch) error
+}
+
+// applyAtomicOps applies the shared memory requests of [ops] atomically with
+// [batch].
+func applyAtomicOps(ctx *snow.Context, ops AtomicOps, batch database.Batch) error {
+	requests, err := ops.Requests()
+	if err != nil {
+		return err
+	}
+	return ctx.SharedMemory.Apply(requests, batch)
+}
+
+// mergeAtomicOps merges the shared memory requests of [txs], so that they can
+// be applied to shared memory at once.
+func mergeAtomicOps(txs []*Tx) (map[ids.ID]*atomic.Requests, error) {
+	merged := make(map[ids.ID]*atomic.Requests)
+	for _, tx := range txs {
+		requests, err := tx.UnsignedAtomicTx.Requests()
+		if err != nil {
+			return nil, err
+		}
+		for chainID, chainRequests := range requests {
+			mergedRequests, ok := merged[chainID]
+			if !ok {
+				merged[chainID] = chainRequests
+				continue
+			}
+			mergedRequests.RemoveRequests = append(mergedRequests.RemoveRequests, chainRequests.RemoveRequests...)
+			mergedRequests.PutRequests = append(mergedRequests.PutRequests, chainRequests.PutRequests...)
+		}
+	}
+	return merged, nil
+}
+
+// verifyAtomicTxSize verifies that an atomic tx with [numInputs] inputs and
+// [numOutputs] outputs does not exceed the limits set by [rules].
+func verifyAtomicTxSize(numInputs, numOutputs int, rules params.Rules) error {
+	if rules.MaxAtomicInputs != 0 && uint64(numInputs) > rules.MaxAtomicInputs {
+		return fmt.Errorf("%w: %d > %d", errTooManyAtomicInputs, numInputs, rules.MaxAtomicInputs)
+	}
+	if rules.MaxAtomicOutputs != 0 && uint64(numOutputs) > rules.MaxAtomicOutputs {
+		return fmt.Errorf("%w: %d > %d", errTooManyAtomicOutputs, numOutputs, rules.MaxAtomicOutputs)
+	}
+	return nil
+}
+
+// verifyAtomicTxOrder verifies that [txs] are sorted by ID and unique as of
+// Apricot Phase 5.
+func verifyAtomicTxOrder(txs []*Tx, rules params.Rules) error {
+	if !rules.IsApricotPhase5 {
+		return nil
+	}
+	for i := 1; i < len(txs); i++ {
+		prevID, txID := txs[i-1].ID(), txs[i].ID()
+		if bytes.Compare(prevID[:], txID[:]) >= 0 {
+			return fmt.Errorf("%w: %s is not before %s", errAtomicTxsNotSorted, prevID, txID)
+		}
+	}
+	return nil
+}
+
+// EncodeAtomicTxs encodes [txs] for inclusion in the extra data of a block.
+// An empty list is encoded as no extra data.
+func EncodeAtomicTxs(txs []*Tx) ([]byte, error) {
+	if len(txs) == 0 {
+		return nil, nil
+	}
+	return Codec.Marshal(codecVersion, txs)
+}
+
+// DecodeAtomicTxs decodes and initializes the atomic txs encoded in [data] by
+// EncodeAtomicTxs. Empty data decodes to an empty list.
+func DecodeAtomicTxs(data []byte) ([]*Tx, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var txs []*Tx
+	if _, err := Codec.Unmarshal(data, &txs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal atomic txs: %w", err)
+	}
+	for i, tx := range txs {
+		if err := tx.Sign(Codec, nil); err != nil {
+			return nil, fmt.Errorf("failed to initialize atomic tx at index %d: %w", i, err)
+		}
+	}
+	return txs, nil
 }
 
 // Tx is a signed transaction
@@ -123,6 +249,87 @@ type Tx struct {
 
 	// The credentials of this transaction
 	Creds []verify.Verifiable `serialize:"true" json:"credentials"`
+
+	// size caches the serialized size of a tx that was never initialized
+	size int
+}
+
+// Size returns the length of the canonical serialization of [tx]. The
+// serialization of a signed or parsed tx is cached when it is initialized.
+// For any other tx, it is computed on the first call and its size cached.
+func (tx *Tx) Size() (int, error) {
+	if signedBytes := tx.Bytes(); len(signedBytes) > 0 {
+		return len(signedBytes), nil
+	}
+	if tx.size == 0 {
+		signedBytes, err := Codec.Marshal(codecVersion, tx)
+		if err != nil {
+			return 0, fmt.Errorf("couldn't marshal Tx: %w", err)
+		}
+		tx.size = len(signedBytes)
+	}
+	return tx.size, nil
+}
+
+// GasUsed returns the gas used by [tx] under [rules]. Before Apricot Phase 5,
+// only the unsigned bytes of [tx] are charged. As of Apricot Phase 5, its
+// credentials are charged as well, using the cached Size of [tx].
+func (tx *Tx) GasUsed(rules params.Rules) (uint64, error) {
+	if !rules.IsApricotPhase5 {
+		return tx.UnsignedAtomicTx.GasUsed(len(tx.UnsignedBytes()))
+	}
+	size, err := tx.Size()
+	if err != nil {
+		return 0, err
+	}
+	return tx.UnsignedAtomicTx.GasUsed(size)
+}
+
+// Verify verifies that [tx] is well-formed, including that it carries one
+// credential per input. This is checked before any signature is recovered.
+// As of Apricot Phase 5, the size of [tx] is limited as well.
+func (tx *Tx) Verify(xChainID ids.ID, ctx *snow.Context, rules params.Rules) error {
+	if err := tx.UnsignedAtomicTx.Verify(xChainID, ctx, rules); err != nil {
+		return err
+	}
+	if rules.IsApricotPhase5 {
+		size, err := tx.Size()
+		if err != nil {
+			return err
+		}
+		if size > params.ApricotPhase5AtomicTxMaxSize {
+			return fmt.Errorf("%w: %d bytes > %d bytes", errAtomicTxTooLarge, size, params.ApricotPhase5AtomicTxMaxSize)
+		}
+	}
+
+	var (
+		txType    string
+		numInputs int
+	)
+	switch utx := tx.UnsignedAtomicTx.(type) {
+	case *UnsignedImportTx:
+		txType, numInputs = "import", len(utx.ImportedInputs)
+	case *UnsignedSponsoredImportTx:
+		// The sponsor signs with the last credential
+		txType, numInputs = "sponsored import", len(utx.ImportedInputs)+1
+	case *UnsignedContractImportTx:
+		txType, numInputs = "contract import", len(utx.ImportedInputs)
+	case *UnsignedExportTx:
+		txType, numInputs = "export", len(utx.Ins)
+	default:
+		return fmt.Errorf("unknown atomic tx type %T", utx)
+	}
+	if len(tx.Creds) != numInputs {
+		return fmt.Errorf("%s tx contained %w: %d inputs, but %d credentials", txType, errWrongCredentialCount, numInputs, len(tx.Creds))
+	}
+	return nil
+}
+
+// signingBytes returns the canonical serialization of [utx] that the
+// credentials of a tx sign: each signature is over the SHA256 hash of these
+// bytes. The tx ID is not derived from them, but from the signed bytes.
+func signingBytes(utx UnsignedAtomicTx) ([]byte, error) {
+	return Codec.Marshal(codecVersion, &utx)
 }
 
 // Sign this transaction with the provided signers
@@ -156,18 +363,49 @@ func (tx *Tx) Sign(c codec.Manager, signers [][]*crypto.PrivateKeySECP256K1R) er
 	return nil
 }
 
+// ParseTx parses [txBytes] into an initialized atomic transaction. Malformed
+// input is reported as errTxTruncated, errTxUnknownTypeID or errTxTrailingBytes,
+// so that callers receive an actionable error rather than the raw codec failure.
+func ParseTx(txBytes []byte) (*Tx, error) {
+	// The codec version is followed by the type ID of the unsigned tx
+	if len(txBytes) < wrappers.ShortLen+wrappers.IntLen {
+		return nil, fmt.Errorf("%w: %d bytes is shorter than the tx header", errTxTruncated, len(txBytes))
+	}
+	if version := binary.BigEndian.Uint16(txBytes); version != codecVersion {
+		return nil, fmt.Errorf("unknown codec version %d", version)
+	}
+	if typeID := binary.BigEndian.Uint32(txBytes[wrappers.ShortLen:]); !unsignedAtomicTxTypeIDs[typeID] {
+		return nil, fmt.Errorf("%w: %d", errTxUnknownTypeID, typeID)
+	}
+
+	tx := &Tx{}
+	if _, err := Codec.Unmarshal(txBytes, tx); err != nil {
+		// The codec only checks for trailing bytes once the whole tx has been
+		// decoded, in which case [tx] re-encodes to a prefix of [txBytes].
+		encoded, marshalErr := Codec.Marshal(codecVersion, tx)
+		if marshalErr == nil && len(encoded) < len(txBytes) && bytes.HasPrefix(txBytes, encoded) {
+			return nil, fmt.Errorf("%w: %d bytes follow the tx", errTxTrailingBytes, len(txBytes)-len(encoded))
+		}
+		return nil, fmt.Errorf("%w: %v", errTxTruncated, err)
+	}
+	if err := tx.Sign(Codec, nil); err != nil {
+		return nil, fmt.Errorf("problem initializing transaction: %w", err)
+	}
+	return tx, nil
+}
+
 // BlockFeeContribution calculates how much AVAX towards the block fee contribution was paid
 // for via this transaction denominated in [avaxAssetID] with [baseFee] used to calculate the
 // cost of this transaction. This function also returns the [gasUsed] by the
-// transaction for inclusion in the [baseFee] algorithm.
-func (tx *Tx) BlockFeeContribution(avaxAssetID ids.ID, baseFee *big.Int) (*big.Int, *big.Int, error) {
+// transaction under [rules] for inclusion in the [baseFee] algorithm.
+func (tx *Tx) BlockFeeContribution(avaxAssetID ids.ID, baseFee *big.Int, rules params.Rules) (*big.Int, *big.Int, error) {
 	if baseFee == nil {
 		return nil, nil, errNilBaseFee
 	}
 	if baseFee.Cmp(common.Big0) <= 0 {
 		return nil, nil, fmt.Errorf("cannot calculate tip with base fee %d <= 0", baseFee)
 	}
-	gasUsed, err := tx.GasUsed()
+	gasUsed, err := tx.GasUsed(rules)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -186,8 +424,49 @@ func (tx *Tx) BlockFeeContribution(avaxAssetID ids.ID, baseFee *big.Int) (*big.I
 
 	// Calculate the amount of AVAX that has been burned above the required fee denominated
 	// in C-Chain native 18 decimal places
-	blockFeeContribution := new(big.Int).Mul(new(big.Int).SetUint64(excessBurned), x2cRate)
-	return blockFeeContribution, new(big.Int).SetUint64(gasUsed), nil
+	return XtoC(excessBurned), new(big.Int).SetUint64(gasUsed), nil
+}
+
+// EffectiveFee returns the amount of [avaxAssetID] burned by [tx] and the
+// resulting fee paid per unit of gas under [rules], which is the price the
+// mempool orders txs by.
+func (tx *Tx) EffectiveFee(avaxAssetID ids.ID, rules params.Rules) (uint64, uint64, error) {
+	gasUsed, err := tx.GasUsed(rules)
+	if err != nil {
+		return 0, 0, err
+	}
+	if gasUsed == 0 {
+		return 0, 0, errNoGasUsed
+	}
+	burned, err := tx.Burned(avaxAssetID)
+	if err != nil {
+		return 0, 0, err
+	}
+	return burned, burned / gasUsed, nil
+}
+
+// creditAtomicFee credits the AVAX burned by [tx] to the atomic fee recipient
+// configured in [rules]. If no recipient is configured, the fee stays burned.
+func creditAtomicFee(ctx *snow.Context, tx UnsignedAtomicTx, state *state.StateDB, rules params.Rules) error {
+	if rules.AtomicFeeRecipient == nil {
+		return nil
+	}
+	burned, err := tx.Burned(ctx.AVAXAssetID)
+	if err != nil {
+		return err
+	}
+	fee := XtoC(burned)
+	if overflowsBalance(state.GetBalance(*rules.AtomicFeeRecipient), fee) {
+		return fmt.Errorf("%w: crediting fee %s to %s", errBalanceOverflow, fee, *rules.AtomicFeeRecipient)
+	}
+	state.AddBalance(*rules.AtomicFeeRecipient, fee)
+	return nil
+}
+
+// overflowsBalance returns true if crediting [amount] to [balance] would
+// exceed the maximum balance representable in the state (2^256 - 1).
+func overflowsBalance(balance, amount *big.Int) bool {
+	return new(big.Int).Add(balance, amount).BitLen() > 256
 }
 
 // innerSortInputsAndSigners implements sort.Interface for EVMInput
@@ -259,6 +538,49 @@ func IsSortedAndUniqueEVMOutputs(outputs []EVMOutput) bool {
 	return utils.IsSortedAndUnique(&innerSortEVMOutputs{outputs: outputs})
 }
 
+// evmOutputKey identifies the account balance credited by an EVMOutput
+type evmOutputKey struct {
+	address common.Address
+	assetID ids.ID
+}
+
+// verifyUniqueEVMOutputs returns an error if more than one of [outputs]
+// credits the same asset to the same address.
+func verifyUniqueEVMOutputs(outputs []EVMOutput) error {
+	seen := make(map[evmOutputKey]struct{}, len(outputs))
+	for _, out := range outputs {
+		key := evmOutputKey{address: out.Address, assetID: out.AssetID}
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("%w: %s credited with %s more than once", errDuplicateOutput, out.Address.Hex(), out.AssetID)
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
+
+// mergeEVMOutputs returns [outputs] with all the outputs crediting the same
+// asset to the same address combined into a single output. The outputs keep
+// the order in which each address and asset pair first appears.
+func mergeEVMOutputs(outputs []EVMOutput) ([]EVMOutput, error) {
+	indices := make(map[evmOutputKey]int, len(outputs))
+	merged := make([]EVMOutput, 0, len(outputs))
+	for _, out := range outputs {
+		key := evmOutputKey{address: out.Address, assetID: out.AssetID}
+		i, ok := indices[key]
+		if !ok {
+			indices[key] = len(merged)
+			merged = append(merged, out)
+			continue
+		}
+		amount, err := math.Add64(merged[i].Amount, out.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge outputs crediting %s to %s: %w", out.AssetID, out.Address.Hex(), err)
+		}
+		merged[i].Amount = amount
+	}
+	return merged, nil
+}
+
 // calculates the amount of AVAX that must be burned by an atomic transaction
 // that consumes [cost] at [baseFee].
 func calculateDynamicFee(cost uint64, baseFee *big.Int) (uint64, error) {
@@ -4,13 +4,25 @@
 package evm
 
 import (
+	"bytes"
+	"errors"
 	"math/big"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/flare-foundation/coreth/params"
 	"github.com/flare-foundation/flare/chains/atomic"
+	"github.com/flare-foundation/flare/ids"
 	"github.com/flare-foundation/flare/snow"
+	"github.com/flare-foundation/flare/utils/crypto"
+	"github.com/flare-foundation/flare/utils/hashing"
+	"github.com/flare-foundation/flare/utils/wrappers"
+	"github.com/flare-foundation/flare/vms/components/avax"
+	"github.com/flare-foundation/flare/vms/components/verify"
+	"github.com/flare-foundation/flare/vms/secp256k1fx"
 )
 
 func TestCalculateDynamicFee(t *testing.T) {
@@ -132,7 +144,7 @@ func executeTxTest(t *testing.T, test atomicTxTest) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := tx.UnsignedAtomicTx.EVMStateTransfer(vm.ctx, sdb); len(test.evmStateTransferErr) == 0 && err != nil {
+	if err := tx.UnsignedAtomicTx.EVMStateTransfer(vm.ctx, sdb, rules); len(test.evmStateTransferErr) == 0 && err != nil {
 		t.Fatalf("EVMStateTransfer failed unexpectedly due to: %s", err)
 	} else if len(test.evmStateTransferErr) != 0 {
 		if err == nil {
@@ -177,3 +189,550 @@ func executeTxTest(t *testing.T, test atomicTxTest) {
 		test.checkState(t, vm)
 	}
 }
+
+func TestParseTx(t *testing.T) {
+	ctx := NewContext()
+	tx := &Tx{UnsignedAtomicTx: &UnsignedImportTx{
+		NetworkID:    ctx.NetworkID,
+		BlockchainID: ctx.ChainID,
+		SourceChain:  ctx.XChainID,
+		ImportedInputs: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+			Asset:  avax.Asset{ID: ctx.AVAXAssetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   1,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}},
+		Outs: []EVMOutput{{
+			Address: testEthAddrs[0],
+			Amount:  1,
+			AssetID: ctx.AVAXAssetID,
+		}},
+	}}
+	if err := tx.Sign(Codec, nil); err != nil {
+		t.Fatal(err)
+	}
+	txBytes := tx.Bytes()
+
+	parsedTx, err := ParseTx(txBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse valid tx: %s", err)
+	}
+	if parsedTx.ID() != tx.ID() {
+		t.Fatalf("Expected parsed tx ID %s, found %s", tx.ID(), parsedTx.ID())
+	}
+
+	unknownTypeBytes := make([]byte, len(txBytes))
+	copy(unknownTypeBytes, txBytes)
+	// The type ID of the unsigned tx immediately follows the 2 byte codec version.
+	copy(unknownTypeBytes[2:6], []byte{0xff, 0xff, 0xff, 0xff})
+	// A registered type that is not an unsigned tx is unknown as well
+	nonTxTypeBytes := make([]byte, len(txBytes))
+	copy(nonTxTypeBytes, txBytes)
+	copy(nonTxTypeBytes[2:6], []byte{0x00, 0x00, 0x00, 0x05})
+
+	tests := map[string]struct {
+		bytes       []byte
+		expectedErr error
+	}{
+		"empty": {
+			bytes:       nil,
+			expectedErr: errTxTruncated,
+		},
+		"truncated": {
+			bytes:       txBytes[:len(txBytes)-1],
+			expectedErr: errTxTruncated,
+		},
+		"unknown type ID": {
+			bytes:       unknownTypeBytes,
+			expectedErr: errTxUnknownTypeID,
+		},
+		"non-tx type ID": {
+			bytes:       nonTxTypeBytes,
+			expectedErr: errTxUnknownTypeID,
+		},
+		"trailing bytes": {
+			bytes:       append(append([]byte{}, txBytes...), 0x00),
+			expectedErr: errTxTrailingBytes,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseTx(test.bytes)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("Expected error %s, found %v", test.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyAtomicTxOrder(t *testing.T) {
+	txs := make([]*Tx, 3)
+	for i := range txs {
+		txs[i] = &Tx{UnsignedAtomicTx: &UnsignedImportTx{NetworkID: uint32(i)}}
+		if err := txs[i].Sign(Codec, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		iID, jID := txs[i].ID(), txs[j].ID()
+		return bytes.Compare(iID[:], jID[:]) < 0
+	})
+	unordered := []*Tx{txs[1], txs[0], txs[2]}
+	duplicated := []*Tx{txs[0], txs[0]}
+
+	tests := map[string]struct {
+		txs         []*Tx
+		rules       params.Rules
+		expectedErr error
+	}{
+		"ordered": {
+			txs:   txs,
+			rules: apricotRulesPhase5,
+		},
+		"unordered": {
+			txs:         unordered,
+			rules:       apricotRulesPhase5,
+			expectedErr: errAtomicTxsNotSorted,
+		},
+		"duplicated": {
+			txs:         duplicated,
+			rules:       apricotRulesPhase5,
+			expectedErr: errAtomicTxsNotSorted,
+		},
+		"unordered before Apricot Phase 5": {
+			txs:   unordered,
+			rules: apricotRulesPhase4,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := verifyAtomicTxOrder(test.txs, test.rules); !errors.Is(err, test.expectedErr) {
+				t.Fatalf("Expected %v, found %v", test.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestTxVerifyCredentialCount(t *testing.T) {
+	ctx := NewContext()
+	importTx := &Tx{UnsignedAtomicTx: &UnsignedImportTx{
+		NetworkID:    testNetworkID,
+		BlockchainID: testCChainID,
+		SourceChain:  testXChainID,
+		ImportedInputs: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+			Asset:  avax.Asset{ID: testAvaxAssetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   10,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}},
+		Outs: []EVMOutput{{
+			Address: testEthAddrs[0],
+			Amount:  10,
+			AssetID: testAvaxAssetID,
+		}},
+	}}
+	exportTx := &Tx{UnsignedAtomicTx: &UnsignedExportTx{
+		NetworkID:        testNetworkID,
+		BlockchainID:     testCChainID,
+		DestinationChain: testXChainID,
+		Ins: []EVMInput{{
+			Address: testEthAddrs[0],
+			Amount:  10,
+			AssetID: testAvaxAssetID,
+		}},
+		ExportedOutputs: []*avax.TransferableOutput{{
+			Asset: avax.Asset{ID: testAvaxAssetID},
+			Out: &secp256k1fx.TransferOutput{
+				Amt: 10,
+				OutputOwners: secp256k1fx.OutputOwners{
+					Threshold: 1,
+					Addrs:     []ids.ShortID{testShortIDAddrs[0]},
+				},
+			},
+		}},
+	}}
+
+	for name, tx := range map[string]*Tx{"import": importTx, "export": exportTx} {
+		t.Run(name, func(t *testing.T) {
+			// The unsigned tx is well-formed, but has no credential for its
+			// input.
+			if err := tx.UnsignedAtomicTx.Verify(testXChainID, ctx, apricotRulesPhase3); err != nil {
+				t.Fatal(err)
+			}
+			if err := tx.Verify(testXChainID, ctx, apricotRulesPhase3); !errors.Is(err, errWrongCredentialCount) {
+				t.Fatalf("Expected %s without credentials, found %v", errWrongCredentialCount, err)
+			}
+
+			if err := tx.Sign(Codec, [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := tx.Verify(testXChainID, ctx, apricotRulesPhase3); err != nil {
+				t.Fatalf("Failed to verify tx with one credential per input: %s", err)
+			}
+
+			tx.Creds = append(tx.Creds, tx.Creds[0])
+			if err := tx.Verify(testXChainID, ctx, apricotRulesPhase3); !errors.Is(err, errWrongCredentialCount) {
+				t.Fatalf("Expected %s with extra credentials, found %v", errWrongCredentialCount, err)
+			}
+		})
+	}
+}
+
+func TestTxSize(t *testing.T) {
+	tx := &Tx{UnsignedAtomicTx: &UnsignedImportTx{
+		NetworkID:    testNetworkID,
+		BlockchainID: testCChainID,
+		SourceChain:  testXChainID,
+		ImportedInputs: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+			Asset:  avax.Asset{ID: testAvaxAssetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   10,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}},
+		Outs: []EVMOutput{{
+			Address: testEthAddrs[0],
+			Amount:  10,
+			AssetID: testAvaxAssetID,
+		}},
+	}}
+
+	// The size of a tx that was never initialized is that of its encoding.
+	txBytes, err := Codec.Marshal(codecVersion, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err := tx.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != len(txBytes) {
+		t.Fatalf("Expected size %d of uninitialized tx, found %d", len(txBytes), size)
+	}
+
+	// Signing adds a credential, which the size reflects.
+	if err := tx.Sign(Codec, [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}}); err != nil {
+		t.Fatal(err)
+	}
+	size, err = tx.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != len(tx.Bytes()) {
+		t.Fatalf("Expected size %d of signed tx, found %d", len(tx.Bytes()), size)
+	}
+
+	parsedTx, err := ParseTx(tx.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, err := parsedTx.Size(); err != nil || size != len(tx.Bytes()) {
+		t.Fatalf("Expected size %d of parsed tx, found %d (err: %v)", len(tx.Bytes()), size, err)
+	}
+}
+
+func TestTxGasUsed(t *testing.T) {
+	tx := &Tx{UnsignedAtomicTx: &UnsignedImportTx{
+		NetworkID:    testNetworkID,
+		BlockchainID: testCChainID,
+		SourceChain:  testXChainID,
+		ImportedInputs: []*avax.TransferableInput{{
+			UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+			Asset:  avax.Asset{ID: testAvaxAssetID},
+			In: &secp256k1fx.TransferInput{
+				Amt:   10,
+				Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+			},
+		}},
+		Outs: []EVMOutput{{
+			Address: testEthAddrs[0],
+			Amount:  10,
+			AssetID: testAvaxAssetID,
+		}},
+	}}
+	if err := tx.Sign(Codec, [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Before Apricot Phase 5, only the unsigned bytes are charged.
+	gasUsed, err := tx.GasUsed(apricotRulesPhase4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedGasUsed, err := tx.UnsignedAtomicTx.GasUsed(len(tx.UnsignedBytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gasUsed != expectedGasUsed {
+		t.Fatalf("Expected gas used %d before Apricot Phase 5, found %d", expectedGasUsed, gasUsed)
+	}
+
+	// As of Apricot Phase 5, the credentials are charged as well.
+	apricotPhase5GasUsed, err := tx.GasUsed(apricotRulesPhase5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialsGas := calcBytesCost(len(tx.Bytes()) - len(tx.UnsignedBytes()))
+	if apricotPhase5GasUsed != gasUsed+credentialsGas {
+		t.Fatalf("Expected gas used %d as of Apricot Phase 5, found %d", gasUsed+credentialsGas, apricotPhase5GasUsed)
+	}
+	if credentialsGas != wrappers.IntLen*TxBytesGas+CredentialGas {
+		t.Fatalf("Expected the credential of a single input to cost %d, found %d", wrappers.IntLen*TxBytesGas+CredentialGas, credentialsGas)
+	}
+}
+
+func TestTxVerifyMaxSize(t *testing.T) {
+	ctx := NewContext()
+	// newTx returns an import tx consuming [numInputs] UTXOs, with a single
+	// signature credential per input.
+	newTx := func(numInputs int) *Tx {
+		utx := &UnsignedImportTx{
+			NetworkID:    testNetworkID,
+			BlockchainID: testCChainID,
+			SourceChain:  testXChainID,
+			Outs: []EVMOutput{{
+				Address: testEthAddrs[0],
+				Amount:  10,
+				AssetID: testAvaxAssetID,
+			}},
+		}
+		tx := &Tx{UnsignedAtomicTx: utx}
+		for i := 0; i < numInputs; i++ {
+			utx.ImportedInputs = append(utx.ImportedInputs, &avax.TransferableInput{
+				UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+				Asset:  avax.Asset{ID: testAvaxAssetID},
+				In: &secp256k1fx.TransferInput{
+					Amt:   10,
+					Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+				},
+			})
+			tx.Creds = append(tx.Creds, &secp256k1fx.Credential{
+				Sigs: make([][crypto.SECP256K1RSigLen]byte, 1),
+			})
+		}
+		avax.SortTransferableInputs(utx.ImportedInputs)
+		return tx
+	}
+
+	tx := newTx(512)
+	size, err := tx.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size > params.ApricotPhase5AtomicTxMaxSize {
+		t.Fatalf("Expected tx of %d bytes not to exceed the maximum size", size)
+	}
+	if err := tx.Verify(testXChainID, ctx, apricotRulesPhase5); err != nil {
+		t.Fatalf("Failed to verify tx of %d bytes: %s", size, err)
+	}
+
+	tx = newTx(1024)
+	size, err = tx.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size <= params.ApricotPhase5AtomicTxMaxSize {
+		t.Fatalf("Expected tx of %d bytes to exceed the maximum size", size)
+	}
+	// The size is not limited before Apricot Phase 5.
+	if err := tx.Verify(testXChainID, ctx, apricotRulesPhase4); err != nil {
+		t.Fatalf("Failed to verify tx of %d bytes before Apricot Phase 5: %s", size, err)
+	}
+	if err := tx.Verify(testXChainID, ctx, apricotRulesPhase5); !errors.Is(err, errAtomicTxTooLarge) {
+		t.Fatalf("Expected %s for tx of %d bytes, found %v", errAtomicTxTooLarge, size, err)
+	}
+}
+
+func TestSigningBytes(t *testing.T) {
+	importAmount := uint64(50000000)
+	_, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase2, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: importAmount,
+	})
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	parent := vm.LastAcceptedBlockInternal().(*Block)
+	rules := vm.currentRules()
+
+	// signExternally signs [utx] over its signing bytes with [key], as an
+	// offline signer would, and assembles the signed tx.
+	signExternally := func(utx UnsignedAtomicTx, unsignedBytes []byte, key *crypto.PrivateKeySECP256K1R) *Tx {
+		sig, err := key.SignHash(hashing.ComputeHash256(unsignedBytes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cred := &secp256k1fx.Credential{Sigs: make([][crypto.SECP256K1RSigLen]byte, 1)}
+		copy(cred.Sigs[0][:], sig)
+		tx := &Tx{UnsignedAtomicTx: utx, Creds: []verify.Verifiable{cred}}
+		signedBytes, err := Codec.Marshal(codecVersion, tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.Initialize(unsignedBytes, signedBytes)
+		return tx
+	}
+
+	tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	utx := &UnsignedImportTx{}
+	*utx = *tx.UnsignedAtomicTx.(*UnsignedImportTx)
+	unsignedBytes, err := utx.SigningBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unsignedBytes, tx.UnsignedBytes()) {
+		t.Fatal("Expected the signing bytes to match the unsigned bytes of the tx")
+	}
+
+	signedTx := signExternally(utx, unsignedBytes, testKeys[0])
+	if err := utx.SemanticVerify(vm, signedTx, parent, initialBaseFee, rules); err != nil {
+		t.Fatalf("Failed to verify import tx signed over its signing bytes: %s", err)
+	}
+
+	// A signature by a key that doesn't own the UTXO fails verification.
+	signedTx = signExternally(utx, unsignedBytes, testKeys[1])
+	if err := utx.SemanticVerify(vm, signedTx, parent, initialBaseFee, rules); err == nil {
+		t.Fatal("Expected import tx signed by the wrong key to fail verification")
+	}
+
+	genesisJSON, err := fundAddressByGenesis([]common.Address{testEthAddrs[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, exportVM, _, _, _ := GenesisVM(t, true, genesisJSON, "", "")
+	defer func() {
+		if err := exportVM.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	tx, err = exportVM.newExportTx(exportVM.ctx.AVAXAssetID, 1000, exportVM.ctx.XChainID, testShortIDAddrs[0], initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exportTx := &UnsignedExportTx{}
+	*exportTx = *tx.UnsignedAtomicTx.(*UnsignedExportTx)
+	unsignedBytes, err = exportTx.SigningBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unsignedBytes, tx.UnsignedBytes()) {
+		t.Fatal("Expected the signing bytes to match the unsigned bytes of the tx")
+	}
+
+	signedTx = signExternally(exportTx, unsignedBytes, testKeys[0])
+	parent = exportVM.LastAcceptedBlockInternal().(*Block)
+	if err := exportTx.SemanticVerify(exportVM, signedTx, parent, initialBaseFee, exportVM.currentRules()); err != nil {
+		t.Fatalf("Failed to verify export tx signed over its signing bytes: %s", err)
+	}
+}
+
+func TestEncodeAtomicTxs(t *testing.T) {
+	newTx := func(amount uint64) *Tx {
+		tx := &Tx{UnsignedAtomicTx: &UnsignedImportTx{
+			NetworkID:    testNetworkID,
+			BlockchainID: testCChainID,
+			SourceChain:  testXChainID,
+			ImportedInputs: []*avax.TransferableInput{{
+				UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+				Asset:  avax.Asset{ID: testAvaxAssetID},
+				In: &secp256k1fx.TransferInput{
+					Amt:   amount,
+					Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+				},
+			}},
+			Outs: []EVMOutput{{
+				Address: testEthAddrs[0],
+				Amount:  amount,
+				AssetID: testAvaxAssetID,
+			}},
+		}}
+		if err := tx.Sign(Codec, [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}}); err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+
+	for _, txs := range [][]*Tx{
+		nil,
+		{newTx(1)},
+		{newTx(1), newTx(2)},
+	} {
+		data, err := EncodeAtomicTxs(txs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeAtomicTxs(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(decoded) != len(txs) {
+			t.Fatalf("Expected %d decoded txs, found %d", len(txs), len(decoded))
+		}
+		for i, tx := range txs {
+			if decoded[i].ID() != tx.ID() || !bytes.Equal(decoded[i].Bytes(), tx.Bytes()) {
+				t.Fatalf("Expected decoded tx %d to be %s, found %s", i, tx.ID(), decoded[i].ID())
+			}
+			if !bytes.Equal(decoded[i].UnsignedBytes(), tx.UnsignedBytes()) {
+				t.Fatalf("Expected decoded tx %d to be initialized with its unsigned bytes", i)
+			}
+		}
+	}
+
+	if _, err := DecodeAtomicTxs([]byte{0, 0, 1}); err == nil {
+		t.Fatal("Expected decoding malformed data to fail")
+	}
+}
+
+// unserializedFields returns the names of the exported fields of the struct
+// type [typ] that the codec would skip because they lack a serialize tag.
+// The embedded avax.Metadata is only used to cache bytes, so it is skipped.
+func unserializedFields(typ reflect.Type) []string {
+	var missing []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" || field.Type == reflect.TypeOf(avax.Metadata{}) {
+			continue
+		}
+		if field.Tag.Get("serialize") != "true" {
+			missing = append(missing, field.Name)
+		}
+	}
+	return missing
+}
+
+func TestAtomicTxFieldsSerialized(t *testing.T) {
+	for _, v := range []interface{}{
+		UnsignedImportTx{},
+		UnsignedExportTx{},
+		UnsignedSponsoredImportTx{},
+		UnsignedContractImportTx{},
+		EVMInput{},
+		EVMOutput{},
+		Tx{},
+	} {
+		typ := reflect.TypeOf(v)
+		if missing := unserializedFields(typ); len(missing) != 0 {
+			t.Errorf("%s has exported fields without a serialize tag: %v", typ.Name(), missing)
+		}
+	}
+
+	type untagged struct {
+		avax.Metadata
+		Tagged   uint64 `serialize:"true"`
+		Untagged uint64
+		skipped  uint64
+	}
+	if missing := unserializedFields(reflect.TypeOf(untagged{})); !reflect.DeepEqual(missing, []string{"Untagged"}) {
+		t.Fatalf("Expected only the untagged field to be reported, found %v", missing)
+	}
+}
@@ -4,13 +4,16 @@
 package evm
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/flare-foundation/coreth/accounts/keystore"
 	"github.com/flare-foundation/flare/database/encdb"
 	"github.com/flare-foundation/flare/ids"
 	"github.com/flare-foundation/flare/utils/crypto"
+	"github.com/flare-foundation/flare/vms/secp256k1fx"
 )
 
 // Key in the database whose corresponding value is the list of
@@ -26,6 +29,23 @@ type user struct {
 	secpFactory *crypto.FactorySECP256K1R
 	// This user's database, acquired from the keystore
 	db *encdb.Database
+	// The password of this user, which decrypts keys stored with scrypt
+	password string
+	// The scrypt cost parameters of new keys. If [scryptN] is 0, keys are
+	// only protected by the encryption of [db].
+	scryptN, scryptP int
+}
+
+// newUser returns the user owning [db], which was opened with [password],
+// storing new keys with the keystore scrypt parameters of the VM config
+func (vm *VM) newUser(db *encdb.Database, password string) user {
+	return user{
+		secpFactory: &vm.secpFactory,
+		db:          db,
+		password:    password,
+		scryptN:     vm.config.KeystoreScryptN,
+		scryptP:     vm.config.KeystoreScryptP,
+	}
 }
 
 // Get the addresses controlled by this user
@@ -80,7 +100,17 @@ func (u *user) putAddress(privKey *crypto.PrivateKeySECP256K1R) error {
 		return nil
 	}
 
-	if err := u.db.Put(address.Bytes(), privKey.Bytes()); err != nil { // Address --> private key
+	keyBytes := privKey.Bytes()
+	if u.scryptN != 0 {
+		encryptedKey, err := keystore.EncryptDataV3(keyBytes, []byte(u.password), u.scryptN, u.scryptP)
+		if err != nil {
+			return err
+		}
+		if keyBytes, err = json.Marshal(encryptedKey); err != nil {
+			return err
+		}
+	}
+	if err := u.db.Put(address.Bytes(), keyBytes); err != nil { // Address --> private key
 		return err
 	}
 
@@ -117,6 +147,16 @@ func (u *user) getKey(address common.Address) (*crypto.PrivateKeySECP256K1R, err
 	if err != nil {
 		return nil, err
 	}
+	// Keys stored with scrypt are encoded as JSON, rather than as the raw key
+	if len(bytes) != crypto.SECP256K1RSKLen {
+		var encryptedKey keystore.CryptoJSON
+		if err := json.Unmarshal(bytes, &encryptedKey); err != nil {
+			return nil, fmt.Errorf("couldn't parse encrypted key: %w", err)
+		}
+		if bytes, err = keystore.DecryptDataV3(encryptedKey, u.password); err != nil {
+			return nil, fmt.Errorf("couldn't decrypt key: %w", err)
+		}
+	}
 	sk, err := u.secpFactory.ToPrivateKey(bytes)
 	if err != nil {
 		return nil, err
@@ -143,3 +183,33 @@ func (u *user) getKeys() ([]*crypto.PrivateKeySECP256K1R, error) {
 	}
 	return keys, nil
 }
+
+// signedTx returns true iff at least one of the signatures of [tx] was made
+// by a key controlled by this user
+func (u *user) signedTx(tx *Tx) (bool, error) {
+	for _, credIntf := range tx.Creds {
+		cred, ok := credIntf.(*secp256k1fx.Credential)
+		if !ok {
+			return false, fmt.Errorf("expected *secp256k1fx.Credential but got %T", credIntf)
+		}
+		for _, sig := range cred.Sigs {
+			pubKeyIntf, err := u.secpFactory.RecoverPublicKey(tx.UnsignedBytes(), sig[:])
+			if err != nil {
+				return false, err
+			}
+			pubKey, ok := pubKeyIntf.(*crypto.PublicKeySECP256K1R)
+			if !ok {
+				// This should never happen
+				return false, fmt.Errorf("expected *crypto.PublicKeySECP256K1R but got %T", pubKeyIntf)
+			}
+			controls, err := u.controlsAddress(PublicKeyToEthAddress(pubKey))
+			if err != nil {
+				return false, err
+			}
+			if controls {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -14,11 +14,13 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	coreth "github.com/flare-foundation/coreth/chain"
 	"github.com/flare-foundation/coreth/consensus/dummy"
 	"github.com/flare-foundation/coreth/core"
+	"github.com/flare-foundation/coreth/core/rawdb"
 	"github.com/flare-foundation/coreth/core/state"
 	"github.com/flare-foundation/coreth/core/types"
 	"github.com/flare-foundation/coreth/eth/ethconfig"
@@ -27,6 +29,7 @@ import (
 	"github.com/flare-foundation/flare/database/versiondb"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/flare-foundation/coreth/rpc"
@@ -89,6 +92,13 @@ const (
 	decidedCacheSize    = 100
 	missingCacheSize    = 50
 	unverifiedCacheSize = 50
+
+	// The total supply is cached every [supplyCheckpointInterval] blocks
+	supplyCheckpointInterval = 1024
+
+	// Max time from current time the timestamp of a built block may be
+	// ahead, which leaves room for the clocks of other nodes to be behind
+	maxBlockTimestampDrift = maxFutureBlockTime / 2
 )
 
 // Define the API endpoints for the VM
@@ -105,9 +115,16 @@ var (
 	acceptedPrefix         = []byte("snowman_accepted")
 	ethDBPrefix            = []byte("ethdb")
 	atomicTxPrefix         = []byte("atomicTxDB")
+	atomicTxAddressPrefix  = []byte("atomicTxAddressDB")
+	exportedUTXOPrefix     = []byte("exportedUTXODB")
 	pruneRejectedBlocksKey = []byte("pruned_rejected_blocks")
 )
 
+var (
+	atomicTxGasUsedCounter  = metrics.NewRegisteredCounter("atomic/txs/gas", nil)
+	atomicTxAcceptedCounter = metrics.NewRegisteredCounter("atomic/txs/accepted", nil)
+)
+
 var (
 	errEmptyBlock                     = errors.New("empty block")
 	errUnsupportedFXs                 = errors.New("unsupported feature extensions")
@@ -116,15 +133,22 @@ var (
 	errInsufficientAtomicTxFee        = errors.New("atomic tx fee too low for atomic mempool")
 	errAssetIDMismatch                = errors.New("asset IDs in the input don't match the utxo")
 	errNoImportInputs                 = errors.New("tx has no imported inputs")
+	errOutputsExceedInputs            = errors.New("tx outputs exceed imported inputs")
+	errExportImbalance                = errors.New("export tx inputs do not cover outputs plus fee")
 	errInputsNotSortedUnique          = errors.New("inputs not sorted and unique")
 	errPublicKeySignatureMismatch     = errors.New("signature doesn't match public key")
 	errWrongChainID                   = errors.New("tx has wrong chain ID")
 	errInsufficientFunds              = errors.New("insufficient funds")
 	errNoExportOutputs                = errors.New("tx has no export outputs")
+	errZeroExportAmount               = errors.New("export amount is zero")
 	errOutputsNotSorted               = errors.New("tx outputs not sorted")
 	errOutputsNotSortedUnique         = errors.New("outputs not sorted and unique")
 	errOverflowExport                 = errors.New("overflow when computing export amount + txFee")
+	errOverflowImport                 = errors.New("overflow when summing imported inputs")
+	errOverflowCredit                 = errors.New("overflow when summing credited outputs")
+	errZeroImportInput                = errors.New("imported input has zero amount")
 	errInvalidNonce                   = errors.New("invalid nonce")
+	errNonSequentialNonces            = errors.New("inputs from the same address have different nonces")
 	errConflictingAtomicInputs        = errors.New("invalid block due to conflicting atomic inputs")
 	errUnclesUnsupported              = errors.New("uncles unsupported")
 	errTxHashMismatch                 = errors.New("txs hash does not match header")
@@ -136,12 +160,40 @@ var (
 	errInvalidExtDataHash             = errors.New("invalid extra data hash")
 	errHeaderExtraDataTooBig          = errors.New("header extra data too big")
 	errInsufficientFundsForFee        = errors.New("insufficient AVAX funds to pay transaction fee")
+	errInvalidFeeOutput               = errors.New("invalid fee-paying output")
+	errInvalidImportSplit             = errors.New("import splits must pay AVAX")
 	errNoEVMOutputs                   = errors.New("tx has no EVM outputs")
 	errNilBaseFeeApricotPhase3        = errors.New("nil base fee is invalid after apricotPhase3")
 	errNilExtDataGasUsedApricotPhase4 = errors.New("nil extDataGasUsed is invalid after apricotPhase4")
 	errNilBlockGasCostApricotPhase4   = errors.New("nil blockGasCost is invalid after apricotPhase4")
 	errConflictingAtomicTx            = errors.New("conflicting atomic tx present")
 	errTooManyAtomicTx                = errors.New("too many atomic tx")
+	errConflictingAtomicTxBatch       = errors.New("atomic tx batch contains conflicting txs")
+	errTooManyAtomicInputs            = errors.New("tx has too many inputs")
+	errTooManyAtomicOutputs           = errors.New("tx has too many outputs")
+	errExportAmountTooLarge           = errors.New("export amount exceeds the maximum")
+	errNonceMismatch                  = errors.New("input nonce does not match pending nonce")
+	errAtomicFeeTooLow                = errors.New("atomic tx fee per gas is below the minimum")
+	errIncompatibleChainConfig        = errors.New("chain config is incompatible with the accepted chain")
+	errEthForksNotNeutralized         = errors.New("chain config schedules Ethereum forks after genesis")
+	errWrongUTXOSource                = errors.New("imported UTXO did not originate on the source chain")
+	errAtomicTxsNotSorted             = errors.New("atomic txs not sorted by ID")
+	errWrongCredentialCount           = errors.New("mismatched number of inputs/credentials")
+	errAssetNotAllowed                = errors.New("asset is not allowed in atomic txs")
+	errAtomicTxDisabled               = errors.New("atomic txs are disabled")
+	errChainIDMismatch                = errors.New("VM chain ID does not match chain config chain ID")
+	errSponsoredImportNotActive       = errors.New("sponsored import txs are not active")
+	errSponsorAssetNotAVAX            = errors.New("sponsor must pay the fee in AVAX")
+	errNegativeAmount                 = errors.New("amount is negative")
+	errAmountOverflow                 = errors.New("amount overflows uint64 nAVAX")
+	errBlockTimestampTooFarAhead      = errors.New("next block timestamp is too far ahead of the current time")
+	errForkNotReached                 = errors.New("next block would activate a fork ahead of the current time")
+	errAtomicTxTooLarge               = errors.New("atomic tx is too large")
+	errContractImportNotActive        = errors.New("contract import txs are not active")
+	errInvalidContractImportOutput    = errors.New("contract import must credit only AVAX to the contract")
+	errNotAContract                   = errors.New("address has no contract code")
+	errContractCallFailed             = errors.New("contract call failed")
+	errContractCallLogs               = errors.New("contract import call emitted logs")
 	defaultLogLevel                   = log.LvlDebug
 )
 
@@ -164,11 +216,12 @@ type VM struct {
 
 	config Config
 
-	chainID     *big.Int
-	networkID   uint64
-	genesisHash common.Hash
-	chain       *coreth.ETHChain
-	chainConfig *params.ChainConfig
+	chainID          *big.Int
+	networkID        uint64
+	genesisHash      common.Hash
+	genesisTimestamp uint64
+	chain            *coreth.ETHChain
+	chainConfig      *params.ChainConfig
 	// [db] is the VM's current database managed by ChainState
 	db *versiondb.Database
 	// [chaindb] is the database supplied to the Ethereum backend
@@ -178,6 +231,12 @@ type VM struct {
 	acceptedBlockDB database.Database
 	// [acceptedAtomicTxDB] maintains an index of accepted atomic txs.
 	acceptedAtomicTxDB database.Database
+	// [atomicTxAddressDB] indexes accepted atomic txs by the EVM addresses
+	// they credit or debit.
+	atomicTxAddressDB database.Database
+	// [exportedUTXODB] maps the IDs of the UTXOs produced by accepted export
+	// txs to the tx that produced them.
+	exportedUTXODB database.Database
 
 	builder *blockBuilder
 
@@ -194,6 +253,22 @@ type VM struct {
 	fx          secp256k1fx.Fx
 	secpFactory crypto.FactorySECP256K1R
 
+	// [allowedAtomicAssets] is the set of assets, other than AVAX, accepted in
+	// atomic txs issued to the mempool. If empty, every asset is accepted.
+	allowedAtomicAssets ids.Set
+
+	// [atomicTxGasUsed] and [atomicTxsAccepted] track the atomic transactions
+	// accepted since the VM started. They must be accessed atomically.
+	atomicTxGasUsed   uint64
+	atomicTxsAccepted uint64
+
+	// [supplyCheckpoints] caches the total supply, in wei, at every multiple
+	// of [supplyCheckpointInterval] computed so far, starting with the
+	// supply allocated at genesis. [supplyLock] only guards the map, so that
+	// blocks are not read from disk while holding it.
+	supplyLock        sync.Mutex
+	supplyCheckpoints map[uint64]*big.Int
+
 	// Continuous Profiler
 	profiler profiler.ContinuousProfiler
 }
@@ -263,6 +338,18 @@ func (vm *VM) Initialize(
 		return errUnsupportedFXs
 	}
 
+	if err := vm.config.validateKeystoreKDF(); err != nil {
+		return err
+	}
+
+	for _, assetStr := range vm.config.AllowedAtomicAssets {
+		assetID, err := ids.FromString(assetStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse allowed atomic asset %q: %w", assetStr, err)
+		}
+		vm.allowedAtomicAssets.Add(assetID)
+	}
+
 	vm.shutdownChan = make(chan struct{}, 1)
 	vm.ctx = ctx
 	baseDB := dbManager.Current().Database
@@ -272,10 +359,19 @@ func (vm *VM) Initialize(
 	vm.db = versiondb.New(baseDB)
 	vm.acceptedBlockDB = prefixdb.New(acceptedPrefix, vm.db)
 	vm.acceptedAtomicTxDB = prefixdb.New(atomicTxPrefix, vm.db)
+	vm.atomicTxAddressDB = prefixdb.New(atomicTxAddressPrefix, vm.db)
+	vm.exportedUTXODB = prefixdb.New(exportedUTXOPrefix, vm.db)
 	g := new(core.Genesis)
 	if err := json.Unmarshal(genesisBytes, g); err != nil {
 		return err
 	}
+	genesisSupply := new(big.Int)
+	for _, account := range g.Alloc {
+		if account.Balance != nil {
+			genesisSupply.Add(genesisSupply, account.Balance)
+		}
+	}
+	vm.supplyCheckpoints = map[uint64]*big.Int{0: genesisSupply}
 
 	// Set the chain config for mainnet/fuji chain IDs
 	switch {
@@ -294,7 +390,17 @@ func (vm *VM) Initialize(
 	songbirdExtDataHashes = nil
 	flareExtDataHashes = nil
 
+	if err := prepareChainConfig(g); err != nil {
+		return err
+	}
+
 	vm.chainID = g.Config.ChainID
+	if !g.Config.EthForksNeutralized() {
+		return errEthForksNotNeutralized
+	}
+	if err := g.Config.Validate(); err != nil {
+		return fmt.Errorf("invalid chain config: %w", err)
+	}
 
 	ethConfig := ethconfig.NewDefaultConfig()
 	ethConfig.Genesis = g
@@ -311,6 +417,10 @@ func (vm *VM) Initialize(
 
 	vm.setLogLevel(logLevel)
 
+	for _, warning := range g.Config.Lint() {
+		log.Warn("chain config lint warning", "code", warning.Code, "msg", warning.Message)
+	}
+
 	// Set minimum price for mining and default gas price oracle value to the min
 	// gas price to prevent so transactions and blocks all use the correct fees
 	ethConfig.RPCGasCap = vm.config.RPCGasCap
@@ -325,6 +435,9 @@ func (vm *VM) Initialize(
 	ethConfig.SnapshotVerify = vm.config.SnapshotVerify
 
 	vm.chainConfig = g.Config
+	if err := vm.verifyChainIDConsistency(); err != nil {
+		return err
+	}
 	vm.networkID = ethConfig.NetworkId
 	vm.secpFactory = crypto.FactorySECP256K1R{Cache: cache.LRU{Size: secpFactoryCacheSize}}
 
@@ -338,7 +451,7 @@ func (vm *VM) Initialize(
 	vm.codec = Codec
 
 	// TODO: read size from settings
-	vm.mempool = NewMempool(ctx.AVAXAssetID, defaultMempoolSize)
+	vm.mempool = NewMempool(ctx.AVAXAssetID, defaultMempoolSize, vm.currentRules)
 
 	// Attempt to load last accepted block to determine if it is necessary to
 	// initialize state with the genesis block.
@@ -355,6 +468,9 @@ func (vm *VM) Initialize(
 	default:
 		lastAcceptedHash = common.BytesToHash(lastAcceptedBytes)
 	}
+	if err := vm.verifyConfigCompatibility(lastAcceptedHash); err != nil {
+		return err
+	}
 	ethChain, err := coreth.NewETHChain(&ethConfig, &nodecfg, vm.chaindb, vm.config.EthBackendSettings(), vm.createConsensusCallbacks(), lastAcceptedHash)
 	if err != nil {
 		return err
@@ -378,8 +494,11 @@ func (vm *VM) Initialize(
 
 	vm.chain.Start()
 
-	vm.genesisHash = vm.chain.GetGenesisBlock().Hash()
+	genesisBlock := vm.chain.GetGenesisBlock()
+	vm.genesisHash = genesisBlock.Hash()
+	vm.genesisTimestamp = genesisBlock.Time()
 	log.Info(fmt.Sprintf("lastAccepted = %s", lastAccepted.Hash().Hex()))
+	logPhaseActivations(log.Root(), vm.chainConfig, lastAccepted.Time())
 
 	vm.State = chain.NewState(&chain.Config{
 		DecidedCacheSize:    decidedCacheSize,
@@ -417,6 +536,113 @@ func (vm *VM) Initialize(
 	return vm.fx.Initialize(vm)
 }
 
+// logPhaseActivations logs the activation time of each scheduled Apricot
+// Phase of [config] to [logger] and whether the phase is already active at
+// [timestamp].
+func logPhaseActivations(logger log.Logger, config *params.ChainConfig, timestamp uint64) {
+	bigTimestamp := new(big.Int).SetUint64(timestamp)
+	for _, phase := range []struct {
+		name       string
+		activation *big.Int
+		isActive   func(*big.Int) bool
+	}{
+		{"Apricot Phase 1", config.ApricotPhase1BlockTimestamp, config.IsApricotPhase1},
+		{"Apricot Phase 2", config.ApricotPhase2BlockTimestamp, config.IsApricotPhase2},
+		{"Apricot Phase 3", config.ApricotPhase3BlockTimestamp, config.IsApricotPhase3},
+		{"Apricot Phase 4", config.ApricotPhase4BlockTimestamp, config.IsApricotPhase4},
+		{"Apricot Phase 5", config.ApricotPhase5BlockTimestamp, config.IsApricotPhase5},
+	} {
+		if phase.activation == nil {
+			continue
+		}
+		status := "pending"
+		if phase.isActive(bigTimestamp) {
+			status = "active"
+		}
+		logger.Info(fmt.Sprintf("%s is %s", phase.name, status),
+			"activation", time.Unix(phase.activation.Int64(), 0).UTC().Format(time.RFC3339),
+			"lastAcceptedTime", time.Unix(int64(timestamp), 0).UTC().Format(time.RFC3339),
+		)
+	}
+}
+
+// verifyConfigCompatibility verifies that the chain config does not reschedule
+// any fork that was already active at the last accepted block, identified by
+// [lastAcceptedHash], according to the chain config stored in the database.
+// It must be called before the chain is created, since creating the chain
+// overwrites the stored chain config.
+func (vm *VM) verifyConfigCompatibility(lastAcceptedHash common.Hash) error {
+	genesisHash := rawdb.ReadCanonicalHash(vm.chaindb, 0)
+	if genesisHash == (common.Hash{}) {
+		// The database is empty, so there is nothing to be compatible with.
+		return nil
+	}
+	storedConfig := rawdb.ReadChainConfig(vm.chaindb, genesisHash)
+	if storedConfig == nil {
+		return nil
+	}
+	genesisHeader := rawdb.ReadHeader(vm.chaindb, genesisHash, 0)
+	if genesisHeader == nil {
+		return fmt.Errorf("missing header of genesis block %s", genesisHash.Hex())
+	}
+	// The stored config may have been stored before it was prepared, so it is
+	// prepared the same way before the comparison. Preparing a config never
+	// changes the rules of a block.
+	storedGenesis := &core.Genesis{Config: storedConfig, Timestamp: genesisHeader.Time}
+	if err := prepareChainConfig(storedGenesis); err != nil {
+		return fmt.Errorf("invalid stored chain config: %w", err)
+	}
+	storedConfig = storedGenesis.Config
+	height := rawdb.ReadHeaderNumber(vm.chaindb, lastAcceptedHash)
+	if height == nil {
+		return fmt.Errorf("missing height of last accepted block %s", lastAcceptedHash.Hex())
+	}
+	header := rawdb.ReadHeader(vm.chaindb, lastAcceptedHash, *height)
+	if header == nil {
+		return fmt.Errorf("missing header of last accepted block %s", lastAcceptedHash.Hex())
+	}
+	if compatErr := storedConfig.CheckCompatibleAt(vm.chainConfig, *height, header.Time); compatErr != nil {
+		return fmt.Errorf("%w: %s", errIncompatibleChainConfig, compatErr)
+	}
+	return nil
+}
+
+// prepareChainConfig prepares the chain config of [g] before it is stored. If
+// [g] is a genesis of a known network, the Apricot phases it leaves unset are
+// scheduled as in the canonical config of the network. The config is then
+// normalized, and the forks scheduled before the genesis time are marked as
+// active from genesis.
+func prepareChainConfig(g *core.Genesis) error {
+	if _, err := params.ConfigForChainID(g.Config.ChainID); err == nil {
+		// MigrateChainConfig returns a copy, so the canonical config of the
+		// network is left unmodified.
+		migrated, err := params.MigrateChainConfig(g.Config, g.Config.ChainID)
+		if err != nil {
+			return err
+		}
+		g.Config = migrated
+	}
+	if err := g.Config.Normalize(); err != nil {
+		return fmt.Errorf("invalid chain config: %w", err)
+	}
+	g.Config.ReconcileWithGenesisTime(g.Timestamp)
+	return nil
+}
+
+// verifyChainIDConsistency returns an error if the chain ID used to sign and
+// verify transactions differs from the chain ID of the chain config, since
+// that would silently break signatures and replay protection.
+func (vm *VM) verifyChainIDConsistency() error {
+	var configChainID *big.Int
+	if vm.chainConfig != nil {
+		configChainID = vm.chainConfig.ChainID
+	}
+	if vm.chainID == nil || configChainID == nil || vm.chainID.Cmp(configChainID) != 0 {
+		return fmt.Errorf("%w: VM chain ID %s, config chain ID %s", errChainIDMismatch, vm.chainID, configChainID)
+	}
+	return nil
+}
+
 func (vm *VM) createConsensusCallbacks() *dummy.ConsensusCallbacks {
 	return &dummy.ConsensusCallbacks{
 		OnFinalizeAndAssemble: vm.onFinalizeAndAssemble,
@@ -436,14 +662,20 @@ func (vm *VM) onFinalizeAndAssemble(header *types.Header, state *state.StateDB,
 		// once.
 		snapshot := state.Snapshot()
 		rules := vm.chainConfig.AvalancheRules(header.Number, new(big.Int).SetUint64(header.Time))
-		if err := vm.verifyTx(tx, header.ParentHash, header.BaseFee, state, rules); err != nil {
+		if err := vm.verifyTx(tx, header, state, rules); err != nil {
 			// Discard the transaction from the mempool on failed verification.
 			vm.mempool.DiscardCurrentTx()
 			state.RevertToSnapshot(snapshot)
 			continue
 		}
 
-		atomicTxBytes, err := vm.codec.Marshal(codecVersion, tx)
+		var atomicTxBytes []byte
+		var err error
+		if rules.IsApricotPhase5 {
+			atomicTxBytes, err = EncodeAtomicTxs([]*Tx{tx})
+		} else {
+			atomicTxBytes, err = vm.codec.Marshal(codecVersion, tx)
+		}
 		if err != nil {
 			// Discard the transaction from the mempool and error if the transaction
 			// cannot be marshalled. This should never happen.
@@ -452,7 +684,7 @@ func (vm *VM) onFinalizeAndAssemble(header *types.Header, state *state.StateDB,
 		}
 		var contribution, gasUsed *big.Int
 		if rules.IsApricotPhase4 {
-			contribution, gasUsed, err = tx.BlockFeeContribution(vm.ctx.AVAXAssetID, header.BaseFee)
+			contribution, gasUsed, err = tx.BlockFeeContribution(vm.ctx.AVAXAssetID, header.BaseFee, rules)
 			if err != nil {
 				return nil, nil, nil, err
 			}
@@ -469,27 +701,38 @@ func (vm *VM) onFinalizeAndAssemble(header *types.Header, state *state.StateDB,
 }
 
 func (vm *VM) onExtraStateChange(block *types.Block, state *state.StateDB) (*big.Int, *big.Int, error) {
-	tx, err := vm.extractAtomicTx(block)
+	txs, err := vm.extractAtomicTxs(block)
 	if err != nil {
 		return nil, nil, err
 	}
-	// If [tx] is nil, we can return nil for the extra state contribution instead of allocating
+	// If there are no atomic txs, we can return nil for the extra state contribution instead of allocating
 	// a big Int for 0.
-	if tx == nil {
+	if len(txs) == 0 {
 		return nil, nil, nil
 	}
-	if err := tx.UnsignedAtomicTx.EVMStateTransfer(vm.ctx, state); err != nil {
-		return nil, nil, err
+	rules := vm.chainConfig.AvalancheRules(block.Number(), new(big.Int).SetUint64(block.Time()))
+	header := block.Header()
+	for _, tx := range txs {
+		if err := vm.applyAtomicTx(tx, header, state, rules); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	switch {
-	// If ApricotPahse4 is enabled, calculate the block fee contribution
-	case vm.chainConfig.IsApricotPhase4(new(big.Int).SetUint64(block.Time())):
-		return tx.BlockFeeContribution(vm.ctx.AVAXAssetID, block.BaseFee())
-	default:
-		// Otherwise, there is no contribution
+	// If ApricotPahse4 is not enabled, there is no contribution
+	if !vm.chainConfig.IsApricotPhase4(new(big.Int).SetUint64(block.Time())) {
 		return nil, nil, nil
 	}
+	// Otherwise, calculate the block fee contribution
+	contribution, gasUsed := new(big.Int), new(big.Int)
+	for _, tx := range txs {
+		txContribution, txGasUsed, err := tx.BlockFeeContribution(vm.ctx.AVAXAssetID, block.BaseFee(), rules)
+		if err != nil {
+			return nil, nil, err
+		}
+		contribution.Add(contribution, txContribution)
+		gasUsed.Add(gasUsed, txGasUsed)
+	}
+	return contribution, gasUsed, nil
 }
 
 func (vm *VM) pruneChain() error {
@@ -542,7 +785,11 @@ func (vm *VM) Shutdown() error {
 
 // buildBlock builds a block to be wrapped by ChainState
 func (vm *VM) buildBlock() (snowman.Block, error) {
-	block, err := vm.chain.GenerateBlock()
+	var block *types.Block
+	timestamp, err := vm.nextBlockTimestamp()
+	if err == nil {
+		block, err = vm.chain.GenerateBlockAt(timestamp)
+	}
 	vm.builder.handleGenerateBlock()
 	if err != nil {
 		vm.mempool.CancelCurrentTx()
@@ -709,6 +956,10 @@ func (vm *VM) CreateHandlers() (map[string]*commonEng.HTTPHandler, error) {
 		errs.Add(handler.RegisterName("web3", &Web3API{}))
 		enabledAPIs = append(enabledAPIs, "web3")
 	}
+	if vm.config.DebugAPIEnabled {
+		// Extends the debug namespace registered by the Ethereum service
+		errs.Add(handler.RegisterName("debug", &DebugAPI{vm}))
+	}
 	if errs.Errored() {
 		return nil, errs.Err
 	}
@@ -749,13 +1000,21 @@ func (vm *VM) CreateStaticHandlers() (map[string]*commonEng.HTTPHandler, error)
  *********************************** Helpers **********************************
  ******************************************************************************
  */
-// extractAtomicTx returns the atomic transaction in [block] if
-// one exists.
-func (vm *VM) extractAtomicTx(block *types.Block) (*Tx, error) {
+// extractAtomicTxs returns the atomic transactions in [block]. From Apricot
+// Phase 5, the extra data of a block encodes a list of atomic transactions.
+// Before that, it encodes at most one.
+func (vm *VM) extractAtomicTxs(block *types.Block) ([]*Tx, error) {
 	extdata := block.ExtData()
 	if len(extdata) == 0 {
 		return nil, nil
 	}
+	if vm.chainConfig.IsApricotPhase5(new(big.Int).SetUint64(block.Time())) {
+		txs, err := DecodeAtomicTxs(extdata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode atomic txs in block %s: %w", block.Hash().Hex(), err)
+		}
+		return txs, nil
+	}
 	atx := new(Tx)
 	if _, err := vm.codec.Unmarshal(extdata, atx); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal atomic tx due to %w", err)
@@ -764,18 +1023,149 @@ func (vm *VM) extractAtomicTx(block *types.Block) (*Tx, error) {
 		return nil, fmt.Errorf("failed to initialize atomic tx in block %s", block.Hash().Hex())
 	}
 
-	return atx, nil
+	return []*Tx{atx}, nil
+}
+
+// feesBurned returns the fees, in wei, burned by [block]: the base fee paid for
+// the gas used by its transactions and the AVAX burned by its atomic txs. The
+// AVAX burned by atomic txs is not counted if it is credited to the atomic fee
+// recipient instead.
+func (vm *VM) feesBurned(block *types.Block) (*big.Int, error) {
+	burned := new(big.Int)
+	if baseFee := block.BaseFee(); baseFee != nil {
+		burned.Mul(baseFee, new(big.Int).SetUint64(block.GasUsed()))
+	}
+
+	rules := vm.chainConfig.AvalancheRules(block.Number(), new(big.Int).SetUint64(block.Time()))
+	if rules.AtomicFeeRecipient != nil {
+		return burned, nil
+	}
+	txs, err := vm.extractAtomicTxs(block)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range txs {
+		atomicBurned, err := tx.Burned(vm.ctx.AVAXAssetID)
+		if err != nil {
+			return nil, err
+		}
+		burned.Add(burned, XtoC(atomicBurned))
+	}
+	return burned, nil
+}
+
+// supplyDelta returns the change of the total supply, in wei, caused by
+// [block]: the AVAX imported by its atomic txs, minus the AVAX they export and
+// burn. The fees paid by the EVM txs of [block] are credited to its coinbase,
+// so they do not change the supply. Neither do the fees of its atomic txs if
+// they are credited to the atomic fee recipient.
+func (vm *VM) supplyDelta(block *types.Block) (*big.Int, error) {
+	txs, err := vm.extractAtomicTxs(block)
+	if err != nil {
+		return nil, err
+	}
+	rules := vm.chainConfig.AvalancheRules(block.Number(), new(big.Int).SetUint64(block.Time()))
+
+	var imported, exported, burned uint64
+	for _, tx := range txs {
+		if rules.AtomicFeeRecipient == nil {
+			txBurned, err := tx.Burned(vm.ctx.AVAXAssetID)
+			if err != nil {
+				return nil, err
+			}
+			if burned, err = math.Add64(burned, txBurned); err != nil {
+				return nil, err
+			}
+		}
+
+		// The fee paid by the sponsor of an import is included in the burned fees
+		utx := tx.UnsignedAtomicTx
+		switch wrapped := utx.(type) {
+		case *UnsignedSponsoredImportTx:
+			utx = &wrapped.UnsignedImportTx
+		case *UnsignedContractImportTx:
+			utx = &wrapped.UnsignedImportTx
+		}
+
+		switch utx := utx.(type) {
+		case *UnsignedImportTx:
+			for _, in := range utx.ImportedInputs {
+				if in.AssetID() != vm.ctx.AVAXAssetID {
+					continue
+				}
+				if imported, err = math.Add64(imported, in.Input().Amount()); err != nil {
+					return nil, err
+				}
+			}
+		case *UnsignedExportTx:
+			for _, out := range utx.ExportedOutputs {
+				if out.AssetID() != vm.ctx.AVAXAssetID {
+					continue
+				}
+				if exported, err = math.Add64(exported, out.Output().Amount()); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	delta := XtoC(imported)
+	delta.Sub(delta, XtoC(exported))
+	delta.Sub(delta, XtoC(burned))
+	return delta, nil
+}
+
+// totalSupply returns the total supply, in wei, after the accepted block at
+// [height]. It starts from the closest cached checkpoint at or below
+// [height] and caches the checkpoints it passes.
+func (vm *VM) totalSupply(height uint64) (*big.Int, error) {
+	start, supply := vm.supplyCheckpoint(height)
+	for h := start + 1; h <= height; h++ {
+		block := vm.chain.GetBlockByNumber(h)
+		if block == nil {
+			return nil, fmt.Errorf("couldn't find block at height %d", h)
+		}
+		delta, err := vm.supplyDelta(block)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't calculate supply change at height %d: %w", h, err)
+		}
+		supply.Add(supply, delta)
+		if h%supplyCheckpointInterval == 0 {
+			vm.setSupplyCheckpoint(h, supply)
+		}
+	}
+	return supply, nil
+}
+
+// supplyCheckpoint returns the closest cached supply checkpoint at or below
+// [height], and the total supply at it.
+func (vm *VM) supplyCheckpoint(height uint64) (uint64, *big.Int) {
+	vm.supplyLock.Lock()
+	defer vm.supplyLock.Unlock()
+
+	start := height - height%supplyCheckpointInterval
+	for vm.supplyCheckpoints[start] == nil {
+		start -= supplyCheckpointInterval
+	}
+	return start, new(big.Int).Set(vm.supplyCheckpoints[start])
+}
+
+// setSupplyCheckpoint caches [supply] as the total supply at [height].
+func (vm *VM) setSupplyCheckpoint(height uint64, supply *big.Int) {
+	vm.supplyLock.Lock()
+	defer vm.supplyLock.Unlock()
+
+	vm.supplyCheckpoints[height] = new(big.Int).Set(supply)
 }
 
 func (vm *VM) conflicts(inputs ids.Set, ancestor *Block) error {
 	for ancestor.Status() != choices.Accepted {
-		atx, err := vm.extractAtomicTx(ancestor.ethBlock)
+		atxs, err := vm.extractAtomicTxs(ancestor.ethBlock)
 		if err != nil {
-			return fmt.Errorf("problem parsing atomic tx of ancestor block %s: %w", ancestor.ID(), err)
+			return fmt.Errorf("problem parsing atomic txs of ancestor block %s: %w", ancestor.ID(), err)
 		}
 		// If the ancestor isn't an atomic block, it can't conflict with
 		// the import tx.
-		if atx != nil {
+		for _, atx := range atxs {
 			ancestorInputs := atx.UnsignedAtomicTx.InputUTXOs()
 			if inputs.Overlaps(ancestorInputs) {
 				return errConflictingAtomicInputs
@@ -809,6 +1199,20 @@ func (vm *VM) conflicts(inputs ids.Set, ancestor *Block) error {
 	return nil
 }
 
+// recordAcceptedAtomicTx adds the gas used by the accepted atomic transaction
+// [tx] under [rules] to the VM's atomic gas statistics.
+func (vm *VM) recordAcceptedAtomicTx(tx *Tx, rules params.Rules) {
+	gasUsed, err := tx.GasUsed(rules)
+	if err != nil {
+		log.Warn("failed to calculate gas used by accepted atomic tx", "txID", tx.ID(), "err", err)
+		return
+	}
+	atomic.AddUint64(&vm.atomicTxGasUsed, gasUsed)
+	atomic.AddUint64(&vm.atomicTxsAccepted, 1)
+	atomicTxGasUsedCounter.Inc(int64(gasUsed))
+	atomicTxAcceptedCounter.Inc(1)
+}
+
 // getAcceptedAtomicTx attempts to get [txID] from the database.
 func (vm *VM) getAcceptedAtomicTx(txID ids.ID) (*Tx, uint64, error) {
 	indexedTxBytes, err := vm.acceptedAtomicTxDB.Get(txID[:])
@@ -862,7 +1266,107 @@ func (vm *VM) writeAtomicTx(blk *Block, tx *Tx) error {
 	packer.PackBytes(txBytes)
 	txID := tx.ID()
 
-	return vm.acceptedAtomicTxDB.Put(txID[:], packer.Bytes)
+	if err := vm.acceptedAtomicTxDB.Put(txID[:], packer.Bytes); err != nil {
+		return err
+	}
+	for _, addr := range atomicTxAddresses(tx) {
+		if err := vm.atomicTxAddressDB.Put(atomicTxAddressKey(addr, height, txID), nil); err != nil {
+			return err
+		}
+	}
+	if exportTx, ok := tx.UnsignedAtomicTx.(*UnsignedExportTx); ok {
+		for i, utxoID := range ExportUTXOIDs(txID, len(exportTx.ExportedOutputs)) {
+			packer := wrappers.Packer{Bytes: make([]byte, len(txID)+wrappers.IntLen)}
+			packer.PackFixedBytes(txID[:])
+			packer.PackInt(uint32(i))
+			if err := vm.exportedUTXODB.Put(utxoID[:], packer.Bytes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getExportTxForUTXO returns the ID of the accepted export tx that produced
+// [utxoID] and the index of the output. Returns database.ErrNotFound if no
+// accepted export tx produced [utxoID].
+func (vm *VM) getExportTxForUTXO(utxoID ids.ID) (ids.ID, uint32, error) {
+	value, err := vm.exportedUTXODB.Get(utxoID[:])
+	if err != nil {
+		return ids.Empty, 0, err
+	}
+	packer := wrappers.Packer{Bytes: value}
+	txID, err := ids.ToID(packer.UnpackFixedBytes(len(ids.Empty)))
+	if err != nil {
+		return ids.Empty, 0, err
+	}
+	outputIndex := packer.UnpackInt()
+	return txID, outputIndex, packer.Err
+}
+
+// atomicTxAddresses returns the EVM addresses credited or debited by [tx]
+func atomicTxAddresses(tx *Tx) []common.Address {
+	addrs := make(map[common.Address]struct{})
+	switch utx := tx.UnsignedAtomicTx.(type) {
+	case *UnsignedImportTx:
+		for _, out := range utx.Outs {
+			addrs[out.Address] = struct{}{}
+		}
+	case *UnsignedSponsoredImportTx:
+		for _, out := range utx.Outs {
+			addrs[out.Address] = struct{}{}
+		}
+		addrs[utx.Sponsor.Address] = struct{}{}
+	case *UnsignedContractImportTx:
+		addrs[utx.Contract] = struct{}{}
+	case *UnsignedExportTx:
+		for _, in := range utx.Ins {
+			addrs[in.Address] = struct{}{}
+		}
+	}
+	addrList := make([]common.Address, 0, len(addrs))
+	for addr := range addrs {
+		addrList = append(addrList, addr)
+	}
+	return addrList
+}
+
+// atomicTxAddressKey returns the key of the entry of [atomicTxAddressDB]
+// recording that the tx [txID], accepted at [height], credits or debits
+// [addr]. Keys sort by address, then by height.
+func atomicTxAddressKey(addr common.Address, height uint64, txID ids.ID) []byte {
+	key := make([]byte, 0, common.AddressLength+wrappers.LongLen+len(txID))
+	key = append(key, addr.Bytes()...)
+	key = append(key, make([]byte, wrappers.LongLen)...)
+	binary.BigEndian.PutUint64(key[common.AddressLength:], height)
+	return append(key, txID[:]...)
+}
+
+// getAtomicTxsByAddress returns the IDs and heights of up to [limit] accepted
+// atomic txs that credit or debit [addr], ordered by height, starting after
+// the tx [startTxID] accepted at [startHeight].
+func (vm *VM) getAtomicTxsByAddress(addr common.Address, startHeight uint64, startTxID ids.ID, limit int) ([]ids.ID, []uint64, error) {
+	iter := vm.atomicTxAddressDB.NewIteratorWithStartAndPrefix(atomicTxAddressKey(addr, startHeight, startTxID), addr.Bytes())
+	defer iter.Release()
+
+	var (
+		txIDs   []ids.ID
+		heights []uint64
+	)
+	for len(txIDs) < limit && iter.Next() {
+		key := iter.Key()
+		height := binary.BigEndian.Uint64(key[common.AddressLength:])
+		txID, err := ids.ToID(key[common.AddressLength+wrappers.LongLen:])
+		if err != nil {
+			return nil, nil, err
+		}
+		if height == startHeight && txID == startTxID {
+			continue
+		}
+		txIDs = append(txIDs, txID)
+		heights = append(heights, height)
+	}
+	return txIDs, heights, iter.Error()
 }
 
 // ParseAddress takes in an address and produces the ID of the chain it's for
@@ -891,6 +1395,70 @@ func (vm *VM) ParseAddress(addrStr string) (ids.ID, ids.ShortID, error) {
 	return chainID, addr, nil
 }
 
+// IssueStatus is the outcome of issuing an atomic tx
+type IssueStatus uint8
+
+const (
+	// IssueAdded means the tx was verified and added to the mempool
+	IssueAdded IssueStatus = iota
+	// IssueKnown means the tx was already processing or accepted, so it was
+	// not issued again
+	IssueKnown
+	// IssueRejected means the tx failed verification or was refused by the
+	// mempool
+	IssueRejected
+)
+
+func (s IssueStatus) String() string {
+	switch s {
+	case IssueAdded:
+		return "Added"
+	case IssueKnown:
+		return "Known"
+	case IssueRejected:
+		return "Rejected"
+	default:
+		return "Invalid issue status"
+	}
+}
+
+// MarshalJSON marshals [s] as its name
+func (s IssueStatus) MarshalJSON() ([]byte, error) {
+	if s > IssueRejected {
+		return nil, errUnknownStatus
+	}
+	return []byte(fmt.Sprintf("%q", s)), nil
+}
+
+// IssueResult describes the outcome of issuing the atomic tx [TxID]
+type IssueResult struct {
+	TxID   ids.ID
+	Status IssueStatus
+}
+
+// IssueTxWithResult issues the locally submitted [tx] like issueTx, but
+// reports whether [tx] was newly added, already known or rejected. The error
+// returned with a rejected tx explains why it was rejected.
+func (vm *VM) IssueTxWithResult(tx *Tx) (IssueResult, error) {
+	result := IssueResult{TxID: tx.ID()}
+
+	_, status, _, err := vm.getAtomicTx(result.TxID)
+	if err != nil {
+		return result, fmt.Errorf("failed to look up tx %s: %w", result.TxID, err)
+	}
+	if status == Processing || status == Accepted {
+		result.Status = IssueKnown
+		return result, nil
+	}
+
+	if err := vm.issueTx(tx, true /*=local*/); err != nil {
+		result.Status = IssueRejected
+		return result, err
+	}
+	result.Status = IssueAdded
+	return result, nil
+}
+
 // issueTx verifies [tx] as valid to be issued on top of the currently preferred block
 // and then issues [tx] into the mempool if valid.
 func (vm *VM) issueTx(tx *Tx, local bool) error {
@@ -929,8 +1497,38 @@ func (vm *VM) issueTx(tx *Tx, local bool) error {
 	return nil
 }
 
+// issueTxs verifies that the locally submitted [txs] are valid to be issued
+// together, in order, on top of the currently preferred block and then adds
+// them to the mempool. Either all of [txs] are issued or none of them are.
+func (vm *VM) issueTxs(txs []*Tx) error {
+	inputs := ids.Set{}
+	for _, tx := range txs {
+		txInputs := tx.InputUTXOs()
+		if inputs.Overlaps(txInputs) {
+			return fmt.Errorf("%w: %s", errConflictingAtomicTxBatch, tx.ID())
+		}
+		inputs.Union(txInputs)
+	}
+
+	if err := vm.verifyTxsAtTip(txs); err != nil {
+		return err
+	}
+
+	// The txs are only gossiped once they are all added to the mempool.
+	if err := vm.mempool.AddTxs(txs); err != nil {
+		return fmt.Errorf("failed to issue txs: %w", err)
+	}
+	return nil
+}
+
 // verifyTxAtTip verifies that [tx] is valid to be issued on top of the currently preferred block
 func (vm *VM) verifyTxAtTip(tx *Tx) error {
+	return vm.verifyTxsAtTip([]*Tx{tx})
+}
+
+// verifyTxsAtTip verifies that [txs] are valid to be issued, in order, on top
+// of the currently preferred block
+func (vm *VM) verifyTxsAtTip(txs []*Tx) error {
 	preferredBlock := vm.chain.CurrentBlock()
 	preferredState, err := vm.chain.BlockState(preferredBlock)
 	if err != nil {
@@ -949,16 +1547,95 @@ func (vm *VM) verifyTxAtTip(tx *Tx) error {
 		}
 	}
 
-	return vm.verifyTx(tx, parentHeader.Hash(), nextBaseFee, preferredState, rules)
+	// The expected header of the next block, as far as the contract call of a
+	// contract import can observe it. The call does not observe the coinbase
+	// or difficulty, which are left unset.
+	header := &types.Header{
+		ParentHash: parentHeader.Hash(),
+		Difficulty: new(big.Int),
+		Number:     new(big.Int).Add(parentHeader.Number, common.Big1),
+		GasLimit:   parentHeader.GasLimit,
+		Time:       uint64(timestamp),
+		BaseFee:    nextBaseFee,
+	}
+	for _, tx := range txs {
+		if err := vm.canIssueAtomicTx(tx, bigTimestamp); err != nil {
+			return err
+		}
+		if err := vm.verifyTx(tx, header, preferredState, rules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canIssueAtomicTx verifies that [tx] only spends allowed assets and burns at
+// least the minimum fee per gas configured by the chain config, which is only
+// enforced once Apricot Phase 3 is active at [timestamp].
+func (vm *VM) canIssueAtomicTx(tx *Tx, timestamp *big.Int) error {
+	if err := vm.verifyAllowedAssets(tx.UnsignedAtomicTx); err != nil {
+		return err
+	}
+
+	minFeePerGas := vm.chainConfig.MinAtomicFeePerGas
+	if minFeePerGas == nil || !vm.chainConfig.IsApricotPhase3(timestamp) {
+		return nil
+	}
+	feePerGas, err := vm.mempool.atomicTxGasPrice(tx)
+	if err != nil {
+		return err
+	}
+	if new(big.Int).SetUint64(feePerGas).Cmp(minFeePerGas) < 0 {
+		return fmt.Errorf("%w: tx %s pays %d nAVAX per gas, but the minimum is %d", errAtomicFeeTooLow, tx.ID(), feePerGas, minFeePerGas)
+	}
+	return nil
+}
+
+// verifyAllowedAssets verifies that every input of [utx] spends AVAX or an
+// asset in [vm.allowedAtomicAssets], unless no allowed assets are configured.
+// This is a local policy, so it must not be used to verify blocks.
+func (vm *VM) verifyAllowedAssets(utx UnsignedAtomicTx) error {
+	if vm.allowedAtomicAssets.Len() == 0 {
+		return nil
+	}
+
+	var assetIDs []ids.ID
+	switch utx := utx.(type) {
+	case *UnsignedImportTx:
+		for _, in := range utx.ImportedInputs {
+			assetIDs = append(assetIDs, in.AssetID())
+		}
+	case *UnsignedSponsoredImportTx:
+		// The sponsor is verified to pay in AVAX
+		for _, in := range utx.ImportedInputs {
+			assetIDs = append(assetIDs, in.AssetID())
+		}
+	case *UnsignedContractImportTx:
+		for _, in := range utx.ImportedInputs {
+			assetIDs = append(assetIDs, in.AssetID())
+		}
+	case *UnsignedExportTx:
+		for _, in := range utx.Ins {
+			assetIDs = append(assetIDs, in.AssetID)
+		}
+	default:
+		return fmt.Errorf("unknown atomic tx type %T", utx)
+	}
+	for _, assetID := range assetIDs {
+		if assetID != vm.ctx.AVAXAssetID && !vm.allowedAtomicAssets.Contains(assetID) {
+			return fmt.Errorf("%w: %s", errAssetNotAllowed, assetID)
+		}
+	}
+	return nil
 }
 
-// verifyTx verifies that [tx] is valid to be issued into a block with parent block [parentHash]
+// verifyTx verifies that [tx] is valid to be issued into the block with [header]
 // and validated at [state] using [rules] as the current rule set.
 // Note: verifyTx may modify [state]. If [state] needs to be properly maintained, the caller is responsible
 // for reverting to the correct snapshot after calling this function. If this function is called with a
 // throwaway state, then this is not necessary.
-func (vm *VM) verifyTx(tx *Tx, parentHash common.Hash, baseFee *big.Int, state *state.StateDB, rules params.Rules) error {
-	parentIntf, err := vm.GetBlockInternal(ids.ID(parentHash))
+func (vm *VM) verifyTx(tx *Tx, header *types.Header, state *state.StateDB, rules params.Rules) error {
+	parentIntf, err := vm.GetBlockInternal(ids.ID(header.ParentHash))
 	if err != nil {
 		return fmt.Errorf("failed to get parent block: %w", err)
 	}
@@ -966,10 +1643,24 @@ func (vm *VM) verifyTx(tx *Tx, parentHash common.Hash, baseFee *big.Int, state *
 	if !ok {
 		return fmt.Errorf("parent block %s had unexpected type %T", parentIntf.ID(), parentIntf)
 	}
-	if err := tx.UnsignedAtomicTx.SemanticVerify(vm, tx, parent, baseFee, rules); err != nil {
+	if err := tx.UnsignedAtomicTx.SemanticVerify(vm, tx, parent, header.BaseFee, rules); err != nil {
 		return err
 	}
-	return tx.UnsignedAtomicTx.EVMStateTransfer(vm.ctx, state)
+	return vm.applyAtomicTx(tx, header, state, rules)
+}
+
+// applyAtomicTx applies the state transfer of [tx] to [state], followed by
+// the contract call of a contract import, in the block with [header].
+func (vm *VM) applyAtomicTx(tx *Tx, header *types.Header, state *state.StateDB, rules params.Rules) error {
+	if err := tx.UnsignedAtomicTx.EVMStateTransfer(vm.ctx, state, rules); err != nil {
+		return err
+	}
+	caller, ok := tx.UnsignedAtomicTx.(contractCaller)
+	if !ok {
+		return nil
+	}
+	blockCtx := core.NewEVMBlockContext(header, vm.chain.BlockChain(), &header.Coinbase)
+	return caller.EVMCall(blockCtx, state, vm.chainConfig)
 }
 
 // GetAtomicUTXOs returns the utxos that at least one of the provided addresses is
@@ -1047,9 +1738,12 @@ func (vm *VM) GetSpendableFunds(
 		addr := GetEthAddress(key)
 		var balance uint64
 		if assetID == vm.ctx.AVAXAssetID {
-			// If the asset is AVAX, we divide by the x2cRate to convert back to the correct
+			// If the asset is AVAX, we convert the balance back to the
 			// denomination of AVAX that can be exported.
-			balance = new(big.Int).Div(state.GetBalance(addr), x2cRate).Uint64()
+			balance, err = CtoX(state.GetBalance(addr))
+			if err != nil {
+				return nil, nil, err
+			}
 		} else {
 			balance = state.GetBalanceMultiCoin(addr, common.Hash(assetID)).Uint64()
 		}
@@ -1084,7 +1778,8 @@ func (vm *VM) GetSpendableFunds(
 // order) to total [amount] + [fee] of [AVAX] owned by [keys].
 // This function accounts for the added cost of the additional inputs needed to
 // create the transaction and makes sure to skip any keys with a balance that is
-// insufficient to cover the additional fee.
+// insufficient to cover the additional fee. As of Apricot Phase 5 under
+// [rules], the credential of each additional input is charged as well.
 // Note: we return [][]*crypto.PrivateKeySECP256K1R even though each input
 // corresponds to a single key, so that the signers can be passed in to
 // [tx.Sign] which supports multiple keys on a single input.
@@ -1093,6 +1788,7 @@ func (vm *VM) GetSpendableAVAXWithFee(
 	amount uint64,
 	cost uint64,
 	baseFee *big.Int,
+	rules params.Rules,
 ) ([]EVMInput, [][]*crypto.PrivateKeySECP256K1R, error) {
 	// Note: current state uses the state of the preferred block.
 	state, err := vm.chain.CurrentState()
@@ -1111,6 +1807,11 @@ func (vm *VM) GetSpendableAVAXWithFee(
 	}
 	amount = newAmount
 
+	inputGas := EVMInputGas
+	if rules.IsApricotPhase5 {
+		inputGas += CredentialGas
+	}
+
 	inputs := []EVMInput{}
 	signers := [][]*crypto.PrivateKeySECP256K1R{}
 	// Note: we assume that each key in [keys] is unique, so that iterating over
@@ -1125,7 +1826,7 @@ func (vm *VM) GetSpendableAVAXWithFee(
 			return nil, nil, err
 		}
 
-		newCost := cost + EVMInputGas
+		newCost := cost + inputGas
 		newFee, err := calculateDynamicFee(newCost, baseFee)
 		if err != nil {
 			return nil, nil, err
@@ -1134,9 +1835,12 @@ func (vm *VM) GetSpendableAVAXWithFee(
 		additionalFee := newFee - prevFee
 
 		addr := GetEthAddress(key)
-		// Since the asset is AVAX, we divide by the x2cRate to convert back to
-		// the correct denomination of AVAX that can be exported.
-		balance := new(big.Int).Div(state.GetBalance(addr), x2cRate).Uint64()
+		// Since the asset is AVAX, we convert the balance back to the
+		// denomination of AVAX that can be exported.
+		balance, err := CtoX(state.GetBalance(addr))
+		if err != nil {
+			return nil, nil, err
+		}
 		// If the balance for [addr] is insufficient to cover the additional cost
 		// of adding an input to the transaction, skip adding the input altogether
 		if balance <= additionalFee {
@@ -1191,6 +1895,33 @@ func (vm *VM) GetCurrentNonce(address common.Address) (uint64, error) {
 	return state.GetNonce(address), nil
 }
 
+// nextBlockTimestamp returns the timestamp of the next block built on top of
+// the preferred block. This is the current time, unless the preferred block is
+// not older than that, in which case it is one second after the preferred
+// block. Returns an error if that is more than [maxBlockTimestampDrift] ahead
+// of the current time, or would activate a fork that is not yet active at the
+// current time, in which case the block must be built later.
+func (vm *VM) nextBlockTimestamp() (uint64, error) {
+	parent := vm.chain.CurrentBlock()
+	now := uint64(vm.clock.Unix())
+	if now > parent.Time() {
+		return now, nil
+	}
+
+	next := parent.Time() + 1
+	if maxTimestamp := now + uint64(maxBlockTimestampDrift.Seconds()); next > maxTimestamp {
+		return 0, fmt.Errorf("%w: %d > allowed %d", errBlockTimestampTooFarAhead, next, maxTimestamp)
+	}
+	height := new(big.Int).Add(parent.Number(), common.Big1)
+	// Forks are never deactivated, so a fork activates between [now] and
+	// [next] if and only if fewer forks are active at [now].
+	nextForks := vm.chainConfig.ActiveForks(height, new(big.Int).SetUint64(next))
+	if nowForks := vm.chainConfig.ActiveForks(height, new(big.Int).SetUint64(now)); len(nextForks) != len(nowForks) {
+		return 0, fmt.Errorf("%w: %d forks are active at %d, but only %d at %d", errForkNotReached, len(nextForks), next, len(nowForks), now)
+	}
+	return next, nil
+}
+
 // currentRules returns the chain rules for the current block.
 func (vm *VM) currentRules() params.Rules {
 	header := vm.chain.APIBackend().CurrentHeader()
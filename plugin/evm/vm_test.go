@@ -4,6 +4,7 @@
 package evm
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/json"
@@ -12,6 +13,7 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -43,6 +45,7 @@ import (
 
 	"github.com/flare-foundation/coreth/consensus/dummy"
 	"github.com/flare-foundation/coreth/core"
+	"github.com/flare-foundation/coreth/core/rawdb"
 	"github.com/flare-foundation/coreth/core/types"
 	"github.com/flare-foundation/coreth/eth"
 	"github.com/flare-foundation/coreth/params"
@@ -64,17 +67,19 @@ var (
 	password         = "CjasdjhiPeirbSenfeI13" // #nosec G101
 	// Use chainId: 43111, so that it does not overlap with any Avalanche ChainIDs, which may have their
 	// config overridden in vm.Initialize.
-	genesisJSONApricotPhase0 = "{\"config\":{\"chainId\":43111,\"homesteadBlock\":0,\"daoForkBlock\":0,\"daoForkSupport\":true,\"eip150Block\":0,\"eip150Hash\":\"0x2086799aeebeae135c246c65021c82b4e15a2c451340993aacfd2751886514f0\",\"eip155Block\":0,\"eip158Block\":0,\"byzantiumBlock\":0,\"constantinopleBlock\":0,\"petersburgBlock\":0,\"istanbulBlock\":0,\"muirGlacierBlock\":0},\"nonce\":\"0x0\",\"timestamp\":\"0x0\",\"extraData\":\"0x00\",\"gasLimit\":\"0x5f5e100\",\"difficulty\":\"0x0\",\"mixHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\",\"coinbase\":\"0x0000000000000000000000000000000000000000\",\"alloc\":{\"0100000000000000000000000000000000000000\":{\"code\":\"0x7300000000000000000000000000000000000000003014608060405260043610603d5760003560e01c80631e010439146042578063b6510bb314606e575b600080fd5b605c60048036036020811015605657600080fd5b503560b1565b60408051918252519081900360200190f35b818015607957600080fd5b5060af60048036036080811015608e57600080fd5b506001600160a01b03813516906020810135906040810135906060013560b6565b005b30cd90565b836001600160a01b031681836108fc8690811502906040516000604051808303818888878c8acf9550505050505015801560f4573d6000803e3d6000fd5b505050505056fea26469706673582212201eebce970fe3f5cb96bf8ac6ba5f5c133fc2908ae3dcd51082cfee8f583429d064736f6c634300060a0033\",\"balance\":\"0x0\"}},\"number\":\"0x0\",\"gasUsed\":\"0x0\",\"parentHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\"}"
-	genesisJSONApricotPhase1 = "{\"config\":{\"chainId\":43111,\"homesteadBlock\":0,\"daoForkBlock\":0,\"daoForkSupport\":true,\"eip150Block\":0,\"eip150Hash\":\"0x2086799aeebeae135c246c65021c82b4e15a2c451340993aacfd2751886514f0\",\"eip155Block\":0,\"eip158Block\":0,\"byzantiumBlock\":0,\"constantinopleBlock\":0,\"petersburgBlock\":0,\"istanbulBlock\":0,\"muirGlacierBlock\":0,\"apricotPhase1BlockTimestamp\":0},\"nonce\":\"0x0\",\"timestamp\":\"0x0\",\"extraData\":\"0x00\",\"gasLimit\":\"0x5f5e100\",\"difficulty\":\"0x0\",\"mixHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\",\"coinbase\":\"0x0000000000000000000000000000000000000000\",\"alloc\":{\"0100000000000000000000000000000000000000\":{\"code\":\"0x7300000000000000000000000000000000000000003014608060405260043610603d5760003560e01c80631e010439146042578063b6510bb314606e575b600080fd5b605c60048036036020811015605657600080fd5b503560b1565b60408051918252519081900360200190f35b818015607957600080fd5b5060af60048036036080811015608e57600080fd5b506001600160a01b03813516906020810135906040810135906060013560b6565b005b30cd90565b836001600160a01b031681836108fc8690811502906040516000604051808303818888878c8acf9550505050505015801560f4573d6000803e3d6000fd5b505050505056fea26469706673582212201eebce970fe3f5cb96bf8ac6ba5f5c133fc2908ae3dcd51082cfee8f583429d064736f6c634300060a0033\",\"balance\":\"0x0\"}},\"number\":\"0x0\",\"gasUsed\":\"0x0\",\"parentHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\"}"
-	genesisJSONApricotPhase2 = "{\"config\":{\"chainId\":43111,\"homesteadBlock\":0,\"daoForkBlock\":0,\"daoForkSupport\":true,\"eip150Block\":0,\"eip150Hash\":\"0x2086799aeebeae135c246c65021c82b4e15a2c451340993aacfd2751886514f0\",\"eip155Block\":0,\"eip158Block\":0,\"byzantiumBlock\":0,\"constantinopleBlock\":0,\"petersburgBlock\":0,\"istanbulBlock\":0,\"muirGlacierBlock\":0,\"apricotPhase1BlockTimestamp\":0,\"apricotPhase2BlockTimestamp\":0},\"nonce\":\"0x0\",\"timestamp\":\"0x0\",\"extraData\":\"0x00\",\"gasLimit\":\"0x5f5e100\",\"difficulty\":\"0x0\",\"mixHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\",\"coinbase\":\"0x0000000000000000000000000000000000000000\",\"alloc\":{\"0100000000000000000000000000000000000000\":{\"code\":\"0x7300000000000000000000000000000000000000003014608060405260043610603d5760003560e01c80631e010439146042578063b6510bb314606e575b600080fd5b605c60048036036020811015605657600080fd5b503560b1565b60408051918252519081900360200190f35b818015607957600080fd5b5060af60048036036080811015608e57600080fd5b506001600160a01b03813516906020810135906040810135906060013560b6565b005b30cd90565b836001600160a01b031681836108fc8690811502906040516000604051808303818888878c8acf9550505050505015801560f4573d6000803e3d6000fd5b505050505056fea26469706673582212201eebce970fe3f5cb96bf8ac6ba5f5c133fc2908ae3dcd51082cfee8f583429d064736f6c634300060a0033\",\"balance\":\"0x0\"}},\"number\":\"0x0\",\"gasUsed\":\"0x0\",\"parentHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\"}"
-	genesisJSONApricotPhase3 = "{\"config\":{\"chainId\":43111,\"homesteadBlock\":0,\"daoForkBlock\":0,\"daoForkSupport\":true,\"eip150Block\":0,\"eip150Hash\":\"0x2086799aeebeae135c246c65021c82b4e15a2c451340993aacfd2751886514f0\",\"eip155Block\":0,\"eip158Block\":0,\"byzantiumBlock\":0,\"constantinopleBlock\":0,\"petersburgBlock\":0,\"istanbulBlock\":0,\"muirGlacierBlock\":0,\"apricotPhase1BlockTimestamp\":0,\"apricotPhase2BlockTimestamp\":0,\"apricotPhase3BlockTimestamp\":0},\"nonce\":\"0x0\",\"timestamp\":\"0x0\",\"extraData\":\"0x00\",\"gasLimit\":\"0x5f5e100\",\"difficulty\":\"0x0\",\"mixHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\",\"coinbase\":\"0x0000000000000000000000000000000000000000\",\"alloc\":{\"0100000000000000000000000000000000000000\":{\"code\":\"0x7300000000000000000000000000000000000000003014608060405260043610603d5760003560e01c80631e010439146042578063b6510bb314606e575b600080fd5b605c60048036036020811015605657600080fd5b503560b1565b60408051918252519081900360200190f35b818015607957600080fd5b5060af60048036036080811015608e57600080fd5b506001600160a01b03813516906020810135906040810135906060013560b6565b005b30cd90565b836001600160a01b031681836108fc8690811502906040516000604051808303818888878c8acf9550505050505015801560f4573d6000803e3d6000fd5b505050505056fea26469706673582212201eebce970fe3f5cb96bf8ac6ba5f5c133fc2908ae3dcd51082cfee8f583429d064736f6c634300060a0033\",\"balance\":\"0x0\"}},\"number\":\"0x0\",\"gasUsed\":\"0x0\",\"parentHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\"}"
-	genesisJSONApricotPhase4 = "{\"config\":{\"chainId\":43111,\"homesteadBlock\":0,\"daoForkBlock\":0,\"daoForkSupport\":true,\"eip150Block\":0,\"eip150Hash\":\"0x2086799aeebeae135c246c65021c82b4e15a2c451340993aacfd2751886514f0\",\"eip155Block\":0,\"eip158Block\":0,\"byzantiumBlock\":0,\"constantinopleBlock\":0,\"petersburgBlock\":0,\"istanbulBlock\":0,\"muirGlacierBlock\":0,\"apricotPhase1BlockTimestamp\":0,\"apricotPhase2BlockTimestamp\":0,\"apricotPhase3BlockTimestamp\":0,\"apricotPhase4BlockTimestamp\":0},\"nonce\":\"0x0\",\"timestamp\":\"0x0\",\"extraData\":\"0x00\",\"gasLimit\":\"0x5f5e100\",\"difficulty\":\"0x0\",\"mixHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\",\"coinbase\":\"0x0000000000000000000000000000000000000000\",\"alloc\":{\"0100000000000000000000000000000000000000\":{\"code\":\"0x7300000000000000000000000000000000000000003014608060405260043610603d5760003560e01c80631e010439146042578063b6510bb314606e575b600080fd5b605c60048036036020811015605657600080fd5b503560b1565b60408051918252519081900360200190f35b818015607957600080fd5b5060af60048036036080811015608e57600080fd5b506001600160a01b03813516906020810135906040810135906060013560b6565b005b30cd90565b836001600160a01b031681836108fc8690811502906040516000604051808303818888878c8acf9550505050505015801560f4573d6000803e3d6000fd5b505050505056fea26469706673582212201eebce970fe3f5cb96bf8ac6ba5f5c133fc2908ae3dcd51082cfee8f583429d064736f6c634300060a0033\",\"balance\":\"0x0\"}},\"number\":\"0x0\",\"gasUsed\":\"0x0\",\"parentHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\"}"
-
-	apricotRulesPhase0 = params.Rules{}
-	apricotRulesPhase1 = params.Rules{IsApricotPhase1: true}
-	apricotRulesPhase2 = params.Rules{IsApricotPhase1: true, IsApricotPhase2: true}
-	apricotRulesPhase3 = params.Rules{IsApricotPhase1: true, IsApricotPhase2: true, IsApricotPhase3: true}
-	apricotRulesPhase4 = params.Rules{IsApricotPhase1: true, IsApricotPhase2: true, IsApricotPhase3: true, IsApricotPhase4: true}
+	genesisJSONApricotPhase0 = "{\"config\":{\"chainId\":43111,\"atomicTxsTimestamp\":0,\"homesteadBlock\":0,\"daoForkBlock\":0,\"daoForkSupport\":true,\"eip150Block\":0,\"eip150Hash\":\"0x2086799aeebeae135c246c65021c82b4e15a2c451340993aacfd2751886514f0\",\"eip155Block\":0,\"eip158Block\":0,\"byzantiumBlock\":0,\"constantinopleBlock\":0,\"petersburgBlock\":0,\"istanbulBlock\":0,\"muirGlacierBlock\":0},\"nonce\":\"0x0\",\"timestamp\":\"0x0\",\"extraData\":\"0x00\",\"gasLimit\":\"0x5f5e100\",\"difficulty\":\"0x0\",\"mixHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\",\"coinbase\":\"0x0000000000000000000000000000000000000000\",\"alloc\":{\"0100000000000000000000000000000000000000\":{\"code\":\"0x7300000000000000000000000000000000000000003014608060405260043610603d5760003560e01c80631e010439146042578063b6510bb314606e575b600080fd5b605c60048036036020811015605657600080fd5b503560b1565b60408051918252519081900360200190f35b818015607957600080fd5b5060af60048036036080811015608e57600080fd5b506001600160a01b03813516906020810135906040810135906060013560b6565b005b30cd90565b836001600160a01b031681836108fc8690811502906040516000604051808303818888878c8acf9550505050505015801560f4573d6000803e3d6000fd5b505050505056fea26469706673582212201eebce970fe3f5cb96bf8ac6ba5f5c133fc2908ae3dcd51082cfee8f583429d064736f6c634300060a0033\",\"balance\":\"0x0\"}},\"number\":\"0x0\",\"gasUsed\":\"0x0\",\"parentHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\"}"
+	genesisJSONApricotPhase1 = "{\"config\":{\"chainId\":43111,\"atomicTxsTimestamp\":0,\"homesteadBlock\":0,\"daoForkBlock\":0,\"daoForkSupport\":true,\"eip150Block\":0,\"eip150Hash\":\"0x2086799aeebeae135c246c65021c82b4e15a2c451340993aacfd2751886514f0\",\"eip155Block\":0,\"eip158Block\":0,\"byzantiumBlock\":0,\"constantinopleBlock\":0,\"petersburgBlock\":0,\"istanbulBlock\":0,\"muirGlacierBlock\":0,\"apricotPhase1BlockTimestamp\":0},\"nonce\":\"0x0\",\"timestamp\":\"0x0\",\"extraData\":\"0x00\",\"gasLimit\":\"0x5f5e100\",\"difficulty\":\"0x0\",\"mixHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\",\"coinbase\":\"0x0000000000000000000000000000000000000000\",\"alloc\":{\"0100000000000000000000000000000000000000\":{\"code\":\"0x7300000000000000000000000000000000000000003014608060405260043610603d5760003560e01c80631e010439146042578063b6510bb314606e575b600080fd5b605c60048036036020811015605657600080fd5b503560b1565b60408051918252519081900360200190f35b818015607957600080fd5b5060af60048036036080811015608e57600080fd5b506001600160a01b03813516906020810135906040810135906060013560b6565b005b30cd90565b836001600160a01b031681836108fc8690811502906040516000604051808303818888878c8acf9550505050505015801560f4573d6000803e3d6000fd5b505050505056fea26469706673582212201eebce970fe3f5cb96bf8ac6ba5f5c133fc2908ae3dcd51082cfee8f583429d064736f6c634300060a0033\",\"balance\":\"0x0\"}},\"number\":\"0x0\",\"gasUsed\":\"0x0\",\"parentHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\"}"
+	genesisJSONApricotPhase2 = "{\"config\":{\"chainId\":43111,\"atomicTxsTimestamp\":0,\"homesteadBlock\":0,\"daoForkBlock\":0,\"daoForkSupport\":true,\"eip150Block\":0,\"eip150Hash\":\"0x2086799aeebeae135c246c65021c82b4e15a2c451340993aacfd2751886514f0\",\"eip155Block\":0,\"eip158Block\":0,\"byzantiumBlock\":0,\"constantinopleBlock\":0,\"petersburgBlock\":0,\"istanbulBlock\":0,\"muirGlacierBlock\":0,\"apricotPhase1BlockTimestamp\":0,\"apricotPhase2BlockTimestamp\":0},\"nonce\":\"0x0\",\"timestamp\":\"0x0\",\"extraData\":\"0x00\",\"gasLimit\":\"0x5f5e100\",\"difficulty\":\"0x0\",\"mixHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\",\"coinbase\":\"0x0000000000000000000000000000000000000000\",\"alloc\":{\"0100000000000000000000000000000000000000\":{\"code\":\"0x7300000000000000000000000000000000000000003014608060405260043610603d5760003560e01c80631e010439146042578063b6510bb314606e575b600080fd5b605c60048036036020811015605657600080fd5b503560b1565b60408051918252519081900360200190f35b818015607957600080fd5b5060af60048036036080811015608e57600080fd5b506001600160a01b03813516906020810135906040810135906060013560b6565b005b30cd90565b836001600160a01b031681836108fc8690811502906040516000604051808303818888878c8acf9550505050505015801560f4573d6000803e3d6000fd5b505050505056fea26469706673582212201eebce970fe3f5cb96bf8ac6ba5f5c133fc2908ae3dcd51082cfee8f583429d064736f6c634300060a0033\",\"balance\":\"0x0\"}},\"number\":\"0x0\",\"gasUsed\":\"0x0\",\"parentHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\"}"
+	genesisJSONApricotPhase3 = "{\"config\":{\"chainId\":43111,\"atomicTxsTimestamp\":0,\"homesteadBlock\":0,\"daoForkBlock\":0,\"daoForkSupport\":true,\"eip150Block\":0,\"eip150Hash\":\"0x2086799aeebeae135c246c65021c82b4e15a2c451340993aacfd2751886514f0\",\"eip155Block\":0,\"eip158Block\":0,\"byzantiumBlock\":0,\"constantinopleBlock\":0,\"petersburgBlock\":0,\"istanbulBlock\":0,\"muirGlacierBlock\":0,\"apricotPhase1BlockTimestamp\":0,\"apricotPhase2BlockTimestamp\":0,\"apricotPhase3BlockTimestamp\":0},\"nonce\":\"0x0\",\"timestamp\":\"0x0\",\"extraData\":\"0x00\",\"gasLimit\":\"0x5f5e100\",\"difficulty\":\"0x0\",\"mixHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\",\"coinbase\":\"0x0000000000000000000000000000000000000000\",\"alloc\":{\"0100000000000000000000000000000000000000\":{\"code\":\"0x7300000000000000000000000000000000000000003014608060405260043610603d5760003560e01c80631e010439146042578063b6510bb314606e575b600080fd5b605c60048036036020811015605657600080fd5b503560b1565b60408051918252519081900360200190f35b818015607957600080fd5b5060af60048036036080811015608e57600080fd5b506001600160a01b03813516906020810135906040810135906060013560b6565b005b30cd90565b836001600160a01b031681836108fc8690811502906040516000604051808303818888878c8acf9550505050505015801560f4573d6000803e3d6000fd5b505050505056fea26469706673582212201eebce970fe3f5cb96bf8ac6ba5f5c133fc2908ae3dcd51082cfee8f583429d064736f6c634300060a0033\",\"balance\":\"0x0\"}},\"number\":\"0x0\",\"gasUsed\":\"0x0\",\"parentHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\"}"
+	genesisJSONApricotPhase4 = "{\"config\":{\"chainId\":43111,\"atomicTxsTimestamp\":0,\"homesteadBlock\":0,\"daoForkBlock\":0,\"daoForkSupport\":true,\"eip150Block\":0,\"eip150Hash\":\"0x2086799aeebeae135c246c65021c82b4e15a2c451340993aacfd2751886514f0\",\"eip155Block\":0,\"eip158Block\":0,\"byzantiumBlock\":0,\"constantinopleBlock\":0,\"petersburgBlock\":0,\"istanbulBlock\":0,\"muirGlacierBlock\":0,\"apricotPhase1BlockTimestamp\":0,\"apricotPhase2BlockTimestamp\":0,\"apricotPhase3BlockTimestamp\":0,\"apricotPhase4BlockTimestamp\":0},\"nonce\":\"0x0\",\"timestamp\":\"0x0\",\"extraData\":\"0x00\",\"gasLimit\":\"0x5f5e100\",\"difficulty\":\"0x0\",\"mixHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\",\"coinbase\":\"0x0000000000000000000000000000000000000000\",\"alloc\":{\"0100000000000000000000000000000000000000\":{\"code\":\"0x7300000000000000000000000000000000000000003014608060405260043610603d5760003560e01c80631e010439146042578063b6510bb314606e575b600080fd5b605c60048036036020811015605657600080fd5b503560b1565b60408051918252519081900360200190f35b818015607957600080fd5b5060af60048036036080811015608e57600080fd5b506001600160a01b03813516906020810135906040810135906060013560b6565b005b30cd90565b836001600160a01b031681836108fc8690811502906040516000604051808303818888878c8acf9550505050505015801560f4573d6000803e3d6000fd5b505050505056fea26469706673582212201eebce970fe3f5cb96bf8ac6ba5f5c133fc2908ae3dcd51082cfee8f583429d064736f6c634300060a0033\",\"balance\":\"0x0\"}},\"number\":\"0x0\",\"gasUsed\":\"0x0\",\"parentHash\":\"0x0000000000000000000000000000000000000000000000000000000000000000\"}"
+	genesisJSONApricotPhase5 = strings.Replace(genesisJSONApricotPhase4, "\"apricotPhase4BlockTimestamp\":0", "\"apricotPhase4BlockTimestamp\":0,\"apricotPhase5BlockTimestamp\":0", 1)
+
+	apricotRulesPhase0 = params.Rules{IsAtomicTxs: true}
+	apricotRulesPhase1 = params.Rules{IsAtomicTxs: true, IsApricotPhase1: true}
+	apricotRulesPhase2 = params.Rules{IsAtomicTxs: true, IsApricotPhase1: true, IsApricotPhase2: true}
+	apricotRulesPhase3 = params.Rules{IsAtomicTxs: true, IsApricotPhase1: true, IsApricotPhase2: true, IsApricotPhase3: true}
+	apricotRulesPhase4 = params.Rules{IsAtomicTxs: true, IsApricotPhase1: true, IsApricotPhase2: true, IsApricotPhase3: true, IsApricotPhase4: true}
+	apricotRulesPhase5 = params.Rules{IsAtomicTxs: true, IsApricotPhase1: true, IsApricotPhase2: true, IsApricotPhase3: true, IsApricotPhase4: true, IsApricotPhase5: true}
 )
 
 func init() {
@@ -408,7 +413,7 @@ func TestIssueAtomicTxs(t *testing.T) {
 		}
 	}()
 
-	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -522,7 +527,7 @@ func TestBuildEthTxBlock(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm.newImportTx(vm.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -687,13 +692,13 @@ func TestConflictingImportTxs(t *testing.T) {
 	importTxs := make([]*Tx, 0, 3)
 	conflictTxs := make([]*Tx, 0, 3)
 	for i, key := range testKeys {
-		importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[i], initialBaseFee, []*crypto.PrivateKeySECP256K1R{key})
+		importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[i], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{key})
 		if err != nil {
 			t.Fatal(err)
 		}
 		importTxs = append(importTxs, importTx)
 
-		conflictTx, err := vm.newImportTx(vm.ctx.XChainID, conflictKey.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{key})
+		conflictTx, err := vm.newImportTx(vm.ctx.XChainID, conflictKey.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{key})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -794,7 +799,7 @@ func TestSetPreferenceRace(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1038,12 +1043,12 @@ func TestConflictingTransitiveAncestryWithGap(t *testing.T) {
 	newTxPoolHeadChan := make(chan core.NewTxPoolReorgEvent, 1)
 	vm.chain.GetTxPool().SubscribeNewReorgEvent(newTxPoolHeadChan)
 
-	importTx0A, err := vm.newImportTx(vm.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{key0})
+	importTx0A, err := vm.newImportTx(vm.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{key0})
 	if err != nil {
 		t.Fatal(err)
 	}
 	// Create a conflicting transaction
-	importTx0B, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[2], initialBaseFee, []*crypto.PrivateKeySECP256K1R{key0})
+	importTx0B, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[2], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{key0})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1101,7 +1106,7 @@ func TestConflictingTransitiveAncestryWithGap(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx1, err := vm.newImportTx(vm.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{key1})
+	importTx1, err := vm.newImportTx(vm.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{key1})
 	if err != nil {
 		t.Fatalf("Failed to issue importTx1 due to: %s", err)
 	}
@@ -1180,7 +1185,7 @@ func TestBonusBlocksTxs(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1273,7 +1278,7 @@ func TestReorgProtection(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1456,7 +1461,7 @@ func TestNonCanonicalAccept(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1632,7 +1637,7 @@ func TestStickyPreference(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1906,7 +1911,7 @@ func TestUncleBlock(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2095,7 +2100,7 @@ func TestEmptyBlock(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm.newImportTx(vm.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2177,7 +2182,7 @@ func TestAcceptReorg(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2379,7 +2384,7 @@ func TestFutureBlock(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm.newImportTx(vm.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2427,6 +2432,89 @@ func TestFutureBlock(t *testing.T) {
 	}
 }
 
+func TestApricotPhase5AtomicTxOrdering(t *testing.T) {
+	importAmount := uint64(1000000000)
+	issuer, vm, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase5, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: importAmount,
+		testShortIDAddrs[1]: importAmount,
+	})
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	txs := make([]*Tx, 2)
+	for i := range txs {
+		tx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[i], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[i]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs[i] = tx
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		iID, jID := txs[i].ID(), txs[j].ID()
+		return bytes.Compare(iID[:], jID[:]) < 0
+	})
+
+	if err := vm.issueTx(txs[0], true /*=local*/); err != nil {
+		t.Fatal(err)
+	}
+	<-issuer
+	blk, err := vm.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ethBlock := blk.(*chain.BlockWrapper).Block.(*Block).ethBlock
+
+	// From Apricot Phase 5, the extra data of a block is a list of atomic txs.
+	blkTxs, err := DecodeAtomicTxs(ethBlock.ExtData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blkTxs) != 1 || blkTxs[0].ID() != txs[0].ID() {
+		t.Fatalf("Expected block to contain only tx %s, found %d txs", txs[0].ID(), len(blkTxs))
+	}
+
+	// A block with its atomic txs out of order fails verification.
+	var gasUsed uint64
+	for _, tx := range txs {
+		txGasUsed, err := tx.GasUsed(vm.currentRules())
+		if err != nil {
+			t.Fatal(err)
+		}
+		gasUsed += txGasUsed
+	}
+	extData, err := EncodeAtomicTxs([]*Tx{txs[1], txs[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := types.CopyHeader(ethBlock.Header())
+	header.ExtDataGasUsed = new(big.Int).SetUint64(gasUsed)
+	unorderedEthBlock := types.NewBlock(header, nil, nil, nil, new(trie.Trie), extData, true)
+	unorderedBlock := &Block{
+		vm:       vm,
+		ethBlock: unorderedEthBlock,
+		id:       ids.ID(unorderedEthBlock.Hash()),
+	}
+	if err := unorderedBlock.Verify(); !errors.Is(err, errAtomicTxsNotSorted) {
+		t.Fatalf("Expected block with unordered atomic txs to fail with %s, found %v", errAtomicTxsNotSorted, err)
+	}
+
+	if err := blk.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.SetPreference(blk.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := blk.Accept(); err != nil {
+		t.Fatal(err)
+	}
+	if _, status, _, _ := vm.getAtomicTx(txs[0].ID()); status != Accepted {
+		t.Fatalf("Expected tx to be %s, found %s", Accepted, status)
+	}
+}
+
 // Regression test to ensure we can build blocks if we are starting with the
 // Apricot Phase 1 ruleset in genesis.
 func TestBuildApricotPhase1Block(t *testing.T) {
@@ -2448,7 +2536,7 @@ func TestBuildApricotPhase1Block(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm.newImportTx(vm.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2569,7 +2657,7 @@ func TestLastAcceptedBlockNumberAllow(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm.newImportTx(vm.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2647,7 +2735,7 @@ func TestReissueAtomicTx(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2973,7 +3061,7 @@ func TestBuildApricotPhase4Block(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	importTx, err := vm.newImportTx(vm.ctx.XChainID, key.Address, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	importTx, err := vm.newImportTx(vm.ctx.XChainID, key.Address, nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -3167,3 +3255,545 @@ func TestConsecutiveAtomicTransactionsRevertSnapshot(t *testing.T) {
 		t.Fatal("Expected build block to fail due to empty block")
 	}
 }
+
+func TestCanIssueAtomicTxMinFeePerGas(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase3, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	minFeePerGas := uint64(100)
+	vm.chainConfig.MinAtomicFeePerGas = new(big.Int).SetUint64(minFeePerGas)
+
+	// newTx returns an import tx burning [burned] nAVAX. The gas used by the
+	// tx does not depend on the amounts, so it is the same for every call.
+	importAmount := uint64(100_000_000)
+	newTx := func(burned uint64) *Tx {
+		tx := newTestImportTx(t, vm, avax.UTXOID{TxID: ids.GenerateTestID()}, importAmount)
+		tx.UnsignedAtomicTx.(*UnsignedImportTx).Outs[0].Amount = importAmount - burned
+		if err := tx.Sign(vm.codec, [][]*crypto.PrivateKeySECP256K1R{{testKeys[0]}}); err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	gasUsed, err := newTx(0).GasUsed(vm.currentRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp := big.NewInt(time.Now().Unix())
+	belowFloor := newTx(minFeePerGas*gasUsed - 1)
+	if err := vm.canIssueAtomicTx(belowFloor, timestamp); !errors.Is(err, errAtomicFeeTooLow) {
+		t.Fatalf("Expected tx just below the minimum fee per gas to fail with %s, found %v", errAtomicFeeTooLow, err)
+	}
+	atFloor := newTx(minFeePerGas * gasUsed)
+	if err := vm.canIssueAtomicTx(atFloor, timestamp); err != nil {
+		t.Fatalf("Expected tx paying the minimum fee per gas to be issuable, found %s", err)
+	}
+	aboveFloor := newTx((minFeePerGas + 1) * gasUsed)
+	if err := vm.canIssueAtomicTx(aboveFloor, timestamp); err != nil {
+		t.Fatalf("Expected tx just above the minimum fee per gas to be issuable, found %s", err)
+	}
+
+	// The minimum is not enforced before Apricot Phase 3.
+	vm.chainConfig.ApricotPhase3BlockTimestamp = new(big.Int).Add(timestamp, common.Big1)
+	if err := vm.canIssueAtomicTx(belowFloor, timestamp); err != nil {
+		t.Fatalf("Expected minimum fee per gas to be ignored before Apricot Phase 3, found %s", err)
+	}
+}
+
+func TestVerifyConfigCompatibility(t *testing.T) {
+	// genesisJSON returns a genesis with a timestamp of 100, so that Apricot
+	// Phase 4 is already active at the genesis block if it is scheduled
+	// before it, while Apricot Phase 5 is still pending.
+	genesisJSON := func(apricotPhase4, apricotPhase5 int64) []byte {
+		genesis := &core.Genesis{}
+		if err := json.Unmarshal([]byte(genesisJSONApricotPhase3), genesis); err != nil {
+			t.Fatal(err)
+		}
+		genesis.Timestamp = 100
+		genesis.Config.ApricotPhase4BlockTimestamp = big.NewInt(apricotPhase4)
+		genesis.Config.ApricotPhase5BlockTimestamp = big.NewInt(apricotPhase5)
+		genesisBytes, err := json.Marshal(genesis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return genesisBytes
+	}
+
+	issuer, vm, dbManager, _, _ := GenesisVM(t, false, string(genesisJSON(50, 1000)), "", "")
+	if err := vm.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	restart := func(genesisBytes []byte) error {
+		restartedVM := &VM{}
+		err := restartedVM.Initialize(
+			NewContext(),
+			dbManager,
+			genesisBytes,
+			[]byte(""),
+			[]byte(""),
+			issuer,
+			[]*engCommon.Fx{},
+			nil,
+		)
+		if err == nil {
+			if err := restartedVM.Shutdown(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return err
+	}
+
+	// Moving Apricot Phase 4 to another time before genesis does not change
+	// the rules of any block, since it is active from genesis either way.
+	if err := restart(genesisJSON(10, 1000)); err != nil {
+		t.Fatalf("Expected restart with Apricot Phase 4 moved before genesis to succeed, found %s", err)
+	}
+	// Moving Apricot Phase 4 after the last accepted block once it activated
+	// must be rejected.
+	if err := restart(genesisJSON(200, 1000)); !errors.Is(err, errIncompatibleChainConfig) {
+		t.Fatalf("Expected restart with Apricot Phase 4 moved forwards to fail with %s, found %v", errIncompatibleChainConfig, err)
+	}
+	// Moving a pending fork is allowed.
+	if err := restart(genesisJSON(50, 500)); err != nil {
+		t.Fatalf("Expected restart with a rescheduled pending fork to succeed, found %s", err)
+	}
+}
+
+func TestInitializePreparesChainConfig(t *testing.T) {
+	genesis := &core.Genesis{}
+	if err := json.Unmarshal([]byte(genesisJSONApricotPhase2), genesis); err != nil {
+		t.Fatal(err)
+	}
+	genesis.Timestamp = 100
+	genesis.Config.ApricotPhase3BlockTimestamp = big.NewInt(50)
+	genesis.Config.ApricotPhase4BlockTimestamp = big.NewInt(1000)
+	genesisJSON, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, vm, _, _, _ := GenesisVM(t, false, string(genesisJSON), "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Apricot Phase 3 was scheduled before genesis, so it is active from
+	// genesis, both in the config of the VM and in the stored config.
+	genesisHash := vm.chain.GetGenesisBlock().Hash()
+	for name, config := range map[string]*params.ChainConfig{
+		"VM":     vm.chainConfig,
+		"stored": rawdb.ReadChainConfig(vm.chaindb, genesisHash),
+	} {
+		if config.ApricotPhase3BlockTimestamp.Sign() != 0 || config.ApricotPhase4BlockTimestamp.Cmp(big.NewInt(1000)) != 0 {
+			t.Fatalf("Expected %s config to schedule Apricot Phase 3 at 0 and Apricot Phase 4 at 1000, found %s", name, config)
+		}
+	}
+}
+
+func TestInitializeMigratesChainConfig(t *testing.T) {
+	genesis := &core.Genesis{}
+	if err := json.Unmarshal([]byte(genesisJSONApricotPhase0), genesis); err != nil {
+		t.Fatal(err)
+	}
+	genesis.Config.ChainID = params.CostonChainID
+	genesisJSON, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, vm, _, _, _ := GenesisVM(t, false, string(genesisJSON), "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// The Apricot phases left unset by the genesis are scheduled as on Coston.
+	for _, id := range []params.ForkID{params.ApricotPhase1Fork, params.ApricotPhase2Fork, params.ApricotPhase3Fork, params.ApricotPhase4Fork} {
+		expected, err := params.CostonChainConfig.ForkTimestamp(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found, err := vm.chainConfig.ForkTimestamp(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.Cmp(expected) != 0 {
+			t.Fatalf("Expected %s to be scheduled at %d, found %v", id, expected, found)
+		}
+	}
+	if params.CostonChainConfig.ApricotPhase1BlockTimestamp == vm.chainConfig.ApricotPhase1BlockTimestamp {
+		t.Fatal("Expected the chain config to be a copy of the canonical config")
+	}
+}
+
+func TestVerifyChainIDConsistency(t *testing.T) {
+	config := *params.TestApricotPhase4Config
+	vm := &VM{chainID: new(big.Int).Set(config.ChainID), chainConfig: &config}
+	if err := vm.verifyChainIDConsistency(); err != nil {
+		t.Fatalf("Expected matching chain IDs to pass, found %s", err)
+	}
+
+	vm.chainID = new(big.Int).Add(config.ChainID, common.Big1)
+	err := vm.verifyChainIDConsistency()
+	if !errors.Is(err, errChainIDMismatch) {
+		t.Fatalf("Expected mismatched chain IDs to fail with %s, found %v", errChainIDMismatch, err)
+	}
+	if !strings.Contains(err.Error(), vm.chainID.String()) || !strings.Contains(err.Error(), config.ChainID.String()) {
+		t.Fatalf("Expected error to contain both chain IDs, found %q", err)
+	}
+
+	vm.chainID = nil
+	if err := vm.verifyChainIDConsistency(); !errors.Is(err, errChainIDMismatch) {
+		t.Fatalf("Expected missing VM chain ID to fail with %s, found %v", errChainIDMismatch, err)
+	}
+}
+
+func TestAllowUnprotectedTxs(t *testing.T) {
+	tests := map[string]struct {
+		chainAllows bool
+		nodeAllows  bool
+		expected    bool
+	}{
+		"neither":           {},
+		"chain config only": {chainAllows: true},
+		"node option only":  {nodeAllows: true},
+		"both":              {chainAllows: true, nodeAllows: true, expected: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			genesis := &core.Genesis{}
+			if err := json.Unmarshal([]byte(genesisJSONApricotPhase3), genesis); err != nil {
+				t.Fatal(err)
+			}
+			genesis.Config.AllowUnprotectedTxs = test.chainAllows
+			genesisJSON, err := json.Marshal(genesis)
+			if err != nil {
+				t.Fatal(err)
+			}
+			configJSON := fmt.Sprintf(`{"allow-unprotected-txs":%t}`, test.nodeAllows)
+
+			_, vm, _, _, _ := GenesisVM(t, false, string(genesisJSON), configJSON, "")
+			defer func() {
+				if err := vm.Shutdown(); err != nil {
+					t.Fatal(err)
+				}
+			}()
+			if allowed := vm.chain.APIBackend().UnprotectedAllowed(); allowed != test.expected {
+				t.Fatalf("Expected unprotected txs allowed to be %t, found %t", test.expected, allowed)
+			}
+		})
+	}
+}
+
+func TestLogPhaseActivations(t *testing.T) {
+	config := *params.TestApricotPhase2Config
+	config.ApricotPhase1BlockTimestamp = big.NewInt(0)
+	config.ApricotPhase2BlockTimestamp = big.NewInt(100)
+	config.ApricotPhase3BlockTimestamp = big.NewInt(200)
+
+	var records []*log.Record
+	logger := log.New()
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		records = append(records, r)
+		return nil
+	}))
+	logPhaseActivations(logger, &config, 150)
+
+	expected := []string{
+		"Apricot Phase 1 is active",
+		"Apricot Phase 2 is active",
+		"Apricot Phase 3 is pending",
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d log lines, found %d", len(expected), len(records))
+	}
+	for i, record := range records {
+		if record.Msg != expected[i] {
+			t.Fatalf("Expected log line %d to be %q, found %q", i, expected[i], record.Msg)
+		}
+		if record.Lvl != log.LvlInfo {
+			t.Fatalf("Expected log line %d to be logged at %s, found %s", i, log.LvlInfo, record.Lvl)
+		}
+	}
+	if activation := records[2].Ctx[1]; activation != "1970-01-01T00:03:20Z" {
+		t.Fatalf("Expected Apricot Phase 3 activation to be formatted as 1970-01-01T00:03:20Z, found %v", activation)
+	}
+}
+
+func TestIssueTxWithResult(t *testing.T) {
+	_, vm, _, _, _ := GenesisVM(t, true, genesisJSONApricotPhase0, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// The UTXO spent by [tx] was never added to shared memory, so it fails
+	// verification.
+	tx := newTestImportTx(t, vm, avax.UTXOID{TxID: ids.GenerateTestID()}, 1)
+	result, err := vm.IssueTxWithResult(tx)
+	if err == nil {
+		t.Fatal("Expected issuing an invalid tx to fail")
+	}
+	if result.TxID != tx.ID() || result.Status != IssueRejected {
+		t.Fatalf("Expected tx %s to be %s, found tx %s %s", tx.ID(), IssueRejected, result.TxID, result.Status)
+	}
+
+	if err := vm.mempool.ForceAddTx(tx); err != nil {
+		t.Fatal(err)
+	}
+	result, err = vm.IssueTxWithResult(tx)
+	if err != nil {
+		t.Fatalf("Expected issuing a processing tx to succeed, found %s", err)
+	}
+	if result.TxID != tx.ID() || result.Status != IssueKnown {
+		t.Fatalf("Expected tx %s to be %s, found tx %s %s", tx.ID(), IssueKnown, result.TxID, result.Status)
+	}
+}
+
+func TestRejectSiblingAfterReorgKeepsAtomicTxAccepted(t *testing.T) {
+	importAmount := uint64(1000000000)
+	issuer1, vm1, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase0, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: importAmount,
+	})
+	issuer2, vm2, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase0, "", "", map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: importAmount,
+	})
+	defer func() {
+		if err := vm1.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+		if err := vm2.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	importTx, err := vm1.newImportTx(vm1.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, []*crypto.PrivateKeySECP256K1R{testKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both VMs build a block containing [importTx], at different timestamps
+	// so that the blocks conflict.
+	if err := vm1.issueTx(importTx, true /*=local*/); err != nil {
+		t.Fatal(err)
+	}
+	<-issuer1
+	vm1.clock.Set(time.Unix(100, 0))
+	blkA, err := vm1.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vm2.issueTx(importTx, true /*=local*/); err != nil {
+		t.Fatal(err)
+	}
+	<-issuer2
+	vm2.clock.Set(time.Unix(105, 0))
+	vm2BlkB, err := vm2.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blkB, err := vm1.ParseBlock(vm2BlkB.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blkA.ID() == blkB.ID() {
+		t.Fatal("Expected the blocks built by each VM to conflict")
+	}
+
+	if err := blkA.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm1.SetPreference(blkA.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := blkB.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	// Reorg to [blkB] and accept it.
+	if err := vm1.SetPreference(blkB.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := blkB.Accept(); err != nil {
+		t.Fatal(err)
+	}
+	if _, height, err := vm1.getAcceptedAtomicTx(importTx.ID()); err != nil || height != blkB.Height() {
+		t.Fatalf("Expected tx to be indexed as accepted at height %d, found %d (err: %v)", blkB.Height(), height, err)
+	}
+
+	// Rejecting [blkA] leaves the tx accepted in [blkB] indexed.
+	if err := blkA.Reject(); err != nil {
+		t.Fatal(err)
+	}
+	if _, status, _, _ := vm1.getAtomicTx(importTx.ID()); status != Accepted {
+		t.Fatalf("Expected tx to remain %s after rejecting its sibling block, found %s", Accepted, status)
+	}
+}
+
+func TestVerifyAllowedAssets(t *testing.T) {
+	allowedAssetID := ids.GenerateTestID()
+	disallowedAssetID := ids.GenerateTestID()
+	configJSON := fmt.Sprintf(`{"allowed-atomic-assets": [%q]}`, allowedAssetID)
+
+	importTx := func(assetID ids.ID) *UnsignedImportTx {
+		return &UnsignedImportTx{
+			ImportedInputs: []*avax.TransferableInput{{
+				UTXOID: avax.UTXOID{TxID: ids.GenerateTestID()},
+				Asset:  avax.Asset{ID: assetID},
+				In:     &secp256k1fx.TransferInput{Amt: 1},
+			}},
+		}
+	}
+
+	for name, test := range map[string]struct {
+		configJSON  string
+		assetID     func(vm *VM) ids.ID
+		expectedErr error
+	}{
+		"native asset": {
+			configJSON: configJSON,
+			assetID:    func(vm *VM) ids.ID { return vm.ctx.AVAXAssetID },
+		},
+		"allowed asset": {
+			configJSON: configJSON,
+			assetID:    func(*VM) ids.ID { return allowedAssetID },
+		},
+		"disallowed asset": {
+			configJSON:  configJSON,
+			assetID:     func(*VM) ids.ID { return disallowedAssetID },
+			expectedErr: errAssetNotAllowed,
+		},
+		"empty registry": {
+			assetID: func(*VM) ids.ID { return disallowedAssetID },
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, vm, _, _, _ := GenesisVM(t, false, genesisJSONApricotPhase0, test.configJSON, "")
+			defer func() {
+				if err := vm.Shutdown(); err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			assetID := test.assetID(vm)
+			if err := vm.verifyAllowedAssets(importTx(assetID)); !errors.Is(err, test.expectedErr) {
+				t.Fatalf("Expected %v importing %s, found %v", test.expectedErr, assetID, err)
+			}
+			exportTx := &UnsignedExportTx{Ins: []EVMInput{{Address: testEthAddrs[0], Amount: 1, AssetID: assetID}}}
+			if err := vm.verifyAllowedAssets(exportTx); !errors.Is(err, test.expectedErr) {
+				t.Fatalf("Expected %v exporting %s, found %v", test.expectedErr, assetID, err)
+			}
+		})
+	}
+}
+
+func TestNextBlockTimestamp(t *testing.T) {
+	genesisJSON := strings.Replace(genesisJSONApricotPhase4, `"timestamp":"0x0"`, `"timestamp":"0x64"`, 1)
+	_, vm, _, _, _ := GenesisVM(t, false, genesisJSON, "", "")
+	defer func() {
+		if err := vm.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if parentTime := vm.chain.CurrentBlock().Time(); parentTime != 100 {
+		t.Fatalf("Expected parent timestamp 100, found %d", parentTime)
+	}
+
+	// The clock is behind the parent, so the next block must follow it.
+	vm.clock.Set(time.Unix(99, 0))
+	if timestamp, err := vm.nextBlockTimestamp(); err != nil || timestamp != 101 {
+		t.Fatalf("Expected next block timestamp 101, found %d (err: %v)", timestamp, err)
+	}
+
+	// Following the parent must not drift too far ahead of the clock.
+	vm.clock.Set(time.Unix(50, 0))
+	if _, err := vm.nextBlockTimestamp(); !errors.Is(err, errBlockTimestampTooFarAhead) {
+		t.Fatalf("Expected %s, found %v", errBlockTimestampTooFarAhead, err)
+	}
+
+	vm.clock.Set(time.Unix(200, 0))
+	if timestamp, err := vm.nextBlockTimestamp(); err != nil || timestamp != 200 {
+		t.Fatalf("Expected next block timestamp 200, found %d (err: %v)", timestamp, err)
+	}
+
+	// The miner refuses to build a block that is not after its parent.
+	if _, err := vm.chain.GenerateBlockAt(100); err == nil {
+		t.Fatal("Expected generating a block at the parent timestamp to fail")
+	}
+
+	// Following the parent would activate the fee manager while the clock
+	// hasn't reached it, so the block must be built later.
+	genesisJSON = strings.Replace(genesisJSON, `"apricotPhase4BlockTimestamp":0`, `"apricotPhase4BlockTimestamp":0,"feeManagerActivationTimestamp":101`, 1)
+	_, vm2, _, _, _ := GenesisVM(t, false, genesisJSON, "", "")
+	defer func() {
+		if err := vm2.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	vm2.clock.Set(time.Unix(99, 0))
+	if _, err := vm2.nextBlockTimestamp(); !errors.Is(err, errForkNotReached) {
+		t.Fatalf("Expected %s, found %v", errForkNotReached, err)
+	}
+	vm2.clock.Set(time.Unix(101, 0))
+	if timestamp, err := vm2.nextBlockTimestamp(); err != nil || timestamp != 101 {
+		t.Fatalf("Expected next block timestamp 101, found %d (err: %v)", timestamp, err)
+	}
+}
+
+func TestAtomicTxsFork(t *testing.T) {
+	importAmount := uint64(50000000)
+	utxos := map[ids.ShortID]uint64{
+		testShortIDAddrs[0]: importAmount,
+	}
+	_, enabledVM, _, _, _ := GenesisVMWithUTXOs(t, true, genesisJSONApricotPhase2, "", "", utxos)
+	defer func() {
+		if err := enabledVM.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	// Atomic txs are disabled unless the atomic txs fork is scheduled
+	disabledGenesisJSON := strings.Replace(genesisJSONApricotPhase2, `"atomicTxsTimestamp":0,`, "", 1)
+	_, disabledVM, _, _, _ := GenesisVMWithUTXOs(t, true, disabledGenesisJSON, "", "", utxos)
+	defer func() {
+		if err := disabledVM.Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	keys := []*crypto.PrivateKeySECP256K1R{testKeys[0]}
+	importTx, err := enabledVM.newImportTx(enabledVM.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := enabledVM.LastAcceptedBlockInternal().(*Block)
+	if err := importTx.UnsignedAtomicTx.SemanticVerify(enabledVM, importTx, parent, initialBaseFee, enabledVM.currentRules()); err != nil {
+		t.Fatalf("Expected import tx to be valid when atomic txs are enabled, found %s", err)
+	}
+	if err := enabledVM.issueTx(importTx, true /*=local*/); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same tx is rejected by a VM whose chain config does not activate
+	// atomic txs, although the UTXO it spends is in shared memory.
+	if _, err := disabledVM.newImportTx(disabledVM.ctx.XChainID, testEthAddrs[0], nil, nil, initialBaseFee, keys); !errors.Is(err, errAtomicTxDisabled) {
+		t.Fatalf("Expected building an import tx to fail with %s, found %v", errAtomicTxDisabled, err)
+	}
+	if _, err := disabledVM.newExportTx(disabledVM.ctx.AVAXAssetID, 1, disabledVM.ctx.XChainID, testShortIDAddrs[0], initialBaseFee, keys); !errors.Is(err, errAtomicTxDisabled) {
+		t.Fatalf("Expected building an export tx to fail with %s, found %v", errAtomicTxDisabled, err)
+	}
+	parent = disabledVM.LastAcceptedBlockInternal().(*Block)
+	if err := importTx.UnsignedAtomicTx.SemanticVerify(disabledVM, importTx, parent, initialBaseFee, disabledVM.currentRules()); !errors.Is(err, errAtomicTxDisabled) {
+		t.Fatalf("Expected verifying an import tx to fail with %s, found %v", errAtomicTxDisabled, err)
+	}
+	if err := disabledVM.issueTx(importTx, true /*=local*/); !errors.Is(err, errAtomicTxDisabled) {
+		t.Fatalf("Expected issuing an import tx to fail with %s, found %v", errAtomicTxDisabled, err)
+	}
+}